@@ -0,0 +1,103 @@
+// Package apperrors defines the kinds of errors repositories and services report and the codes
+// clients switch on. The kind decides the HTTP status, the code names the exact cause and never
+// changes once released.
+package apperrors
+
+import "errors"
+
+// Kinds of errors, test for them with errors.Is
+var (
+	ErrNotFound     = errors.New("not found")           // The resource does not exist or is in the trash
+	ErrConflict     = errors.New("conflict")            // The request clashes with what is stored, such as a taken name
+	ErrValidation   = errors.New("validation failed")   // The request is malformed or breaks a rule
+	ErrForbidden    = errors.New("forbidden")           // The caller may not do this
+	ErrUnauthorized = errors.New("unauthorized")        // The caller is not, or no longer, signed in
+	ErrPrecondition = errors.New("precondition failed") // The document changed since the client read it
+)
+
+// FieldError explains why a single field of a request was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error of one of the kinds above. Errors without a kind are internal errors.
+type Error struct {
+	Kind       error                  // One of the kinds above, nil for internal errors
+	Code       string                 // Machine-readable code such as "trainee_not_found"
+	Message    string                 // Explanation for people
+	Fields     []FieldError           // The fields that failed validation, if any
+	Extensions map[string]interface{} // More members of the problem response, such as the current document
+	Err        error                  // The underlying error, if any
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil && e.Kind == nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// With returns a copy of the error carrying another member of the problem response
+func (e *Error) With(key string, value interface{}) *Error {
+	copied := *e
+	copied.Extensions = map[string]interface{}{key: value}
+	for k, v := range e.Extensions {
+		if k != key {
+			copied.Extensions[k] = v
+		}
+	}
+	return &copied
+}
+
+func NotFound(code string, message string) *Error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: message}
+}
+
+func Conflict(code string, message string) *Error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message}
+}
+
+func Validation(code string, message string) *Error {
+	return &Error{Kind: ErrValidation, Code: code, Message: message}
+}
+
+func Forbidden(code string, message string) *Error {
+	return &Error{Kind: ErrForbidden, Code: code, Message: message}
+}
+
+func Unauthorized(code string, message string) *Error {
+	return &Error{Kind: ErrUnauthorized, Code: code, Message: message}
+}
+
+func PreconditionFailed(code string, message string) *Error {
+	return &Error{Kind: ErrPrecondition, Code: code, Message: message}
+}
+
+// InvalidFields reports a request some of whose fields failed validation
+func InvalidFields(fields ...FieldError) *Error {
+	return &Error{Kind: ErrValidation, Code: "validation_failed", Message: "Validation failed", Fields: fields}
+}
+
+// Internal wraps an unexpected error. The message is what the client sees, the cause is only logged.
+func Internal(message string, err error) *Error {
+	return &Error{Code: "internal_error", Message: message, Err: err}
+}
+
+// As returns err as an Error if it is or wraps one
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
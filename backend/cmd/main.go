@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"ironcoach/database"
+	"ironcoach/mailer"
+	"ironcoach/migrations"
+	"ironcoach/repositories"
 	"ironcoach/routes"
+	"ironcoach/services"
+	"log"
 	"os"
 	"time"
+	_ "time/tzdata" // Trainer time zones must load on hosts without a time zone database
 
 	"ironcoach/middlewares"
 
@@ -13,7 +20,39 @@ import (
 )
 
 func main() {
-	database.ConnectDB()
+	db := database.ConnectDB().Database("ironcoach")
+
+	// Bring the stored data up to date before serving it. Set MIGRATE_ON_START=false to leave that to cmd/migrate.
+	if os.Getenv("MIGRATE_ON_START") != "false" {
+		results, err := migrations.New(db).Up(context.Background(), 0, false)
+		for _, result := range results {
+			log.Printf("Applied migration %04d %s, %d documents changed", result.Version, result.Description, result.Changed)
+		}
+		if err == migrations.ErrLocked {
+			log.Println("Another instance is running migrations, starting without waiting for them")
+		} else if err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+
+	// Create the indexes queries, uniqueness and search rely on. One that cannot be created is
+	// reported and the server starts anyway, slower or without that uniqueness guarantee.
+	indexes, err := repositories.EnsureIndexes(db, true)
+	if err != nil {
+		log.Fatalf("Failed to check indexes: %v", err)
+	}
+	for _, index := range indexes {
+		switch index.State {
+		case repositories.IndexCreated:
+			log.Printf("Created index %s.%s", index.Collection, index.Name)
+		case repositories.IndexMissing:
+			log.Printf("Index %s.%s is missing: %v", index.Collection, index.Name, index.Err)
+		case repositories.IndexExtra:
+			log.Printf("Index %s.%s is not declared by any repository", index.Collection, index.Name)
+		}
+	}
+
+	app := services.New(repositories.NewMongoRepositories(db), mailer.NewFromEnv())
 
 	router := gin.Default()
 
@@ -26,13 +65,16 @@ func main() {
 			"https://ironcoach--ironcoach-staging.expo.app",
 			"https://ironcoach.expo.app",
 		},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", "Idempotency-Key"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "Idempotent-Replayed"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Answer the errors of handlers and middlewares as problem+json
+	router.Use(middlewares.Problems())
+
 	// Global rate limiting: e.g., 120 requests per minute per IP, burst 60
 	router.Use(middlewares.RateLimitMiddleware(150, time.Minute, 25))
 
@@ -42,14 +84,16 @@ func main() {
 	})
 
 	// Register routes
-	routes.RegisterTrainerRoutes(router)
-	routes.RegisterTraineeRoutes(router)
-	routes.RegisterWorkoutLogRoutes(router)
-	routes.RegisterCategoryRoutes(router)
-	routes.RegisterExerciseRoutes(router)
-	routes.RegisterDietEntryRoutes(router)
-	routes.RegisterProgressRoutes(router)
-	routes.RegisterImageRoutes(router)
+	routes.RegisterRoutes(router, app)
+
+	// Permanently delete what has been in the trash for longer than the retention window
+	app.Trash.StartPurger(services.TrashRetention())
+
+	// Forget idempotency keys and their responses once they expire
+	app.Idempotency.StartCleanup()
+
+	// Encrypt health data written before encryption was enabled or sealed with a retired key
+	app.Trainees.StartResealing()
 
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
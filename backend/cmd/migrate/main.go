@@ -0,0 +1,123 @@
+// Command migrate applies and rolls back the versioned database migrations and checks the indexes.
+//
+//	migrate [-dry-run] status
+//	migrate [-dry-run] up [version]    apply pending migrations, all of them or up to the version
+//	migrate [-dry-run] down [version]  roll back the latest migration, or every one above the version
+//	migrate [-dry-run] indexes         create missing indexes and list missing and extra ones
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"ironcoach/database"
+	"ironcoach/migrations"
+	"ironcoach/repositories"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing anything")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate [-dry-run] status | up [version] | down [version] | indexes")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command, target, err := parseArgs(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db := database.ConnectDB().Database("ironcoach")
+	migrator := migrations.New(db)
+	ctx := context.Background()
+
+	var results []migrations.Result
+	switch command {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migrations: %v", err)
+		}
+		for _, status := range statuses {
+			applied := ""
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-9s  %-25s  %s\n", status.Version, status.State, applied, status.Description)
+		}
+		return
+	case "indexes":
+		indexes, err := repositories.EnsureIndexes(db, !*dryRun)
+		if err != nil {
+			log.Fatalf("Failed to check indexes: %v", err)
+		}
+		for _, index := range indexes {
+			line := fmt.Sprintf("%-16s  %-20s  %s", index.Collection, index.Name, index.State)
+			if index.Err != nil {
+				line += "  " + index.Err.Error()
+			}
+			fmt.Println(line)
+		}
+		return
+	case "up":
+		results, err = migrator.Up(ctx, target, *dryRun)
+	case "down":
+		if target < 0 {
+			if target, err = migrator.Previous(ctx); err != nil {
+				log.Fatalf("Failed to read migrations: %v", err)
+			}
+		}
+		results, err = migrator.Down(ctx, target, *dryRun)
+	}
+
+	verb := "changed"
+	if *dryRun {
+		verb = "would change"
+	}
+	for _, result := range results {
+		fmt.Printf("%04d  %-4s  %s %d documents in %s  %s\n", result.Version, result.Direction, verb, result.Changed, result.Duration.Round(time.Millisecond), result.Description)
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	if len(results) == 0 {
+		fmt.Println("Nothing to do")
+	}
+}
+
+// parseArgs reads the command and its optional target version. Without a version down rolls back
+// a single migration, which is marked by a target of -1.
+func parseArgs(args []string) (string, int, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", 0, fmt.Errorf("expected a command")
+	}
+
+	command, target := args[0], 0
+	if command == "down" {
+		target = -1
+	}
+	switch command {
+	case "status", "indexes":
+		if len(args) > 1 {
+			return "", 0, fmt.Errorf("%s takes no version", command)
+		}
+	case "up", "down":
+		if len(args) == 2 {
+			version, err := strconv.Atoi(args[1])
+			if err != nil || version < 0 {
+				return "", 0, fmt.Errorf("invalid version %q", args[1])
+			}
+			target = version
+		}
+	default:
+		return "", 0, fmt.Errorf("unknown command %q", command)
+	}
+	return command, target, nil
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/services"
+	"ironcoach/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	service *services.AuditService
+}
+
+// Constructor for AuditController
+func NewAuditController(service *services.AuditService) *AuditController {
+	return &AuditController{
+		service: service,
+	}
+}
+
+// List audit entries, filtered by trainee_id, resource_type, resource_id, actor and a from/to date range
+func (ctrl *AuditController) GetAuditLog(c *gin.Context) {
+	filter := services.AuditFilter{
+		TraineeID:    c.Query("trainee_id"),
+		ResourceType: c.Query("resource_type"),
+		ResourceID:   c.Query("resource_id"),
+		Actor:        c.Query("actor"),
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(c.Query("from"), false); err != nil {
+		respondFieldErrors(c, []apperrors.FieldError{{Field: "from", Message: "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"}})
+		return
+	}
+	if filter.To, err = parseAuditTime(c.Query("to"), true); err != nil {
+		respondFieldErrors(c, []apperrors.FieldError{{Field: "to", Message: "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"}})
+		return
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if filter.Limit, err = strconv.ParseInt(limit, 10, 64); err != nil || filter.Limit <= 0 {
+			respondFieldErrors(c, []apperrors.FieldError{{Field: "limit", Message: "must be a positive number"}})
+			return
+		}
+	}
+
+	entries, err := ctrl.service.GetAuditLog(c.MustGet("identity").(utils.Identity), filter)
+	if err != nil {
+		switch err {
+		case services.ErrResourceNotFound:
+			c.Error(errTraineeNotFound)
+		case services.ErrAccessDenied:
+			c.Error(errNoAccess)
+		default:
+			respondError(c, err, "Failed to fetch audit log")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// parseAuditTime accepts a plain date or a full timestamp. A plain date used as the end of a
+// range covers the whole day.
+func parseAuditTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		parsed = parsed.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &parsed, nil
+}
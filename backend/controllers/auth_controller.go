@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"ironcoach/services"
+	"ironcoach/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthController struct {
+	service               *services.SessionService
+	accountService        *services.AccountService
+	traineeAccountService *services.TraineeAccountService
+}
+
+// Constructor for AuthController
+func NewAuthController(service *services.SessionService, accountService *services.AccountService, traineeAccountService *services.TraineeAccountService) *AuthController {
+	return &AuthController{
+		service:               service,
+		accountService:        accountService,
+		traineeAccountService: traineeAccountService,
+	}
+}
+
+// TraineeLogin signs a trainee in with the invite code and PIN issued by their trainer
+func (ctrl *AuthController) TraineeLogin(c *gin.Context) {
+	var request struct {
+		InviteCode string `json:"invite_code" binding:"required"`
+		PIN        string `json:"pin" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	tokens, err := ctrl.traineeAccountService.Login(request.InviteCode, request.PIN)
+	if err != nil {
+		respondError(c, err, "Failed to log in")
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh exchanges a refresh token for a new access/refresh token pair
+func (ctrl *AuthController) Refresh(c *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	tokens, err := ctrl.service.Refresh(request.RefreshToken)
+	if err != nil {
+		respondError(c, err, "Failed to refresh token")
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the session of the current access token
+func (ctrl *AuthController) Logout(c *gin.Context) {
+	if err := ctrl.service.Logout(c.MustGet("session_id").(string)); err != nil {
+		respondError(c, err, "Failed to log out")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every session of the logged-in trainer or trainee
+func (ctrl *AuthController) LogoutAll(c *gin.Context) {
+	if err := ctrl.service.LogoutAll(c.MustGet("identity").(utils.Identity)); err != nil {
+		respondError(c, err, "Failed to log out")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
+
+// ForgotPassword emails a password reset link. It always succeeds so it cannot reveal which emails exist.
+func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
+	var request struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if err := ctrl.accountService.ForgotPassword(request.Email); err != nil {
+		respondError(c, err, "Failed to send password reset email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a reset token
+func (ctrl *AuthController) ResetPassword(c *gin.Context) {
+	var request struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=5"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if err := ctrl.accountService.ResetPassword(request.Token, request.Password); err != nil {
+		respondError(c, err, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// VerifyEmail confirms a trainer's email address using a verification token
+func (ctrl *AuthController) VerifyEmail(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if err := ctrl.accountService.VerifyEmail(request.Token); err != nil {
+		respondError(c, err, "Failed to verify email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a fresh verification email to the logged-in trainer
+func (ctrl *AuthController) ResendVerification(c *gin.Context) {
+	if err := ctrl.accountService.SendVerificationEmail(c.MustGet("email").(string)); err != nil {
+		respondError(c, err, "Failed to send verification email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
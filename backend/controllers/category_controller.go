@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/services"
+	"ironcoach/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,16 +15,16 @@ type CategoryController struct {
 }
 
 // Constructor for TraineeController
-func NewCategoryController() *CategoryController {
+func NewCategoryController(service *services.CategoryService) *CategoryController {
 	return &CategoryController{
-		service: services.NewCategoryService(),
+		service: service,
 	}
 }
 
 func (c *CategoryController) AddCategory(ctx *gin.Context) {
 	var category models.Category
 	if err := ctx.ShouldBindJSON(&category); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(ctx, err)
 		return
 	}
 
@@ -31,11 +33,15 @@ func (c *CategoryController) AddCategory(ctx *gin.Context) {
 	category.TrainerID = trainerID
 
 	if err := c.service.AddCategory(category); err != nil {
-		if err.Error() == "category already exists for this trainer" {
-			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		if err == services.ErrResourceNotFound {
+			ctx.Error(errOrganizationNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add category"})
+		if err == services.ErrAccessDenied {
+			ctx.Error(apperrors.Forbidden("access_denied", "Only owners and coaches can add to the organization library"))
+			return
+		}
+		respondError(ctx, err, "Failed to add category")
 		return
 	}
 
@@ -43,10 +49,11 @@ func (c *CategoryController) AddCategory(ctx *gin.Context) {
 }
 
 func (c *CategoryController) GetCategories(ctx *gin.Context) {
-	trainerID := ctx.MustGet("email").(string)
-	categories, err := c.service.GetCategories(trainerID)
+	// Trainees read the library of the trainer who coaches them
+	identity := ctx.MustGet("identity").(utils.Identity)
+	categories, err := c.service.GetCategories(identity)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve categories"})
+		respondError(ctx, err, "Failed to retrieve categories")
 		return
 	}
 
@@ -55,21 +62,16 @@ func (c *CategoryController) GetCategories(ctx *gin.Context) {
 
 func (c *CategoryController) UpdateCategory(ctx *gin.Context) {
 	id := ctx.Param("id")
-	trainerEmail := ctx.MustGet("email").(string)
 	var body struct {
 		Name string `json:"name" binding:"required"`
 	}
 	if err := ctx.ShouldBindJSON(&body); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(ctx, err)
 		return
 	}
 
-	if err := c.service.UpdateCategory(id, body.Name, trainerEmail); err != nil {
-		if err.Error() == "category already exists for this trainer" {
-			ctx.JSON(http.StatusConflict, gin.H{"error": "Category name already exists"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+	if err := c.service.UpdateCategory(id, body.Name); err != nil {
+		respondError(ctx, err, "Failed to update category")
 		return
 	}
 
@@ -78,10 +80,11 @@ func (c *CategoryController) UpdateCategory(ctx *gin.Context) {
 
 func (c *CategoryController) DeleteCategory(ctx *gin.Context) {
 	id := ctx.Param("id")
-	if err := c.service.DeleteCategory(id); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+	summary, err := c.service.DeleteCategory(id)
+	if err != nil {
+		respondError(ctx, err, "Failed to delete category")
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully", "deleted": summary})
 }
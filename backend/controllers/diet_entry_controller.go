@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/services"
+	"ironcoach/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,9 +15,9 @@ type DietEntryController struct {
 }
 
 // Constructor for DietEntryController
-func NewDietEntryController() *DietEntryController {
+func NewDietEntryController(service *services.DietEntryService) *DietEntryController {
 	return &DietEntryController{
-		service: services.NewDietEntryService(),
+		service: service,
 	}
 }
 
@@ -24,31 +26,34 @@ func (ctrl *DietEntryController) AddDietEntry(c *gin.Context) {
 	var entry models.DietEntry
 
 	if err := c.ShouldBindJSON(&entry); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	if err := ctrl.service.AddDietEntry(entry); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add diet entry"})
+		respondError(c, err, "Failed to add diet entry")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Diet entry added successfully"})
 }
 
-// Get all diet entries for a trainee
+// Get a page of the diet entries of a trainee, newest first by default, optionally within a from/to date range
 func (ctrl *DietEntryController) GetDietEntries(c *gin.Context) {
 	traineeID := c.Param("trainee_id")
-	date := c.Query("date")
 
 	if traineeID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "trainee_id is required"})
+		respondFieldErrors(c, []apperrors.FieldError{{Field: "trainee_id", Message: "is required"}})
+		return
+	}
+	list, ok := bindList(c, "desc", "date", "created_at", "updated_at")
+	if !ok || !bindDateRange(c, &list) {
 		return
 	}
 
-	entries, err := ctrl.service.GetDietEntriesByTrainee(traineeID, date)
+	entries, err := ctrl.service.GetDietEntriesByTrainee(traineeID, list)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch diet entries"})
+		respondListError(c, err, "Failed to fetch diet entries")
 		return
 	}
 
@@ -60,16 +65,17 @@ func (ctrl *DietEntryController) GetDietEntryByID(c *gin.Context) {
 	entryID := c.Param("entry_id")
 
 	if entryID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "entry_id is required"})
+		respondFieldErrors(c, []apperrors.FieldError{{Field: "entry_id", Message: "is required"}})
 		return
 	}
 
 	entry, err := ctrl.service.GetDietEntryByID(entryID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch diet entries"})
+		respondError(c, err, "Failed to fetch diet entries")
 		return
 	}
 
+	c.Header("ETag", utils.ETag(entry.Version))
 	c.JSON(http.StatusOK, entry)
 }
 
@@ -80,20 +86,34 @@ func (ctrl *DietEntryController) UpdateDietEntry(c *gin.Context) {
 
     // Bind the incoming JSON to the DietEntry struct
     if err := c.ShouldBindJSON(&updateData); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        respondBindError(c, err)
+        return
+    }
+
+    // The update replaces the entry, it would otherwise lose its date
+    if updateData.Date.IsZero() {
+        respondFieldErrors(c, []apperrors.FieldError{{Field: "date", Message: "is required"}})
         return
     }
 
+    // Keep the entry attached to the trainee it was authorized for
+    updateData.TraineeID = c.GetString("authorized_trainee_id")
+
     // Call the service to update the diet entry
-    if err := ctrl.service.UpdateDietEntry(entryID, updateData); err != nil {
-        if err.Error() == "not found" {
-            c.JSON(http.StatusNotFound, gin.H{"error": "Diet entry not found"})
-        } else {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update diet entry"})
+    version := ifMatch(c)
+    if err := ctrl.service.UpdateDietEntry(entryID, version, updateData); err != nil {
+        switch err {
+        case services.ErrVersionConflict:
+            ctrl.respondConflict(c, entryID)
+        case services.ErrResourceNotFound:
+            c.Error(errDietEntryNotFound)
+        default:
+            respondError(c, err, "Failed to update diet entry")
         }
         return
     }
 
+    c.Header("ETag", utils.ETag(version+1))
     c.JSON(http.StatusOK, gin.H{"message": "Diet entry updated successfully"})
 }
 
@@ -102,10 +122,23 @@ func (ctrl *DietEntryController) UpdateDietEntry(c *gin.Context) {
 func (ctrl *DietEntryController) DeleteDietEntry(c *gin.Context) {
 	entryID := c.Param("entry_id")
 
-	if err := ctrl.service.DeleteDietEntry(entryID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete diet entry"})
+	if err := ctrl.service.DeleteDietEntry(entryID, ifMatch(c)); err != nil {
+		switch err {
+		case services.ErrVersionConflict:
+			ctrl.respondConflict(c, entryID)
+		case services.ErrResourceNotFound:
+			c.Error(errDietEntryNotFound)
+		default:
+			respondError(c, err, "Failed to delete diet entry")
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Diet entry deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Diet entry moved to trash"})
+}
+
+// respondConflict sends the entry as it is now to a client that tried to change an older version
+func (ctrl *DietEntryController) respondConflict(c *gin.Context, entryID string) {
+	entry, err := ctrl.service.GetDietEntryByID(entryID)
+	respondVersionConflict(c, entry, entry.Version, err)
 }
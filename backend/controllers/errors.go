@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError hands err to the Problems middleware, which answers with its problem+json. Errors
+// that are not domain errors become internal errors, message is all the client learns of them.
+func respondError(c *gin.Context, err error, message string) {
+	if _, ok := apperrors.As(err); !ok {
+		err = apperrors.Internal(message, err)
+	}
+	c.Error(err)
+}
+
+// respondBindError answers a request body that could not be decoded or failed its binding rules
+func respondBindError(c *gin.Context, err error) {
+	c.Error(apperrors.Validation("invalid_body", err.Error()))
+}
+
+// Errors several handlers answer with, named after what the request was about
+var (
+	errTraineeNotFound      = apperrors.NotFound("trainee_not_found", "Trainee not found")
+	errTrainerNotFound      = apperrors.NotFound("trainer_not_found", "Trainer not found")
+	errOrganizationNotFound = apperrors.NotFound("organization_not_found", "Organization not found")
+	errDietEntryNotFound    = apperrors.NotFound("diet_entry_not_found", "Diet entry not found")
+	errNoAccess             = apperrors.Forbidden("access_denied", "You do not have access to this resource")
+)
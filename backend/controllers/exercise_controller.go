@@ -3,6 +3,7 @@ package controllers
 import (
 	"ironcoach/models"
 	"ironcoach/services"
+	"ironcoach/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,29 +14,21 @@ type ExerciseController struct {
 }
 
 // Constructor for ExerciseController
-func NewExerciseController() *ExerciseController {
+func NewExerciseController(service *services.ExerciseService) *ExerciseController {
 	return &ExerciseController{
-		service: services.NewExerciseService(),
+		service: service,
 	}
 }
 
 func (c *ExerciseController) AddExercise(ctx *gin.Context) {
 	var exercise models.Exercise
 	if err := ctx.ShouldBindJSON(&exercise); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(ctx, err)
 		return
 	}
 
 	if err := c.service.AddExercise(exercise); err != nil {
-		if err.Error() == "category does not exist" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Category does not exist"})
-			return
-		}
-		if err.Error() == "exercise already exists in this category" {
-			ctx.JSON(http.StatusConflict, gin.H{"error": "Exercise already exists in this category"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err, "Failed to add exercise")
 		return
 	}
 
@@ -44,17 +37,18 @@ func (c *ExerciseController) AddExercise(ctx *gin.Context) {
 
 func (c *ExerciseController) GetExercisesByCategory(ctx *gin.Context) {
 	category := ctx.Param("category")
-	trainerEmail := ctx.MustGet("email").(string)
+	// Trainees read the library of the trainer who coaches them
+	identity := ctx.MustGet("identity").(utils.Identity)
 
-	categoryID, err := c.service.GetCategoryIDByName(category, trainerEmail)
+	categoryID, err := c.service.GetCategoryIDByName(category, identity)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve category ID"})
+		respondError(ctx, err, "Failed to retrieve category ID")
 		return
 	}
 
 	exercises, err := c.service.GetExercisesByCategoryID(categoryID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exercises"})
+		respondError(ctx, err, "Failed to retrieve exercises")
 		return
 	}
 
@@ -67,28 +61,36 @@ func (c *ExerciseController) UpdateExercise(ctx *gin.Context) {
 		Name string `json:"name" binding:"required"`
 	}
 	if err := ctx.ShouldBindJSON(&body); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(ctx, err)
 		return
 	}
 
 	if err := c.service.UpdateExercise(id, body.Name); err != nil {
-		if err.Error() == "exercise already exists in this category" {
-			ctx.JSON(http.StatusConflict, gin.H{"error": "Exercise name already exists in this category"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exercise"})
+		respondError(ctx, err, "Failed to update exercise")
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Exercise updated successfully"})
 }
 
+// DeleteExercise archives the exercise by default, ?mode=pull deletes it and removes its entries from workout logs
 func (c *ExerciseController) DeleteExercise(ctx *gin.Context) {
 	id := ctx.Param("id")
-	if err := c.service.DeleteExercise(id); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exercise"})
+	mode := ctx.DefaultQuery("mode", services.ExerciseDeleteArchive)
+
+	deletion, err := c.service.DeleteExercise(id, mode)
+	if err != nil {
+		if err == services.ErrInvalidExerciseDeleteMode {
+			respondBindError(ctx, err)
+			return
+		}
+		respondError(ctx, err, "Failed to delete exercise")
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Exercise deleted successfully"})
+	message := "Exercise archived successfully"
+	if mode == services.ExerciseDeletePull {
+		message = "Exercise deleted successfully"
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": message, "affected": deletion})
 }
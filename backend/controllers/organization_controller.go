@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationController struct {
+	service *services.OrganizationService
+}
+
+// Constructor for OrganizationController
+func NewOrganizationController(service *services.OrganizationService) *OrganizationController {
+	return &OrganizationController{
+		service: service,
+	}
+}
+
+// Create an organization owned by the calling trainer
+func (ctrl *OrganizationController) CreateOrganization(c *gin.Context) {
+	var request struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	organization, err := ctrl.service.CreateOrganization(request.Name, c.MustGet("email").(string))
+	if err != nil {
+		respondError(c, err, "Failed to create organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+// Get the organizations the calling trainer is a member of
+func (ctrl *OrganizationController) GetOrganizations(c *gin.Context) {
+	organizations, err := ctrl.service.GetOrganizations(c.MustGet("email").(string))
+	if err != nil {
+		respondError(c, err, "Failed to fetch organizations")
+		return
+	}
+
+	c.JSON(http.StatusOK, organizations)
+}
+
+// Get an organization with its members
+func (ctrl *OrganizationController) GetOrganizationByID(c *gin.Context) {
+	organization, err := ctrl.service.GetOrganizationByID(c.Param("org_id"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+// Add an existing trainer to the organization
+func (ctrl *OrganizationController) AddMember(c *gin.Context) {
+	var request struct {
+		Email string `json:"email" binding:"required,email"`
+		Role  string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if err := ctrl.service.AddMember(c.Param("org_id"), request.Email, request.Role); err != nil {
+		respondMemberError(c, err, "Failed to add member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member added successfully"})
+}
+
+// Change the role of a member
+func (ctrl *OrganizationController) UpdateMemberRole(c *gin.Context) {
+	var request struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if err := ctrl.service.UpdateMemberRole(c.Param("org_id"), c.Param("email"), request.Role); err != nil {
+		respondMemberError(c, err, "Failed to update member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member updated successfully"})
+}
+
+// Remove a member from the organization
+func (ctrl *OrganizationController) RemoveMember(c *gin.Context) {
+	if err := ctrl.service.RemoveMember(c.Param("org_id"), c.Param("email")); err != nil {
+		respondMemberError(c, err, "Failed to remove member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+func respondMemberError(c *gin.Context, err error, fallback string) {
+	switch err {
+	case services.ErrInvalidOrganizationRole:
+		respondBindError(c, err)
+	case services.ErrResourceNotFound:
+		c.Error(apperrors.NotFound("member_not_found", "Member not found"))
+	default:
+		respondError(c, err, fallback)
+	}
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindList reads the cursor, limit, sort and order query parameters of a list endpoint. sorts are the
+// fields the list can be ordered by, the first one is the default. On invalid input it responds with
+// 400 and the offending parameters, and returns false.
+func bindList(c *gin.Context, defaultOrder string, sorts ...string) (models.ListOptions, bool) {
+	list := models.ListOptions{Cursor: c.Query("cursor"), Sort: c.DefaultQuery("sort", sorts[0])}
+	var fieldErrs []apperrors.FieldError
+
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		if list.Limit, err = strconv.ParseInt(limit, 10, 64); err != nil || list.Limit <= 0 {
+			fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "limit", Message: "must be a positive number"})
+		}
+	}
+
+	valid := false
+	for _, sort := range sorts {
+		valid = valid || list.Sort == sort
+	}
+	if !valid {
+		fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "sort", Message: "must be one of " + strings.Join(sorts, " ")})
+	}
+
+	switch c.DefaultQuery("order", defaultOrder) {
+	case "asc":
+	case "desc":
+		list.Desc = true
+	default:
+		fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "order", Message: "must be one of asc desc"})
+	}
+
+	if len(fieldErrs) > 0 {
+		respondFieldErrors(c, fieldErrs)
+		return list, false
+	}
+	return list, true
+}
+
+// bindDateRange reads the from and to query parameters (YYYY-MM-DD, both inclusive) into the list options.
+// A single date stands for a range of one day.
+func bindDateRange(c *gin.Context, list *models.ListOptions) bool {
+	from, to := c.Query("from"), c.Query("to")
+	if date := c.Query("date"); date != "" {
+		from, to = date, date
+	}
+
+	var fieldErrs []apperrors.FieldError
+	var err error
+	if list.From, err = models.ParseDate(from); err != nil {
+		fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "from", Message: "must be a date (YYYY-MM-DD)"})
+	}
+	if list.To, err = models.ParseDate(to); err != nil {
+		fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "to", Message: "must be a date (YYYY-MM-DD)"})
+	}
+	if len(fieldErrs) == 0 && !list.From.IsZero() && !list.To.IsZero() && list.To.Before(list.From.Time) {
+		fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "to", Message: "must not be before from"})
+	}
+	if len(fieldErrs) > 0 {
+		respondFieldErrors(c, fieldErrs)
+		return false
+	}
+	return true
+}
+
+// respondListError answers a failed list request, what went wrong with the cursor is the client's to fix
+func respondListError(c *gin.Context, err error, message string) {
+	if err == services.ErrInvalidCursor {
+		respondFieldErrors(c, []apperrors.FieldError{{Field: "cursor", Message: "is invalid or was made for another sort order"}})
+		return
+	}
+	respondError(c, err, message)
+}
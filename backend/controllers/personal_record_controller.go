@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PersonalRecordController struct {
+	service *services.PersonalRecordService
+}
+
+// Constructor for PersonalRecordController
+func NewPersonalRecordController(service *services.PersonalRecordService) *PersonalRecordController {
+	return &PersonalRecordController{
+		service: service,
+	}
+}
+
+// Get the personal records of a trainee newest first, or with ?current=true only those not beaten since
+func (ctrl *PersonalRecordController) GetPersonalRecords(c *gin.Context) {
+	current := false
+	if value := c.Query("current"); value != "" {
+		var err error
+		if current, err = strconv.ParseBool(value); err != nil {
+			respondFieldErrors(c, []apperrors.FieldError{{Field: "current", Message: "must be true or false"}})
+			return
+		}
+	}
+
+	records, err := ctrl.service.GetPersonalRecords(c.Param("trainee_id"), current)
+	if err != nil {
+		respondError(c, err, "Failed to fetch personal records")
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const versionConflictMessage = "The document has been changed since you loaded it"
+
+// ifMatch is the version the client based its write on, see middlewares.RequireIfMatch
+func ifMatch(c *gin.Context) int64 {
+	return c.GetInt64("if_match")
+}
+
+// respondVersionConflict answers 412 with the document as it is now and its ETag, so the client can
+// offer to merge its changes. loadErr is the error from loading the current document, if any.
+func respondVersionConflict(c *gin.Context, current interface{}, version int64, loadErr error) {
+	conflict := apperrors.PreconditionFailed("version_conflict", versionConflictMessage)
+	if loadErr != nil {
+		c.Error(conflict)
+		return
+	}
+
+	c.Header("ETag", utils.ETag(version))
+	c.Error(conflict.With("current", current))
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProgramController struct {
+	service *services.ProgramService
+}
+
+// Constructor for ProgramController
+func NewProgramController(service *services.ProgramService) *ProgramController {
+	return &ProgramController{
+		service: service,
+	}
+}
+
+// Create a program written by the calling trainer
+func (ctrl *ProgramController) CreateProgram(c *gin.Context) {
+	var program models.Program
+	if err := c.ShouldBindJSON(&program); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	program, err := ctrl.service.CreateProgram(c.MustGet("email").(string), program)
+	if err != nil {
+		respondError(c, err, "Failed to create program")
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
+
+// Get the programs of the calling trainer
+func (ctrl *ProgramController) GetPrograms(c *gin.Context) {
+	programs, err := ctrl.service.GetPrograms(c.MustGet("email").(string))
+	if err != nil {
+		respondError(c, err, "Failed to fetch programs")
+		return
+	}
+
+	c.JSON(http.StatusOK, programs)
+}
+
+// Get a program with its workouts
+func (ctrl *ProgramController) GetProgramByID(c *gin.Context) {
+	program, err := ctrl.service.GetProgramByID(c.Param("program_id"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch program")
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
+
+// Replace the plan of a program
+func (ctrl *ProgramController) UpdateProgram(c *gin.Context) {
+	var update models.Program
+	if err := c.ShouldBindJSON(&update); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	program, err := ctrl.service.UpdateProgram(c.Param("program_id"), update)
+	if err != nil {
+		respondError(c, err, "Failed to update program")
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
+
+// Delete a program no trainee follows
+func (ctrl *ProgramController) DeleteProgram(c *gin.Context) {
+	if err := ctrl.service.DeleteProgram(c.Param("program_id")); err != nil {
+		respondError(c, err, "Failed to delete program")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Program deleted successfully"})
+}
+
+// Make a trainee follow a program from a start date, today if it has none
+func (ctrl *ProgramController) AssignProgram(c *gin.Context) {
+	var request struct {
+		ProgramID string      `json:"program_id" binding:"required"`
+		StartDate models.Date `json:"start_date"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	assignment, err := ctrl.service.AssignProgram(c.Param("id"), c.MustGet("email").(string), request.ProgramID, request.StartDate)
+	if err != nil {
+		switch err {
+		case services.ErrResourceNotFound:
+			c.Error(apperrors.NotFound("program_not_found", "Program not found"))
+		case services.ErrAccessDenied:
+			c.Error(apperrors.Forbidden("access_denied", "Trainers can only assign their own programs"))
+		default:
+			respondError(c, err, "Failed to assign program")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, assignment)
+}
+
+// Get the program a trainee follows
+func (ctrl *ProgramController) GetAssignment(c *gin.Context) {
+	assignment, err := ctrl.service.GetAssignment(c.Param("trainee_id"))
+	if err != nil {
+		respondAssignmentError(c, err, "Failed to fetch program")
+		return
+	}
+
+	c.JSON(http.StatusOK, assignment)
+}
+
+// Stop a trainee following their program
+func (ctrl *ProgramController) UnassignProgram(c *gin.Context) {
+	if err := ctrl.service.UnassignProgram(c.Param("id")); err != nil {
+		respondAssignmentError(c, err, "Failed to unassign program")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Program unassigned successfully"})
+}
+
+// Get the workout a trainee's program plans for today, or for the date given as ?date=
+func (ctrl *ProgramController) GetPlannedWorkout(c *gin.Context) {
+	date, ok := bindPlanDate(c, c.Query("date"))
+	if !ok {
+		return
+	}
+
+	planned, err := ctrl.service.GetPlannedWorkout(c.Param("trainee_id"), date)
+	if err != nil {
+		respondAssignmentError(c, err, "Failed to fetch planned workout")
+		return
+	}
+
+	c.JSON(http.StatusOK, planned)
+}
+
+// Start a workout log pre-filled from a template of the trainee's program, the one planned for the
+// date unless a template_id is given
+func (ctrl *ProgramController) LogPlannedWorkout(c *gin.Context) {
+	var request struct {
+		TemplateID string `json:"template_id"`
+		Date       string `json:"date"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	date, ok := bindPlanDate(c, request.Date)
+	if !ok {
+		return
+	}
+
+	log, err := ctrl.service.LogPlannedWorkout(c.Param("id"), request.TemplateID, date)
+	if err != nil {
+		respondAssignmentError(c, err, "Failed to start workout log")
+		return
+	}
+
+	c.JSON(http.StatusOK, log)
+}
+
+func bindPlanDate(c *gin.Context, value string) (models.Date, bool) {
+	date, err := models.ParseDate(value)
+	if err != nil {
+		respondFieldErrors(c, []apperrors.FieldError{{Field: "date", Message: "must be a date (YYYY-MM-DD)"}})
+		return date, false
+	}
+	return date, true
+}
+
+func respondAssignmentError(c *gin.Context, err error, fallback string) {
+	if err == services.ErrResourceNotFound {
+		c.Error(apperrors.NotFound("program_not_assigned", "Trainee does not follow a program"))
+		return
+	}
+	respondError(c, err, fallback)
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/services"
 	"net/http"
@@ -13,23 +14,46 @@ type ProgressController struct {
 }
 
 // Constructor for ProgressController
-func NewProgressController() *ProgressController {
+func NewProgressController(service *services.ProgressService) *ProgressController {
 	return &ProgressController{
-		service: services.NewProgressService(),
+		service: service,
 	}
 }
 
-// GetProgress retrieves the progress data for a trainee
+// GetProgress retrieves the progress data for a trainee, optionally within a from/to date range. The
+// series are bucketed by ?bucket=day|week|month, weekly by default, and the one-rep maxes estimated
+// with ?formula=epley|brzycki, Epley by default.
 func (ctrl *ProgressController) GetProgress(c *gin.Context) {
 	traineeID := c.Param("trainee_id")
 	if traineeID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Trainee ID is required"})
+		respondFieldErrors(c, []apperrors.FieldError{{Field: "trainee_id", Message: "is required"}})
+		return
+	}
+	var dates models.ListOptions
+	if !bindDateRange(c, &dates) {
 		return
 	}
 
-	progress, err := ctrl.service.GetProgress(traineeID)
+	options := models.AnalyticsOptions{Bucket: c.Query("bucket"), Formula: c.Query("formula")}
+	var fields []apperrors.FieldError
+	switch options.Bucket {
+	case "", models.BucketDay, models.BucketWeek, models.BucketMonth:
+	default:
+		fields = append(fields, apperrors.FieldError{Field: "bucket", Message: "must be day, week or month"})
+	}
+	switch options.Formula {
+	case "", models.FormulaEpley, models.FormulaBrzycki:
+	default:
+		fields = append(fields, apperrors.FieldError{Field: "formula", Message: "must be epley or brzycki"})
+	}
+	if len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	progress, err := ctrl.service.GetProgress(traineeID, dates, options)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch progress"})
+		respondError(c, err, "Failed to fetch progress")
 		return
 	}
 
@@ -40,18 +64,26 @@ func (ctrl *ProgressController) GetProgress(c *gin.Context) {
 func (ctrl *ProgressController) AddWeightProgress(c *gin.Context) {
 	var weightBMI models.WeightBMIProgress
 	if err := c.ShouldBindJSON(&weightBMI); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
-	if weightBMI.TraineeID == "" || weightBMI.Date == "" || weightBMI.Weight == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Trainee ID, Date, and Weight are required"})
+	// Without a date the weight counts for today where the trainer is
+	var fields []apperrors.FieldError
+	if weightBMI.TraineeID == "" {
+		fields = append(fields, apperrors.FieldError{Field: "trainee_id", Message: "is required"})
+	}
+	if weightBMI.Weight == 0 {
+		fields = append(fields, apperrors.FieldError{Field: "weight", Message: "is required"})
+	}
+	if len(fields) > 0 {
+		respondFieldErrors(c, fields)
 		return
 	}
 
 	err := ctrl.service.AddWeightProgress(weightBMI)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add weight progress"})
+		respondError(c, err, "Failed to add weight progress")
 		return
 	}
 
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/services"
+	"ironcoach/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxSearchQueryLength = 200
+
+type SearchController struct {
+	service *services.SearchService
+}
+
+// Constructor for SearchController
+func NewSearchController(service *services.SearchService) *SearchController {
+	return &SearchController{
+		service: service,
+	}
+}
+
+// Search the caller's trainees, exercises, workout logs and diet entries for q. types narrows the
+// search down to a comma separated list of those, limit caps the hits of each type.
+func (ctrl *SearchController) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	var fieldErrs []apperrors.FieldError
+	if query == "" {
+		fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "q", Message: "is required"})
+	} else if len(query) > maxSearchQueryLength {
+		fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "q", Message: "must be at most " + strconv.Itoa(maxSearchQueryLength) + " characters"})
+	}
+
+	types := services.SearchTypes
+	if value := c.Query("types"); value != "" {
+		types = nil
+		for _, kind := range strings.Split(value, ",") {
+			if !isSearchType(kind) {
+				fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "types", Message: "must be a list of " + strings.Join(services.SearchTypes, " ")})
+				break
+			}
+			types = append(types, kind)
+		}
+	}
+
+	var limit int64
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.ParseInt(value, 10, 64); err != nil || limit <= 0 {
+			fieldErrs = append(fieldErrs, apperrors.FieldError{Field: "limit", Message: "must be a positive number"})
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		respondFieldErrors(c, fieldErrs)
+		return
+	}
+
+	results, err := ctrl.service.Search(c.MustGet("identity").(utils.Identity), query, types, limit)
+	if err != nil {
+		respondError(c, err, "Failed to search")
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func isSearchType(kind string) bool {
+	for _, searchType := range services.SearchTypes {
+		if kind == searchType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/services"
+	"ironcoach/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SyncController struct {
+	service *services.SyncService
+}
+
+// Constructor for SyncController
+func NewSyncController(service *services.SyncService) *SyncController {
+	return &SyncController{
+		service: service,
+	}
+}
+
+// Apply a batch of records changed offline. Every change gets its own result, conflicts and
+// rejected changes do not fail the batch.
+func (ctrl *SyncController) Push(c *gin.Context) {
+	var push models.SyncPush
+	if err := c.ShouldBindJSON(&push); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	results := ctrl.service.Push(c.MustGet("identity").(utils.Identity), push.Changes)
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// Get the changes made after a cursor, with tombstones for deleted records. Pull with an empty cursor to start over.
+func (ctrl *SyncController) Pull(c *gin.Context) {
+	var limit int64
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.ParseInt(value, 10, 64); err != nil || limit <= 0 {
+			respondFieldErrors(c, []apperrors.FieldError{{Field: "limit", Message: "must be a positive number"}})
+			return
+		}
+	}
+
+	pull, err := ctrl.service.Pull(c.MustGet("identity").(utils.Identity), c.Query("cursor"), limit)
+	if err != nil {
+		switch err {
+		case services.ErrInvalidSyncCursor:
+			c.Error(apperrors.Validation("invalid_sync_cursor", "Invalid cursor, pull without one to start over"))
+		case services.ErrResourceNotFound:
+			c.Error(errTraineeNotFound)
+		case services.ErrAccessDenied:
+			c.Error(errNoAccess)
+		default:
+			respondError(c, err, "Failed to fetch changes")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, pull)
+}
@@ -1,56 +1,71 @@
 package controllers
 
 import (
+    "ironcoach/apperrors"
     "net/http"
     "ironcoach/models"
     "ironcoach/services"
+    "ironcoach/utils"
 
     "github.com/gin-gonic/gin"
 )
 
 type TraineeController struct {
-    service *services.TraineeService
+    service        *services.TraineeService
+    accountService *services.TraineeAccountService
 }
 
 // Constructor for TraineeController
-func NewTraineeController() *TraineeController {
+func NewTraineeController(service *services.TraineeService, accountService *services.TraineeAccountService) *TraineeController {
     return &TraineeController{
-        service: services.NewTraineeService(),
+        service:        service,
+        accountService: accountService,
     }
 }
 
 // Add a new trainee
 func (ctrl *TraineeController) AddTrainee(c *gin.Context) {
     var trainee models.Trainee
-	
-    // Get trainer ID from JWT claims
-    trainerID := c.MustGet("email").(string)
-    trainee.TrainerID = trainerID
 
     // Bind JSON request
     if err := c.ShouldBindJSON(&trainee); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        respondBindError(c, err)
         return
     }
 
+    // Get trainer ID from JWT claims, never from the request body
+    trainee.TrainerID = c.MustGet("email").(string)
 
     // Add trainee
     if err := ctrl.service.AddTrainee(trainee); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add trainee"})
+        if err == services.ErrResourceNotFound {
+            c.Error(errOrganizationNotFound)
+            return
+        }
+        if err == services.ErrAccessDenied {
+            c.Error(apperrors.Forbidden("access_denied", "Only owners and coaches can add trainees to the organization"))
+            return
+        }
+        respondError(c, err, "Failed to add trainee")
         return
     }
 
     c.JSON(http.StatusOK, gin.H{"message": "Trainee added successfully"})
 }
 
-// Get trainees for a trainer
+// Get a page of the trainees of a trainer, sorted by name, start_date, created_at or updated_at,
+// optionally limited to those who started within a from/to date range
 func (ctrl *TraineeController) GetTrainees(c *gin.Context) {
     status := c.Query("active_status") // Get query parameter
     trainerID := c.MustGet("email").(string)
+    list, ok := bindList(c, "asc", "name", "start_date", "created_at", "updated_at")
+    if !ok || !bindDateRange(c, &list) {
+        return
+    }
 
-    trainees, err := ctrl.service.GetTraineesByTrainer(trainerID,status)
+    trainees, err := ctrl.service.GetTraineesByTrainer(trainerID, status, list)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trainees"})
+        respondListError(c, err, "Failed to fetch trainees")
         return
     }
 
@@ -63,29 +78,40 @@ func (ctrl *TraineeController) GetTraineeByID(c *gin.Context) {
    
     trainee, err := ctrl.service.GetTraineeByID(traineeID)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trainees"})
+        respondError(c, err, "Failed to fetch trainees")
         return
     }
 
+    c.Header("ETag", utils.ETag(trainee.Version))
     c.JSON(http.StatusOK, trainee)
 }
 
 
-// Update a trainee
+// Update a trainee. Only the fields present in the body are changed.
 func (ctrl *TraineeController) UpdateTrainee(c *gin.Context) {
     id := c.Param("id")
-    var update map[string]interface{}
+    var patch models.TraineePatch
 
-    if err := c.ShouldBindJSON(&update); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    if !bindPatch(c, &patch) {
         return
     }
 
-    if err := ctrl.service.UpdateTrainee(id, update); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update trainee"})
+    version := ifMatch(c)
+    if err := ctrl.service.UpdateTrainee(id, version, patch); err != nil {
+        switch err {
+        case services.ErrEmptyUpdate, services.ErrEncryptedFieldPath:
+            respondBindError(c, err)
+        case services.ErrVersionConflict:
+            ctrl.respondConflict(c, id)
+        case services.ErrResourceNotFound:
+            c.Error(errTraineeNotFound)
+        default:
+            respondError(c, err, "Failed to update trainee")
+        }
         return
     }
 
+    c.Header("ETag", utils.ETag(version+1))
     c.JSON(http.StatusOK, gin.H{"message": "Trainee updated successfully"})
 }
 
@@ -93,10 +119,93 @@ func (ctrl *TraineeController) UpdateTrainee(c *gin.Context) {
 func (ctrl *TraineeController) DeleteTrainee(c *gin.Context) {
     id := c.Param("id")
 
-    if err := ctrl.service.DeleteTrainee(id); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete trainee"})
+    if err := ctrl.service.DeleteTrainee(id, ifMatch(c)); err != nil {
+        switch err {
+        case services.ErrVersionConflict:
+            ctrl.respondConflict(c, id)
+        case services.ErrResourceNotFound:
+            c.Error(errTraineeNotFound)
+        default:
+            respondError(c, err, "Failed to delete trainee")
+        }
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Trainee moved to trash"})
+}
+
+// respondConflict sends the trainee as it is now to a client that tried to change an older version
+func (ctrl *TraineeController) respondConflict(c *gin.Context, id string) {
+    trainee, err := ctrl.service.GetTraineeByID(id)
+    respondVersionConflict(c, trainee, trainee.Version, err)
+}
+
+// Issue (or reissue) the invite code and PIN a trainee logs in with
+func (ctrl *TraineeController) IssueTraineeAccess(c *gin.Context) {
+    traineeID := c.Param("id")
+    trainerID := c.MustGet("email").(string)
+
+    access, err := ctrl.accountService.IssueAccess(traineeID, trainerID)
+    if err != nil {
+        respondError(c, err, "Failed to issue trainee access")
+        return
+    }
+
+    c.JSON(http.StatusOK, access)
+}
+
+// Revoke a trainee's login and end their sessions
+func (ctrl *TraineeController) RevokeTraineeAccess(c *gin.Context) {
+    traineeID := c.Param("id")
+
+    if err := ctrl.accountService.RevokeAccess(traineeID); err != nil {
+        if err == services.ErrResourceNotFound {
+            c.Error(apperrors.NotFound("trainee_account_not_found", "Trainee has no account"))
+            return
+        }
+        respondError(c, err, "Failed to revoke trainee access")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Trainee access revoked successfully"})
+}
+
+// Transfer a trainee to another trainer, optionally moving it into or out of an organization
+func (ctrl *TraineeController) TransferTrainee(c *gin.Context) {
+    id := c.Param("id")
+    var request struct {
+        TrainerEmail   string  `json:"trainer_email" binding:"required,email"`
+        OrganizationID *string `json:"organization_id"` // Omit to keep the current organization, "" to make the trainee personal
+    }
+    if err := c.ShouldBindJSON(&request); err != nil {
+        respondBindError(c, err)
+        return
+    }
+
+    organizationID := ""
+    if request.OrganizationID != nil {
+        organizationID = *request.OrganizationID
+    } else {
+        trainee, err := ctrl.service.GetTraineeByID(id)
+        if err != nil {
+            respondError(c, err, "Failed to transfer trainee")
+            return
+        }
+        organizationID = trainee.OrganizationID
+    }
+
+    err := ctrl.service.TransferTrainee(id, c.MustGet("email").(string), request.TrainerEmail, organizationID)
+    if err != nil {
+        switch err {
+        case services.ErrResourceNotFound:
+            c.Error(errOrganizationNotFound)
+        case services.ErrAccessDenied:
+            c.Error(apperrors.Forbidden("access_denied", "Only owners and coaches can move trainees into the organization"))
+        default:
+            respondError(c, err, "Failed to transfer trainee")
+        }
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "Trainee deleted successfully"})
+    c.JSON(http.StatusOK, gin.H{"message": "Trainee transferred successfully"})
 }
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/services"
 	"net/http"
@@ -14,9 +15,9 @@ type TrainerController struct {
 
 //constructor for TrainerController
 
-func NewTrainerController() *TrainerController {
+func NewTrainerController(service *services.TrainerService) *TrainerController {
 	return &TrainerController{
-		service: services.NewTrainerService(),
+		service: service,
 	}
 }
 
@@ -26,13 +27,17 @@ func (ctrl *TrainerController) RegisterTrainer(c *gin.Context) {
 
 	//Bind JSON req to Trainer Struct
 	if err := c.ShouldBindJSON(&trainer); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	//call service to register trainer
 	if err := ctrl.service.RegisterTrainer(trainer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err == services.ErrInvalidTimeZone {
+			respondFieldErrors(c, []apperrors.FieldError{{Field: "time_zone", Message: "must be an IANA time zone such as Europe/Berlin"}})
+			return
+		}
+		respondError(c, err, "Failed to register trainer")
 		return
 	}
 
@@ -47,26 +52,31 @@ func (ctrl *TrainerController) LoginTrainer(c *gin.Context) {
 
 	//Bind JSON request
 	if err := c.ShouldBindJSON(&loginRequest); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	//Call the service to authenticate trainer
-	token, err := ctrl.service.LoginTrainer(loginRequest.Email, loginRequest.Password)
+	tokens, err := ctrl.service.LoginTrainer(loginRequest.Email, loginRequest.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err, "Failed to log in")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokens)
 
 }
 
-// Get Trainers
+// Get a page of trainers, sorted by name, experience, rating, hourly_rate or created_at
 func (c *TrainerController) GetTrainers(ctx *gin.Context) {
-	trainers, err := c.service.GetTrainers()
+	list, ok := bindList(ctx, "asc", "name", "experience", "rating", "hourly_rate", "created_at")
+	if !ok {
+		return
+	}
+
+	trainers, err := c.service.GetTrainers(list)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve categories"})
+		respondListError(ctx, err, "Failed to retrieve trainers")
 		return
 	}
 
@@ -77,12 +87,43 @@ func (c *TrainerController) GetTrainerDetails(ctx *gin.Context) {
 	trainerID := ctx.MustGet("email").(string)
 	trainer, err := c.service.GetTrainerByID(trainerID)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Trainer not found"})
+		ctx.Error(errTrainerNotFound)
 		return
 	}
 	ctx.JSON(http.StatusOK, trainer)
 }
 
+// SetTimeZone sets the time zone of the logged-in trainer, which decides the date of logs, diet
+// entries and weights recorded without one
+func (c *TrainerController) SetTimeZone(ctx *gin.Context) {
+	if ctx.Param("email") != ctx.MustGet("email").(string) {
+		ctx.Error(apperrors.Forbidden("access_denied", "Trainers can only change their own time zone"))
+		return
+	}
+
+	var request struct {
+		TimeZone string `json:"time_zone" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		respondBindError(ctx, err)
+		return
+	}
+
+	if err := c.service.SetTimeZone(ctx.Param("email"), request.TimeZone); err != nil {
+		switch err {
+		case services.ErrInvalidTimeZone:
+			respondFieldErrors(ctx, []apperrors.FieldError{{Field: "time_zone", Message: "must be an IANA time zone such as Europe/Berlin"}})
+		case services.ErrResourceNotFound:
+			ctx.Error(errTrainerNotFound)
+		default:
+			respondError(ctx, err, "Failed to update time zone")
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Time zone updated successfully"})
+}
+
 // DeleteTrainer performs cascading delete of trainer and all associated data
 func (c *TrainerController) DeleteTrainer(ctx *gin.Context) {
 	// Get the email of the logged-in trainer from the JWT token
@@ -90,28 +131,29 @@ func (c *TrainerController) DeleteTrainer(ctx *gin.Context) {
 
 	// Check if the logged-in trainer is admin
 	if loggedInTrainerEmail != "admin@gmail.com" {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only admin can delete trainers"})
+		ctx.Error(apperrors.Forbidden("access_denied", "Only admin can delete trainers"))
 		return
 	}
 
 	trainerEmail := ctx.Param("email")
 	if trainerEmail == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Trainer email is required"})
+		respondFieldErrors(ctx, []apperrors.FieldError{{Field: "email", Message: "is required"}})
 		return
 	}
 
 	// Check if trainer exists
 	_, err := c.service.GetTrainerByID(trainerEmail)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Trainer not found"})
+		ctx.Error(errTrainerNotFound)
 		return
 	}
 
 	// Perform cascading delete
-	if err := c.service.DeleteTrainerCascade(trainerEmail); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete trainer: " + err.Error()})
+	summary, err := c.service.DeleteTrainerCascade(trainerEmail)
+	if err != nil {
+		respondError(ctx, err, "Failed to delete trainer")
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Trainer and all associated data deleted successfully"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "Trainer and all associated data deleted successfully", "deleted": summary})
 }
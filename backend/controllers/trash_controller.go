@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/services"
+	"ironcoach/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashController struct {
+	service *services.TrashService
+}
+
+// Constructor for TrashController
+func NewTrashController(service *services.TrashService) *TrashController {
+	return &TrashController{
+		service: service,
+	}
+}
+
+// List the trainees, workout logs and diet entries in the caller's trash
+func (ctrl *TrashController) GetTrash(c *gin.Context) {
+	trash, err := ctrl.service.GetTrash(c.MustGet("identity").(utils.Identity))
+	if err != nil {
+		respondError(c, err, "Failed to fetch trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, trash)
+}
+
+// Restore a trainee, workout log or diet entry from the trash
+func (ctrl *TrashController) Restore(c *gin.Context) {
+	identity := c.MustGet("identity").(utils.Identity)
+
+	if err := ctrl.service.Restore(identity, c.Param("kind"), c.Param("id")); err != nil {
+		respondTrashError(c, err, "Failed to restore")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Restored successfully"})
+}
+
+// Permanently delete a trainee, workout log or diet entry from the trash
+func (ctrl *TrashController) Purge(c *gin.Context) {
+	identity := c.MustGet("identity").(utils.Identity)
+
+	summary, err := ctrl.service.Purge(identity, c.Param("kind"), c.Param("id"))
+	if err != nil {
+		respondTrashError(c, err, "Failed to delete permanently")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted permanently", "deleted": summary})
+}
+
+func respondTrashError(c *gin.Context, err error, fallback string) {
+	switch err {
+	case services.ErrUnknownTrashKind, services.ErrResourceNotFound:
+		c.Error(apperrors.NotFound("not_in_trash", "Not found in trash"))
+	case services.ErrAccessDenied:
+		c.Error(errNoAccess)
+	default:
+		respondError(c, err, fallback)
+	}
+}
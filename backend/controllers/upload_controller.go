@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"ironcoach/apperrors"
 	// "fmt"
 	"ironcoach/services"
 	"net/http"
@@ -13,9 +14,9 @@ type ImageController struct {
 }
 
 // Constructor for ImageController
-func NewImageController() *ImageController {
+func NewImageController(service *services.ImageService) *ImageController {
     return &ImageController{
-        service: services.NewImageService(),
+        service: service,
     }
 }
 
@@ -23,7 +24,7 @@ func NewImageController() *ImageController {
 func (ctrl *ImageController) UploadImage(c *gin.Context) {
     file, err := c.FormFile("image")
     if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+        respondFieldErrors(c, []apperrors.FieldError{{Field: "image", Message: "is required"}})
         return
     }
     // fmt.Println("image cntrlr: ", file )
@@ -31,7 +32,7 @@ func (ctrl *ImageController) UploadImage(c *gin.Context) {
     // Call the service to handle file upload
     imageURL, err := ctrl.service.UploadImage(file)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image"})
+        respondError(c, err, "Failed to upload image")
         return
     }
 
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Dates are validated as their YYYY-MM-DD form, so required rejects a missing or empty date
+func init() {
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+			return field.Interface().(models.Date).String()
+		}, models.Date{})
+	}
+}
+
+// bindPatch decodes a partial update into a patch DTO and checks it against its binding rules.
+// Fields the DTO does not declare are rejected rather than ignored. On failure it responds with
+// 400 and the offending fields, and returns false.
+func bindPatch(c *gin.Context, patch interface{}) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Error(apperrors.Validation("invalid_body", "Failed to read request body"))
+		return false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patch); err != nil {
+		if fieldErr, ok := decodeFieldError(err); ok {
+			respondFieldErrors(c, []apperrors.FieldError{fieldErr})
+			return false
+		}
+		respondBindError(c, err)
+		return false
+	}
+
+	if err := binding.Validator.ValidateStruct(patch); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			respondBindError(c, err)
+			return false
+		}
+
+		fieldErrs := make([]apperrors.FieldError, 0, len(validationErrs))
+		for _, validationErr := range validationErrs {
+			fieldErrs = append(fieldErrs, apperrors.FieldError{
+				Field:   jsonFieldName(patch, validationErr.StructField()),
+				Message: validationMessage(validationErr),
+			})
+		}
+		respondFieldErrors(c, fieldErrs)
+		return false
+	}
+	return true
+}
+
+func respondFieldErrors(c *gin.Context, fieldErrs []apperrors.FieldError) {
+	c.Error(apperrors.InvalidFields(fieldErrs...))
+}
+
+// decodeFieldError turns JSON errors caused by a single field into a apperrors.FieldError
+func decodeFieldError(err error) (apperrors.FieldError, bool) {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return apperrors.FieldError{Field: typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type)}, true
+	}
+
+	// encoding/json has no typed error for unknown fields
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return apperrors.FieldError{Field: strings.Trim(name, `"`), Message: "cannot be changed"}, true
+	}
+	return apperrors.FieldError{}, false
+}
+
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+func jsonFieldName(patch interface{}, structField string) string {
+	field, ok := reflect.Indirect(reflect.ValueOf(patch)).Type().FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+func validationMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if err.Param() == "1" {
+			return "cannot be empty"
+		}
+		return "must be at least " + err.Param()
+	case "max":
+		if err.Kind() == reflect.String {
+			return "must be at most " + err.Param() + " characters long"
+		}
+		return "must be at most " + err.Param()
+	case "gte":
+		return "must be greater than or equal to " + err.Param()
+	case "lte":
+		return "must be less than or equal to " + err.Param()
+	case "oneof":
+		return "must be one of " + err.Param()
+	default:
+		return fmt.Sprintf("failed the %q rule", err.Tag())
+	}
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/services"
+	"ironcoach/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,9 +15,9 @@ type WorkoutLogController struct {
 }
 
 // Constructor for WorkoutLogController
-func NewWorkoutLogController() *WorkoutLogController {
+func NewWorkoutLogController(service *services.WorkoutLogService) *WorkoutLogController {
 	return &WorkoutLogController{
-		service: services.NewWorkoutLogService(),
+		service: service,
 	}
 }
 
@@ -25,48 +27,75 @@ func (ctrl *WorkoutLogController) AddWorkoutLog(c *gin.Context) {
 
 	// Bind JSON request to WorkoutLog model
 	if err := c.ShouldBindJSON(&log); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	// Call service to add workout log
-	if err := ctrl.service.AddWorkoutLog(log); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add workout log"})
+	id, err := ctrl.service.AddWorkoutLog(log)
+	if err != nil {
+		respondError(c, err, "Failed to add workout log")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Workout log added successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Workout log added successfully", "id": id})
 }
 
-// Get all workout logs for a trainee
+// Get a page of the workout logs of a trainee, newest first by default, optionally within a from/to date range
 func (ctrl *WorkoutLogController) GetWorkoutLogs(c *gin.Context) {
 	traineeID := c.Param("trainee_id")
-	date := c.Query("date")
+	list, ok := bindList(c, "desc", "date", "created_at", "updated_at")
+	if !ok || !bindDateRange(c, &list) {
+		return
+	}
 
-	logs, err := ctrl.service.GetWorkoutLogsByTrainee(traineeID,date)
+	logs, err := ctrl.service.GetWorkoutLogsByTrainee(traineeID, list)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workout logs"})
+		respondListError(c, err, "Failed to fetch workout logs")
 		return
 	}
 
 	c.JSON(http.StatusOK, logs)
 }
 
+// Get a single workout log, with its version as the ETag
+func (ctrl *WorkoutLogController) GetWorkoutLogByID(c *gin.Context) {
+	log, err := ctrl.service.GetWorkoutLogByID(c.Param("log_id"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch workout log")
+		return
+	}
+
+	c.Header("ETag", utils.ETag(log.Version))
+	c.JSON(http.StatusOK, log)
+}
+
+// Update a workout log. Only the fields present in the body are changed.
 func (ctrl *WorkoutLogController) UpdateWorkoutLog(c *gin.Context) {
 	logID := c.Param("log_id") // Get log ID from path
-	var update map[string]interface{}
+	var patch models.WorkoutLogPatch
 
-	if err := c.ShouldBindJSON(&update); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindPatch(c, &patch) {
 		return
 	}
 
 	// Call service to update the workout log
-	if err := ctrl.service.UpdateWorkoutLog(logID, update); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	version := ifMatch(c)
+	if err := ctrl.service.UpdateWorkoutLog(logID, version, patch); err != nil {
+		switch err {
+		case services.ErrEmptyUpdate:
+			respondBindError(c, err)
+		case services.ErrVersionConflict:
+			ctrl.respondConflict(c, logID)
+		case services.ErrResourceNotFound:
+			c.Error(apperrors.NotFound("workout_log_not_found", "Workout log not found"))
+		default:
+			respondError(c, err, "Failed to update workout log")
+		}
 		return
 	}
 
+	c.Header("ETag", utils.ETag(version+1))
 	c.JSON(http.StatusOK, gin.H{"message": "Workout log updated successfully"})
 }
 
@@ -74,12 +103,22 @@ func (ctrl *WorkoutLogController) DeleteWorkoutLog(c *gin.Context) {
 	logID := c.Param("log_id")
 
 	// Call service to delete the workout log
-	if err := ctrl.service.DeleteWorkoutLog(logID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := ctrl.service.DeleteWorkoutLog(logID, ifMatch(c)); err != nil {
+		if err == services.ErrVersionConflict {
+			ctrl.respondConflict(c, logID)
+			return
+		}
+		respondError(c, err, "Failed to delete workout log")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Workout log deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Workout log moved to trash"})
+}
+
+// respondConflict sends the log as it is now to a client that tried to change an older version
+func (ctrl *WorkoutLogController) respondConflict(c *gin.Context, logID string) {
+	log, err := ctrl.service.GetWorkoutLogByID(logID)
+	respondVersionConflict(c, log, log.Version, err)
 }
 
 
@@ -88,7 +127,7 @@ func (ctrl *WorkoutLogController) GetTraineeProgress(c *gin.Context) {
 
     progress, err := ctrl.service.GetTraineeProgress(traineeID)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch progress"})
+        respondError(c, err, "Failed to fetch progress")
         return
     }
 
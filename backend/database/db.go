@@ -12,9 +12,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var DB *mongo.Client
-
-func ConnectDB() {
+// ConnectDB connects to the MongoDB at MONGODB_URI and exits if it cannot be reached
+func ConnectDB() *mongo.Client {
    // Load environment variables based on the environment
 	env := os.Getenv("ENV") // Check for the ENV variable
 	if env != "production" {
@@ -52,7 +51,6 @@ func ConnectDB() {
         log.Fatalf("Failed to ping MongoDB: %v", err)
     }
 
-    // Save the client
-    DB = client
     log.Println("Connected to MongoDB!")
+    return client
 }
@@ -0,0 +1,188 @@
+package encryption
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const keySize = 32 // AES-256
+
+var (
+	ErrNoKeys     = errors.New("no field encryption keys configured, set FIELD_ENCRYPTION_KEY_FILE or FIELD_ENCRYPTION_KEYS")
+	ErrUnknownKey = errors.New("value was sealed with a key that is not configured")
+)
+
+// Sealed is an encrypted value together with its data key, wrapped by one of the key encryption keys.
+// Every value gets its own data key, so rotating a key encryption key only rewraps the data keys.
+type Sealed struct {
+	KeyID      string `bson:"kid"` // Key encryption key that wrapped the data key
+	WrappedKey []byte `bson:"dek"`
+	Ciphertext []byte `bson:"ct"`
+}
+
+// IsSealed tells a Sealed value apart from an arbitrary document decoded into one
+func (s Sealed) IsSealed() bool {
+	return s.KeyID != "" && len(s.WrappedKey) > 0 && len(s.Ciphertext) > 0
+}
+
+// Keyring holds the key encryption keys (KEKs). New values are wrapped with the active key,
+// the others are kept so values sealed before a rotation can still be opened.
+type Keyring struct {
+	keys     map[string][]byte
+	activeID string
+}
+
+// NewKeyringFromEnv loads the KEKs as lines of "<key id>:<base64 of 32 random bytes>":
+//   - FIELD_ENCRYPTION_KEY_FILE names a file with one key per line ("#" starts a comment)
+//   - FIELD_ENCRYPTION_KEYS lists the keys separated by commas, for environments without a key file
+//
+// FIELD_ENCRYPTION_ACTIVE_KEY picks the key new values are sealed with, by default the last one listed.
+// To rotate, add a new key, make it active, and drop the old one once the stored values have been resealed.
+func NewKeyringFromEnv() (*Keyring, error) {
+	var entries []string
+	if path := os.Getenv("FIELD_ENCRYPTION_KEY_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			entries = append(entries, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else {
+		entries = strings.Split(os.Getenv("FIELD_ENCRYPTION_KEYS"), ",")
+	}
+
+	keyring := &Keyring{keys: map[string][]byte{}}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("field encryption key %q must look like <id>:<base64 key>", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("field encryption key %q must be %d bytes of base64", id, keySize)
+		}
+		keyring.keys[id] = key
+		keyring.activeID = id
+	}
+	if len(keyring.keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	if active := os.Getenv("FIELD_ENCRYPTION_ACTIVE_KEY"); active != "" {
+		if _, ok := keyring.keys[active]; !ok {
+			return nil, fmt.Errorf("active field encryption key %q is not configured", active)
+		}
+		keyring.activeID = active
+	}
+	return keyring, nil
+}
+
+// ActiveKeyID is the key new values are sealed with
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// Seal encrypts plaintext under a fresh data key. The associated data (such as the document and field)
+// must be passed again to open the value, so a sealed value cannot be moved elsewhere.
+func (k *Keyring) Seal(plaintext []byte, associatedData []byte) (Sealed, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return Sealed{}, err
+	}
+
+	ciphertext, err := encrypt(dataKey, plaintext, associatedData)
+	if err != nil {
+		return Sealed{}, err
+	}
+	wrappedKey, err := encrypt(k.keys[k.activeID], dataKey, []byte(k.activeID))
+	if err != nil {
+		return Sealed{}, err
+	}
+	return Sealed{KeyID: k.activeID, WrappedKey: wrappedKey, Ciphertext: ciphertext}, nil
+}
+
+// Open decrypts a sealed value
+func (k *Keyring) Open(sealed Sealed, associatedData []byte) ([]byte, error) {
+	dataKey, err := k.unwrap(sealed)
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(dataKey, sealed.Ciphertext, associatedData)
+}
+
+// Rewrap wraps the data key of a sealed value with the active key, leaving the ciphertext as is
+func (k *Keyring) Rewrap(sealed Sealed) (Sealed, error) {
+	dataKey, err := k.unwrap(sealed)
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	wrappedKey, err := encrypt(k.keys[k.activeID], dataKey, []byte(k.activeID))
+	if err != nil {
+		return Sealed{}, err
+	}
+	return Sealed{KeyID: k.activeID, WrappedKey: wrappedKey, Ciphertext: sealed.Ciphertext}, nil
+}
+
+func (k *Keyring) unwrap(sealed Sealed) ([]byte, error) {
+	key, ok := k.keys[sealed.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return decrypt(key, sealed.WrappedKey, []byte(sealed.KeyID))
+}
+
+// encrypt seals with AES-256-GCM, prefixing the random nonce to the ciphertext
+func encrypt(key []byte, plaintext []byte, associatedData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+func decrypt(key []byte, ciphertext []byte, associatedData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, associatedData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,105 @@
+package encryption_test
+
+import (
+	"bytes"
+	"ironcoach/encryption"
+	"testing"
+)
+
+const (
+	key1 = "k1:MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="
+	key2 = "k2:YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXphYmNkZWY="
+)
+
+// keyring loads a keyring from FIELD_ENCRYPTION_KEYS, the last key listed being the active one
+func keyring(t *testing.T, keys string) *encryption.Keyring {
+	t.Helper()
+	t.Setenv("FIELD_ENCRYPTION_KEY_FILE", "")
+	t.Setenv("FIELD_ENCRYPTION_ACTIVE_KEY", "")
+	t.Setenv("FIELD_ENCRYPTION_KEYS", keys)
+	keyring, err := encryption.NewKeyringFromEnv()
+	if err != nil {
+		t.Fatalf("NewKeyringFromEnv(%q): %v", keys, err)
+	}
+	return keyring
+}
+
+func TestSealOpen(t *testing.T) {
+	keys := keyring(t, key1)
+	plaintext := []byte("asthma")
+	associatedData := []byte("trainees/1/medical_history")
+
+	sealed, err := keys.Seal(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if sealed.KeyID != "k1" || !sealed.IsSealed() {
+		t.Fatalf("sealed = %+v, want a value wrapped by k1", sealed)
+	}
+	if bytes.Contains(sealed.Ciphertext, plaintext) {
+		t.Error("ciphertext holds the plaintext")
+	}
+	opened, err := keys.Open(sealed, associatedData)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open = %q, want %q", opened, plaintext)
+	}
+
+	tamper := func(b []byte) []byte {
+		b = append([]byte(nil), b...)
+		b[len(b)-1] ^= 1
+		return b
+	}
+	for _, tc := range []struct {
+		name           string
+		keys           *encryption.Keyring
+		sealed         encryption.Sealed
+		associatedData []byte
+	}{
+		{"other document", keys, sealed, []byte("trainees/2/medical_history")},
+		{"other field", keys, sealed, []byte("trainees/1/lab_tests")},
+		{"tampered ciphertext", keys, encryption.Sealed{KeyID: sealed.KeyID, WrappedKey: sealed.WrappedKey, Ciphertext: tamper(sealed.Ciphertext)}, associatedData},
+		{"tampered data key", keys, encryption.Sealed{KeyID: sealed.KeyID, WrappedKey: tamper(sealed.WrappedKey), Ciphertext: sealed.Ciphertext}, associatedData},
+		{"wrong key under the same ID", keyring(t, "k1:"+key2[len("k2:"):]), sealed, associatedData},
+		{"unknown key", keyring(t, key2), sealed, associatedData},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if opened, err := tc.keys.Open(tc.sealed, tc.associatedData); err == nil {
+				t.Errorf("Open = %q, want an error", opened)
+			}
+		})
+	}
+}
+
+func TestRewrapAfterRotation(t *testing.T) {
+	associatedData := []byte("trainees/1/lab_tests")
+	sealed, err := keyring(t, key1).Seal([]byte("cholesterol 180"), associatedData)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// k2 is added and made active, k1 is kept until the values are rewrapped
+	rotated := keyring(t, key1+","+key2)
+	rewrapped, err := rotated.Rewrap(sealed)
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if rewrapped.KeyID != "k2" || !bytes.Equal(rewrapped.Ciphertext, sealed.Ciphertext) {
+		t.Fatalf("rewrapped = %+v, want the same ciphertext wrapped by k2", rewrapped)
+	}
+
+	// Once k1 is dropped only the rewrapped value opens
+	retired := keyring(t, key2)
+	if _, err := retired.Open(sealed, associatedData); err != encryption.ErrUnknownKey {
+		t.Errorf("Open of the value wrapped by k1 = %v, want ErrUnknownKey", err)
+	}
+	opened, err := retired.Open(rewrapped, associatedData)
+	if err != nil {
+		t.Fatalf("Open after rotation: %v", err)
+	}
+	if string(opened) != "cholesterol 180" {
+		t.Errorf("Open after rotation = %q", opened)
+	}
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// LogMailer writes messages to a logger instead of delivering them.
+// It is meant for development and for exercising mail flows offline.
+type LogMailer struct {
+	logger *log.Logger
+	from   string
+}
+
+// Constructor for LogMailer
+func NewLogMailer(logger *log.Logger, from string) *LogMailer {
+	return &LogMailer{logger: logger, from: from}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	m.logger.Printf("mail to=%q subject=%q\n%s", msg.To, msg.Subject, formatMessage(m.from, msg))
+	return nil
+}
+
+// FileMailer appends every message to a file, one after another
+type FileMailer struct {
+	path string
+	from string
+	mu   sync.Mutex
+}
+
+// Constructor for FileMailer
+func NewFileMailer(path string, from string) *FileMailer {
+	return &FileMailer{path: path, from: from}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(formatMessage(m.from, msg), "\r\n\r\n"...)); err != nil {
+		return err
+	}
+	return nil
+}
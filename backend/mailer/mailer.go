@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewFromEnv picks a Mailer based on MAIL_DRIVER:
+//   - "smtp" sends through SMTP_HOST/SMTP_PORT with SMTP_USERNAME/SMTP_PASSWORD
+//   - "file" appends messages to MAIL_FILE (default "mail.log")
+//   - anything else writes messages to the application log
+func NewFromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@ironcoach.app"
+	}
+
+	switch os.Getenv("MAIL_DRIVER") {
+	case "smtp":
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			port = 587
+		}
+		return NewSMTPMailer(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	case "file":
+		path := os.Getenv("MAIL_FILE")
+		if path == "" {
+			path = "mail.log"
+		}
+		return NewFileMailer(path, from)
+	default:
+		return NewLogMailer(log.Default(), from)
+	}
+}
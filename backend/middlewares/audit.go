@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"ironcoach/models"
+	"ironcoach/services"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Auditor records every successful POST, PUT, PATCH and DELETE in the audit log together with the
+// fields the request changed. It must run after AuthMiddleware.
+type Auditor struct {
+	service *services.AuditService
+}
+
+// Constructor for Auditor
+func NewAuditor(service *services.AuditService) *Auditor {
+	return &Auditor{
+		service: service,
+	}
+}
+
+// Resource audits the routes of a group whose first path parameter, if any, identifies the
+// document being changed. Requests without one create a new document.
+func (a *Auditor) Resource(resourceType string) gin.HandlerFunc {
+	return a.audit(func(c *gin.Context) (string, string) {
+		if len(c.Params) == 0 {
+			return resourceType, ""
+		}
+		return resourceType, c.Params[0].Value
+	})
+}
+
+// Trash audits the trash routes, where the kind parameter names the collection
+func (a *Auditor) Trash() gin.HandlerFunc {
+	return a.audit(func(c *gin.Context) (string, string) {
+		return c.Param("kind"), c.Param("id")
+	})
+}
+
+func (a *Auditor) audit(resolve func(c *gin.Context) (string, string)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+
+		resourceType, resourceID := resolve(c)
+		var before map[string]interface{}
+		var err error
+		if resourceID != "" {
+			if before, err = a.service.Snapshot(resourceType, resourceID); err != nil {
+				log.Printf("audit: failed to load %s %s: %v", resourceType, resourceID, err)
+			}
+		}
+		var payload map[string]interface{}
+		if resourceID == "" {
+			payload = peekBody(c)
+		}
+
+		c.Next()
+		writeProblem(c)
+
+		// Rejected requests and replayed responses changed nothing
+		if c.Writer.Status() >= http.StatusBadRequest || c.GetBool("idempotent_replay") {
+			return
+		}
+
+		entry := models.AuditEntry{
+			Action:       auditAction(c.Request.Method, resourceID),
+			Route:        c.Request.Method + " " + c.FullPath(),
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			TraineeID:    c.GetString("authorized_trainee_id"),
+			Status:       c.Writer.Status(),
+		}
+		caller := identity(c)
+		entry.ActorRole = caller.Role
+		entry.ActorEmail = caller.Email
+		entry.ActorTraineeID = caller.TraineeID
+
+		after := payload
+		if resourceID != "" {
+			if after, err = a.service.Snapshot(resourceType, resourceID); err != nil {
+				log.Printf("audit: failed to load %s %s: %v", resourceType, resourceID, err)
+			}
+		}
+
+		// The response has already been sent, a failure here must not turn it into an error
+		if err := a.service.Record(entry, before, after); err != nil {
+			log.Printf("audit: failed to record %s: %v", entry.Route, err)
+		}
+	}
+}
+
+// auditAction maps a request to the action it performs on its resource
+func auditAction(method string, resourceID string) string {
+	switch {
+	case method == http.MethodDelete:
+		return models.AuditDelete
+	case method == http.MethodPost && resourceID == "":
+		return models.AuditCreate
+	default:
+		return models.AuditUpdate
+	}
+}
+
+// peekBody decodes a JSON body and restores it for the handler, returning nil if it is not a JSON object
+func peekBody(c *gin.Context) map[string]interface{} {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	return payload
+}
@@ -1,13 +1,14 @@
 package middlewares
 
 import (
+	"ironcoach/apperrors"
+	"ironcoach/services"
 	"ironcoach/utils"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(sessions *services.SessionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Always set CORS headers FIRST
 		origin := c.Request.Header.Get("Origin")
@@ -16,8 +17,8 @@ func AuthMiddleware() gin.HandlerFunc {
 			origin == "https://ironcoach.expo.app" {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-			c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
-			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, If-Match, Idempotency-Key")
+			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		}
 
 		// Handle OPTIONS preflight
@@ -29,17 +30,51 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Auth check after headers
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
+			abortWithError(c, apperrors.Unauthorized("token_required", "Authorization token required"))
 			return
 		}
 
 		claims, err := utils.VerifyJWT(tokenString)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			abortWithError(c, apperrors.Unauthorized("invalid_token", "invalid or expired token"))
 			return
 		}
 
-		c.Set("email", claims.Email)
+		// Reject tokens whose session was logged out or revoked
+		active, err := sessions.IsSessionActive(claims.ID)
+		if err != nil {
+			abortWithError(c, apperrors.Internal("Failed to verify session", err))
+			return
+		}
+		if !active {
+			abortWithError(c, apperrors.Unauthorized("session_revoked", "session has been revoked"))
+			return
+		}
+
+		c.Set("identity", claims.Identity)
+		c.Set("role", claims.Role)
+		c.Set("session_id", claims.ID)
+		if claims.Role == utils.RoleTrainee {
+			c.Set("trainee_id", claims.TraineeID)
+			c.Set("trainer_email", claims.TrainerID)
+		} else {
+			c.Set("email", claims.Email)
+			c.Set("trainer_email", claims.Email)
+		}
 		c.Next()
 	}
 }
+
+// RequireRole only lets callers with one of the given roles through. It must run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		abortWithError(c, apperrors.Forbidden("role_not_allowed", "This action is not available for your account"))
+	}
+}
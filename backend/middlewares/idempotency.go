@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"ironcoach/apperrors"
+	"ironcoach/services"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// Idempotent lets clients retry a create safely by sending an Idempotency-Key header. The first response
+// to a key is stored and replayed to retries of the same request, reusing the key with a different body is
+// rejected with 422. Requests without the header run as usual. It must run after AuthMiddleware.
+func Idempotent(service *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, apperrors.Validation("invalid_idempotency_key", "Idempotency-Key must be at most 255 characters"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, apperrors.Validation("invalid_body", "Failed to read request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		caller := identity(c)
+		actor := caller.Role + ":" + caller.Email + ":" + caller.TraineeID
+		record, replay, err := service.Begin(actor, c.Request.Method+" "+c.FullPath(), key, body)
+		switch {
+		case err == services.ErrIdempotencyKeyReused:
+			abortWithProblem(c, http.StatusUnprocessableEntity, services.ErrIdempotencyKeyReused)
+			return
+		case err != nil:
+			if _, ok := apperrors.As(err); !ok {
+				err = apperrors.Internal("Failed to check Idempotency-Key", err)
+			}
+			abortWithError(c, err)
+			return
+		case replay:
+			// Already recorded by the auditor the first time round
+			c.Set("idempotent_replay", true)
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.Status, record.ContentType, record.Body)
+			c.Abort()
+			return
+		}
+
+		// A handler that panics must not leave the key blocked until it expires
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if err := service.Finish(record.ID, http.StatusInternalServerError, "", nil); err != nil {
+					log.Printf("idempotency: failed to release key %s: %v", key, err)
+				}
+				panic(recovered)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		writeProblem(c)
+
+		if err := service.Finish(record.ID, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("idempotency: failed to store response for key %s: %v", key, err)
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body while writing it to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
@@ -0,0 +1,216 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"ironcoach/apperrors"
+	"ironcoach/services"
+	"ironcoach/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OwnershipGuard rejects requests for trainees, workout logs, diet entries, exercise
+// libraries and programs that the caller may not access: trainers reach their own trainees and those
+// shared through their organizations, trainees only reach themselves.
+// It must run after AuthMiddleware.
+type OwnershipGuard struct {
+	service *services.AuthorizationService
+}
+
+// Constructor for OwnershipGuard
+func NewOwnershipGuard(service *services.AuthorizationService) *OwnershipGuard {
+	return &OwnershipGuard{
+		service: service,
+	}
+}
+
+// Trainee authorizes the trainee referenced by the given path parameter.
+// Reads need read access, anything else needs write access.
+func (g *OwnershipGuard) Trainee(param string) gin.HandlerFunc {
+	return g.trainee(param, -1)
+}
+
+// ManageTrainee authorizes changes to the trainee itself, such as editing, deleting or transferring it
+func (g *OwnershipGuard) ManageTrainee(param string) gin.HandlerFunc {
+	return g.trainee(param, services.AccessManage)
+}
+
+func (g *OwnershipGuard) trainee(param string, access services.Access) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		required := access
+		if required < 0 {
+			required = accessFor(c)
+		}
+
+		traineeID := c.Param(param)
+		if _, err := g.service.AuthorizeTrainee(identity(c), traineeID, required); err != nil {
+			abortUnauthorized(c, "Trainee", err)
+			return
+		}
+
+		c.Set("authorized_trainee_id", traineeID)
+		c.Next()
+	}
+}
+
+// TraineeInBody authorizes the trainee referenced by the "trainee_id" field of a JSON body
+func (g *OwnershipGuard) TraineeInBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traineeID, ok := peekBodyField(c, "trainee_id")
+		if !ok {
+			return
+		}
+
+		if _, err := g.service.AuthorizeTrainee(identity(c), traineeID, services.AccessWrite); err != nil {
+			abortUnauthorized(c, "Trainee", err)
+			return
+		}
+
+		c.Set("authorized_trainee_id", traineeID)
+		c.Next()
+	}
+}
+
+// WorkoutLog authorizes the workout log referenced by the given path parameter
+func (g *OwnershipGuard) WorkoutLog(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traineeID, err := g.service.AuthorizeWorkoutLog(identity(c), c.Param(param), accessFor(c))
+		if err != nil {
+			abortUnauthorized(c, "Workout log", err)
+			return
+		}
+
+		c.Set("authorized_trainee_id", traineeID)
+		c.Next()
+	}
+}
+
+// DietEntry authorizes the diet entry referenced by the given path parameter
+func (g *OwnershipGuard) DietEntry(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traineeID, err := g.service.AuthorizeDietEntry(identity(c), c.Param(param), accessFor(c))
+		if err != nil {
+			abortUnauthorized(c, "Diet entry", err)
+			return
+		}
+
+		c.Set("authorized_trainee_id", traineeID)
+		c.Next()
+	}
+}
+
+// Category authorizes the exercise category referenced by the given path parameter
+func (g *OwnershipGuard) Category(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := g.service.AuthorizeCategory(identity(c), c.Param(param), accessFor(c)); err != nil {
+			abortUnauthorized(c, "Category", err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// CategoryInBody authorizes the category referenced by the "category_id" field of a JSON body
+func (g *OwnershipGuard) CategoryInBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categoryID, ok := peekBodyField(c, "category_id")
+		if !ok {
+			return
+		}
+
+		if _, err := g.service.AuthorizeCategory(identity(c), categoryID, services.AccessWrite); err != nil {
+			abortUnauthorized(c, "Category", err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Exercise authorizes the exercise referenced by the given path parameter
+func (g *OwnershipGuard) Exercise(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := g.service.AuthorizeExercise(identity(c), c.Param(param), accessFor(c)); err != nil {
+			abortUnauthorized(c, "Exercise", err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Program authorizes the training program referenced by the given path parameter
+func (g *OwnershipGuard) Program(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := g.service.AuthorizeProgram(identity(c), c.Param(param)); err != nil {
+			abortUnauthorized(c, "Program", err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Organization only lets members holding one of the given roles through (any member if none are given)
+func (g *OwnershipGuard) Organization(param string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := g.service.AuthorizeOrganization(c.GetString("email"), c.Param(param), roles...); err != nil {
+			abortUnauthorized(c, "Organization", err)
+			return
+		}
+		c.Next()
+	}
+}
+
+func identity(c *gin.Context) utils.Identity {
+	return c.MustGet("identity").(utils.Identity)
+}
+
+// accessFor derives the access a request needs from its method
+func accessFor(c *gin.Context) services.Access {
+	if c.Request.Method == http.MethodGet {
+		return services.AccessRead
+	}
+	return services.AccessWrite
+}
+
+// peekBodyField reads a string field from a JSON body and restores the body so the handler can still bind it.
+// It aborts the request and returns false if the field is missing.
+func peekBodyField(c *gin.Context, field string) (string, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		abortWithError(c, apperrors.Validation("invalid_body", "Failed to read request body"))
+		return "", false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		abortWithError(c, apperrors.Validation("invalid_body", err.Error()))
+		return "", false
+	}
+
+	value, _ := payload[field].(string)
+	if value == "" {
+		abortWithError(c, apperrors.InvalidFields(apperrors.FieldError{Field: field, Message: "is required"}))
+		return "", false
+	}
+	return value, true
+}
+
+// abortUnauthorized maps authorization failures to 404/403 responses naming the resource
+func abortUnauthorized(c *gin.Context, resource string, err error) {
+	switch err {
+	case services.ErrResourceNotFound:
+		code := strings.ToLower(strings.ReplaceAll(resource, " ", "_")) + "_not_found"
+		abortWithError(c, apperrors.NotFound(code, resource+" not found"))
+	case services.ErrAccessDenied:
+		abortWithError(c, apperrors.Forbidden("access_denied", "You do not have access to this resource"))
+	default:
+		if _, ok := apperrors.As(err); !ok {
+			err = apperrors.Internal("Failed to verify access", err)
+		}
+		abortWithError(c, err)
+	}
+}
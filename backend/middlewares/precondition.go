@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireIfMatch rejects writes to versioned documents that do not name the version they were based on,
+// so two clients cannot silently overwrite each other. The version is stored as "if_match" for the handler,
+// which must still check it against the stored document.
+func RequireIfMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("If-Match")
+		if header == "" {
+			abortWithProblem(c, http.StatusPreconditionRequired, apperrors.PreconditionFailed("if_match_required", "If-Match header with the ETag of the document is required"))
+			return
+		}
+
+		version, ok := utils.ParseETag(header)
+		if !ok {
+			abortWithError(c, apperrors.PreconditionFailed("version_conflict", "If-Match does not match the current version of the document"))
+			return
+		}
+
+		c.Set("if_match", version)
+		c.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"ironcoach/apperrors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problems answers the errors that handlers report with c.Error as problem+json.
+// Errors of the apperrors package get the status of their kind, any other error is an internal
+// error whose details stay in the log. It must run before every other middleware.
+func Problems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		writeProblem(c)
+	}
+}
+
+// writeProblem sends the last error of the request unless a response was already written.
+// Middlewares that look at the response after c.Next call it first, so they see the problem.
+func writeProblem(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+	err := c.Errors.Last().Err
+	appErr, ok := apperrors.As(err)
+	if !ok {
+		appErr = apperrors.Internal("Internal server error", err)
+	}
+	respondProblem(c, problemStatus(appErr), appErr)
+}
+
+// abortWithError ends the request with the problem for err
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+	writeProblem(c)
+}
+
+// abortWithProblem ends the request with the error at the given status, for the statuses that are
+// about HTTP itself rather than a kind of domain error
+func abortWithProblem(c *gin.Context, status int, err *apperrors.Error) {
+	c.Abort()
+	respondProblem(c, status, err)
+}
+
+// respondProblem writes the error as an RFC 7807 problem. Code is stable across releases, clients
+// switch on it rather than on the status or the text. error repeats the detail for clients that still
+// read the error field of earlier responses, the extensions of the error are added as they are.
+func respondProblem(c *gin.Context, status int, err *apperrors.Error) {
+	problem := gin.H{}
+	for key, value := range err.Extensions {
+		problem[key] = value
+	}
+	problem["type"] = "urn:ironcoach:problem:" + err.Code
+	problem["title"] = http.StatusText(status)
+	problem["status"] = status
+	problem["detail"] = err.Message
+	problem["instance"] = c.Request.URL.Path
+	problem["code"] = err.Code
+	problem["error"] = err.Message
+	if len(err.Fields) > 0 {
+		problem["fields"] = err.Fields
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.JSON(status, problem)
+}
+
+// problemStatus maps the kind of an error to its HTTP status
+func problemStatus(err *apperrors.Error) int {
+	switch err.Kind {
+	case apperrors.ErrNotFound:
+		return http.StatusNotFound
+	case apperrors.ErrConflict:
+		return http.StatusConflict
+	case apperrors.ErrValidation:
+		return http.StatusBadRequest
+	case apperrors.ErrForbidden:
+		return http.StatusForbidden
+	case apperrors.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case apperrors.ErrPrecondition:
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"ironcoach/apperrors"
 	"net"
 	"net/http"
 	"strings"
@@ -157,10 +158,7 @@ func RateLimitMiddleware(maxRequests int, per time.Duration, burst int) gin.Hand
 		if !limiter.allow(ip) {
 			c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", maxRequests))
 			c.Header("X-RateLimit-Window", per.String())
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error":   "Rate limit exceeded",
-				"message": "Too many requests, please slow down",
-			})
+			abortWithProblem(c, http.StatusTooManyRequests, &apperrors.Error{Code: "rate_limited", Message: "Too many requests, please slow down"})
 			return
 		}
 
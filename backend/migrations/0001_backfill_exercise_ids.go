@@ -0,0 +1,183 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Workout logs written before exercises had IDs only name their exercises. This links each such
+// entry to the exercise of that name in the library of the trainee's trainer and organization.
+// Entries whose exercise cannot be found keep no ID and are only logged.
+var backfillExerciseIDs = Migration{
+	Version:     1,
+	Description: "link workout log entries to their exercises by name",
+	Up: func(ctx context.Context, run *Run) error {
+		logs := run.DB.Collection("workout_logs")
+		libraries := newLibraryResolver(run.DB)
+
+		missing := bson.M{"$in": bson.A{nil, primitive.NilObjectID}}
+		cursor, err := logs.Find(ctx,
+			bson.M{"workouts": bson.M{"$elemMatch": bson.M{"exercise_id": missing}}},
+			options.Find().SetProjection(bson.M{"trainee_id": 1, "workouts.exercise": 1, "workouts.exercise_id": 1}),
+		)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		var unresolved int64
+		for cursor.Next(ctx) {
+			var log struct {
+				ID        primitive.ObjectID `bson:"_id"`
+				TraineeID string             `bson:"trainee_id"`
+				Workouts  []struct {
+					Exercise   string             `bson:"exercise"`
+					ExerciseID primitive.ObjectID `bson:"exercise_id"`
+				} `bson:"workouts"`
+			}
+			if err := cursor.Decode(&log); err != nil {
+				return err
+			}
+
+			set := bson.M{}
+			filter := bson.M{"_id": log.ID}
+			for i, workout := range log.Workouts {
+				if !workout.ExerciseID.IsZero() {
+					continue
+				}
+				exerciseID, err := libraries.exerciseID(ctx, log.TraineeID, workout.Exercise)
+				if err != nil {
+					return err
+				}
+				if exerciseID.IsZero() {
+					unresolved++
+					continue
+				}
+				set[fmt.Sprintf("workouts.%d.exercise_id", i)] = exerciseID
+				// Only if the entry is still the same exercise, the log may have been edited meanwhile
+				filter[fmt.Sprintf("workouts.%d.exercise", i)] = workout.Exercise
+			}
+			if len(set) == 0 {
+				continue
+			}
+
+			if run.DryRun {
+				run.Changed++
+				continue
+			}
+			result, err := logs.UpdateOne(ctx, filter, bson.M{"$set": set})
+			if err != nil {
+				return err
+			}
+			run.Changed += result.ModifiedCount
+		}
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+
+		if unresolved > 0 {
+			run.Logf("%d entries name an exercise that is not in their trainer's library, left without an ID", unresolved)
+		}
+		return nil
+	},
+	// The IDs are correct whatever the version, rolling back leaves them in place
+	Down: func(ctx context.Context, run *Run) error {
+		return nil
+	},
+}
+
+// libraryResolver looks up exercises by name in the library a trainee's logs draw from: the categories of
+// their trainer and of their organization. Lookups are cached, logs of a trainee repeat the same exercises.
+type libraryResolver struct {
+	db         *mongo.Database
+	categories map[string][]primitive.ObjectID
+	exercises  map[string]primitive.ObjectID
+}
+
+func newLibraryResolver(db *mongo.Database) *libraryResolver {
+	return &libraryResolver{
+		db:         db,
+		categories: map[string][]primitive.ObjectID{},
+		exercises:  map[string]primitive.ObjectID{},
+	}
+}
+
+// exerciseID returns the ID of the named exercise in the trainee's library, or a zero ID if there is none.
+// Exercises that are still active win over archived ones of the same name.
+func (r *libraryResolver) exerciseID(ctx context.Context, traineeID string, name string) (primitive.ObjectID, error) {
+	key := traineeID + "\x00" + name
+	if id, ok := r.exercises[key]; ok {
+		return id, nil
+	}
+
+	categoryIDs, err := r.library(ctx, traineeID)
+	if err != nil || len(categoryIDs) == 0 {
+		return primitive.NilObjectID, err
+	}
+
+	var exercise struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err = r.db.Collection("exercises").FindOne(ctx,
+		bson.M{"name": name, "category_id": bson.M{"$in": categoryIDs}},
+		options.FindOne().SetSort(bson.D{{Key: "archived_at", Value: 1}}).SetProjection(bson.M{"_id": 1}),
+	).Decode(&exercise)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return primitive.NilObjectID, err
+	}
+	r.exercises[key] = exercise.ID
+	return exercise.ID, nil
+}
+
+func (r *libraryResolver) library(ctx context.Context, traineeID string) ([]primitive.ObjectID, error) {
+	if categoryIDs, ok := r.categories[traineeID]; ok {
+		return categoryIDs, nil
+	}
+
+	var trainee struct {
+		TrainerID      string `bson:"trainer_id"`
+		OrganizationID string `bson:"organization_id"`
+	}
+	objectID, err := primitive.ObjectIDFromHex(traineeID)
+	if err != nil {
+		r.categories[traineeID] = nil
+		return nil, nil
+	}
+	err = r.db.Collection("trainees").FindOne(ctx, bson.M{"_id": objectID},
+		options.FindOne().SetProjection(bson.M{"trainer_id": 1, "organization_id": 1}),
+	).Decode(&trainee)
+	if err == mongo.ErrNoDocuments {
+		r.categories[traineeID] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	owners := bson.A{bson.M{"trainer_id": trainee.TrainerID}}
+	if trainee.OrganizationID != "" {
+		owners = append(owners, bson.M{"organization_id": trainee.OrganizationID})
+	}
+	cursor, err := r.db.Collection("categories").Find(ctx, bson.M{"$or": owners},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var categoryIDs []primitive.ObjectID
+	for cursor.Next(ctx) {
+		id, ok := cursor.Current.Lookup("_id").ObjectIDOK()
+		if ok {
+			categoryIDs = append(categoryIDs, id)
+		}
+	}
+	r.categories[traineeID] = categoryIDs
+	return categoryIDs, cursor.Err()
+}
@@ -0,0 +1,90 @@
+package migrations
+
+import (
+	"context"
+	"ironcoach/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// typedDateFields lists the fields of each collection that held dates as free-form strings before they were typed
+var typedDateFields = map[string][]string{
+	"workout_logs": {"date"},
+	"diet_entries": {"date"},
+	"progress":     {"date"},
+	"trainees":     {"dob", "start_date"},
+}
+
+// Dates used to be free-form strings. This stores them as BSON dates at midnight UTC, trashed documents
+// included. Empty strings are removed, values in no known layout are left alone and logged.
+// Versions are not bumped, the dates themselves do not change.
+var typedDates = Migration{
+	Version:     2,
+	Description: "store dates as BSON dates",
+	Up: func(ctx context.Context, run *Run) error {
+		return convertDateFields(ctx, run, bson.M{"$type": "string"}, func(value bson.RawValue) (bson.M, bool) {
+			text := value.StringValue()
+			if text == "" {
+				return bson.M{"$unset": ""}, true
+			}
+			date, err := models.ParseDate(text)
+			if err != nil {
+				return nil, false
+			}
+			return bson.M{"$set": date}, true
+		})
+	},
+	// Dates go back to YYYY-MM-DD strings, the layout nearly all of them were written in
+	Down: func(ctx context.Context, run *Run) error {
+		return convertDateFields(ctx, run, bson.M{"$type": "date"}, func(value bson.RawValue) (bson.M, bool) {
+			return bson.M{"$set": models.DateOf(value.Time().UTC()).String()}, true
+		})
+	},
+}
+
+// convertDateFields rewrites every date field holding a value of the matched type. convert returns the
+// update operator and value for the field, or false to leave it alone.
+func convertDateFields(ctx context.Context, run *Run, match bson.M, convert func(value bson.RawValue) (bson.M, bool)) error {
+	for name, fields := range typedDateFields {
+		collection := run.DB.Collection(name)
+		for _, field := range fields {
+			cursor, err := collection.Find(ctx, bson.M{field: match}, options.Find().SetProjection(bson.M{field: 1}))
+			if err != nil {
+				return err
+			}
+
+			for cursor.Next(ctx) {
+				id := cursor.Current.Lookup("_id")
+				value := cursor.Current.Lookup(field)
+				operation, ok := convert(value)
+				if !ok {
+					run.Logf("skipped %s %v: %s %v is not a date", name, id, field, value)
+					continue
+				}
+				if run.DryRun {
+					run.Changed++
+					continue
+				}
+
+				update := bson.M{}
+				for operator, converted := range operation {
+					update[operator] = bson.M{field: converted}
+				}
+				// Leave values alone that were rewritten since they were read
+				result, err := collection.UpdateOne(ctx, bson.M{"_id": id, field: value}, update)
+				if err != nil {
+					cursor.Close(ctx)
+					return err
+				}
+				run.Changed += result.ModifiedCount
+			}
+			err = cursor.Err()
+			cursor.Close(ctx)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"ironcoach/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Workouts used to record their sets as a count with the reps and the weight of each in parallel
+// arrays. This turns each pair of reps and weight into a working set, trashed logs included. Values
+// without a counterpart, when the arrays were of different lengths, are dropped and logged.
+// Versions are not bumped, what was logged does not change.
+var structuredSets = Migration{
+	Version:     3,
+	Description: "store workout sets as structured sets",
+	Up: func(ctx context.Context, run *Run) error {
+		var dropped int64
+		err := convertWorkouts(ctx, run, bson.M{"$not": bson.M{"$type": "array"}}, func(workout bson.Raw) (bson.M, error) {
+			if workout.Lookup("sets").Type == bson.TypeArray {
+				return nil, nil
+			}
+			var legacy struct {
+				Reps   []int     `bson:"reps"`
+				Weight []float64 `bson:"weight"`
+			}
+			if err := bson.Unmarshal(workout, &legacy); err != nil {
+				return nil, err
+			}
+
+			count := min(len(legacy.Reps), len(legacy.Weight))
+			if len(legacy.Reps) != len(legacy.Weight) {
+				dropped++
+			}
+			sets := make(bson.A, count)
+			for i := 0; i < count; i++ {
+				sets[i] = bson.M{"type": models.SetWorking, "reps": legacy.Reps[i], "weight": legacy.Weight[i]}
+			}
+			return bson.M{
+				"$set":   bson.M{"sets": sets},
+				"$unset": bson.M{"reps": "", "weight": ""},
+			}, nil
+		})
+		if dropped > 0 {
+			run.Logf("%d workouts had reps and weights of different lengths, the values without a counterpart were dropped", dropped)
+		}
+		return err
+	},
+	// Sets go back to their count, reps and weight. What else they record is lost, warm-ups become
+	// plain sets.
+	Down: func(ctx context.Context, run *Run) error {
+		return convertWorkouts(ctx, run, bson.M{"$type": "array"}, func(workout bson.Raw) (bson.M, error) {
+			if workout.Lookup("sets").Type != bson.TypeArray {
+				return nil, nil
+			}
+			var structured struct {
+				Sets []struct {
+					Reps   int     `bson:"reps"`
+					Weight float64 `bson:"weight"`
+				} `bson:"sets"`
+			}
+			if err := bson.Unmarshal(workout, &structured); err != nil {
+				return nil, err
+			}
+
+			reps := make(bson.A, len(structured.Sets))
+			weights := make(bson.A, len(structured.Sets))
+			for i, set := range structured.Sets {
+				reps[i], weights[i] = set.Reps, set.Weight
+			}
+			return bson.M{"$set": bson.M{"sets": len(structured.Sets), "reps": reps, "weight": weights}}, nil
+		})
+	},
+}
+
+// convertWorkouts rewrites the workouts of every log that has one whose sets match. convert returns
+// the update operators for the fields of a workout, or nil to leave it alone. Workouts it fails to
+// read are logged and left alone.
+func convertWorkouts(ctx context.Context, run *Run, match bson.M, convert func(workout bson.Raw) (bson.M, error)) error {
+	logs := run.DB.Collection("workout_logs")
+	cursor, err := logs.Find(ctx,
+		bson.M{"workouts": bson.M{"$elemMatch": bson.M{"sets": match}}},
+		options.Find().SetProjection(bson.M{"workouts": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		id := cursor.Current.Lookup("_id")
+		workouts, err := cursor.Current.Lookup("workouts").Array().Values()
+		if err != nil {
+			return err
+		}
+
+		update := bson.M{}
+		filter := bson.M{"_id": id}
+		for i, value := range workouts {
+			workout, ok := value.DocumentOK()
+			if !ok {
+				continue
+			}
+			operation, err := convert(workout)
+			if err != nil {
+				run.Logf("skipped workout %d of log %v: %v", i, id, err)
+				continue
+			}
+			if operation == nil {
+				continue
+			}
+			for operator, fields := range operation {
+				if update[operator] == nil {
+					update[operator] = bson.M{}
+				}
+				for field, converted := range fields.(bson.M) {
+					update[operator].(bson.M)[fmt.Sprintf("workouts.%d.%s", i, field)] = converted
+				}
+			}
+			// Only if the workout is still the same, the log may have been edited meanwhile
+			filter[fmt.Sprintf("workouts.%d", i)] = workout
+		}
+		if len(update) == 0 {
+			continue
+		}
+
+		if run.DryRun {
+			run.Changed++
+			continue
+		}
+		result, err := logs.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return err
+		}
+		run.Changed += result.ModifiedCount
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"context"
+	"ironcoach/repositories"
+	"ironcoach/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Personal records are kept up to date as logs are written, for the exercises each write touches.
+// This works out every record of each trainee who has logs, those logged before records were kept
+// included. Changed counts the trainees whose records were worked out.
+var backfillPersonalRecords = Migration{
+	Version:     4,
+	Description: "work out the personal records of every trainee",
+	Up: func(ctx context.Context, run *Run) error {
+		traineeIDs, err := run.DB.Collection("workout_logs").Distinct(ctx, "trainee_id", bson.M{"deleted_at": bson.M{"$exists": false}})
+		if err != nil {
+			return err
+		}
+
+		records := services.NewPersonalRecordService(repositories.Repositories{
+			PersonalRecords: repositories.NewMongoPersonalRecordRepository(run.DB),
+			WorkoutLogs:     repositories.NewMongoWorkoutLogRepository(run.DB),
+		})
+		for _, value := range traineeIDs {
+			traineeID, ok := value.(string)
+			if !ok {
+				continue
+			}
+			if !run.DryRun {
+				if err := records.Recompute(traineeID); err != nil {
+					return err
+				}
+			}
+			run.Changed++
+		}
+		return nil
+	},
+	// Records are worked out from the logs, dropping them loses nothing up cannot restore
+	Down: func(ctx context.Context, run *Run) error {
+		personalRecords := run.DB.Collection("personal_records")
+		if run.DryRun {
+			count, err := personalRecords.CountDocuments(ctx, bson.M{})
+			run.Changed += count
+			return err
+		}
+		result, err := personalRecords.DeleteMany(ctx, bson.M{})
+		if err != nil {
+			return err
+		}
+		run.Changed += result.DeletedCount
+		return nil
+	},
+}
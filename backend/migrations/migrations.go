@@ -0,0 +1,348 @@
+// Package migrations changes stored data from one schema version to the next. Migrations are
+// numbered, run in order and recorded in the schema_migrations collection, so each is applied once.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionName = "schema_migrations"
+	lockID         = "lock"
+	lockTTL        = 5 * time.Minute
+)
+
+// States of a recorded migration
+const (
+	StatePending   = "pending"   // Not applied yet
+	StateRunning   = "running"   // Started but not finished, it is retried on the next up
+	StateApplied   = "applied"   // Done
+	StateReverting = "reverting" // Rollback started but not finished, it is retried on the next down
+)
+
+var (
+	ErrLocked       = errors.New("another migration run is in progress")
+	ErrIrreversible = errors.New("migration cannot be rolled back")
+)
+
+// Migration is one versioned change to the stored data. A run that is interrupted is retried from
+// the start, so Up and Down must only change what still needs changing.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, run *Run) error
+	Down        func(ctx context.Context, run *Run) error // nil if the migration cannot be rolled back
+}
+
+// all lists every migration. New migrations get the next version and are never renumbered once released.
+var all = []Migration{
+	backfillExerciseIDs,
+	typedDates,
+	structuredSets,
+	backfillPersonalRecords,
+}
+
+// Run is what a migration works with. In a dry run nothing may be written, the migration only
+// counts what it would change.
+type Run struct {
+	DB      *mongo.Database
+	DryRun  bool
+	Changed int64 // Documents changed, or that would be in a dry run
+	prefix  string
+}
+
+// Logf logs progress of the migration
+func (r *Run) Logf(format string, args ...interface{}) {
+	log.Printf(r.prefix+format, args...)
+}
+
+// Status is the state of one migration in the database
+type Status struct {
+	Version     int        `bson:"_id"`
+	Description string     `bson:"description"`
+	State       string     `bson:"state"`
+	StartedAt   time.Time  `bson:"started_at"`
+	AppliedAt   *time.Time `bson:"applied_at,omitempty"`
+	Changed     int64      `bson:"changed"`
+}
+
+// Result reports a migration that was run
+type Result struct {
+	Version     int
+	Description string
+	Direction   string
+	Changed     int64
+	Duration    time.Duration
+}
+
+// Migrator applies and rolls back the migrations of a database
+type Migrator struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+	migrations []Migration
+	owner      string
+}
+
+// Constructor for Migrator
+func New(db *mongo.Database) *Migrator {
+	host, _ := os.Hostname()
+	return &Migrator{
+		db:         db,
+		collection: db.Collection(collectionName),
+		migrations: all,
+		owner:      fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano()),
+	}
+}
+
+// Status lists every known migration with its state, followed by any applied by a newer version of the server
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	recorded, err := m.recorded(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status, ok := recorded[migration.Version]
+		if !ok {
+			status = Status{Version: migration.Version, Description: migration.Description, State: StatePending}
+		}
+		delete(recorded, migration.Version)
+		statuses = append(statuses, status)
+	}
+	for _, status := range recorded {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Up applies the migrations that are not applied yet, in order, up to and including the target version.
+// A target of 0 applies all of them. It stops at the first migration that fails.
+// A dry run reports each migration as if the ones before it had been applied.
+func (m *Migrator) Up(ctx context.Context, target int, dryRun bool) ([]Result, error) {
+	var results []Result
+	err := m.locked(ctx, dryRun, func(recorded map[int]Status) error {
+		for _, migration := range m.migrations {
+			if target > 0 && migration.Version > target {
+				break
+			}
+			if recorded[migration.Version].State == StateApplied {
+				continue
+			}
+
+			result, err := m.run(ctx, migration, "up", dryRun)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Down rolls back the applied migrations above the target version, newest first. Use a target of 0 to
+// roll back everything. Migrations that cannot be rolled back stop it before anything below them.
+func (m *Migrator) Down(ctx context.Context, target int, dryRun bool) ([]Result, error) {
+	var results []Result
+	err := m.locked(ctx, dryRun, func(recorded map[int]Status) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if migration.Version <= target {
+				break
+			}
+			if _, ok := recorded[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("%04d %s: %w", migration.Version, migration.Description, ErrIrreversible)
+			}
+
+			result, err := m.run(ctx, migration, "down", dryRun)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Previous returns the version before the latest applied one, the target that rolls back a single migration
+func (m *Migrator) Previous(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	previous, latest := 0, 0
+	for _, status := range statuses {
+		if status.State != StatePending {
+			previous, latest = latest, status.Version
+		}
+	}
+	return previous, nil
+}
+
+func (m *Migrator) run(ctx context.Context, migration Migration, direction string, dryRun bool) (Result, error) {
+	result := Result{Version: migration.Version, Description: migration.Description, Direction: direction}
+	run := &Run{DB: m.db, DryRun: dryRun, prefix: fmt.Sprintf("migration %04d %s: ", migration.Version, direction)}
+	started := time.Now()
+
+	step, state := migration.Up, StateRunning
+	if direction == "down" {
+		step, state = migration.Down, StateReverting
+	}
+
+	if !dryRun {
+		// Mark the migration first, an interrupted run is then retried rather than taken as done
+		_, err := m.collection.UpdateOne(ctx,
+			bson.M{"_id": migration.Version},
+			bson.M{
+				"$set":         bson.M{"description": migration.Description, "state": state},
+				"$setOnInsert": bson.M{"started_at": started},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if err := step(ctx, run); err != nil {
+		return result, fmt.Errorf("%04d %s %s: %w", migration.Version, migration.Description, direction, err)
+	}
+	result.Changed = run.Changed
+	result.Duration = time.Since(started)
+	if dryRun {
+		return result, nil
+	}
+
+	var err error
+	if direction == "down" {
+		_, err = m.collection.DeleteOne(ctx, bson.M{"_id": migration.Version})
+	} else {
+		_, err = m.collection.UpdateOne(ctx, bson.M{"_id": migration.Version}, bson.M{"$set": bson.M{
+			"state":      StateApplied,
+			"applied_at": time.Now(),
+			"changed":    run.Changed,
+		}})
+	}
+	return result, err
+}
+
+// locked runs fn while holding the migration lock, so two servers starting at once or a server
+// and the CLI never migrate at the same time. Dry runs write nothing and take no lock.
+func (m *Migrator) locked(ctx context.Context, dryRun bool, fn func(recorded map[int]Status) error) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if err := m.lock(ctx); err != nil {
+			return err
+		}
+		defer m.unlock()
+
+		// Keep the lock while migrations run longer than it lasts
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(lockTTL / 3)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					m.collection.UpdateOne(context.Background(),
+						bson.M{"_id": lockID, "owner": m.owner},
+						bson.M{"$set": bson.M{"expires_at": time.Now().Add(lockTTL)}},
+					)
+				}
+			}
+		}()
+	}
+
+	recorded, err := m.recorded(ctx)
+	if err != nil {
+		return err
+	}
+	return fn(recorded)
+}
+
+func (m *Migrator) lock(ctx context.Context) error {
+	now := time.Now()
+	lock := bson.M{"_id": lockID, "owner": m.owner, "expires_at": now.Add(lockTTL)}
+	_, err := m.collection.InsertOne(ctx, lock)
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	// Take over a lock whose owner died without releasing it
+	result, err := m.collection.UpdateOne(ctx,
+		bson.M{"_id": lockID, "expires_at": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"owner": m.owner, "expires_at": now.Add(lockTTL)}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLocked
+	}
+	return nil
+}
+
+func (m *Migrator) unlock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := m.collection.DeleteOne(ctx, bson.M{"_id": lockID, "owner": m.owner}); err != nil {
+		log.Printf("Failed to release the migration lock: %v", err)
+	}
+}
+
+func (m *Migrator) recorded(ctx context.Context) (map[int]Status, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$ne": lockID}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var statuses []Status
+	if err := cursor.All(ctx, &statuses); err != nil {
+		return nil, err
+	}
+	recorded := map[int]Status{}
+	for _, status := range statuses {
+		recorded[status.Version] = status
+	}
+	return recorded, nil
+}
+
+// validate catches migrations listed out of order or twice
+func (m *Migrator) validate() error {
+	for i, migration := range m.migrations {
+		if migration.Version <= 0 || migration.Up == nil {
+			return fmt.Errorf("migration %d needs a positive version and an up step", migration.Version)
+		}
+		if i > 0 && migration.Version <= m.migrations[i-1].Version {
+			return fmt.Errorf("migration %d is listed after %d, versions must increase", migration.Version, m.migrations[i-1].Version)
+		}
+	}
+	return nil
+}
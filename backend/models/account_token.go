@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// AccountToken records a signed single-use token sent to a trainer by email
+type AccountToken struct {
+	ID        string     `bson:"_id" json:"id"`                              // Token ID, carried in the jti claim
+	Email     string     `bson:"email" json:"email"`                         // Trainer the token was issued to
+	Purpose   string     `bson:"purpose" json:"purpose"`                     // password_reset or email_verification
+	ExpiresAt time.Time  `bson:"expires_at" json:"expires_at"`               // Token expiry
+	UsedAt    *time.Time `bson:"used_at,omitempty" json:"used_at,omitempty"` // Set once the token is consumed or superseded
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit actions, derived from the HTTP method of the request
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditChange is the value of a single field before and after a request
+type AuditChange struct {
+	Before interface{} `bson:"before,omitempty" json:"before,omitempty"`
+	After  interface{} `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+// AuditEntry records one successful mutation. Entries are only ever appended, never edited.
+type AuditEntry struct {
+	ID             primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ActorEmail     string                 `bson:"actor_email,omitempty" json:"actor_email,omitempty"`           // Trainer who made the change
+	ActorTraineeID string                 `bson:"actor_trainee_id,omitempty" json:"actor_trainee_id,omitempty"` // Trainee who made the change from their own login
+	ActorRole      string                 `bson:"actor_role" json:"actor_role"`
+	Action         string                 `bson:"action" json:"action"`
+	Route          string                 `bson:"route" json:"route"` // e.g. "POST /trainees/:id/transfer"
+	ResourceType   string                 `bson:"resource_type" json:"resource_type"`
+	ResourceID     string                 `bson:"resource_id,omitempty" json:"resource_id,omitempty"` // Empty for creates
+	TraineeID      string                 `bson:"trainee_id,omitempty" json:"trainee_id,omitempty"`
+	OrganizationID string                 `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	Changes        map[string]AuditChange `bson:"changes" json:"changes"`
+	Status         int                    `bson:"status" json:"status"`
+	CreatedAt      time.Time              `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DateLayout is how dates are sent to clients and accepted in queries
+const DateLayout = "2006-01-02"
+
+// Layouts accepted from clients: YYYY-MM-DD, the DD/MM/YYYY the trainee forms use and timestamps,
+// whose date is taken as written rather than converted to UTC
+var dateLayouts = []string{DateLayout, "02/01/2006", time.RFC3339, "2006-01-02T15:04:05"}
+
+var ErrInvalidDate = errors.New("invalid date, expected YYYY-MM-DD")
+
+// Date is a calendar day without a time of day or time zone. It is sent as YYYY-MM-DD and stored as a
+// BSON date at midnight UTC, so stored dates sort and compare correctly in range queries.
+// The zero Date stands for no date and is sent as "".
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the given day
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf returns the day of a point in time in the time zone it is in
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return NewDate(year, month, day)
+}
+
+// Today returns the current day in the time zone
+func Today(location *time.Location) Date {
+	return DateOf(time.Now().In(location))
+}
+
+// ParseDate reads a date in any of the layouts clients send. An empty value is the zero Date.
+func ParseDate(value string) (Date, error) {
+	if value == "" {
+		return Date{}, nil
+	}
+	for _, layout := range dateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return DateOf(parsed), nil
+		}
+	}
+	return Date{}, ErrInvalidDate
+}
+
+// AddDays returns the date the given number of days later, or earlier if negative
+func (d Date) AddDays(days int) Date {
+	return Date{d.Time.AddDate(0, 0, days)}
+}
+
+// String returns the date as YYYY-MM-DD, or "" for the zero Date
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = Date{}
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid date %s, expected YYYY-MM-DD", data)
+	}
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Date) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if d.IsZero() {
+		return bson.TypeNull, nil, nil
+	}
+	return bson.MarshalValue(primitive.NewDateTimeFromTime(d.Time))
+}
+
+// UnmarshalBSONValue reads stored dates. Dates stored as strings before they were typed are parsed,
+// those that cannot be are read as the zero Date rather than failing the whole query.
+func (d *Date) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bson.TypeDateTime:
+		*d = DateOf(value.Time().UTC())
+	case bson.TypeString:
+		*d, _ = ParseDate(value.StringValue())
+	case bson.TypeNull, bson.TypeUndefined:
+		*d = Date{}
+	default:
+		return fmt.Errorf("cannot decode %v into a date", t)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package models
+
+// DeletionSummary counts the documents a cascading delete removed, keyed by collection name
+type DeletionSummary map[string]int64
+
+// Add records the documents deleted from a collection
+func (s DeletionSummary) Add(collection string, deleted int64) {
+	s[collection] += deleted
+}
+
+// Total is the number of documents deleted across all collections
+func (s DeletionSummary) Total() int64 {
+	var total int64
+	for _, deleted := range s {
+		total += deleted
+	}
+	return total
+}
@@ -23,13 +23,18 @@ type Meal struct {
 
 // DietEntry represents a log entry for a trainee's diet
 type DietEntry struct {
-	ID            string    `bson:"_id,omitempty" json:"id"`                // Unique ID for the diet entry
-	TraineeID     string    `json:"trainee_id" bson:"trainee_id"`           // Link to Trainee
-	Date          string    `json:"date" bson:"date"`                       // Date of the diet entry (ISO 8601)
-	Meals         []Meal    `json:"meals" bson:"meals"`                     // List of meals
-	TotalCalories float64   `json:"total_calories" bson:"total_calories"`   // Total calories for the day
-	TotalProteins float64   `json:"total_proteins" bson:"total_proteins"`   // Total proteins for the day
-	Notes         string    `json:"notes,omitempty" bson:"notes,omitempty"` // Additional notes
-	CreatedAt     time.Time `json:"created_at" bson:"created_at"`           // Timestamp for record creation
-	UpdatedAt     time.Time `json:"updated_at" bson:"updated_at"`           // Timestamp for last update
+	ID            string     `bson:"_id,omitempty" json:"id"`                          // Unique ID for the diet entry
+	TraineeID     string     `json:"trainee_id" bson:"trainee_id"`                     // Link to Trainee
+	Date          Date       `json:"date" bson:"date"`                                 // Date of the diet entry
+	Meals         []Meal     `json:"meals" bson:"meals"`                               // List of meals
+	TotalCalories float64    `json:"total_calories" bson:"total_calories"`             // Total calories for the day
+	TotalProteins float64    `json:"total_proteins" bson:"total_proteins"`             // Total proteins for the day
+	Notes         string     `json:"notes,omitempty" bson:"notes,omitempty"`           // Additional notes
+	CreatedAt     time.Time  `json:"created_at" bson:"created_at"`                     // Timestamp for record creation
+	UpdatedAt     time.Time  `json:"updated_at" bson:"updated_at"`                     // Timestamp for last update
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // Set while the entry is in the trash
+	Version       int64      `json:"version" bson:"version"`                           // Incremented on every write, sent as the ETag
+	ClientID      string     `json:"client_id,omitempty" bson:"client_id,omitempty"`   // ID the offline client created it under
+	LastChangeID  string     `json:"-" bson:"last_change_id,omitempty"`                // Last change applied through sync, to recognize retries
+	SyncSeq       int64      `json:"-" bson:"sync_seq"`                                // Position in the change feed offline clients pull
 }
@@ -11,6 +11,7 @@ type Exercise struct {
 	Name       string             `bson:"name" json:"name" binding:"required"`
 	Category   string             `bson:"category" json:"category" binding:"required"`
 	CategoryID primitive.ObjectID `json:"category_id" bson:"category_id"`
-	CreatedAt  time.Time          `json:"created_at" bson:"created_at"` // Timestamp for record creation
-	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"` // Timestamp for last update
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`                       // Timestamp for record creation
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`                       // Timestamp for last update
+	ArchivedAt *time.Time         `json:"archived_at,omitempty" bson:"archived_at,omitempty"` // Set once archived, logged history keeps pointing at it
 }
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// States of an idempotency record
+const (
+	IdempotencyPending   = "pending"   // The first request with the key is still running
+	IdempotencyCompleted = "completed" // The response is stored and replayed on retries
+)
+
+// IdempotencyRecord remembers the response to a create request sent with an Idempotency-Key header
+type IdempotencyRecord struct {
+	ID          string    `bson:"_id" json:"id"`                  // Hash of the caller, route and key
+	Key         string    `bson:"key" json:"key"`                 // Idempotency-Key as sent by the client
+	Actor       string    `bson:"actor" json:"actor"`             // Trainer email or trainee ID the key belongs to
+	Route       string    `bson:"route" json:"route"`             // Method and route the key was used on
+	RequestHash string    `bson:"request_hash" json:"-"`          // SHA-256 of the request body
+	State       string    `bson:"state" json:"state"`             // pending or completed
+	Status      int       `bson:"status,omitempty" json:"status"` // Stored response status
+	ContentType string    `bson:"content_type,omitempty" json:"-"`
+	Body        []byte    `bson:"body,omitempty" json:"-"` // Stored response body
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	ExpiresAt   time.Time `bson:"expires_at" json:"expires_at"` // The key can be used for a new request after this
+}
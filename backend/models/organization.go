@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Member roles within an organization
+const (
+	OrgRoleOwner     = "owner"     // Manages members, full access to every trainee and library
+	OrgRoleCoach     = "coach"     // Full access to every trainee and library
+	OrgRoleAssistant = "assistant" // Reads trainees and records logs, diet and progress
+)
+
+// OrganizationMember is a trainer's membership in an organization
+type OrganizationMember struct {
+	Email    string    `bson:"email" json:"email"`
+	Role     string    `bson:"role" json:"role"`
+	JoinedAt time.Time `bson:"joined_at" json:"joined_at"`
+}
+
+// Organization is a gym whose coaches share trainees and exercise libraries
+type Organization struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name      string               `bson:"name" json:"name" binding:"required"`
+	Members   []OrganizationMember `bson:"members" json:"members"`
+	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// MemberRole returns the role of the trainer in the organization, or "" if they are not a member
+func (o Organization) MemberRole(email string) string {
+	for _, member := range o.Members {
+		if member.Email == email {
+			return member.Role
+		}
+	}
+	return ""
+}
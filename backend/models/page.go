@@ -0,0 +1,17 @@
+package models
+
+// ListOptions selects one page of a list endpoint
+type ListOptions struct {
+	Cursor string // next_cursor of the previous page, empty for the first page
+	Limit  int64
+	Sort   string // Field to order by
+	Desc   bool
+	From   Date // Inclusive date range for lists of dated records, either end may be open
+	To     Date
+}
+
+// Page is the envelope every paginated list is sent in
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor"` // Pass as cursor to get the next page, empty on the last one
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+)
+
+// PatchFields turns a patch DTO into a $set document. Patches hold a pointer for every field a client
+// may change, a nil pointer leaves the stored value alone. Fields are named after their bson tags.
+func PatchFields(patch interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	value := reflect.Indirect(reflect.ValueOf(patch))
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() != reflect.Ptr || field.IsNil() {
+			continue
+		}
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("bson"), ",")
+		fields[name] = field.Interface()
+	}
+	return fields
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of personal records
+const (
+	RecordHeaviestWeight = "heaviest_weight" // Heaviest weight lifted for at least one rep
+	RecordRepsAtWeight   = "reps_at_weight"  // Most reps at a weight, or at a heavier one
+	RecordEstimated1RM   = "estimated_1rm"   // Best one-rep max estimated from a set with the Epley formula
+	RecordSessionVolume  = "session_volume"  // Most weight times reps of the exercise in one workout log
+)
+
+// PersonalRecord is a workout log that beat the best the trainee had done at an exercise. The first
+// session of an exercise sets the marks to beat and holds no records. Records are worked out again
+// from the logs whenever one is written, so editing or deleting a log moves them to where they now
+// belong.
+type PersonalRecord struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TraineeID  string             `json:"trainee_id" bson:"trainee_id"`
+	Exercise   string             `json:"exercise" bson:"exercise"`       // Exercise name
+	ExerciseID primitive.ObjectID `json:"exercise_id" bson:"exercise_id"` // Link to Exercise
+	Kind       string             `json:"kind" bson:"kind"`
+	Value      float64            `json:"value" bson:"value"`                       // Reps for reps_at_weight, kg for the other kinds
+	Previous   float64            `json:"previous" bson:"previous"`                 // Best it beat
+	Weight     float64            `json:"weight,omitempty" bson:"weight,omitempty"` // Set the record was made with, not set for session volume
+	Reps       int                `json:"reps,omitempty" bson:"reps,omitempty"`
+	LogID      string             `json:"log_id" bson:"log_id"` // Workout log it was made in
+	Date       Date               `json:"date" bson:"date"`
+	Current    bool               `json:"current" bson:"current"`       // Not beaten since, per kind and, for reps, per weight
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"` // When the record was first seen
+	Key        string             `json:"-" bson:"key,omitempty"`       // Log, exercise, kind and weight, unique per trainee
+}
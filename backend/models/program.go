@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetTarget is what a plan prescribes for one exercise. The load is either a fixed weight or a
+// percentage of the trainee's one-rep max, which is turned into a weight when a workout is logged.
+type SetTarget struct {
+	Sets        int     `json:"sets" bson:"sets" binding:"min=1"`                                                  // Number of working sets
+	RepsMin     int     `json:"reps_min" bson:"reps_min" binding:"min=1"`                                          // Bottom of the rep range
+	RepsMax     int     `json:"reps_max,omitempty" bson:"reps_max,omitempty" binding:"omitempty,gtefield=RepsMin"` // Top of the rep range, the same as RepsMin if unset
+	Weight      float64 `json:"weight,omitempty" bson:"weight,omitempty" binding:"gte=0"`                          // Target load
+	Percent1RM  float64 `json:"percent_1rm,omitempty" bson:"percent_1rm,omitempty" binding:"gte=0,lte=120"`        // Target load as a percentage of the 1RM, 75 for 75%
+	RestSeconds int     `json:"rest_seconds,omitempty" bson:"rest_seconds,omitempty" binding:"gte=0"`              // Rest between sets
+}
+
+// TemplateExercise is one exercise of a workout template
+type TemplateExercise struct {
+	Exercise   string             `json:"exercise" bson:"exercise" binding:"required"` // Exercise name
+	ExerciseID primitive.ObjectID `json:"exercise_id" bson:"exercise_id"`              // Link to Exercise
+	SetTarget  `bson:",inline"`
+	Notes      string `json:"notes,omitempty" bson:"notes,omitempty"` // Cues for the trainee
+}
+
+// WorkoutTemplate is a planned workout on one day of a program. Week and Day count from the day
+// the trainee starts the program, so day 1 of week 1 is the start date.
+type WorkoutTemplate struct {
+	ID        string             `json:"id" bson:"id"`                                    // Stable within the program, assigned when missing
+	Name      string             `json:"name" bson:"name" binding:"required"`             // e.g. "Upper A"
+	Week      int                `json:"week" bson:"week" binding:"min=1"`                // Week of the program, from 1
+	Day       int                `json:"day" bson:"day" binding:"min=1,max=7"`            // Day of that week, from 1 to 7
+	Exercises []TemplateExercise `json:"exercises" bson:"exercises" binding:"min=1,dive"` // Planned exercises
+	Notes     string             `json:"notes,omitempty" bson:"notes,omitempty"`          // Additional notes
+}
+
+// Program is a multi-week training plan a trainer builds once and assigns to trainees
+type Program struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TrainerID   string             `json:"trainer_id" bson:"trainer_id"` // Trainer who wrote the program
+	Name        string             `json:"name" bson:"name" binding:"required"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Weeks       int                `json:"weeks" bson:"weeks" binding:"min=1,max=52"` // Length of the program
+	Workouts    []WorkoutTemplate  `json:"workouts" bson:"workouts" binding:"dive"`   // Planned workouts, rest days have none
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// Template returns the workout template with the given ID
+func (p Program) Template(id string) (WorkoutTemplate, bool) {
+	for _, template := range p.Workouts {
+		if template.ID == id {
+			return template, true
+		}
+	}
+	return WorkoutTemplate{}, false
+}
+
+// TemplateOn returns the workout template planned for a day of a week
+func (p Program) TemplateOn(week int, day int) (WorkoutTemplate, bool) {
+	for _, template := range p.Workouts {
+		if template.Week == week && template.Day == day {
+			return template, true
+		}
+	}
+	return WorkoutTemplate{}, false
+}
+
+// ProgramAssignment is the program a trainee follows. A trainee follows one program at a time.
+type ProgramAssignment struct {
+	TraineeID  string    `json:"trainee_id" bson:"_id"` // Same as the trainee ID, one assignment per trainee
+	ProgramID  string    `json:"program_id" bson:"program_id"`
+	AssignedBy string    `json:"assigned_by" bson:"assigned_by"` // Trainer who assigned the program
+	StartDate  Date      `json:"start_date" bson:"start_date"`   // Day 1 of week 1
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	Program    *Program  `json:"program,omitempty" bson:"-"` // Filled in when the assignment is read
+}
+
+// PlannedWorkout is what a trainee's program has planned for a date. Workout is nil on rest days
+// and outside the program.
+type PlannedWorkout struct {
+	ProgramID   string           `json:"program_id"`
+	ProgramName string           `json:"program_name"`
+	Date        Date             `json:"date"`
+	Week        int              `json:"week"`     // 0 before the program starts
+	Day         int              `json:"day"`      // 0 before the program starts
+	Finished    bool             `json:"finished"` // The date is after the last week of the program
+	Workout     *WorkoutTemplate `json:"workout"`
+}
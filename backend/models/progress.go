@@ -2,34 +2,131 @@ package models
 
 import "time"
 
+// Progress is what the progress screen charts: the weight of the trainee and the analytics of their
+// workout logs, with the series bucketed by day, week or month
 type Progress struct {
-	ID        string              `bson:"_id,omitempty" json:"id"`
-	TraineeID string              `bson:"trainee_id" json:"trainee_id"` // Linked to Trainee
-	WeightBMI []WeightBMIProgress `bson:"weight_bmi" json:"weight_bmi"`
-	Exercises []ExerciseProgress  `bson:"exercises" json:"exercises"`
-	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+	ID           string              `bson:"_id,omitempty" json:"id"`
+	TraineeID    string              `bson:"trainee_id" json:"trainee_id"` // Linked to Trainee
+	WeightBMI    []WeightBMIProgress `bson:"weight_bmi" json:"weight_bmi"`
+	Exercises    []ExerciseProgress  `bson:"exercises" json:"exercises"`
+	Sessions     []SessionVolume     `bson:"sessions" json:"sessions"`           // Volume of each logged session
+	Volume       []VolumePoint       `bson:"volume" json:"volume"`               // Volume of all exercises per bucket
+	MuscleGroups []MuscleGroupVolume `bson:"muscle_groups" json:"muscle_groups"` // Volume per category of exercise
+	Workload     []WorkloadPoint     `bson:"workload" json:"workload"`           // Acute:chronic workload ratio at the end of each bucket
+	Bucket       string              `bson:"bucket" json:"bucket"`               // Size of the buckets of the series
+	Formula      string              `bson:"formula" json:"formula"`             // Formula the one-rep maxes are estimated with
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// Buckets the series of the progress analytics are grouped in. Weeks start on Monday.
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// Formulas a one-rep max is estimated from a set with
+const (
+	FormulaEpley   = "epley"
+	FormulaBrzycki = "brzycki"
+)
+
+// AnalyticsOptions choose how the progress analytics are worked out. Empty fields take the defaults,
+// weekly buckets and the Epley formula.
+type AnalyticsOptions struct {
+	Bucket  string
+	Formula string
 }
 
 type WeightBMIProgress struct {
-	TraineeID string    `bson:"trainee_id" json:"trainee_id"` // Add this field
-	Date      string    `bson:"date" json:"date"`             // Format YYYY-MM-DD
-	Weight    float64   `bson:"weight" json:"weight"`
-	BMI       float64   `bson:"bmi" json:"bmi"`
-	BodyFat   float64   `bson:"body_fat,omitempty" json:"body_fat,omitempty"`
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	ID           string     `bson:"_id,omitempty" json:"id"`
+	TraineeID    string     `bson:"trainee_id" json:"trainee_id"` // Add this field
+	Date         Date       `bson:"date" json:"date"`             // Day the weight was taken
+	Weight       float64    `bson:"weight" json:"weight"`
+	BMI          float64    `bson:"bmi" json:"bmi"`
+	BodyFat      float64    `bson:"body_fat,omitempty" json:"body_fat,omitempty"`
+	CreatedAt    time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `bson:"updated_at" json:"updated_at"`
+	DeletedAt    *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Only set by offline clients, removed entries are purged with the trash
+	Version      int64      `bson:"version" json:"version"`
+	ClientID     string     `bson:"client_id,omitempty" json:"client_id,omitempty"` // ID the offline client created it under
+	LastChangeID string     `bson:"last_change_id,omitempty" json:"-"`
+	SyncSeq      int64      `bson:"sync_seq" json:"-"`
 }
 
+// ExerciseProgress sums up the sets logged for one exercise. AvgWeight is the average load per rep,
+// so a heavy single counts for less than a heavy set of ten.
 type ExerciseProgress struct {
-	Exercise  string           `bson:"exercise" json:"exercise"`
-	MaxWeight float64          `bson:"max_weight" json:"max_weight"`
-	AvgWeight float64          `bson:"avg_weight" json:"avg_weight"`
-	Records   []ExerciseRecord `bson:"records" json:"records"`
+	Exercise     string           `bson:"exercise" json:"exercise"`
+	ExerciseID   string           `bson:"exercise_id,omitempty" json:"exercise_id,omitempty"`
+	MuscleGroup  string           `bson:"muscle_group" json:"muscle_group"` // Category of the exercise
+	MaxWeight    float64          `bson:"max_weight" json:"max_weight"`
+	AvgWeight    float64          `bson:"avg_weight" json:"avg_weight"`
+	AvgReps      float64          `bson:"avg_reps" json:"avg_reps"`           // Average reps per set
+	Estimated1RM float64          `bson:"estimated_1rm" json:"estimated_1rm"` // Best one-rep max estimated from a set
+	Tonnage      float64          `bson:"tonnage" json:"tonnage"`             // Weight times reps of all sets
+	Sets         int              `bson:"sets" json:"sets"`
+	Reps         int              `bson:"reps" json:"reps"`
+	Records      []ExerciseRecord `bson:"records" json:"records"`
+	Series       []ExercisePoint  `bson:"series" json:"series"`
 }
 
 type ExerciseRecord struct {
-	Date   string  `bson:"date" json:"date"` // Format YYYY-MM-DD
-	Weight float64 `bson:"weight" json:"weight"`
-	Reps   int     `bson:"reps" json:"reps"`
+	Date         Date    `bson:"date" json:"date"` // Date of the workout the set was logged in
+	Weight       float64 `bson:"weight" json:"weight"`
+	Reps         int     `bson:"reps" json:"reps"`
+	Estimated1RM float64 `bson:"estimated_1rm" json:"estimated_1rm"`
+}
+
+// ExercisePoint is one bucket of the series of an exercise. Buckets without sets are left out.
+type ExercisePoint struct {
+	Start        Date    `bson:"start" json:"start"` // First day of the bucket
+	Estimated1RM float64 `bson:"estimated_1rm" json:"estimated_1rm"`
+	MaxWeight    float64 `bson:"max_weight" json:"max_weight"`
+	Tonnage      float64 `bson:"tonnage" json:"tonnage"`
+	Sets         int     `bson:"sets" json:"sets"`
+	Reps         int     `bson:"reps" json:"reps"`
+}
+
+// SessionVolume is the volume of one workout log
+type SessionVolume struct {
+	LogID   string  `bson:"log_id" json:"log_id"`
+	Date    Date    `bson:"date" json:"date"`
+	Tonnage float64 `bson:"tonnage" json:"tonnage"`
+	Sets    int     `bson:"sets" json:"sets"`
+	Reps    int     `bson:"reps" json:"reps"`
+}
+
+// VolumePoint is the volume of one bucket. Every bucket of the range is there, empty ones at zero.
+type VolumePoint struct {
+	Start    Date    `bson:"start" json:"start"` // First day of the bucket
+	Tonnage  float64 `bson:"tonnage" json:"tonnage"`
+	Sets     int     `bson:"sets" json:"sets"`
+	Reps     int     `bson:"reps" json:"reps"`
+	Sessions int     `bson:"sessions" json:"sessions"`
+}
+
+// MuscleGroupVolume is the volume of the exercises of one category
+type MuscleGroupVolume struct {
+	MuscleGroup string        `bson:"muscle_group" json:"muscle_group"`
+	Tonnage     float64       `bson:"tonnage" json:"tonnage"`
+	Sets        int           `bson:"sets" json:"sets"`
+	Series      []VolumePoint `bson:"series" json:"series"`
+}
+
+// WorkloadPoint compares the tonnage of the last 7 days (acute) with the weekly average of the last
+// 28 days (chronic). A ratio between 0.8 and 1.3 is the usual target, above 1.5 the load rises faster
+// than the trainee is used to. The ratio is 0 without a chronic load.
+type WorkloadPoint struct {
+	Date    Date    `bson:"date" json:"date"` // Last day of the bucket
+	Acute   float64 `bson:"acute" json:"acute"`
+	Chronic float64 `bson:"chronic" json:"chronic"`
+	Ratio   float64 `bson:"ratio" json:"ratio"`
+}
+
+// ExerciseAverages is the summary of an exercise the workout log progress endpoint answers with
+type ExerciseAverages struct {
+	AverageWeight float64 `json:"average_weight"` // Average load per rep
+	AverageReps   float64 `json:"average_reps"`   // Average reps per set
 }
@@ -0,0 +1,27 @@
+package models
+
+// Kinds of records search covers, named after their collections
+const (
+	SearchTrainees    = "trainees"
+	SearchExercises   = "exercises"
+	SearchWorkoutLogs = "workout_logs"
+	SearchDietEntries = "diet_entries"
+)
+
+// SearchHit is a record that matched the query. Hits of the same type are ranked by score, scores of
+// different types are not comparable.
+type SearchHit struct {
+	ID        string      `json:"id"`
+	TraineeID string      `json:"trainee_id,omitempty"`
+	Score     float64     `json:"score"`
+	Data      interface{} `json:"data"`
+}
+
+// SearchResults groups the hits by type, best match first. Types that were not searched stay empty.
+type SearchResults struct {
+	Query       string      `json:"query"`
+	Trainees    []SearchHit `json:"trainees"`
+	Exercises   []SearchHit `json:"exercises"`
+	WorkoutLogs []SearchHit `json:"workout_logs"`
+	DietEntries []SearchHit `json:"diet_entries"`
+}
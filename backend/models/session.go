@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is a server-side login session backing an access/refresh token pair
+type Session struct {
+	ID               string     `bson:"_id" json:"id"`                                    // Session ID, carried in the jti claim
+	Role             string     `bson:"role" json:"role"`                                 // trainer or trainee
+	Email            string     `bson:"email" json:"email"`                               // Trainer the session belongs to (trainer sessions)
+	TraineeID        string     `bson:"trainee_id,omitempty" json:"trainee_id,omitempty"` // Trainee the session belongs to (trainee sessions)
+	TrainerID        string     `bson:"trainer_id,omitempty" json:"trainer_id,omitempty"` // Owning trainer of the trainee (trainee sessions)
+	RefreshTokenHash string     `bson:"refresh_token_hash" json:"-"`                      // SHA-256 of the current refresh token
+	ExpiresAt        time.Time  `bson:"expires_at" json:"expires_at"`                     // Refresh token expiry
+	RevokedAt        *time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"` // Set on logout or revocation
+	CreatedAt        time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time  `bson:"updated_at" json:"updated_at"`
+}
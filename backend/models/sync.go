@@ -0,0 +1,69 @@
+package models
+
+import "encoding/json"
+
+// Kinds of records offline clients sync, named after their collections
+const (
+	SyncWorkoutLogs = "workout_logs"
+	SyncDietEntries = "diet_entries"
+	SyncProgress    = "progress"
+)
+
+// What became of a pushed change
+const (
+	SyncApplied  = "applied"  // Stored, now or by an earlier attempt
+	SyncConflict = "conflict" // The server copy changed since the client's base version and was kept
+	SyncRejected = "rejected" // Invalid or not allowed, sending it again will not help
+	SyncFailed   = "failed"   // Could not be stored right now, send it again later
+)
+
+// SyncChange is a record an offline client created, edited or deleted.
+// ClientID names the record, ChangeID this particular edit so a retried push is applied only once.
+type SyncChange struct {
+	Type        string          `json:"type" binding:"required,oneof=workout_logs diet_entries progress"`
+	ClientID    string          `json:"client_id" binding:"required,max=64"`
+	ChangeID    string          `json:"change_id" binding:"required,max=64"`
+	BaseVersion int64           `json:"base_version" binding:"gte=0"` // Version the edit was made on, 0 for new records
+	Deleted     bool            `json:"deleted"`
+	Data        json.RawMessage `json:"data"` // The record as the client has it, ignored for deletes
+}
+
+// SyncPush is a batch of changes, applied in order
+type SyncPush struct {
+	Changes []SyncChange `json:"changes" binding:"required,max=500,dive"`
+}
+
+// SyncResult tells the client what became of a change
+type SyncResult struct {
+	ClientID string      `json:"client_id"`
+	ChangeID string      `json:"change_id"`
+	Status   string      `json:"status"`
+	ID       string      `json:"id,omitempty"`
+	Version  int64       `json:"version"`
+	Error    string      `json:"error,omitempty"`
+	Code     string      `json:"code,omitempty"`    // Machine-readable cause of a rejected or failed change
+	Current  *SyncRecord `json:"current,omitempty"` // The server copy, sent with conflicts
+}
+
+// SyncRecord is a record as the server has it. Deleted records are sent as tombstones without data.
+type SyncRecord struct {
+	Type      string      `json:"type"`
+	ID        string      `json:"id"`
+	ClientID  string      `json:"client_id,omitempty"`
+	TraineeID string      `json:"trainee_id"`
+	Version   int64       `json:"version"`
+	Deleted   bool        `json:"deleted"`
+	Data      interface{} `json:"data,omitempty"`
+
+	LastChangeID string `json:"-"`
+	Seq          int64  `json:"-"`
+}
+
+// SyncPull is a page of the changes made since the client's cursor
+type SyncPull struct {
+	Changes  []SyncRecord `json:"changes"`
+	Cursor   string       `json:"cursor"`   // Pass back to get the next page or later changes
+	HasMore  bool         `json:"has_more"` // More changes are waiting, pull again right away
+	Reset    bool         `json:"reset"`    // The cursor was too old, replace the local copy with what follows
+	Trainees []string     `json:"trainees"` // Trainees the caller can see, data of any other trainee should be dropped
+}
@@ -6,14 +6,15 @@ type Trainee struct {
 	ID                string             `bson:"_id,omitempty" json:"id"`                                      // Unique identifier
 	Name              string             `json:"name" bson:"name" binding:"required"`                          // Required trainee name
 	PhoneNumber       string             `json:"phone_number" bson:"phone_number" binding:"required"`          // Phone number of the trainee
-	DOB               string             `json:"dob" bson:"dob" binding:"required"`                            // Date of Birth (ISO 8601)
+	DOB               Date               `json:"dob" bson:"dob" binding:"required"`                            // Date of Birth
 	Gender            string             `json:"gender" bson:"gender" binding:"required"`                      // Gender of the trainee
 	Profession        string             `json:"profession" bson:"profession"`                                 // Profession of the trainee
 	// Weight            float64            `json:"weight" bson:"weight" binding:"gte=0"`                         // Current weight in kg
 	Height            float64            `json:"height" bson:"height" binding:"gte=0"`                         // Height in cm
 	// BMI               float64            `json:"bmi,omitempty" bson:"bmi,omitempty"`                           // Computed BMI (optional)
-	TrainerID         string             `json:"trainer_id" bson:"trainer_id" binding:"required"`              // Link to Trainer
-	StartDate         string             `json:"start_date" bson:"start_date"`                                 // Training start date
+	TrainerID         string             `json:"trainer_id" bson:"trainer_id"`                                 // Link to Trainer, taken from the token rather than the body
+	OrganizationID    string             `json:"organization_id,omitempty" bson:"organization_id,omitempty"`   // Organization sharing this trainee, if any
+	StartDate         Date               `json:"start_date" bson:"start_date,omitempty"`                       // Training start date
 	MembershipType    string             `json:"membership_type" bson:"membership_type"`                       // Type of membership (e.g., Basic, Premium)
 	EmergencyContact  string             `json:"emergency_contact" bson:"emergency_contact"`                   // Emergency contact details
 	MedicalHistory    string             `json:"medical_history,omitempty" bson:"medical_history,omitempty"`   // Medical history
@@ -24,6 +25,8 @@ type Trainee struct {
 	ProgressMetrics   map[string]float64 `json:"progress_metrics,omitempty" bson:"progress_metrics,omitempty"` // Metrics like body fat %, lift weights
 	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`                                 // Creation timestamp
 	UpdatedAt         time.Time          `json:"updated_at" bson:"updated_at"`                                 // Update timestamp
+	DeletedAt         *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`             // Set while the trainee is in the trash
+	Version           int64              `json:"version" bson:"version"`                                       // Incremented on every write, sent as the ETag
 	ImageURL          string             `json:"image_url,omitempty" bson:"image_url,omitempty"`               // Add image URL field
 	ActiveSupplements string             `json:"active_supplements" bson:"active_supplements"`                 // Training start date
 
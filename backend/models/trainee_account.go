@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// TraineeAccount lets a trainee log in with an invite code and a PIN issued by their trainer
+type TraineeAccount struct {
+	ID             string     `bson:"_id" json:"id"`                                      // Same as the trainee ID, one account per trainee
+	TrainerID      string     `bson:"trainer_id" json:"trainer_id"`                       // Trainer who issued the access
+	InviteCode     string     `bson:"invite_code" json:"invite_code"`                     // Public login code shared with the trainee
+	PinHash        string     `bson:"pin_hash" json:"-"`                                  // bcrypt hash of the PIN
+	FailedAttempts int        `bson:"failed_attempts" json:"failed_attempts"`             // Consecutive wrong PINs
+	DisabledAt     *time.Time `bson:"disabled_at,omitempty" json:"disabled_at,omitempty"` // Set when access is revoked or locked
+	LastLoginAt    *time.Time `bson:"last_login_at,omitempty" json:"last_login_at,omitempty"`
+	CreatedAt      time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `bson:"updated_at" json:"updated_at"`
+}
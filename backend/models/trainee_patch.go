@@ -0,0 +1,26 @@
+package models
+
+// TraineePatch is a partial update of a trainee. Only the fields below can be changed by a client,
+// ownership and bookkeeping fields are left to the server. Rules match those used on create.
+type TraineePatch struct {
+	Name                *string              `json:"name" bson:"name" binding:"omitempty,min=1"`
+	PhoneNumber         *string              `json:"phone_number" bson:"phone_number" binding:"omitempty,min=1"`
+	DOB                 *Date                `json:"dob" bson:"dob" binding:"omitempty,min=1"`
+	Gender              *string              `json:"gender" bson:"gender" binding:"omitempty,min=1"`
+	Profession          *string              `json:"profession" bson:"profession"`
+	Height              *float64             `json:"height" bson:"height" binding:"omitempty,gte=0"`
+	StartDate           *Date                `json:"start_date" bson:"start_date"`
+	MembershipType      *string              `json:"membership_type" bson:"membership_type"`
+	EmergencyContact    *string              `json:"emergency_contact" bson:"emergency_contact"`
+	MedicalHistory      *string              `json:"medical_history" bson:"medical_history"`
+	SocialHandle        *string              `json:"social_handle" bson:"social_handle"`
+	Goals               *string              `json:"goals" bson:"goals"`
+	Notes               *string              `json:"notes" bson:"notes"`
+	ActiveStatus        *bool                `json:"active_status" bson:"active_status"`
+	ProgressMetrics     *map[string]float64  `json:"progress_metrics" bson:"progress_metrics"`
+	ImageURL            *string              `json:"image_url" bson:"image_url"`
+	ActiveSupplements   *string              `json:"active_supplements" bson:"active_supplements"`
+	FitnessTests        *[]FitnessTest       `json:"fitness_tests" bson:"fitness_tests"`
+	LabTests            *[]LabTest           `json:"lab_tests" bson:"lab_tests"`
+	HealthQuestionnaire *HealthQuestionnaire `json:"health_questionnaire" bson:"health_questionnaire"`
+}
@@ -20,6 +20,8 @@ type Trainer struct {
 	Availability   string    `json:"availability" bson:"availability"`
 	Rating         float64   `json:"rating" bson:"rating" validate:"gte=0,lte=5"`
 	TrainerType    string    `json:"trainer_type" bson:"trainer_type" validate:"required,oneof=personal group online rehabilitation"`
+	TimeZone       string    `json:"time_zone,omitempty" bson:"time_zone,omitempty"` // IANA name (e.g. Asia/Kolkata) deciding what "today" is, UTC when unset
+	EmailVerified  bool      `json:"email_verified" bson:"email_verified"`
 	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" bson:"updated_at"`
 }
@@ -1,28 +1,112 @@
 package models
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Types of set
+const (
+	SetWarmUp  = "warm_up"
+	SetWorking = "working"
+	SetDrop    = "drop"    // Done right after the previous set with less weight
+	SetAMRAP   = "amrap"   // As many reps as possible
+	SetFailure = "failure" // Taken to failure
+)
+
+// Set is one set of an exercise. Lifts log reps and a weight, 0 for bodyweight; cardio logs a
+// duration or a distance and holds such as a plank a duration.
+type Set struct {
+	Type            string  `json:"type" bson:"type" binding:"oneof=warm_up working drop amrap failure"` // Working if unset
+	Reps            int     `json:"reps,omitempty" bson:"reps,omitempty" binding:"gte=0"`
+	Weight          float64 `json:"weight,omitempty" bson:"weight,omitempty" binding:"gte=0"`                     // Load in kg
+	RPE             float64 `json:"rpe,omitempty" bson:"rpe,omitempty" binding:"omitempty,gte=1,lte=10"`          // Rate of perceived exertion, from 1 to 10
+	RIR             *int    `json:"rir,omitempty" bson:"rir,omitempty" binding:"omitempty,gte=0,lte=10"`          // Reps in reserve, 0 being failure
+	Tempo           string  `json:"tempo,omitempty" bson:"tempo,omitempty" binding:"max=16"`                      // e.g. "3-1-X-0"
+	RestSeconds     int     `json:"rest_seconds,omitempty" bson:"rest_seconds,omitempty" binding:"gte=0"`         // Rest taken after the set
+	DurationSeconds int     `json:"duration_seconds,omitempty" bson:"duration_seconds,omitempty" binding:"gte=0"` // Time under load, or spent on cardio
+	DistanceMeters  float64 `json:"distance_meters,omitempty" bson:"distance_meters,omitempty" binding:"gte=0"`   // Distance covered on cardio
+}
+
 // Workout represents a single exercise in a workout log
 type Workout struct {
-	Exercise   string             `json:"exercise" bson:"exercise"`               // Exercise name
-	ExerciseID primitive.ObjectID `json:"exercise_id" bson:"exercise_id"`         // Link to Exercise
-	Sets       int                `json:"sets" bson:"sets"`                       // Number of sets
-	Reps       []int              `json:"reps" bson:"reps"`                       // Reps for each set
-	Weight     []float64          `json:"weight" bson:"weight"`                   // Weight used for each set
-	Notes      string             `json:"notes,omitempty" bson:"notes,omitempty"` // Additional notes
+	Exercise   string             `json:"exercise" bson:"exercise"`                   // Exercise name
+	ExerciseID primitive.ObjectID `json:"exercise_id" bson:"exercise_id"`             // Link to Exercise
+	Sets       []Set              `json:"sets" bson:"sets" binding:"dive"`            // Sets in the order they were done
+	Notes      string             `json:"notes,omitempty" bson:"notes,omitempty"`     // Additional notes
+	Planned    *SetTarget         `json:"planned,omitempty" bson:"planned,omitempty"` // What the program planned, kept next to what was done
+}
+
+// UnmarshalJSON also reads workouts in the shape clients sent before sets were structured: the number
+// of sets with the reps and the weight of each in parallel arrays, which must be as long as the number of sets.
+// Those become working sets, as do sets sent without a type. Reps or weight without the number of
+// sets are rejected rather than dropped.
+func (w *Workout) UnmarshalJSON(data []byte) error {
+	type workout Workout
+	var decoded struct {
+		workout
+		Sets   json.RawMessage `json:"sets"`
+		Reps   []int           `json:"reps"`
+		Weight []float64       `json:"weight"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*w = Workout(decoded.workout)
+
+	sets := bytes.TrimSpace(decoded.Sets)
+	legacy := len(sets) > 0 && (sets[0] == '-' || sets[0] >= '0' && sets[0] <= '9')
+	if !legacy && (decoded.Reps != nil || decoded.Weight != nil) {
+		return fmt.Errorf("%s has reps or weight without the number of sets, send sets as a list of sets with their reps and weight", w.Exercise)
+	}
+	if legacy {
+		var count int
+		if err := json.Unmarshal(sets, &count); err != nil {
+			return fmt.Errorf("%s has %s sets, the number of sets must be a whole number", w.Exercise, sets)
+		}
+		if count != len(decoded.Reps) {
+			return fmt.Errorf("%s has %d sets for %d reps, reps and weight need one value per set", w.Exercise, count, len(decoded.Reps))
+		}
+		if len(decoded.Reps) != len(decoded.Weight) {
+			return fmt.Errorf("%s has %d reps for %d weights, reps and weight need one value per set", w.Exercise, len(decoded.Reps), len(decoded.Weight))
+		}
+		w.Sets = make([]Set, len(decoded.Reps))
+		for i := range decoded.Reps {
+			w.Sets[i] = Set{Reps: decoded.Reps[i], Weight: decoded.Weight[i]}
+		}
+	} else if len(sets) > 0 {
+		if err := json.Unmarshal(sets, &w.Sets); err != nil {
+			return err
+		}
+	}
+
+	for i := range w.Sets {
+		if w.Sets[i].Type == "" {
+			w.Sets[i].Type = SetWorking
+		}
+	}
+	return nil
 }
 
 // WorkoutLog represents a log entry for a trainee's workout
 type WorkoutLog struct {
-	ID        string    `bson:"_id,omitempty" json:"id"`                // Unique ID for the log
-	TraineeID string    `json:"trainee_id" bson:"trainee_id"`           // Link to Trainee
-	Date      string    `json:"date" bson:"date"`                       // Workout date (ISO 8601)
-	Workouts  []Workout `json:"workouts" bson:"workouts"`               // List of exercises
-	Notes     string    `json:"notes,omitempty" bson:"notes,omitempty"` // Additional notes
-	CreatedAt time.Time `json:"created_at" bson:"created_at"`           // Timestamp for record creation
-	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`           // Timestamp for last update
+	ID           string     `bson:"_id,omitempty" json:"id"`                            // Unique ID for the log
+	TraineeID    string     `json:"trainee_id" bson:"trainee_id"`                       // Link to Trainee
+	Date         Date       `json:"date" bson:"date"`                                   // Workout date
+	Workouts     []Workout  `json:"workouts" bson:"workouts" binding:"dive"`            // List of exercises
+	Notes        string     `json:"notes,omitempty" bson:"notes,omitempty"`             // Additional notes
+	ProgramID    string     `json:"program_id,omitempty" bson:"program_id,omitempty"`   // Program the log was planned in
+	TemplateID   string     `json:"template_id,omitempty" bson:"template_id,omitempty"` // Workout template it was started from
+	CreatedAt    time.Time  `json:"created_at" bson:"created_at"`                       // Timestamp for record creation
+	UpdatedAt    time.Time  `json:"updated_at" bson:"updated_at"`                       // Timestamp for last update
+	DeletedAt    *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`   // Set while the log is in the trash
+	Version      int64      `json:"version" bson:"version"`                             // Incremented on every write, sent as the ETag
+	ClientID     string     `json:"client_id,omitempty" bson:"client_id,omitempty"`     // ID the offline client created it under
+	LastChangeID string     `json:"-" bson:"last_change_id,omitempty"`                  // Last change applied through sync, to recognize retries
+	SyncSeq      int64      `json:"-" bson:"sync_seq"`                                  // Position in the change feed offline clients pull
+	PRsAchieved  bool       `json:"prs_achieved" bson:"-"`                              // The log holds a personal record, filled in when read
 }
@@ -0,0 +1,8 @@
+package models
+
+// WorkoutLogPatch is a partial update of a workout log. The trainee a log belongs to cannot change.
+type WorkoutLogPatch struct {
+	Date     *Date      `json:"date" bson:"date" binding:"omitempty,min=1"`
+	Workouts *[]Workout `json:"workouts" bson:"workouts" binding:"omitempty,dive"`
+	Notes    *string    `json:"notes" bson:"notes"`
+}
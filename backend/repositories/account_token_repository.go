@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoAccountTokenRepository struct {
+	collection *mongo.Collection
+}
+
+// Constructor for MongoAccountTokenRepository
+func NewMongoAccountTokenRepository(db *mongo.Database) *MongoAccountTokenRepository {
+	return &MongoAccountTokenRepository{
+		collection: db.Collection("account_tokens"),
+	}
+}
+
+// Outstanding tokens are invalidated per trainer and purpose
+var accountTokenIndexes = []mongo.IndexModel{
+	ascending("email_purpose", "email", "purpose"),
+}
+
+// Create a new account token
+func (r *MongoAccountTokenRepository) CreateToken(token models.AccountToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+// ConsumeToken atomically marks an unused, unexpired token as used and returns it.
+// It returns ErrNotFound if the token is unknown, expired or already used.
+func (r *MongoAccountTokenRepository) ConsumeToken(id string, purpose string) (models.AccountToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var token models.AccountToken
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":        id,
+			"purpose":    purpose,
+			"used_at":    bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+	).Decode(&token)
+	return token, found(err)
+}
+
+// Invalidate all outstanding tokens of a trainer for a purpose
+func (r *MongoAccountTokenRepository) InvalidateTokens(email string, purpose string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"email": email, "purpose": purpose, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+	)
+	return err
+}
+
+// Delete all account tokens of a trainer (for cascading deletes)
+func (r *MongoAccountTokenRepository) DeleteTokensByEmail(ctx context.Context, email string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"email": email})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
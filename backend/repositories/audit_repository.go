@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoAuditRepository stores the audit log. It only appends and reads, entries are never updated or deleted.
+type MongoAuditRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// Constructor for MongoAuditRepository
+func NewMongoAuditRepository(db *mongo.Database) *MongoAuditRepository {
+	return &MongoAuditRepository{
+		db:         db,
+		collection: db.Collection("audit_logs"),
+	}
+}
+
+// The audit log is read newest first, scoped by actor, trainee or organization
+var auditIndexes = []mongo.IndexModel{
+	ascending("actor_created", "actor_email", "created_at"),
+	partial(ascending("trainee_created", "trainee_id", "created_at"), bson.M{"trainee_id": bson.M{"$exists": true}}),
+	partial(ascending("organization_created", "organization_id", "created_at"), bson.M{"organization_id": bson.M{"$exists": true}}),
+}
+
+// Append an entry to the audit log
+func (r *MongoAuditRepository) InsertEntry(entry models.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
+
+// Get the newest entries matching the filter
+func (r *MongoAuditRepository) FindEntries(filter bson.M, limit int64) ([]models.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetDocument loads the raw document an audited request touches, including trashed and archived ones.
+// It returns ErrNotFound if there is none.
+func (r *MongoAuditRepository) GetDocument(collection string, filter bson.M) (bson.M, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var document bson.M
+	err := r.db.Collection(collection).FindOne(ctx, filter).Decode(&document)
+	return document, found(err)
+}
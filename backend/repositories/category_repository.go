@@ -3,7 +3,6 @@ package repositories
 import (
 	"context"
 	"fmt"
-	"ironcoach/database"
 	"ironcoach/models"
 	"time"
 
@@ -13,31 +12,39 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type CategoryRepository struct {
+type MongoCategoryRepository struct {
 	collection *mongo.Collection
 }
 
-// Constructor for CategoryRepository
-func NewCategoryRepository() *CategoryRepository {
-	db := database.DB.Database("ironcoach")
-	return &CategoryRepository{
+// Constructor for MongoCategoryRepository
+func NewMongoCategoryRepository(db *mongo.Database) *MongoCategoryRepository {
+	return &MongoCategoryRepository{
 		collection: db.Collection("categories"),
 	}
 }
 
-func (r *CategoryRepository) AddCategory(category models.Category) error {
+// Category names are unique per library. Personal categories have no organization_id, which the
+// first index takes as null, so it covers them per trainer. The second covers organization libraries
+// whoever created the category.
+var categoryIndexes = []mongo.IndexModel{
+	unique(ascending("trainer_name", "trainer_id", "organization_id", "name")),
+	partial(unique(ascending("organization_name", "organization_id", "name")), bson.M{"organization_id": bson.M{"$exists": true}}),
+}
+
+// AddCategory inserts the category. It returns ErrDuplicateKey if the library has one of the same name.
+func (r *MongoCategoryRepository) AddCategory(category models.Category) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	_, err := r.collection.InsertOne(ctx, category)
-	return err
+	return duplicateKey(err)
 }
 
-func (r *CategoryRepository) GetCategories(trainerID string) ([]models.Category, error) {
+func (r *MongoCategoryRepository) GetCategories(trainerID string, organizationIDs []string) ([]models.Category, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Filter categories based on trainerID
-	filter := bson.M{"trainer_id": trainerID}
+	// The trainer's own library plus the libraries of their organizations
+	filter := ownerOrOrganizationFilter(trainerID, organizationIDs)
 
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
@@ -56,24 +63,25 @@ func (r *CategoryRepository) GetCategories(trainerID string) ([]models.Category,
 	return categories, nil
 }
 
-func (r *CategoryRepository) UpdateCategory(id string, updatedName string) error {
+// UpdateCategory renames the category. It returns ErrDuplicateKey if the library has one of the new name.
+func (r *MongoCategoryRepository) UpdateCategory(id string, updatedName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return ErrInvalidID
 	}
 	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectId}, bson.M{"$set": bson.M{"name": updatedName}})
-	return err
+	return duplicateKey(err)
 }
 
-func (r *CategoryRepository) CascadeUpdateCategoryInExercises(categoryID string, updatedCategoryName string) error {
+func (r *MongoCategoryRepository) CascadeUpdateCategoryInExercises(categoryID string, updatedCategoryName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	objectId, err := primitive.ObjectIDFromHex(categoryID)
 	if err != nil {
-		return err
+		return ErrInvalidID
 	}
 
 	_, err = r.collection.Database().Collection("exercises").UpdateMany(
@@ -84,18 +92,29 @@ func (r *CategoryRepository) CascadeUpdateCategoryInExercises(categoryID string,
 	return err
 }
 
-func (r *CategoryRepository) DeleteCategory(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Delete categories by ID (for cascading deletes)
+func (r *MongoCategoryRepository) DeleteCategories(ctx context.Context, ids []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	objectId, err := primitive.ObjectIDFromHex(id)
+
+	objectIDs, err := toObjectIDs(ids)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectId})
-	return err
+	if len(objectIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": objectIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
 }
 
-func (r *CategoryRepository) IsCategoryExists(name string, trainerID string) (bool, error) {
+// IsCategoryExists checks for a category with the same name in the same library:
+// the organization's when organizationID is set, the trainer's personal one otherwise
+func (r *MongoCategoryRepository) IsCategoryExists(name string, trainerID string, organizationID string) (bool, error) {
 	if r.collection == nil {
 		return false, fmt.Errorf("MongoDB collection is not initialized")
 	}
@@ -103,7 +122,13 @@ func (r *CategoryRepository) IsCategoryExists(name string, trainerID string) (bo
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	result := r.collection.FindOne(ctx, bson.M{"name": name, "trainer_id": trainerID})
+	filter := personalFilter(trainerID)
+	if organizationID != "" {
+		filter = bson.M{"organization_id": organizationID}
+	}
+	filter["name"] = name
+
+	result := r.collection.FindOne(ctx, filter)
 	if result.Err() != nil {
 		if result.Err() == mongo.ErrNoDocuments {
 			return false, nil // Document does not exist
@@ -114,35 +139,27 @@ func (r *CategoryRepository) IsCategoryExists(name string, trainerID string) (bo
 	return true, nil // Document exists
 }
 
-func (r *CategoryRepository) GetCategoryByID(id string) (models.Category, error) {
+func (r *MongoCategoryRepository) GetCategoryByID(id string) (models.Category, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	var category models.Category
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return category, err
+		return category, ErrInvalidID
 	}
 
 	err = r.collection.FindOne(ctx, bson.M{"_id": objectId}).Decode(&category)
-	return category, err
-}
-
-// Delete all categories by trainer ID
-func (r *CategoryRepository) DeleteCategoriesByTrainer(trainerID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	_, err := r.collection.DeleteMany(ctx, bson.M{"trainer_id": trainerID})
-	return err
+	return category, found(err)
 }
 
-// Get all category IDs by trainer ID (for cascading deletes)
-func (r *CategoryRepository) GetCategoryIDsByTrainer(trainerID string) ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Get all personal category IDs by trainer ID (for cascading deletes).
+// Organization libraries stay with the organization.
+func (r *MongoCategoryRepository) GetCategoryIDsByTrainer(ctx context.Context, trainerID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{"trainer_id": trainerID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	cursor, err := r.collection.Find(ctx, personalFilter(trainerID), options.Find().SetProjection(bson.M{"_id": 1}))
 	if err != nil {
 		return nil, err
 	}
@@ -2,8 +2,6 @@ package repositories
 
 import (
 	"context"
-	"errors"
-	"ironcoach/database"
 	"ironcoach/models"
 	"time"
 
@@ -12,58 +10,57 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-type DietEntryRepository struct {
+type MongoDietEntryRepository struct {
 	collection *mongo.Collection
 }
 
-// Constructor for DietEntryRepository
-func NewDietEntryRepository() *DietEntryRepository {
-	db := database.DB.Database("ironcoach")
-	return &DietEntryRepository{
+// Constructor for MongoDietEntryRepository
+func NewMongoDietEntryRepository(db *mongo.Database) *MongoDietEntryRepository {
+	return &MongoDietEntryRepository{
 		collection: db.Collection("diet_entries"),
 	}
 }
 
+// Client IDs are unique so a change synced twice at once cannot create the entry twice
+var dietEntryIndexes = []mongo.IndexModel{
+	ascending("trainee_date", "trainee_id", "date", "_id"),
+	ascending("trainee_sync", "trainee_id", "sync_seq", "_id"),
+	partial(unique(ascending("client_id", "client_id")), bson.M{"client_id": bson.M{"$type": "string"}}),
+	partial(ascending("deleted", "deleted_at"), bson.M{"deleted_at": bson.M{"$exists": true}}),
+	textIndex(bson.D{{Key: "notes", Value: 1}}),
+}
+
 // Create a new diet entry
-func (r *DietEntryRepository) CreateDietEntry(entry models.DietEntry) error {
+func (r *MongoDietEntryRepository) CreateDietEntry(entry models.DietEntry) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := r.collection.InsertOne(ctx, entry)
+	var done func()
+	var err error
+	if entry.SyncSeq, done, err = takeSyncSeq(ctx, r.collection.Database()); err != nil {
+		return err
+	}
+	defer done()
+	_, err = r.collection.InsertOne(ctx, entry)
 	return err
 }
 
-// Get all diet entries for a trainee
-func (r *DietEntryRepository) GetDietEntriesByTrainee(traineeID string, date string) ([]models.DietEntry, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-
-	query := bson.M{"trainee_id": traineeID}
-	if date != "" {
-		query["date"] = date // Filter by date if provided
-	}
-
-	cursor, err := r.collection.Find(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	// Decode the results
+// Get a page of the diet entries of a trainee, optionally within a date range
+func (r *MongoDietEntryRepository) GetDietEntriesByTrainee(traineeID string, list models.ListOptions) ([]models.DietEntry, string, error) {
 	var dietEntries []models.DietEntry
-	for cursor.Next(ctx) {
-		var entry models.DietEntry
-		if err := cursor.Decode(&entry); err != nil {
-			return nil, err
-		}
-		dietEntries = append(dietEntries, entry)
-	}
+	next, err := findPage(r.collection, dateRangeFilter(notDeleted(bson.M{"trainee_id": traineeID}), "date", list), list, &dietEntries)
+	return dietEntries, next, err
+}
 
-	return dietEntries, nil
+// Find the diet entries of the trainees whose notes match the text query, best match first
+func (r *MongoDietEntryRepository) SearchDietEntries(traineeIDs []string, query string, limit int64) ([]models.DietEntry, []float64, error) {
+	var dietEntries []models.DietEntry
+	scores, err := searchText(r.collection, notDeleted(bson.M{"trainee_id": bson.M{"$in": traineeIDs}}), query, limit, &dietEntries)
+	return dietEntries, scores, err
 }
 
 // Get Diet Entry By ID
-func (r *DietEntryRepository) GetDietEntryByID(entryID string) (models.DietEntry, error) {
+func (r *MongoDietEntryRepository) GetDietEntryByID(entryID string) (models.DietEntry, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -71,61 +68,97 @@ func (r *DietEntryRepository) GetDietEntryByID(entryID string) (models.DietEntry
 
 	objectId, err := primitive.ObjectIDFromHex(entryID)
 	if err != nil {
-		return entry, err
+		return entry, ErrInvalidID
 	}
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectId}).Decode(&entry)
-	return entry, err
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectId})).Decode(&entry)
+	return entry, found(err)
 }
 
-// Update a diet entry with date filter
-func (r *DietEntryRepository) UpdateDietEntry(entryID string, updateData models.DietEntry) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+// Replace the contents of a diet entry that is still at the expected version.
+// The trainee, creation time and trash state are never overwritten.
+func (r *MongoDietEntryRepository) UpdateDietEntry(entryID string, version int64, updateData models.DietEntry) error {
+	return updateVersioned(r.collection, entryID, version, bson.M{"$set": bson.M{
+		"date":           updateData.Date,
+		"meals":          updateData.Meals,
+		"total_calories": updateData.TotalCalories,
+		"total_proteins": updateData.TotalProteins,
+		"notes":          updateData.Notes,
+		"updated_at":     updateData.UpdatedAt,
+	}})
+}
 
-	objectID, err := primitive.ObjectIDFromHex(entryID)
-	if err != nil {
-		return errors.New("invalid ID format")
-	}
+// Apply a change an offline client made to a diet entry that is still at the expected version
+func (r *MongoDietEntryRepository) ApplyDietEntryChange(entryID string, version int64, set map[string]interface{}) error {
+	return updateVersioned(r.collection, entryID, version, bson.M{"$set": set})
+}
+
+// Get the diet entry an offline client created under the given ID, trashed or not
+func (r *MongoDietEntryRepository) GetDietEntryByClientID(clientID string) (models.DietEntry, error) {
+	var entry models.DietEntry
+	err := findByClientID(r.collection, clientID, &entry)
+	return entry, err
+}
 
-	// Add date filter to the query
-	filter := bson.M{
-		"_id": objectID,
+// Get the diet entries of the trainees written after the position, trashed ones included, oldest change first
+func (r *MongoDietEntryRepository) GetDietEntryChanges(traineeIDs []string, after SyncPosition, until int64, limit int64) ([]models.DietEntry, error) {
+	entries := []models.DietEntry{}
+	if len(traineeIDs) == 0 {
+		return entries, nil
 	}
+	err := findChanges(r.collection, traineeIDs, after, until, limit, &entries)
+	return entries, err
+}
 
-	update := bson.M{"$set": updateData}
+// Move a diet entry at the expected version to the trash
+func (r *MongoDietEntryRepository) SoftDeleteDietEntry(entryID string, version int64) error {
+	return softDelete(r.collection, entryID, version)
+}
 
-	result, err := r.collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return err
-	}
+// Take a diet entry out of the trash
+func (r *MongoDietEntryRepository) RestoreDietEntry(entryID string) error {
+	return restore(r.collection, entryID)
+}
 
-	if result.MatchedCount == 0 {
-		return errors.New("not found or date mismatch")
-	}
+// Get a diet entry that is in the trash
+func (r *MongoDietEntryRepository) GetDeletedDietEntryByID(entryID string) (models.DietEntry, error) {
+	var entry models.DietEntry
+	err := findDeleted(r.collection, entryID, &entry)
+	return entry, err
+}
 
-	return nil
+// Get the trashed diet entries of the given trainees
+func (r *MongoDietEntryRepository) GetDeletedDietEntries(traineeIDs []string) ([]models.DietEntry, error) {
+	var entries []models.DietEntry
+	if len(traineeIDs) == 0 {
+		return entries, nil
+	}
+	err := findAllDeleted(r.collection, bson.M{"trainee_id": bson.M{"$in": traineeIDs}}, &entries)
+	return entries, err
 }
 
-// Delete a diet entry
-func (r *DietEntryRepository) DeleteDietEntry(entryID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Permanently delete a diet entry that is in the trash
+func (r *MongoDietEntryRepository) PurgeDietEntry(entryID string) (int64, error) {
+	return purgeDeleted(r.collection, entryID)
+}
 
-	objectID, _ := primitive.ObjectIDFromHex(entryID)
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
-	return err
+// Permanently delete diet entries that have been in the trash since before the cutoff
+func (r *MongoDietEntryRepository) PurgeDietEntriesDeletedBefore(cutoff time.Time) (int64, error) {
+	return purgeDeletedBefore(r.collection, cutoff)
 }
 
-// Delete all diet entries by trainee IDs
-func (r *DietEntryRepository) DeleteDietEntriesByTrainees(traineeIDs []string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Delete all diet entries by trainee IDs (for cascading deletes)
+func (r *MongoDietEntryRepository) DeleteDietEntriesByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	if len(traineeIDs) == 0 {
-		return nil // No trainees to delete entries for
+		return 0, nil // No trainees to delete entries for
 	}
 
 	// TraineeID is stored as string in diet entries, so use string array directly
-	_, err := r.collection.DeleteMany(ctx, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
-	return err
+	result, err := r.collection.DeleteMany(ctx, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
 }
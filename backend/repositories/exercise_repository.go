@@ -2,10 +2,7 @@ package repositories
 
 import (
 	"context"
-	"fmt"
-	"ironcoach/database"
 	"ironcoach/models"
-	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,35 +11,42 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type ExerciseRepository struct {
+type MongoExerciseRepository struct {
 	collection *mongo.Collection
 }
 
-// Constructor for ExerciseRepository
-func NewExerciseRepository() *ExerciseRepository {
-	db := database.DB.Database("ironcoach")
-	return &ExerciseRepository{
+// Constructor for MongoExerciseRepository
+func NewMongoExerciseRepository(db *mongo.Database) *MongoExerciseRepository {
+	return &MongoExerciseRepository{
 		collection: db.Collection("exercises"),
 	}
 }
 
-func (r *ExerciseRepository) AddExercise(exercise models.Exercise) error {
+// Exercise names are unique per category among active exercises. Their archived_at is null, archived
+// ones each have their own, so a name can be reused once its exercise is archived.
+var exerciseIndexes = []mongo.IndexModel{
+	unique(ascending("category_name", "category_id", "name", "archived_at")),
+	textIndex(bson.D{{Key: "name", Value: 1}}),
+}
+
+// AddExercise inserts the exercise. It returns ErrDuplicateKey if the category has one of the same name.
+func (r *MongoExerciseRepository) AddExercise(exercise models.Exercise) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	_, err := r.collection.InsertOne(ctx, exercise)
-	return err
+	return duplicateKey(err)
 }
 
-func (r *ExerciseRepository) GetExercisesByCategoryID(categoryID string) ([]models.Exercise, error) {
+func (r *MongoExerciseRepository) GetExercisesByCategoryID(categoryID string) ([]models.Exercise, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(categoryID)
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidID
 	}
 
-	cursor, err := r.collection.Find(ctx, bson.M{"category_id": objectID})
+	cursor, err := r.collection.Find(ctx, notArchived(bson.M{"category_id": objectID}))
 	if err != nil {
 		return nil, err
 	}
@@ -59,30 +63,36 @@ func (r *ExerciseRepository) GetExercisesByCategoryID(categoryID string) ([]mode
 	return exercises, nil
 }
 
-func (r *ExerciseRepository) UpdateExercise(id string, updatedName string) error {
+// UpdateExercise renames the exercise. It returns ErrDuplicateKey if the category has one of the new name.
+func (r *MongoExerciseRepository) UpdateExercise(id string, updatedName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return ErrInvalidID
 	}
 	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectId}, bson.M{"$set": bson.M{"name": updatedName}})
-	return err
+	return duplicateKey(err)
 }
 
-func (r *ExerciseRepository) CascadeUpdateExerciseInWorkoutLogs(exerciseID string, updatedName string) error {
+func (r *MongoExerciseRepository) CascadeUpdateExerciseInWorkoutLogs(exerciseID string, updatedName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	objectId, err := primitive.ObjectIDFromHex(exerciseID)
 	if err != nil {
-		return err
+		return ErrInvalidID
 	}
 
+	update, done, err := bumpVersion(ctx, r.collection.Database(), bson.M{"$set": bson.M{"workouts.$[elem].exercise": updatedName}})
+	if err != nil {
+		return err
+	}
+	defer done()
 	_, err = r.collection.Database().Collection("workout_logs").UpdateMany(
 		ctx,
 		bson.M{"workouts.exercise_id": objectId},
-		bson.M{"$set": bson.M{"workouts.$[elem].exercise": updatedName}},
+		update,
 		options.Update().SetArrayFilters(options.ArrayFilters{
 			Filters: []interface{}{
 				bson.M{"elem.exercise_id": objectId},
@@ -92,176 +102,161 @@ func (r *ExerciseRepository) CascadeUpdateExerciseInWorkoutLogs(exerciseID strin
 	return err
 }
 
-func (r *ExerciseRepository) DeleteExercise(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Delete an exercise (the caller takes care of the workout logs that use it)
+func (r *MongoExerciseRepository) DeleteExercise(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return ErrInvalidID
 	}
 	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectId})
 	return err
 }
 
-func (r *ExerciseRepository) CascadeDeleteExerciseFromWorkoutLogs(exerciseID string) error {
+// Archive an exercise so it can no longer be picked for new logs while existing logs keep it
+func (r *MongoExerciseRepository) ArchiveExercise(id string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-
-	// Convert exerciseID to ObjectID
-	objectId, err := primitive.ObjectIDFromHex(exerciseID)
+	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return ErrInvalidID
 	}
 
-	// Delete records from "workout_logs" where "workouts.exercise_id" matches
-	_, err = r.collection.Database().Collection("workout_logs").DeleteMany(
-		ctx,
-		bson.M{"workouts.exercise_id": objectId},
+	now := time.Now()
+	_, err = r.collection.UpdateOne(ctx,
+		notArchived(bson.M{"_id": objectId}),
+		bson.M{"$set": bson.M{"archived_at": now, "updated_at": now}},
 	)
 	return err
 }
 
-// DeleteExercisesByCategoryID deletes all exercises in a category by category ID
-func (r *ExerciseRepository) DeleteExercisesByCategoryID(categoryID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Get the IDs of all exercises in the categories (for cascading deletes)
+func (r *MongoExerciseRepository) GetExerciseIDsByCategories(ctx context.Context, categoryIDs []string) ([]primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	// Convert category ID to ObjectID
-	objectID, err := primitive.ObjectIDFromHex(categoryID)
+	objectIDs, err := toObjectIDs(categoryIDs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Step 1: Find all exercises in the specified category
-	cursor, err := r.collection.Find(ctx, bson.M{"category_id": objectID})
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"category_id": bson.M{"$in": objectIDs}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var exercises []bson.M
-	if err := cursor.All(ctx, &exercises); err != nil {
-		return err
-	}
-
-	// Step 2: Extract exercise IDs
 	var exerciseIDs []primitive.ObjectID
-	for _, exercise := range exercises {
-		if id, ok := exercise["_id"].(primitive.ObjectID); ok {
-			exerciseIDs = append(exerciseIDs, id)
+	for cursor.Next(ctx) {
+		var result struct {
+			ID primitive.ObjectID `bson:"_id"`
 		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		exerciseIDs = append(exerciseIDs, result.ID)
 	}
+	return exerciseIDs, nil
+}
 
-	// Step 3: Delete logs in "workout_logs" for these exercises
-	if len(exerciseIDs) > 0 {
-		_, err = r.collection.Database().Collection("workout_logs").DeleteMany(
-			ctx,
-			bson.M{"workouts.exercise_id": bson.M{"$in": exerciseIDs}},
-		)
-		if err != nil {
-			return err
-		}
+// Find the exercises in the categories whose name matches the text query, best match first. Archived exercises are left out.
+func (r *MongoExerciseRepository) SearchExercises(categoryIDs []primitive.ObjectID, query string, limit int64) ([]models.Exercise, []float64, error) {
+	var exercises []models.Exercise
+	scores, err := searchText(r.collection, notArchived(bson.M{"category_id": bson.M{"$in": categoryIDs}}), query, limit, &exercises)
+	return exercises, scores, err
+}
+
+// Delete all exercises in the categories (for cascading deletes)
+func (r *MongoExerciseRepository) DeleteExercisesByCategories(ctx context.Context, categoryIDs []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	objectIDs, err := toObjectIDs(categoryIDs)
+	if err != nil {
+		return 0, err
+	}
+	if len(objectIDs) == 0 {
+		return 0, nil
 	}
 
-	// Step 4: Delete exercises in the specified category
-	_, err = r.collection.DeleteMany(ctx, bson.M{"category_id": objectID})
-	return err
+	result, err := r.collection.DeleteMany(ctx, bson.M{"category_id": bson.M{"$in": objectIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
 }
 
-func (r *ExerciseRepository) IsExerciseExists(name string, categoryID string) (bool, error) {
+func (r *MongoExerciseRepository) IsExerciseExists(name string, categoryID string) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	categoryObjectID, err := primitive.ObjectIDFromHex(categoryID)
 	if err != nil {
-		return false, err
+		return false, ErrInvalidID
 	}
 
-	count, err := r.collection.CountDocuments(ctx, bson.M{"name": name, "category_id": categoryObjectID})
+	count, err := r.collection.CountDocuments(ctx, notArchived(bson.M{"name": name, "category_id": categoryObjectID}))
 	return count > 0, err
 }
 
-func (r *ExerciseRepository) GetExerciseByID(id string) (models.Exercise, error) {
+func (r *MongoExerciseRepository) GetExerciseByID(id string) (models.Exercise, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return models.Exercise{}, err
+		return models.Exercise{}, ErrInvalidID
 	}
 
 	var exercise models.Exercise
 	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&exercise)
-	return exercise, err
+	return exercise, found(err)
 }
 
-func (r *ExerciseRepository) IsExerciseExistsInCategory(name string, categoryID string, excludeID string) (bool, error) {
+// Get the exercises with the given IDs, archived ones included since logged history still points at them
+func (r *MongoExerciseRepository) GetExercisesByIDs(ids []primitive.ObjectID) ([]models.Exercise, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	excludeObjectID, err := primitive.ObjectIDFromHex(excludeID)
-	if err != nil {
-		return false, err
+	exercises := []models.Exercise{}
+	if len(ids) == 0 {
+		return exercises, nil
 	}
 
-	categoryObjectID, err := primitive.ObjectIDFromHex(categoryID)
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
 	if err != nil {
-		return false, err
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	count, err := r.collection.CountDocuments(ctx, bson.M{
-		"name":        name,
-		"category_id": categoryObjectID,
-		"_id":         bson.M{"$ne": excludeObjectID},
-	})
-	return count > 0, err
+	if err := cursor.All(ctx, &exercises); err != nil {
+		return nil, err
+	}
+	return exercises, nil
 }
 
-func (r *ExerciseRepository) MigrateWorkoutLogs() {
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+func (r *MongoExerciseRepository) IsExerciseExistsInCategory(name string, categoryID string, excludeID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := database.DB.Database("ironcoach")
-	workoutLogs := db.Collection("workout_logs")
-	exercises := db.Collection("exercises")
-
-	cursor, err := workoutLogs.Find(ctx, bson.M{})
+	excludeObjectID, err := primitive.ObjectIDFromHex(excludeID)
 	if err != nil {
-		log.Fatalf("Failed to fetch workout logs: %v", err)
+		return false, ErrInvalidID
 	}
-	defer cursor.Close(ctx)
-
-	for cursor.Next(ctx) {
-		var log struct {
-			ID       primitive.ObjectID `bson:"_id"`
-			Workouts []struct {
-				Exercise string `bson:"exercise"`
-			} `bson:"workouts"`
-		}
-
-		if err := cursor.Decode(&log); err != nil {
-			// log.Printf("Failed to decode workout log: %v", err)
-			continue
-		}
 
-		for i, workout := range log.Workouts {
-			var exercise struct {
-				ID primitive.ObjectID `bson:"_id"`
-			}
-			err := exercises.FindOne(ctx, bson.M{"name": workout.Exercise}).Decode(&exercise)
-			if err != nil {
-				// log.Printf("Failed to find exercise for %s: %v", workout.Exercise, err)
-				continue
-			}
-
-			// Update the workout log to include exercise_id
-			_, err = workoutLogs.UpdateOne(ctx, bson.M{"_id": log.ID}, bson.M{
-				"$set": bson.M{fmt.Sprintf("workouts.%d.exercise_id", i): exercise.ID},
-			})
-			if err != nil {
-				// log.Printf("Failed to update workout log: %v", err)
-			}
-		}
+	categoryObjectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		return false, ErrInvalidID
 	}
-	log.Println("Migration completed successfully!")
+
+	count, err := r.collection.CountDocuments(ctx, notArchived(bson.M{
+		"name":        name,
+		"category_id": categoryObjectID,
+		"_id":         bson.M{"$ne": excludeObjectID},
+	}))
+	return count > 0, err
 }
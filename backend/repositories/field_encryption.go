@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"errors"
+	"ironcoach/apperrors"
+	"ironcoach/encryption"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrSealedFieldPath = apperrors.Validation("encrypted_field_path", "encrypted fields can only be replaced as a whole")
+
+var errSealedWithoutID = errors.New("encrypted fields are bound to the document _id, which must be an ObjectID or a string")
+
+var (
+	keyringOnce sync.Once
+	keyring     *encryption.Keyring
+)
+
+// fieldKeyring loads the key encryption keys once. Without them sensitive data would be written
+// in plaintext, so the server refuses to start.
+func fieldKeyring() *encryption.Keyring {
+	keyringOnce.Do(func() {
+		var err error
+		if keyring, err = encryption.NewKeyringFromEnv(); err != nil {
+			log.Fatalf("Failed to load field encryption keys: %v", err)
+		}
+	})
+	return keyring
+}
+
+// fieldCipher seals a fixed set of top-level fields of a collection's documents. Each value is stored
+// as an encryption.Sealed document holding the BSON of {v: <value>}, with "<collection>/<_id>/<field>"
+// as associated data, so a sealed value opens neither in another field nor in another document.
+// Values written before encryption was enabled, and those sealed with the field name alone before the
+// document was bound, are read as they are until they are resealed.
+type fieldCipher struct {
+	keyring    *encryption.Keyring
+	collection string
+	fields     map[string]bool
+}
+
+func newFieldCipher(collection string, fields ...string) *fieldCipher {
+	cipher := &fieldCipher{keyring: fieldKeyring(), collection: collection, fields: map[string]bool{}}
+	for _, field := range fields {
+		cipher.fields[field] = true
+	}
+	return cipher
+}
+
+// registry returns a codec registry that seals the fields whenever a value of the model's type is
+// written and opens them whenever one is read, so the rest of the code only sees plaintext
+func (c *fieldCipher) registry(model interface{}) *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	modelType := reflect.TypeOf(model)
+	registry.RegisterTypeEncoder(modelType, bsoncodec.ValueEncoderFunc(c.encodeValue))
+	registry.RegisterTypeDecoder(modelType, bsoncodec.ValueDecoderFunc(c.decodeValue))
+	return registry
+}
+
+var rawType = reflect.TypeOf(bson.Raw{})
+
+func (c *fieldCipher) encodeValue(ec bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	// The default registry knows nothing about this codec, so this encodes the plain struct
+	plain, err := bson.Marshal(val.Interface())
+	if err != nil {
+		return err
+	}
+	sealed, err := c.sealDocument(plain)
+	if err != nil {
+		return err
+	}
+
+	encoder, err := ec.LookupEncoder(rawType)
+	if err != nil {
+		return err
+	}
+	return encoder.EncodeValue(ec, vw, reflect.ValueOf(sealed))
+}
+
+func (c *fieldCipher) decodeValue(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	decoder, err := dc.LookupDecoder(rawType)
+	if err != nil {
+		return err
+	}
+	var raw bson.Raw
+	if err := decoder.DecodeValue(dc, vr, reflect.ValueOf(&raw).Elem()); err != nil {
+		return err
+	}
+
+	opened, err := c.openDocument(raw)
+	if err != nil {
+		return err
+	}
+	decoded := reflect.New(val.Type())
+	if err := bson.Unmarshal(opened, decoded.Interface()); err != nil {
+		return err
+	}
+	val.Set(decoded.Elem())
+	return nil
+}
+
+// sealDocument seals the encrypted fields of a document that are still in plaintext. A new document
+// gets its _id here rather than from the driver, as the sealed fields are bound to it.
+func (c *fieldCipher) sealDocument(document bson.Raw) (bson.Raw, error) {
+	if _, err := document.LookupErr("_id"); err != nil {
+		if document, err = withNewID(document); err != nil {
+			return nil, err
+		}
+	}
+	id, err := documentID(document)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.mapFields(document, func(field string, value bson.RawValue) (interface{}, error) {
+		if _, ok := c.sealedValue(value); ok || value.Type == bsontype.Null {
+			return value, nil
+		}
+		return c.seal(id, field, value)
+	})
+}
+
+// openDocument replaces the sealed fields of a document with their plaintext
+func (c *fieldCipher) openDocument(document bson.Raw) (bson.Raw, error) {
+	var id string
+	return c.mapFields(document, func(field string, value bson.RawValue) (interface{}, error) {
+		sealed, ok := c.sealedValue(value)
+		if !ok {
+			return value, nil
+		}
+		if id == "" {
+			var err error
+			if id, err = documentID(document); err != nil {
+				return nil, err
+			}
+		}
+
+		plaintext, _, err := c.open(id, field, sealed)
+		if err != nil {
+			return nil, err
+		}
+		return bson.Raw(plaintext).LookupErr("v")
+	})
+}
+
+// sealUpdate seals the encrypted fields of a $set on the document with the given ID.
+// Paths into an encrypted field cannot be sealed on their own.
+func (c *fieldCipher) sealUpdate(id string, update map[string]interface{}) error {
+	for key, value := range update {
+		if field, _, nested := strings.Cut(key, "."); nested && c.fields[field] {
+			return ErrSealedFieldPath
+		}
+		if !c.fields[key] || value == nil {
+			continue
+		}
+
+		sealed, err := c.seal(id, key, value)
+		if err != nil {
+			return err
+		}
+		update[key] = sealed
+	}
+	return nil
+}
+
+// resealDocument returns the encrypted fields of a stored document that are in plaintext, not yet
+// bound to the document, or wrapped with a key other than the active one, sealed again with the active key
+func (c *fieldCipher) resealDocument(document bson.Raw) (bson.M, error) {
+	id, err := documentID(document)
+	if err != nil {
+		return nil, err
+	}
+
+	resealed := bson.M{}
+	for field := range c.fields {
+		value, err := document.LookupErr(field)
+		if err != nil || value.Type == bsontype.Null {
+			continue
+		}
+
+		sealed, ok := c.sealedValue(value)
+		if !ok {
+			if resealed[field], err = c.seal(id, field, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		plaintext, unbound, err := c.open(id, field, sealed)
+		switch {
+		case err != nil:
+			return nil, err
+		case unbound:
+			if resealed[field], err = c.keyring.Seal(plaintext, c.associatedData(id, field)); err != nil {
+				return nil, err
+			}
+		case sealed.KeyID != c.keyring.ActiveKeyID():
+			if resealed[field], err = c.keyring.Rewrap(sealed); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resealed, nil
+}
+
+func (c *fieldCipher) seal(id string, field string, value interface{}) (encryption.Sealed, error) {
+	plaintext, err := bson.Marshal(bson.D{{Key: "v", Value: value}})
+	if err != nil {
+		return encryption.Sealed{}, err
+	}
+	return c.keyring.Seal(plaintext, c.associatedData(id, field))
+}
+
+// open decrypts a value of the document with the given ID. Unbound tells a value sealed with the
+// field name alone, as they were before the document was bound, which resealing replaces.
+func (c *fieldCipher) open(id string, field string, sealed encryption.Sealed) (plaintext []byte, unbound bool, err error) {
+	if plaintext, err = c.keyring.Open(sealed, c.associatedData(id, field)); err == nil {
+		return plaintext, false, nil
+	}
+	if plaintext, legacyErr := c.keyring.Open(sealed, []byte(field)); legacyErr == nil {
+		return plaintext, true, nil
+	}
+	return nil, false, err
+}
+
+func (c *fieldCipher) associatedData(id string, field string) []byte {
+	return []byte(c.collection + "/" + id + "/" + field)
+}
+
+// documentID is the _id sealed values are bound to, ObjectIDs by their hex as the repositories pass them around
+func documentID(document bson.Raw) (string, error) {
+	value, err := document.LookupErr("_id")
+	if err != nil {
+		return "", errSealedWithoutID
+	}
+	if id, ok := value.ObjectIDOK(); ok {
+		return id.Hex(), nil
+	}
+	if id, ok := value.StringValueOK(); ok && id != "" {
+		return id, nil
+	}
+	return "", errSealedWithoutID
+}
+
+// withNewID returns the document with a new ObjectID _id in front, as the driver would have added it
+func withNewID(document bson.Raw) (bson.Raw, error) {
+	elements, err := document.Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	withID := make(bson.D, 0, len(elements)+1)
+	withID = append(withID, bson.E{Key: "_id", Value: primitive.NewObjectID()})
+	for _, element := range elements {
+		withID = append(withID, bson.E{Key: element.Key(), Value: element.Value()})
+	}
+	return bson.Marshal(withID)
+}
+
+func (c *fieldCipher) sealedValue(value bson.RawValue) (encryption.Sealed, bool) {
+	var sealed encryption.Sealed
+	if value.Type != bsontype.EmbeddedDocument || value.Unmarshal(&sealed) != nil {
+		return sealed, false
+	}
+	return sealed, sealed.IsSealed()
+}
+
+// mapFields rebuilds a document with the encrypted fields passed through fn, keeping the field order
+func (c *fieldCipher) mapFields(document bson.Raw, fn func(field string, value bson.RawValue) (interface{}, error)) (bson.Raw, error) {
+	elements, err := document.Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	mapped := make(bson.D, 0, len(elements))
+	for _, element := range elements {
+		var value interface{} = element.Value()
+		if c.fields[element.Key()] {
+			if value, err = fn(element.Key(), element.Value()); err != nil {
+				return nil, err
+			}
+		}
+		mapped = append(mapped, bson.E{Key: element.Key(), Value: value})
+	}
+	return bson.Marshal(mapped)
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"ironcoach/encryption"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testCipher(t *testing.T) *fieldCipher {
+	t.Helper()
+	t.Setenv("FIELD_ENCRYPTION_KEY_FILE", "")
+	t.Setenv("FIELD_ENCRYPTION_KEYS", "k1:MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=")
+	keys, err := encryption.NewKeyringFromEnv()
+	if err != nil {
+		t.Fatalf("NewKeyringFromEnv: %v", err)
+	}
+	return &fieldCipher{keyring: keys, collection: "trainees", fields: map[string]bool{"medical_history": true}}
+}
+
+func TestSealedFieldsAreBoundToTheirDocument(t *testing.T) {
+	cipher := testCipher(t)
+	plain, _ := bson.Marshal(bson.D{{Key: "name", Value: "Alex"}, {Key: "medical_history", Value: "asthma"}})
+
+	sealed, err := cipher.sealDocument(plain)
+	if err != nil {
+		t.Fatalf("sealDocument: %v", err)
+	}
+	if _, ok := sealed.Lookup("_id").ObjectIDOK(); !ok {
+		t.Fatalf("sealed document %v was not given an _id", sealed)
+	}
+	opened, err := cipher.openDocument(sealed)
+	if err != nil {
+		t.Fatalf("openDocument: %v", err)
+	}
+	if history := opened.Lookup("medical_history").StringValue(); history != "asthma" {
+		t.Errorf("medical_history = %q, want asthma", history)
+	}
+
+	// The sealed value copied into another trainee does not open there
+	moved, _ := bson.Marshal(bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "medical_history", Value: sealed.Lookup("medical_history")}})
+	if _, err := cipher.openDocument(moved); err == nil {
+		t.Error("a value moved to another document opened")
+	}
+}
+
+func TestUnboundValuesAreResealed(t *testing.T) {
+	cipher := testCipher(t)
+	id := primitive.NewObjectID()
+	plaintext, _ := bson.Marshal(bson.D{{Key: "v", Value: "asthma"}})
+	legacy, err := cipher.keyring.Seal(plaintext, []byte("medical_history"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	stored, _ := bson.Marshal(bson.D{{Key: "_id", Value: id}, {Key: "medical_history", Value: legacy}})
+
+	if opened, err := cipher.openDocument(stored); err != nil || opened.Lookup("medical_history").StringValue() != "asthma" {
+		t.Fatalf("openDocument of a value sealed with the field name alone = %v, %v", opened, err)
+	}
+
+	resealed, err := cipher.resealDocument(stored)
+	if err != nil {
+		t.Fatalf("resealDocument: %v", err)
+	}
+	bound, ok := resealed["medical_history"].(encryption.Sealed)
+	if !ok {
+		t.Fatalf("resealed = %v, want medical_history sealed again", resealed)
+	}
+	if _, err := cipher.keyring.Open(bound, []byte("trainees/"+id.Hex()+"/medical_history")); err != nil {
+		t.Errorf("resealed value is not bound to the trainee: %v", err)
+	}
+
+	// A bound value under the active key is left alone
+	rebound, _ := bson.Marshal(bson.D{{Key: "_id", Value: id}, {Key: "medical_history", Value: bound}})
+	if resealed, err := cipher.resealDocument(rebound); err != nil || len(resealed) != 0 {
+		t.Errorf("resealDocument of a bound value = %v, %v, want nothing", resealed, err)
+	}
+}
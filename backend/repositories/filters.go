@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ownerOrOrganizationFilter matches documents owned by the trainer or shared through one of the organizations
+func ownerOrOrganizationFilter(trainerID string, organizationIDs []string) bson.M {
+	if len(organizationIDs) == 0 {
+		return bson.M{"trainer_id": trainerID}
+	}
+	return bson.M{"$or": []bson.M{
+		{"trainer_id": trainerID},
+		{"organization_id": bson.M{"$in": organizationIDs}},
+	}}
+}
+
+// personalFilter matches documents the trainer owns outside of any organization
+func personalFilter(trainerID string) bson.M {
+	return bson.M{"trainer_id": trainerID, "organization_id": bson.M{"$exists": false}}
+}
+
+// toObjectIDs converts hex IDs for $in filters
+func toObjectIDs(ids []string) ([]primitive.ObjectID, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, ErrInvalidID
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
+
+// notDeleted adds the condition that hides documents sitting in the trash
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": false}
+	return filter
+}
+
+// onlyDeleted adds the condition that matches documents sitting in the trash
+func onlyDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": true}
+	return filter
+}
+
+// notArchived hides archived exercises from pickers and duplicate checks
+func notArchived(filter bson.M) bson.M {
+	filter["archived_at"] = bson.M{"$exists": false}
+	return filter
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoIdempotencyRepository struct {
+	collection *mongo.Collection
+}
+
+// Constructor for MongoIdempotencyRepository
+func NewMongoIdempotencyRepository(db *mongo.Database) *MongoIdempotencyRepository {
+	return &MongoIdempotencyRepository{
+		collection: db.Collection("idempotency_keys"),
+	}
+}
+
+// Expired records are cleaned up in bulk
+var idempotencyIndexes = []mongo.IndexModel{
+	ascending("expires", "expires_at"),
+}
+
+// ClaimRecord stores a pending record unless an unexpired one with the same ID exists.
+// It returns false when the key is already taken.
+func (r *MongoIdempotencyRepository) ClaimRecord(record models.IdempotencyRecord) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// An expired record frees the key
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": record.ID, "expires_at": bson.M{"$lte": time.Now()}}); err != nil {
+		return false, err
+	}
+
+	_, err := r.collection.InsertOne(ctx, record)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Get an unexpired record by ID
+func (r *MongoIdempotencyRepository) GetRecord(id string) (models.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var record models.IdempotencyRecord
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "expires_at": bson.M{"$gt": time.Now()}}).Decode(&record)
+	return record, found(err)
+}
+
+// Store the response of a pending record so retries can replay it
+func (r *MongoIdempotencyRepository) CompleteRecord(id string, status int, contentType string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "state": models.IdempotencyPending}, bson.M{"$set": bson.M{
+		"state":        models.IdempotencyCompleted,
+		"status":       status,
+		"content_type": contentType,
+		"body":         body,
+	}})
+	return err
+}
+
+// Delete a pending record, freeing the key for another attempt
+func (r *MongoIdempotencyRepository) ReleaseRecord(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "state": models.IdempotencyPending})
+	return err
+}
+
+// Delete every record that has expired
+func (r *MongoIdempotencyRepository) DeleteExpiredRecords() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/apperrors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrDuplicateKey is returned when a write would break a unique index
+var ErrDuplicateKey = apperrors.Conflict("duplicate_key", "duplicate key")
+
+// States of a declared or found index
+const (
+	IndexPresent = "present" // Exists as declared
+	IndexCreated = "created" // Was missing and has been created
+	IndexMissing = "missing" // Declared but absent, or present under its name with another definition
+	IndexExtra   = "extra"   // Exists but is not declared, it is reported and never dropped
+)
+
+// IndexStatus reports one index of a collection
+type IndexStatus struct {
+	Collection string
+	Name       string
+	State      string
+	Err        error // Why a missing index could not be created
+}
+
+// collectionIndexes lists the indexes of every collection. Each repository declares the indexes its
+// queries need next to its constructor. Indexes are told apart by name, so a changed definition
+// needs a new name.
+var collectionIndexes = map[string][]mongo.IndexModel{
+	"trainers":            trainerIndexes,
+	"trainees":            traineeIndexes,
+	"trainee_accounts":    traineeAccountIndexes,
+	"organizations":       organizationIndexes,
+	"sessions":            sessionIndexes,
+	"account_tokens":      accountTokenIndexes,
+	"categories":          categoryIndexes,
+	"exercises":           exerciseIndexes,
+	"workout_logs":        workoutLogIndexes,
+	"diet_entries":        dietEntryIndexes,
+	"personal_records":    personalRecordIndexes,
+	"programs":            programIndexes,
+	"program_assignments": programAssignmentIndexes,
+	"progress":            progressIndexes,
+	"audit_logs":          auditIndexes,
+	"idempotency_keys":    idempotencyIndexes,
+}
+
+// EnsureIndexes compares the declared indexes with those in the database and, if create is set,
+// creates the missing ones. An index that cannot be created, say a unique one over duplicate data,
+// is reported as missing with the reason rather than failing the others.
+func EnsureIndexes(db *mongo.Database, create bool) ([]IndexStatus, error) {
+	names := make([]string, 0, len(collectionIndexes))
+	for name := range collectionIndexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var statuses []IndexStatus
+	for _, name := range names {
+		collectionStatuses, err := ensureCollectionIndexes(db.Collection(name), collectionIndexes[name], create)
+		if err != nil {
+			return statuses, err
+		}
+		statuses = append(statuses, collectionStatuses...)
+	}
+	return statuses, nil
+}
+
+func ensureCollectionIndexes(collection *mongo.Collection, declared []mongo.IndexModel, create bool) ([]IndexStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	existing, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	for _, spec := range existing {
+		found[spec.Name] = true
+	}
+
+	var statuses []IndexStatus
+	for _, index := range declared {
+		name := *index.Options.Name
+		status := IndexStatus{Collection: collection.Name(), Name: name, State: IndexMissing}
+		if found[name] {
+			status.State = IndexPresent
+		}
+		delete(found, name)
+
+		// Creating an index that exists as declared does nothing, one with another definition fails
+		if create {
+			if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+				status.State, status.Err = IndexMissing, err
+			} else if status.State == IndexMissing {
+				status.State = IndexCreated
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	delete(found, "_id_")
+	extra := make([]string, 0, len(found))
+	for name := range found {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		statuses = append(statuses, IndexStatus{Collection: collection.Name(), Name: name, State: IndexExtra})
+	}
+	return statuses, nil
+}
+
+// ascending declares an index on the fields in order
+func ascending(name string, fields ...string) mongo.IndexModel {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name)}
+}
+
+// unique makes an index reject a second document with the same keys
+func unique(index mongo.IndexModel) mongo.IndexModel {
+	index.Options.SetUnique(true)
+	return index
+}
+
+// partial only indexes the documents matching the filter. A unique partial index only requires those
+// to be unique.
+func partial(index mongo.IndexModel, filter bson.M) mongo.IndexModel {
+	index.Options.SetPartialFilterExpression(filter)
+	return index
+}
+
+// duplicateKey turns the error of a write that broke a unique index into ErrDuplicateKey
+func duplicateKey(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateKey
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type AccountTokenRepository struct {
+	collection *collection
+}
+
+// Constructor for AccountTokenRepository
+func NewAccountTokenRepository(store *Store) *AccountTokenRepository {
+	return &AccountTokenRepository{
+		collection: store.collection("account_tokens"),
+	}
+}
+
+func (r *AccountTokenRepository) CreateToken(token models.AccountToken) error {
+	return r.collection.insert(token)
+}
+
+func (r *AccountTokenRepository) ConsumeToken(id string, purpose string) (models.AccountToken, error) {
+	var token models.AccountToken
+	err := r.collection.findOneAndUpdate(
+		bson.M{
+			"_id":        id,
+			"purpose":    purpose,
+			"used_at":    bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+		false,
+		&token,
+	)
+	return token, err
+}
+
+func (r *AccountTokenRepository) InvalidateTokens(email string, purpose string) error {
+	_, _, err := r.collection.update(
+		bson.M{"email": email, "purpose": purpose, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+		true,
+	)
+	return err
+}
+
+func (r *AccountTokenRepository) DeleteTokensByEmail(ctx context.Context, email string) (int64, error) {
+	return r.collection.delete(bson.M{"email": email}, true)
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"ironcoach/models"
+	"ironcoach/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type AuditRepository struct {
+	store      *Store
+	collection *collection
+}
+
+// Constructor for AuditRepository
+func NewAuditRepository(store *Store) *AuditRepository {
+	return &AuditRepository{
+		store:      store,
+		collection: store.collection("audit_logs"),
+	}
+}
+
+func (r *AuditRepository) InsertEntry(entry models.AuditEntry) error {
+	return r.collection.insert(entry)
+}
+
+func (r *AuditRepository) FindEntries(filter bson.M, limit int64) ([]models.AuditEntry, error) {
+	found, err := r.collection.find(filter, bson.D{{Key: "created_at", Value: -1}}, limit)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll[models.AuditEntry](found)
+}
+
+func (r *AuditRepository) GetDocument(collection string, filter bson.M) (bson.M, error) {
+	found, err := r.store.collection(collection).find(filter, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, repositories.ErrNotFound
+	}
+	var document bson.M
+	err = bson.Unmarshal(found[0], &document)
+	return document, err
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CategoryRepository struct {
+	collection *collection
+	exercises  *collection
+}
+
+// Constructor for CategoryRepository
+func NewCategoryRepository(store *Store) *CategoryRepository {
+	return &CategoryRepository{
+		collection: store.collection("categories",
+			uniqueIndex{fields: []string{"trainer_id", "organization_id", "name"}},
+			uniqueIndex{fields: []string{"organization_id", "name"}, partial: bson.M{"organization_id": bson.M{"$exists": true}}},
+		),
+		exercises: store.collection("exercises"),
+	}
+}
+
+func (r *CategoryRepository) AddCategory(category models.Category) error {
+	return r.collection.insert(category)
+}
+
+func (r *CategoryRepository) GetCategories(trainerID string, organizationIDs []string) ([]models.Category, error) {
+	found, err := r.collection.find(ownerOrOrganizationFilter(trainerID, organizationIDs), nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEach[models.Category](found)
+}
+
+func (r *CategoryRepository) UpdateCategory(id string, updatedName string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrInvalidID
+	}
+	_, _, err = r.collection.update(bson.M{"_id": objectID}, bson.M{"$set": bson.M{"name": updatedName}}, false)
+	return err
+}
+
+func (r *CategoryRepository) CascadeUpdateCategoryInExercises(categoryID string, updatedCategoryName string) error {
+	objectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		return repositories.ErrInvalidID
+	}
+	_, _, err = r.exercises.update(bson.M{"category_id": objectID}, bson.M{"$set": bson.M{"category": updatedCategoryName}}, true)
+	return err
+}
+
+func (r *CategoryRepository) DeleteCategories(ctx context.Context, ids []string) (int64, error) {
+	objectIDs, err := toObjectIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(objectIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"_id": bson.M{"$in": objectIDs}}, true)
+}
+
+func (r *CategoryRepository) IsCategoryExists(name string, trainerID string, organizationID string) (bool, error) {
+	filter := personalFilter(trainerID)
+	if organizationID != "" {
+		filter = bson.M{"organization_id": organizationID}
+	}
+	filter["name"] = name
+
+	count, err := r.collection.count(filter)
+	return count > 0, err
+}
+
+func (r *CategoryRepository) GetCategoryByID(id string) (models.Category, error) {
+	var category models.Category
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return category, repositories.ErrInvalidID
+	}
+	err = r.collection.findOne(bson.M{"_id": objectID}, &category)
+	return category, err
+}
+
+func (r *CategoryRepository) GetCategoryIDsByTrainer(ctx context.Context, trainerID string) ([]string, error) {
+	found, err := r.collection.find(personalFilter(trainerID), nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var categoryIDs []string
+	for _, doc := range found {
+		categoryIDs = append(categoryIDs, idHex(doc))
+	}
+	return categoryIDs, nil
+}
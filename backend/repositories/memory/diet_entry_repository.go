@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DietEntryRepository struct {
+	collection *collection
+}
+
+// Constructor for DietEntryRepository
+func NewDietEntryRepository(store *Store) *DietEntryRepository {
+	return &DietEntryRepository{
+		collection: store.collection("diet_entries", clientIDIndex),
+	}
+}
+
+// Diet entries are searched by their notes
+var dietEntrySearchWeights = bson.D{{Key: "notes", Value: 1}}
+
+func (r *DietEntryRepository) CreateDietEntry(entry models.DietEntry) error {
+	var done func()
+	entry.SyncSeq, done = r.collection.store.takeSyncSeq()
+	defer done()
+	return r.collection.insert(entry)
+}
+
+func (r *DietEntryRepository) GetDietEntriesByTrainee(traineeID string, list models.ListOptions) ([]models.DietEntry, string, error) {
+	return findPage[models.DietEntry](r.collection, dateRangeFilter(notDeleted(bson.M{"trainee_id": traineeID}), "date", list), list)
+}
+
+func (r *DietEntryRepository) SearchDietEntries(traineeIDs []string, query string, limit int64) ([]models.DietEntry, []float64, error) {
+	return searchText[models.DietEntry](r.collection, notDeleted(bson.M{"trainee_id": bson.M{"$in": traineeIDs}}), query, limit, dietEntrySearchWeights)
+}
+
+func (r *DietEntryRepository) GetDietEntryByID(entryID string) (models.DietEntry, error) {
+	var entry models.DietEntry
+	objectID, err := primitive.ObjectIDFromHex(entryID)
+	if err != nil {
+		return entry, repositories.ErrInvalidID
+	}
+	err = r.collection.findOne(notDeleted(bson.M{"_id": objectID}), &entry)
+	return entry, err
+}
+
+func (r *DietEntryRepository) UpdateDietEntry(entryID string, version int64, updateData models.DietEntry) error {
+	return updateVersioned(r.collection, entryID, version, bson.M{"$set": bson.M{
+		"date":           updateData.Date,
+		"meals":          updateData.Meals,
+		"total_calories": updateData.TotalCalories,
+		"total_proteins": updateData.TotalProteins,
+		"notes":          updateData.Notes,
+		"updated_at":     updateData.UpdatedAt,
+	}})
+}
+
+func (r *DietEntryRepository) ApplyDietEntryChange(entryID string, version int64, set map[string]interface{}) error {
+	return updateVersioned(r.collection, entryID, version, bson.M{"$set": set})
+}
+
+func (r *DietEntryRepository) GetDietEntryByClientID(clientID string) (models.DietEntry, error) {
+	var entry models.DietEntry
+	err := findByClientID(r.collection, clientID, &entry)
+	return entry, err
+}
+
+func (r *DietEntryRepository) GetDietEntryChanges(traineeIDs []string, after repositories.SyncPosition, until int64, limit int64) ([]models.DietEntry, error) {
+	if len(traineeIDs) == 0 {
+		return []models.DietEntry{}, nil
+	}
+	return findChanges[models.DietEntry](r.collection, traineeIDs, after, until, limit)
+}
+
+func (r *DietEntryRepository) SoftDeleteDietEntry(entryID string, version int64) error {
+	return softDelete(r.collection, entryID, version)
+}
+
+func (r *DietEntryRepository) RestoreDietEntry(entryID string) error {
+	return restore(r.collection, entryID)
+}
+
+func (r *DietEntryRepository) GetDeletedDietEntryByID(entryID string) (models.DietEntry, error) {
+	var entry models.DietEntry
+	err := findDeleted(r.collection, entryID, &entry)
+	return entry, err
+}
+
+func (r *DietEntryRepository) GetDeletedDietEntries(traineeIDs []string) ([]models.DietEntry, error) {
+	if len(traineeIDs) == 0 {
+		return nil, nil
+	}
+	return findAllDeleted[models.DietEntry](r.collection, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
+}
+
+func (r *DietEntryRepository) PurgeDietEntry(entryID string) (int64, error) {
+	return purgeDeleted(r.collection, entryID)
+}
+
+func (r *DietEntryRepository) PurgeDietEntriesDeletedBefore(cutoff time.Time) (int64, error) {
+	return purgeDeletedBefore(r.collection, cutoff)
+}
+
+func (r *DietEntryRepository) DeleteDietEntriesByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"trainee_id": bson.M{"$in": traineeIDs}}, true)
+}
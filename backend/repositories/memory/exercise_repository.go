@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ExerciseRepository struct {
+	collection  *collection
+	workoutLogs *collection
+}
+
+// Constructor for ExerciseRepository
+func NewExerciseRepository(store *Store) *ExerciseRepository {
+	return &ExerciseRepository{
+		collection:  store.collection("exercises", uniqueIndex{fields: []string{"category_id", "name", "archived_at"}}),
+		workoutLogs: store.collection("workout_logs"),
+	}
+}
+
+// Exercises are searched by name
+var exerciseSearchWeights = bson.D{{Key: "name", Value: 1}}
+
+func (r *ExerciseRepository) AddExercise(exercise models.Exercise) error {
+	return r.collection.insert(exercise)
+}
+
+func (r *ExerciseRepository) GetExercisesByCategoryID(categoryID string) ([]models.Exercise, error) {
+	objectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		return nil, repositories.ErrInvalidID
+	}
+	found, err := r.collection.find(notArchived(bson.M{"category_id": objectID}), nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEach[models.Exercise](found)
+}
+
+func (r *ExerciseRepository) UpdateExercise(id string, updatedName string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrInvalidID
+	}
+	_, _, err = r.collection.update(bson.M{"_id": objectID}, bson.M{"$set": bson.M{"name": updatedName}}, false)
+	return err
+}
+
+// CascadeUpdateExerciseInWorkoutLogs renames every entry for the exercise, which the Mongo repository
+// does with an array filter
+func (r *ExerciseRepository) CascadeUpdateExerciseInWorkoutLogs(exerciseID string, updatedName string) error {
+	objectID, err := primitive.ObjectIDFromHex(exerciseID)
+	if err != nil {
+		return repositories.ErrInvalidID
+	}
+
+	bump, done := bumpVersion(r.collection.store, bson.M{})
+	defer done()
+	filter := bson.M{"workouts.exercise_id": objectID}
+	_, _, err = r.workoutLogs.updateFunc(filter, true, func(doc bson.M) error {
+		workouts, _ := doc["workouts"].(bson.A)
+		for _, workout := range workouts {
+			if entry, ok := workout.(bson.M); ok && entry["exercise_id"] == objectID {
+				entry["exercise"] = updatedName
+			}
+		}
+		return applyUpdate(doc, bump, filter)
+	})
+	return err
+}
+
+func (r *ExerciseRepository) DeleteExercise(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrInvalidID
+	}
+	_, err = r.collection.delete(bson.M{"_id": objectID}, false)
+	return err
+}
+
+func (r *ExerciseRepository) ArchiveExercise(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrInvalidID
+	}
+	now := time.Now()
+	_, _, err = r.collection.update(
+		notArchived(bson.M{"_id": objectID}),
+		bson.M{"$set": bson.M{"archived_at": now, "updated_at": now}},
+		false,
+	)
+	return err
+}
+
+func (r *ExerciseRepository) GetExerciseIDsByCategories(ctx context.Context, categoryIDs []string) ([]primitive.ObjectID, error) {
+	objectIDs, err := toObjectIDs(categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	found, err := r.collection.find(bson.M{"category_id": bson.M{"$in": objectIDs}}, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var exerciseIDs []primitive.ObjectID
+	for _, doc := range found {
+		exerciseIDs = append(exerciseIDs, doc.Lookup("_id").ObjectID())
+	}
+	return exerciseIDs, nil
+}
+
+func (r *ExerciseRepository) SearchExercises(categoryIDs []primitive.ObjectID, query string, limit int64) ([]models.Exercise, []float64, error) {
+	return searchText[models.Exercise](r.collection, notArchived(bson.M{"category_id": bson.M{"$in": categoryIDs}}), query, limit, exerciseSearchWeights)
+}
+
+func (r *ExerciseRepository) DeleteExercisesByCategories(ctx context.Context, categoryIDs []string) (int64, error) {
+	objectIDs, err := toObjectIDs(categoryIDs)
+	if err != nil {
+		return 0, err
+	}
+	if len(objectIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"category_id": bson.M{"$in": objectIDs}}, true)
+}
+
+func (r *ExerciseRepository) IsExerciseExists(name string, categoryID string) (bool, error) {
+	categoryObjectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		return false, repositories.ErrInvalidID
+	}
+	count, err := r.collection.count(notArchived(bson.M{"name": name, "category_id": categoryObjectID}))
+	return count > 0, err
+}
+
+func (r *ExerciseRepository) GetExerciseByID(id string) (models.Exercise, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Exercise{}, repositories.ErrInvalidID
+	}
+	var exercise models.Exercise
+	err = r.collection.findOne(bson.M{"_id": objectID}, &exercise)
+	return exercise, err
+}
+
+func (r *ExerciseRepository) GetExercisesByIDs(ids []primitive.ObjectID) ([]models.Exercise, error) {
+	if len(ids) == 0 {
+		return []models.Exercise{}, nil
+	}
+	found, err := r.collection.find(bson.M{"_id": bson.M{"$in": ids}}, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEach[models.Exercise](found)
+}
+
+func (r *ExerciseRepository) IsExerciseExistsInCategory(name string, categoryID string, excludeID string) (bool, error) {
+	excludeObjectID, err := primitive.ObjectIDFromHex(excludeID)
+	if err != nil {
+		return false, repositories.ErrInvalidID
+	}
+	categoryObjectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		return false, repositories.ErrInvalidID
+	}
+	count, err := r.collection.count(notArchived(bson.M{
+		"name":        name,
+		"category_id": categoryObjectID,
+		"_id":         bson.M{"$ne": excludeObjectID},
+	}))
+	return count > 0, err
+}
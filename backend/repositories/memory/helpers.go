@@ -0,0 +1,380 @@
+package memory
+
+import (
+	"encoding/base64"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The helpers below do what their namesakes in the repositories package do, over a memory collection
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+func ownerOrOrganizationFilter(trainerID string, organizationIDs []string) bson.M {
+	if len(organizationIDs) == 0 {
+		return bson.M{"trainer_id": trainerID}
+	}
+	return bson.M{"$or": []bson.M{
+		{"trainer_id": trainerID},
+		{"organization_id": bson.M{"$in": organizationIDs}},
+	}}
+}
+
+func personalFilter(trainerID string) bson.M {
+	return bson.M{"trainer_id": trainerID, "organization_id": bson.M{"$exists": false}}
+}
+
+func toObjectIDs(ids []string) ([]primitive.ObjectID, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, repositories.ErrInvalidID
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
+
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": false}
+	return filter
+}
+
+func onlyDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": true}
+	return filter
+}
+
+func notArchived(filter bson.M) bson.M {
+	filter["archived_at"] = bson.M{"$exists": false}
+	return filter
+}
+
+func versionFilter(filter bson.M, version int64) bson.M {
+	if version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["version"] = version
+	}
+	return filter
+}
+
+func bumpVersion(store *Store, update bson.M) (bson.M, func()) {
+	seq, done := store.takeSyncSeq()
+	update["$inc"] = bson.M{"version": 1}
+	update["$max"] = bson.M{"sync_seq": seq}
+	return update, done
+}
+
+func updateVersioned(c *collection, id string, version int64, update bson.M) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrNotFound
+	}
+
+	update, done := bumpVersion(c.store, update)
+	defer done()
+	matched, _, err := c.update(versionFilter(notDeleted(bson.M{"_id": objectID}), version), update, false)
+	if err != nil {
+		return err
+	}
+	if matched > 0 {
+		return nil
+	}
+
+	count, err := c.count(notDeleted(bson.M{"_id": objectID}))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return repositories.ErrVersionConflict
+	}
+	return repositories.ErrNotFound
+}
+
+func softDelete(c *collection, id string, version int64) error {
+	return updateVersioned(c, id, version, bson.M{"$set": bson.M{"deleted_at": time.Now()}})
+}
+
+func restore(c *collection, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrNotFound
+	}
+	update, done := bumpVersion(c.store, bson.M{"$unset": bson.M{"deleted_at": ""}})
+	defer done()
+	matched, _, err := c.update(onlyDeleted(bson.M{"_id": objectID}), update, false)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func findDeleted(c *collection, id string, result interface{}) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrNotFound
+	}
+	return c.findOne(onlyDeleted(bson.M{"_id": objectID}), result)
+}
+
+func findAllDeleted[T any](c *collection, filter bson.M) ([]T, error) {
+	found, err := c.find(onlyDeleted(filter), bson.D{{Key: "deleted_at", Value: -1}}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll[T](found)
+}
+
+func purgeDeletedBefore(c *collection, cutoff time.Time) (int64, error) {
+	return c.delete(bson.M{"deleted_at": bson.M{"$lt": cutoff}}, true)
+}
+
+func purgeDeleted(c *collection, id string) (int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, repositories.ErrNotFound
+	}
+	deleted, err := c.delete(onlyDeleted(bson.M{"_id": objectID}), false)
+	if err != nil {
+		return 0, err
+	}
+	if deleted == 0 {
+		return 0, repositories.ErrNotFound
+	}
+	return deleted, nil
+}
+
+func findByClientID(c *collection, clientID string, result interface{}) error {
+	filter := bson.M{"client_id": clientID}
+	if objectID, err := primitive.ObjectIDFromHex(clientID); err == nil {
+		filter = bson.M{"$or": []bson.M{filter, {"_id": objectID}}}
+	}
+	return c.findOne(filter, result)
+}
+
+func findChanges[T any](c *collection, traineeIDs []string, after repositories.SyncPosition, until int64, limit int64) ([]T, error) {
+	sameSeq := bson.M{"sync_seq": after.Seq}
+	if after.Seq == 0 {
+		sameSeq["sync_seq"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	if afterID, err := primitive.ObjectIDFromHex(after.ID); err == nil {
+		sameSeq["_id"] = bson.M{"$gt": afterID}
+	}
+	filter := bson.M{
+		"trainee_id": bson.M{"$in": traineeIDs},
+		"$or":        []bson.M{{"sync_seq": bson.M{"$gt": after.Seq, "$lte": until}}, sameSeq},
+	}
+
+	found, err := c.find(filter, bson.D{{Key: "sync_seq", Value: 1}, {Key: "_id", Value: 1}}, limit)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll[T](found)
+}
+
+func dateRangeFilter(filter bson.M, field string, list models.ListOptions) bson.M {
+	dates := bson.M{}
+	if !list.From.IsZero() {
+		dates["$gte"] = list.From
+	}
+	if !list.To.IsZero() {
+		dates["$lt"] = list.To.AddDays(1)
+	}
+	if len(dates) > 0 {
+		filter[field] = dates
+	}
+	return filter
+}
+
+// pageCursor points just past the last item of a page, like the cursors of the Mongo repositories
+type pageCursor struct {
+	Sort  string        `bson:"s"`
+	Desc  bool          `bson:"d"`
+	Value bson.RawValue `bson:"v"`
+	ID    bson.RawValue `bson:"id"`
+}
+
+// findPage returns one page of the documents matching the filter, ordered by the sort field and then
+// by _id, and the cursor of the next page or "" on the last one
+func findPage[T any](c *collection, filter bson.M, list models.ListOptions) ([]T, string, error) {
+	limit := list.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	direction := 1
+	if list.Desc {
+		direction = -1
+	}
+
+	var after *pageCursor
+	if list.Cursor != "" {
+		cursor, err := decodePageCursor(list.Cursor)
+		if err != nil || cursor.Sort != list.Sort || cursor.Desc != list.Desc {
+			return nil, "", repositories.ErrInvalidCursor
+		}
+		after = &cursor
+	}
+
+	found, err := c.find(filter, bson.D{{Key: list.Sort, Value: direction}, {Key: "_id", Value: direction}}, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	if after != nil {
+		rest := found[:0:0]
+		for _, doc := range found {
+			order := compareValues(first(doc, list.Sort), after.Value)
+			if order == 0 {
+				order = compareValues(doc.Lookup("_id"), after.ID)
+			}
+			if order*direction > 0 {
+				rest = append(rest, doc)
+			}
+		}
+		found = rest
+	}
+
+	next := ""
+	if int64(len(found)) > limit {
+		found = found[:limit]
+		last := found[len(found)-1]
+		if last.Lookup(list.Sort).Type == bson.TypeArray {
+			return nil, "", repositories.ErrUnpageableSort
+		}
+		raw, err := bson.Marshal(pageCursor{Sort: list.Sort, Desc: list.Desc, Value: first(last, list.Sort), ID: last.Lookup("_id")})
+		if err != nil {
+			return nil, "", err
+		}
+		next = base64.RawURLEncoding.EncodeToString(raw)
+	}
+
+	items, err := decodeAll[T](found)
+	return items, next, err
+}
+
+func decodePageCursor(encoded string) (pageCursor, error) {
+	var cursor pageCursor
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, err
+	}
+	err = bson.Unmarshal(raw, &cursor)
+	return cursor, err
+}
+
+// searchText returns the documents matching both the filter and the text query, best match first, and
+// their scores. Words match whole words of the weighted fields, ignoring case and a plural s. Words
+// prefixed with - exclude documents. Scores rank like MongoDB's text scores but do not equal them.
+func searchText[T any](c *collection, filter bson.M, query string, limit int64, weights bson.D) ([]T, []float64, error) {
+	var include, exclude []string
+	for _, word := range strings.Fields(query) {
+		if strings.HasPrefix(word, "-") {
+			exclude = append(exclude, searchTerms(word)...)
+		} else {
+			include = append(include, searchTerms(word)...)
+		}
+	}
+
+	found, err := c.find(filter, nil, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type hit struct {
+		doc   bson.Raw
+		score float64
+	}
+	var hits []hit
+	for _, doc := range found {
+		score, excluded := 0.0, false
+		for _, field := range weights {
+			weight, _ := field.Value.(int)
+			for _, value := range lookup(doc, field.Key) {
+				for _, text := range texts(value) {
+					terms := searchTerms(text)
+					for _, term := range terms {
+						for _, word := range exclude {
+							excluded = excluded || term == word
+						}
+						for _, word := range include {
+							if term == word {
+								score += float64(weight) * (0.5 + 0.5/float64(len(terms)))
+							}
+						}
+					}
+				}
+			}
+		}
+		if score > 0 && !excluded {
+			hits = append(hits, hit{doc: doc, score: score})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	if limit > 0 && int64(len(hits)) > limit {
+		hits = hits[:limit]
+	}
+
+	docs := make([]bson.Raw, 0, len(hits))
+	var scores []float64
+	for _, hit := range hits {
+		docs = append(docs, hit.doc)
+		scores = append(scores, hit.score)
+	}
+	items, err := decodeAll[T](docs)
+	return items, scores, err
+}
+
+// texts returns the strings of a value, those of an array included
+func texts(value bson.RawValue) []string {
+	if text, ok := value.StringValueOK(); ok {
+		return []string{text}
+	}
+	var found []string
+	if value.Type == bson.TypeArray {
+		elements, _ := value.Array().Values()
+		for _, element := range elements {
+			found = append(found, texts(element)...)
+		}
+	}
+	return found
+}
+
+// searchTerms splits text into lower case words without a plural s
+func searchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			words[i] = strings.TrimSuffix(word, "s")
+		}
+	}
+	return words
+}
+
+// idHex returns the _id of the document as its hex ID
+func idHex(doc bson.Raw) string {
+	id := doc.Lookup("_id")
+	if objectID, ok := id.ObjectIDOK(); ok {
+		return objectID.Hex()
+	}
+	return id.StringValue()
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type IdempotencyRepository struct {
+	collection *collection
+}
+
+// Constructor for IdempotencyRepository
+func NewIdempotencyRepository(store *Store) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		collection: store.collection("idempotency_keys"),
+	}
+}
+
+func (r *IdempotencyRepository) ClaimRecord(record models.IdempotencyRecord) (bool, error) {
+	if _, err := r.collection.delete(bson.M{"_id": record.ID, "expires_at": bson.M{"$lte": time.Now()}}, false); err != nil {
+		return false, err
+	}
+
+	err := r.collection.insert(record)
+	if err == repositories.ErrDuplicateKey {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *IdempotencyRepository) GetRecord(id string) (models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	err := r.collection.findOne(bson.M{"_id": id, "expires_at": bson.M{"$gt": time.Now()}}, &record)
+	return record, err
+}
+
+func (r *IdempotencyRepository) CompleteRecord(id string, status int, contentType string, body []byte) error {
+	_, _, err := r.collection.update(bson.M{"_id": id, "state": models.IdempotencyPending}, bson.M{"$set": bson.M{
+		"state":        models.IdempotencyCompleted,
+		"status":       status,
+		"content_type": contentType,
+		"body":         body,
+	}}, false)
+	return err
+}
+
+func (r *IdempotencyRepository) ReleaseRecord(id string) error {
+	_, err := r.collection.delete(bson.M{"_id": id, "state": models.IdempotencyPending}, false)
+	return err
+}
+
+func (r *IdempotencyRepository) DeleteExpiredRecords() (int64, error) {
+	return r.collection.delete(bson.M{"expires_at": bson.M{"$lte": time.Now()}}, true)
+}
@@ -0,0 +1,477 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// matches reports whether the document matches the query filter. It covers equality, $and, $or, $nor,
+// $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists, $type and $elemMatch on dotted paths, descending
+// into arrays the way MongoDB does.
+func matches(doc bson.Raw, filter bson.M) (bool, error) {
+	for key, condition := range filter {
+		var ok bool
+		var err error
+		switch key {
+		case "$and", "$or", "$nor":
+			ok, err = matchLogical(doc, key, condition)
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("%w: %s", errUnsupported, key)
+			}
+			ok, err = matchField(doc, key, condition)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchLogical(doc bson.Raw, operator string, condition interface{}) (bool, error) {
+	clauses, err := filterList(condition)
+	if err != nil {
+		return false, err
+	}
+	for _, clause := range clauses {
+		ok, err := matches(doc, clause)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case operator == "$and" && !ok:
+			return false, nil
+		case operator == "$or" && ok:
+			return true, nil
+		case operator == "$nor" && ok:
+			return false, nil
+		}
+	}
+	return operator != "$or", nil
+}
+
+// matchField matches the values the path reaches in the document against a value or an operator document
+func matchField(doc bson.Raw, path string, condition interface{}) (bool, error) {
+	values := lookup(doc, path)
+	operators, ok := operatorDocument(condition)
+	if !ok {
+		return matchEqual(values, condition)
+	}
+
+	for operator, operand := range operators {
+		var ok bool
+		var err error
+		switch operator {
+		case "$eq":
+			ok, err = matchEqual(values, operand)
+		case "$ne":
+			ok, err = matchEqual(values, operand)
+			ok = !ok
+		case "$gt", "$gte", "$lt", "$lte":
+			ok, err = matchCompare(values, operator, operand)
+		case "$in":
+			ok, err = matchIn(values, operand)
+		case "$nin":
+			ok, err = matchIn(values, operand)
+			ok = !ok
+		case "$exists":
+			ok = len(values) > 0 == truthy(operand)
+		case "$type":
+			ok = matchType(values, operand)
+		case "$elemMatch":
+			ok, err = matchElement(values, operand)
+		default:
+			err = fmt.Errorf("%w: %s", errUnsupported, operator)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// candidates are the values a condition is compared with: those the path reaches and the elements of
+// those that are arrays. A missing field compares as null.
+func candidates(values []bson.RawValue) []bson.RawValue {
+	if len(values) == 0 {
+		return []bson.RawValue{{Type: bson.TypeNull}}
+	}
+	var all []bson.RawValue
+	for _, value := range values {
+		all = append(all, value)
+		if value.Type == bson.TypeArray {
+			elements, _ := value.Array().Values()
+			all = append(all, elements...)
+		}
+	}
+	return all
+}
+
+func matchEqual(values []bson.RawValue, operand interface{}) (bool, error) {
+	expected, err := rawValue(operand)
+	if err != nil {
+		return false, err
+	}
+	for _, value := range candidates(values) {
+		if sameBracket(value, expected) && compareValues(value, expected) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchCompare only compares values of the same type bracket, {$gt: ""} only matches strings
+func matchCompare(values []bson.RawValue, operator string, operand interface{}) (bool, error) {
+	bound, err := rawValue(operand)
+	if err != nil {
+		return false, err
+	}
+	for _, value := range candidates(values) {
+		if !sameBracket(value, bound) {
+			continue
+		}
+		order := compareValues(value, bound)
+		switch {
+		case operator == "$gt" && order > 0,
+			operator == "$gte" && order >= 0,
+			operator == "$lt" && order < 0,
+			operator == "$lte" && order <= 0:
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchIn(values []bson.RawValue, operand interface{}) (bool, error) {
+	list := reflect.ValueOf(operand)
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return false, fmt.Errorf("memory: $in needs an array, got %T", operand)
+	}
+	for i := 0; i < list.Len(); i++ {
+		ok, err := matchEqual(values, list.Index(i).Interface())
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+var typeAliases = map[string]bsontype.Type{
+	"double": bson.TypeDouble, "string": bson.TypeString, "object": bson.TypeEmbeddedDocument,
+	"array": bson.TypeArray, "binData": bson.TypeBinary, "objectId": bson.TypeObjectID,
+	"bool": bson.TypeBoolean, "date": bson.TypeDateTime, "null": bson.TypeNull,
+	"int": bson.TypeInt32, "long": bson.TypeInt64,
+}
+
+func matchType(values []bson.RawValue, operand interface{}) bool {
+	alias, _ := operand.(string)
+	for _, value := range values {
+		if value.Type == typeAliases[alias] {
+			return true
+		}
+		if value.Type == bson.TypeArray {
+			elements, _ := value.Array().Values()
+			for _, element := range elements {
+				if element.Type == typeAliases[alias] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchElement matches arrays with an element that matches the filter, or the operators for arrays of scalars
+func matchElement(values []bson.RawValue, operand interface{}) (bool, error) {
+	filter, err := toFilter(operand)
+	if err != nil {
+		return false, err
+	}
+	_, scalar := operatorDocument(filter)
+
+	for _, value := range values {
+		if value.Type != bson.TypeArray {
+			continue
+		}
+		elements, _ := value.Array().Values()
+		for _, element := range elements {
+			var ok bool
+			if scalar {
+				wrapped, _ := bson.Marshal(bson.D{{Key: "v", Value: element}})
+				ok, err = matchField(wrapped, "v", filter)
+			} else if element.Type == bson.TypeEmbeddedDocument {
+				ok, err = matches(element.Document(), filter)
+			}
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// lookup returns every value the dotted path reaches, following numeric indexes into arrays and
+// otherwise into each document of an array
+func lookup(doc bson.Raw, path string) []bson.RawValue {
+	return lookupParts(bson.RawValue{Type: bson.TypeEmbeddedDocument, Value: doc}, strings.Split(path, "."))
+}
+
+func lookupParts(value bson.RawValue, parts []string) []bson.RawValue {
+	if len(parts) == 0 {
+		return []bson.RawValue{value}
+	}
+	switch value.Type {
+	case bson.TypeEmbeddedDocument:
+		child, err := value.Document().LookupErr(parts[0])
+		if err != nil {
+			return nil
+		}
+		return lookupParts(child, parts[1:])
+	case bson.TypeArray:
+		elements, _ := value.Array().Values()
+		if index, err := strconv.Atoi(parts[0]); err == nil {
+			if index < len(elements) {
+				return lookupParts(elements[index], parts[1:])
+			}
+			return nil
+		}
+		var found []bson.RawValue
+		for _, element := range elements {
+			if element.Type == bson.TypeEmbeddedDocument {
+				found = append(found, lookupParts(element, parts)...)
+			}
+		}
+		return found
+	}
+	return nil
+}
+
+// first returns the first value the path reaches, or null
+func first(doc bson.Raw, path string) bson.RawValue {
+	if values := lookup(doc, path); len(values) > 0 {
+		return values[0]
+	}
+	return bson.RawValue{Type: bson.TypeNull}
+}
+
+// Type brackets in the order MongoDB sorts them
+func typeOrder(t bsontype.Type) int {
+	switch t {
+	case bson.TypeNull, bson.TypeUndefined:
+		return 1
+	case bson.TypeDouble, bson.TypeInt32, bson.TypeInt64, bson.TypeDecimal128:
+		return 2
+	case bson.TypeString, bson.TypeSymbol:
+		return 3
+	case bson.TypeEmbeddedDocument:
+		return 4
+	case bson.TypeArray:
+		return 5
+	case bson.TypeBinary:
+		return 6
+	case bson.TypeObjectID:
+		return 7
+	case bson.TypeBoolean:
+		return 8
+	case bson.TypeDateTime:
+		return 9
+	case bson.TypeTimestamp:
+		return 10
+	}
+	return 11
+}
+
+func sameBracket(a bson.RawValue, b bson.RawValue) bool {
+	return typeOrder(a.Type) == typeOrder(b.Type)
+}
+
+// compareValues orders two values the way MongoDB sorts them
+func compareValues(a bson.RawValue, b bson.RawValue) int {
+	if order := typeOrder(a.Type) - typeOrder(b.Type); order != 0 {
+		return sign(order)
+	}
+	switch typeOrder(a.Type) {
+	case 1:
+		return 0
+	case 2:
+		x, y := number(a), number(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case 3:
+		return strings.Compare(a.StringValue(), b.StringValue())
+	case 8:
+		x, y := a.Boolean(), b.Boolean()
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case 9:
+		return sign64(a.DateTime() - b.DateTime())
+	}
+	return bytes.Compare(a.Value, b.Value)
+}
+
+func number(value bson.RawValue) float64 {
+	switch value.Type {
+	case bson.TypeInt32:
+		return float64(value.Int32())
+	case bson.TypeInt64:
+		return float64(value.Int64())
+	case bson.TypeDouble:
+		return value.Double()
+	}
+	return math.NaN()
+}
+
+func sign(n int) int {
+	return sign64(int64(n))
+}
+
+func sign64(n int64) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// rawValue marshals a filter operand so it can be compared with stored values
+func rawValue(value interface{}) (bson.RawValue, error) {
+	if raw, ok := value.(bson.RawValue); ok {
+		return raw, nil
+	}
+	if value == nil {
+		return bson.RawValue{Type: bson.TypeNull}, nil
+	}
+	t, data, err := bson.MarshalValue(value)
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	return bson.RawValue{Type: t, Value: data}, nil
+}
+
+// equalValues compares two decoded documents or values
+func equalValues(a interface{}, b interface{}) bool {
+	x, errX := rawValue(a)
+	y, errY := rawValue(b)
+	if errX != nil || errY != nil {
+		return false
+	}
+	if x.Type != y.Type {
+		return false
+	}
+	if x.Type == bson.TypeEmbeddedDocument {
+		return equalDocuments(x.Document(), y.Document())
+	}
+	if x.Type == bson.TypeArray {
+		xs, _ := x.Array().Values()
+		ys, _ := y.Array().Values()
+		if len(xs) != len(ys) {
+			return false
+		}
+		for i := range xs {
+			if !equalValues(xs[i], ys[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return bytes.Equal(x.Value, y.Value)
+}
+
+// equalDocuments compares documents field by field whatever the order of their fields
+func equalDocuments(a bson.Raw, b bson.Raw) bool {
+	xs, _ := a.Elements()
+	ys, _ := b.Elements()
+	if len(xs) != len(ys) {
+		return false
+	}
+	for _, x := range xs {
+		y, err := b.LookupErr(x.Key())
+		if err != nil || !equalValues(x.Value(), y) {
+			return false
+		}
+	}
+	return true
+}
+
+// operatorDocument returns the condition as operators if it is a document of them
+func operatorDocument(condition interface{}) (bson.M, bool) {
+	filter, err := toFilter(condition)
+	if err != nil || len(filter) == 0 {
+		return nil, false
+	}
+	for key := range filter {
+		if !strings.HasPrefix(key, "$") {
+			return nil, false
+		}
+	}
+	return filter, true
+}
+
+func toFilter(value interface{}) (bson.M, error) {
+	switch filter := value.(type) {
+	case bson.M:
+		return filter, nil
+	case map[string]interface{}:
+		return filter, nil
+	case bson.D:
+		m := bson.M{}
+		for _, element := range filter {
+			m[element.Key] = element.Value
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("memory: expected a document, got %T", value)
+}
+
+func filterList(value interface{}) ([]bson.M, error) {
+	switch list := value.(type) {
+	case []bson.M:
+		return list, nil
+	case bson.A:
+		filters := make([]bson.M, 0, len(list))
+		for _, item := range list {
+			filter, err := toFilter(item)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+		return filters, nil
+	case []interface{}:
+		return filterList(bson.A(list))
+	}
+	return nil, fmt.Errorf("memory: expected a list of filters, got %T", value)
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case int32:
+		return v != 0
+	case int64:
+		return v != 0
+	}
+	return value != nil
+}
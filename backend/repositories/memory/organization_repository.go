@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OrganizationRepository struct {
+	collection *collection
+}
+
+// Constructor for OrganizationRepository
+func NewOrganizationRepository(store *Store) *OrganizationRepository {
+	return &OrganizationRepository{
+		collection: store.collection("organizations"),
+	}
+}
+
+func (r *OrganizationRepository) CreateOrganization(organization models.Organization) error {
+	return r.collection.insert(organization)
+}
+
+func (r *OrganizationRepository) GetOrganizationByID(id string) (models.Organization, error) {
+	var organization models.Organization
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return organization, repositories.ErrInvalidID
+	}
+	err = r.collection.findOne(bson.M{"_id": objectID}, &organization)
+	return organization, err
+}
+
+func (r *OrganizationRepository) GetOrganizationsByMember(email string) ([]models.Organization, error) {
+	found, err := r.collection.find(bson.M{"members.email": email}, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEach[models.Organization](found)
+}
+
+func (r *OrganizationRepository) GetOrganizationIDsByMember(email string, roles ...string) ([]string, error) {
+	member := bson.M{"email": email}
+	if len(roles) > 0 {
+		member["role"] = bson.M{"$in": roles}
+	}
+
+	found, err := r.collection.find(bson.M{"members": bson.M{"$elemMatch": member}}, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, doc := range found {
+		ids = append(ids, idHex(doc))
+	}
+	return ids, nil
+}
+
+func (r *OrganizationRepository) AddMember(id string, member models.OrganizationMember) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, repositories.ErrInvalidID
+	}
+
+	matched, _, err := r.collection.update(
+		bson.M{"_id": objectID, "members.email": bson.M{"$ne": member.Email}},
+		bson.M{
+			"$push": bson.M{"members": member},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+		false,
+	)
+	return matched == 1, err
+}
+
+func (r *OrganizationRepository) UpdateMemberRole(id string, email string, role string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrInvalidID
+	}
+
+	matched, _, err := r.collection.update(
+		bson.M{"_id": objectID, "members.email": email},
+		bson.M{"$set": bson.M{"members.$.role": role, "updated_at": time.Now()}},
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *OrganizationRepository) RemoveMember(id string, email string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrInvalidID
+	}
+
+	matched, _, err := r.collection.update(
+		bson.M{"_id": objectID, "members.email": email},
+		bson.M{
+			"$pull": bson.M{"members": bson.M{"email": email}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *OrganizationRepository) RemoveMemberFromAll(ctx context.Context, email string) error {
+	_, _, err := r.collection.update(
+		bson.M{"members.email": email},
+		bson.M{
+			"$pull": bson.M{"members": bson.M{"email": email}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+		true,
+	)
+	return err
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PersonalRecordRepository struct {
+	collection *collection
+}
+
+// Constructor for PersonalRecordRepository
+func NewPersonalRecordRepository(store *Store) *PersonalRecordRepository {
+	return &PersonalRecordRepository{
+		collection: store.collection("personal_records", recordKeyIndex),
+	}
+}
+
+// Records are saved by key, unique per trainee
+var recordKeyIndex = uniqueIndex{fields: []string{"trainee_id", "key"}, partial: bson.M{"key": bson.M{"$type": "string"}}}
+
+func (r *PersonalRecordRepository) SavePersonalRecords(ctx context.Context, records []models.PersonalRecord) error {
+	for _, record := range records {
+		if err := r.save(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save updates the record stored under the key, keeping its ID and creation time, or inserts it. An
+// insert that loses to one made at the same time updates that one instead.
+func (r *PersonalRecordRepository) save(record models.PersonalRecord) error {
+	data, err := bson.Marshal(record)
+	if err != nil {
+		return err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	delete(fields, "_id")
+	delete(fields, "created_at")
+
+	filter := bson.M{"trainee_id": record.TraineeID, "key": record.Key}
+	for attempt := 0; ; attempt++ {
+		matched, _, err := r.collection.update(filter, bson.M{"$set": fields}, false)
+		if err != nil || matched > 0 {
+			return err
+		}
+		if err := r.collection.insert(record); err != repositories.ErrDuplicateKey || attempt > 0 {
+			return err
+		}
+	}
+}
+
+func (r *PersonalRecordRepository) GetPersonalRecords(traineeID string, current bool) ([]models.PersonalRecord, error) {
+	filter := bson.M{"trainee_id": traineeID}
+	if current {
+		filter["current"] = true
+	}
+	found, err := r.collection.find(filter, bson.D{{Key: "date", Value: -1}, {Key: "exercise", Value: 1}, {Key: "kind", Value: 1}}, 0)
+	if err != nil {
+		return nil, err
+	}
+	records, err := decodeEach[models.PersonalRecord](found)
+	if records == nil {
+		records = []models.PersonalRecord{}
+	}
+	return records, err
+}
+
+func (r *PersonalRecordRepository) GetLogIDsWithRecords(logIDs []string) ([]string, error) {
+	if len(logIDs) == 0 {
+		return nil, nil
+	}
+	found, err := r.collection.find(bson.M{"log_id": bson.M{"$in": logIDs}}, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var ids []string
+	for _, doc := range found {
+		id, _ := doc.Lookup("log_id").StringValueOK()
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *PersonalRecordRepository) DeletePersonalRecords(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"_id": bson.M{"$in": ids}}, true)
+}
+
+func (r *PersonalRecordRepository) DeletePersonalRecordsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"trainee_id": bson.M{"$in": traineeIDs}}, true)
+}
+
+func (r *PersonalRecordRepository) DeletePersonalRecordsByExercises(ctx context.Context, exerciseIDs []primitive.ObjectID) (int64, error) {
+	if len(exerciseIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"exercise_id": bson.M{"$in": exerciseIDs}}, true)
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProgramRepository struct {
+	collection  *collection
+	assignments *collection
+}
+
+// Constructor for ProgramRepository
+func NewProgramRepository(store *Store) *ProgramRepository {
+	return &ProgramRepository{
+		collection:  store.collection("programs"),
+		assignments: store.collection("program_assignments"),
+	}
+}
+
+func (r *ProgramRepository) CreateProgram(program models.Program) error {
+	return r.collection.insert(program)
+}
+
+func (r *ProgramRepository) GetProgramByID(id string) (models.Program, error) {
+	var program models.Program
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return program, repositories.ErrInvalidID
+	}
+	err = r.collection.findOne(bson.M{"_id": objectID}, &program)
+	return program, err
+}
+
+func (r *ProgramRepository) GetProgramsByTrainer(trainerID string) ([]models.Program, error) {
+	found, err := r.collection.find(bson.M{"trainer_id": trainerID}, bson.D{{Key: "name", Value: 1}}, 0)
+	if err != nil {
+		return nil, err
+	}
+	programs, err := decodeEach[models.Program](found)
+	if programs == nil {
+		programs = []models.Program{}
+	}
+	return programs, err
+}
+
+func (r *ProgramRepository) UpdateProgram(program models.Program) error {
+	matched, _, err := r.collection.update(bson.M{"_id": program.ID}, bson.M{"$set": bson.M{
+		"name":        program.Name,
+		"description": program.Description,
+		"weeks":       program.Weeks,
+		"workouts":    program.Workouts,
+		"updated_at":  program.UpdatedAt,
+	}}, false)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ProgramRepository) DeletePrograms(ctx context.Context, ids []string) (int64, error) {
+	objectIDs, err := toObjectIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(objectIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"_id": bson.M{"$in": objectIDs}}, true)
+}
+
+func (r *ProgramRepository) GetProgramIDsByTrainer(ctx context.Context, trainerID string) ([]string, error) {
+	found, err := r.collection.find(bson.M{"trainer_id": trainerID}, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, doc := range found {
+		ids = append(ids, idHex(doc))
+	}
+	return ids, nil
+}
+
+func (r *ProgramRepository) UpsertAssignment(assignment models.ProgramAssignment) error {
+	return r.assignments.replaceOne(bson.M{"_id": assignment.TraineeID}, assignment, true)
+}
+
+func (r *ProgramRepository) GetAssignment(traineeID string) (models.ProgramAssignment, error) {
+	var assignment models.ProgramAssignment
+	err := r.assignments.findOne(bson.M{"_id": traineeID}, &assignment)
+	return assignment, err
+}
+
+func (r *ProgramRepository) CountAssignments(programID string) (int64, error) {
+	return r.assignments.count(bson.M{"program_id": programID})
+}
+
+func (r *ProgramRepository) DeleteAssignment(traineeID string) error {
+	deleted, err := r.assignments.delete(bson.M{"_id": traineeID}, false)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ProgramRepository) DeleteAssignmentsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+	return r.assignments.delete(bson.M{"_id": bson.M{"$in": traineeIDs}}, true)
+}
+
+func (r *ProgramRepository) DeleteAssignmentsByPrograms(ctx context.Context, programIDs []string) (int64, error) {
+	if len(programIDs) == 0 {
+		return 0, nil
+	}
+	return r.assignments.delete(bson.M{"program_id": bson.M{"$in": programIDs}}, true)
+}
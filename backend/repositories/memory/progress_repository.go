@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type ProgressRepository struct {
+	collection *collection
+}
+
+// Constructor for ProgressRepository
+func NewProgressRepository(store *Store) *ProgressRepository {
+	return &ProgressRepository{
+		collection: store.collection("progress", clientIDIndex),
+	}
+}
+
+func (r *ProgressRepository) GetWeightBMIProgress(traineeID string, dates models.ListOptions) ([]models.WeightBMIProgress, error) {
+	filter := dateRangeFilter(notDeleted(bson.M{"trainee_id": traineeID}), "date", dates)
+	found, err := r.collection.find(filter, bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEach[models.WeightBMIProgress](found)
+}
+
+func (r *ProgressRepository) AddWeightProgress(progress models.WeightBMIProgress) error {
+	var done func()
+	progress.SyncSeq, done = r.collection.store.takeSyncSeq()
+	defer done()
+	return r.collection.insert(progress)
+}
+
+func (r *ProgressRepository) ApplyWeightProgressChange(id string, version int64, set map[string]interface{}) error {
+	return updateVersioned(r.collection, id, version, bson.M{"$set": set})
+}
+
+func (r *ProgressRepository) GetWeightProgressByClientID(clientID string) (models.WeightBMIProgress, error) {
+	var progress models.WeightBMIProgress
+	err := findByClientID(r.collection, clientID, &progress)
+	return progress, err
+}
+
+func (r *ProgressRepository) GetWeightProgressChanges(traineeIDs []string, after repositories.SyncPosition, until int64, limit int64) ([]models.WeightBMIProgress, error) {
+	if len(traineeIDs) == 0 {
+		return []models.WeightBMIProgress{}, nil
+	}
+	return findChanges[models.WeightBMIProgress](r.collection, traineeIDs, after, until, limit)
+}
+
+func (r *ProgressRepository) PurgeWeightProgressDeletedBefore(cutoff time.Time) (int64, error) {
+	return purgeDeletedBefore(r.collection, cutoff)
+}
+
+func (r *ProgressRepository) DeleteProgressByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"trainee_id": bson.M{"$in": traineeIDs}}, true)
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type SessionRepository struct {
+	collection *collection
+}
+
+// Constructor for SessionRepository
+func NewSessionRepository(store *Store) *SessionRepository {
+	return &SessionRepository{
+		collection: store.collection("sessions"),
+	}
+}
+
+func (r *SessionRepository) CreateSession(session models.Session) error {
+	return r.collection.insert(session)
+}
+
+func (r *SessionRepository) GetSessionByID(id string) (models.Session, error) {
+	var session models.Session
+	err := r.collection.findOne(bson.M{"_id": id}, &session)
+	return session, err
+}
+
+func (r *SessionRepository) IsSessionActive(id string) (bool, error) {
+	count, err := r.collection.count(bson.M{
+		"_id":        id,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	return count > 0, err
+}
+
+func (r *SessionRepository) RotateRefreshToken(id string, currentHash string, newHash string, expiresAt time.Time) (bool, error) {
+	_, modified, err := r.collection.update(
+		bson.M{
+			"_id":                id,
+			"refresh_token_hash": currentHash,
+			"revoked_at":         bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{
+			"refresh_token_hash": newHash,
+			"expires_at":         expiresAt,
+			"updated_at":         time.Now(),
+		}},
+		false,
+	)
+	return modified == 1, err
+}
+
+func (r *SessionRepository) RevokeSession(id string) error {
+	return r.revoke(bson.M{"_id": id}, false)
+}
+
+func (r *SessionRepository) RevokeSessionsByEmail(email string) error {
+	return r.revoke(bson.M{"email": email, "role": bson.M{"$ne": "trainee"}}, true)
+}
+
+func (r *SessionRepository) RevokeSessionsByTrainee(traineeID string) error {
+	return r.revoke(bson.M{"trainee_id": traineeID}, true)
+}
+
+func (r *SessionRepository) revoke(filter bson.M, many bool) error {
+	now := time.Now()
+	filter["revoked_at"] = bson.M{"$exists": false}
+	_, _, err := r.collection.update(filter, bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}}, many)
+	return err
+}
+
+func (r *SessionRepository) DeleteSessionsByEmail(ctx context.Context, email string) (int64, error) {
+	return r.collection.delete(bson.M{"$or": []bson.M{
+		{"email": email},
+		{"trainer_id": email},
+	}}, true)
+}
+
+func (r *SessionRepository) DeleteSessionsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"trainee_id": bson.M{"$in": traineeIDs}}, true)
+}
@@ -0,0 +1,515 @@
+// Package memory implements the repository interfaces in memory, so services and controllers can be
+// tested without a running MongoDB. Documents are stored as BSON and queried with the same filters and
+// updates the Mongo repositories send, evaluated by a small matcher that covers the operators they use.
+//
+// Unique indexes are enforced and reported as repositories.ErrDuplicateKey, missing documents as
+// repositories.ErrNotFound, like the Mongo repositories do. Transactions roll back on error but are not
+// isolated from writes made outside of them while they run.
+package memory
+
+import (
+	"context"
+	"errors"
+	"ironcoach/repositories"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store holds the collections the repositories of one test share
+type Store struct {
+	mu          sync.Mutex
+	collections map[string]*collection
+	syncSeq     int64
+	inFlight    map[int64]bool // Sequence numbers taken by writes that are not over
+}
+
+// Constructor for Store
+func NewStore() *Store {
+	return &Store{collections: map[string]*collection{}, inFlight: map[int64]bool{}}
+}
+
+// NewRepositories returns every repository over a new, empty store
+func NewRepositories() repositories.Repositories {
+	return NewStore().Repositories()
+}
+
+// Repositories returns every repository over the store
+func (s *Store) Repositories() repositories.Repositories {
+	return repositories.Repositories{
+		Trainers:        NewTrainerRepository(s),
+		Trainees:        NewTraineeRepository(s),
+		TraineeAccounts: NewTraineeAccountRepository(s),
+		Organizations:   NewOrganizationRepository(s),
+		Sessions:        NewSessionRepository(s),
+		AccountTokens:   NewAccountTokenRepository(s),
+		Categories:      NewCategoryRepository(s),
+		Exercises:       NewExerciseRepository(s),
+		WorkoutLogs:     NewWorkoutLogRepository(s),
+		DietEntries:     NewDietEntryRepository(s),
+		PersonalRecords: NewPersonalRecordRepository(s),
+		Programs:        NewProgramRepository(s),
+		Progress:        NewProgressRepository(s),
+		Audit:           NewAuditRepository(s),
+		Idempotency:     NewIdempotencyRepository(s),
+		SyncFeed:        NewSyncFeedRepository(s),
+		Images:          NewImageRepository(),
+		Transactions:    NewTransactor(s),
+	}
+}
+
+// collection returns the named collection, creating it with the unique indexes on first use
+func (s *Store) collection(name string, indexes ...uniqueIndex) *collection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collections[name]
+	if !ok {
+		c = &collection{store: s, name: name, docs: map[string]bson.Raw{}}
+		s.collections[name] = c
+	}
+	if len(indexes) > 0 {
+		c.indexes = indexes
+	}
+	return c
+}
+
+// takeSyncSeq takes the next number of the change sequence and holds it in flight until done is called,
+// once the write stamped with it is over
+func (s *Store) takeSyncSeq() (seq int64, done func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.syncSeq++
+	seq = s.syncSeq
+	s.inFlight[seq] = true
+	return seq, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.inFlight, seq)
+	}
+}
+
+// SyncFeedRepository reads how far the change sequence of the store is over
+type SyncFeedRepository struct {
+	store *Store
+}
+
+// Constructor for SyncFeedRepository
+func NewSyncFeedRepository(store *Store) *SyncFeedRepository {
+	return &SyncFeedRepository{store: store}
+}
+
+func (r *SyncFeedRepository) ReadableSeq() (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	readable := r.store.syncSeq
+	for seq := range r.store.inFlight {
+		readable = min(readable, seq-1)
+	}
+	return readable, nil
+}
+
+// Transactor rolls back the writes of a failed transaction
+type Transactor struct {
+	store *Store
+}
+
+// Constructor for Transactor
+func NewTransactor(store *Store) *Transactor {
+	return &Transactor{store: store}
+}
+
+// WithTransaction runs fn and, if it fails, puts every collection back the way it was before
+func (t *Transactor) WithTransaction(fn func(ctx context.Context) error) error {
+	t.store.mu.Lock()
+	saved := map[string]collectionState{}
+	for name, c := range t.store.collections {
+		saved[name] = c.state()
+	}
+	t.store.mu.Unlock()
+
+	err := fn(context.Background())
+	if err == nil {
+		return nil
+	}
+
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	for name, c := range t.store.collections {
+		state, ok := saved[name]
+		if !ok {
+			state = collectionState{docs: map[string]bson.Raw{}}
+		}
+		c.docs, c.order = state.docs, state.order
+	}
+	return err
+}
+
+// uniqueIndex rejects a second document with the same values of the fields. With a partial filter
+// it only covers the documents matching it.
+type uniqueIndex struct {
+	fields  []string
+	partial bson.M
+}
+
+type collection struct {
+	store   *Store
+	name    string
+	docs    map[string]bson.Raw // By key of their _id, documents are never changed in place
+	order   []string            // Keys in insertion order, the natural order of find
+	indexes []uniqueIndex
+}
+
+type collectionState struct {
+	docs  map[string]bson.Raw
+	order []string
+}
+
+func (c *collection) state() collectionState {
+	docs := make(map[string]bson.Raw, len(c.docs))
+	for key, doc := range c.docs {
+		docs[key] = doc
+	}
+	return collectionState{docs: docs, order: append([]string(nil), c.order...)}
+}
+
+// insert stores a new document, giving it an ObjectID if it has no _id
+func (c *collection) insert(document interface{}) error {
+	_, err := c.insertOne(document)
+	return err
+}
+
+// insertOne is insert returning the document as stored, to read the _id it was given
+func (c *collection) insertOne(document interface{}) (bson.Raw, error) {
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := bson.Raw(raw).LookupErr("_id"); err != nil {
+		if raw, err = withID(raw, primitive.NewObjectID()); err != nil {
+			return nil, err
+		}
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	key := idKey(raw)
+	if _, ok := c.docs[key]; ok {
+		return nil, repositories.ErrDuplicateKey
+	}
+	if err := c.checkUnique(key, raw); err != nil {
+		return nil, err
+	}
+	c.docs[key] = raw
+	c.order = append(c.order, key)
+	return raw, nil
+}
+
+// find returns the documents matching the filter, ordered by the sort fields or else in insertion
+// order. A limit of 0 returns them all.
+func (c *collection) find(filter bson.M, sortBy bson.D, limit int64) ([]bson.Raw, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	var found []bson.Raw
+	for _, key := range c.order {
+		doc := c.docs[key]
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found = append(found, doc)
+		}
+	}
+
+	if len(sortBy) > 0 {
+		sort.SliceStable(found, func(i, j int) bool {
+			for _, field := range sortBy {
+				order := compareValues(first(found[i], field.Key), first(found[j], field.Key))
+				if direction, _ := field.Value.(int); direction < 0 {
+					order = -order
+				}
+				if order != 0 {
+					return order < 0
+				}
+			}
+			return false
+		})
+	}
+	if limit > 0 && int64(len(found)) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+// findOne decodes the first document matching the filter, or returns repositories.ErrNotFound
+func (c *collection) findOne(filter bson.M, result interface{}) error {
+	found, err := c.find(filter, nil, 1)
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		return repositories.ErrNotFound
+	}
+	return bson.Unmarshal(found[0], result)
+}
+
+func (c *collection) count(filter bson.M) (int64, error) {
+	found, err := c.find(filter, nil, 0)
+	return int64(len(found)), err
+}
+
+// update applies the update operators to the first or every document matching the filter. It returns
+// how many documents matched and how many of those actually changed.
+func (c *collection) update(filter bson.M, update bson.M, many bool) (int64, int64, error) {
+	return c.updateFunc(filter, many, func(doc bson.M) error {
+		return applyUpdate(doc, update, filter)
+	})
+}
+
+// updateFunc is update with the change made by change rather than by update operators
+func (c *collection) updateFunc(filter bson.M, many bool, change func(doc bson.M) error) (int64, int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	var matched, modified int64
+	for _, key := range c.order {
+		current := c.docs[key]
+		ok, err := matches(current, filter)
+		if err != nil {
+			return matched, modified, err
+		}
+		if !ok {
+			continue
+		}
+		matched++
+
+		changed, err := changeDocument(current, change)
+		if err != nil {
+			return matched, modified, err
+		}
+		if changed != nil {
+			if err := c.checkUnique(key, changed); err != nil {
+				return matched, modified, err
+			}
+			c.docs[key] = changed
+			modified++
+		}
+		if !many {
+			break
+		}
+	}
+	return matched, modified, nil
+}
+
+// findOneAndUpdate updates the first document matching the filter and decodes it as it was before
+// the update, or as it is after it if after is set
+func (c *collection) findOneAndUpdate(filter bson.M, update bson.M, after bool, result interface{}) error {
+	var found bson.Raw
+	matched, _, err := c.updateFunc(filter, false, func(doc bson.M) error {
+		var err error
+		if !after {
+			if found, err = bson.Marshal(doc); err != nil {
+				return err
+			}
+		}
+		if err := applyUpdate(doc, update, filter); err != nil {
+			return err
+		}
+		if after {
+			found, err = bson.Marshal(doc)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return repositories.ErrNotFound
+	}
+	return bson.Unmarshal(found, result)
+}
+
+// replaceOne replaces the first document matching the filter, or inserts it if there is none and upsert is set
+func (c *collection) replaceOne(filter bson.M, document interface{}, upsert bool) error {
+	replacement, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	c.store.mu.Lock()
+	for _, key := range c.order {
+		ok, err := matches(c.docs[key], filter)
+		if err != nil {
+			c.store.mu.Unlock()
+			return err
+		}
+		if !ok {
+			continue
+		}
+		defer c.store.mu.Unlock()
+		if replacement, err = withID(replacement, c.docs[key].Lookup("_id")); err != nil {
+			return err
+		}
+		if err := c.checkUnique(key, replacement); err != nil {
+			return err
+		}
+		c.docs[key] = replacement
+		return nil
+	}
+	c.store.mu.Unlock()
+
+	if !upsert {
+		return nil
+	}
+	return c.insert(document)
+}
+
+// delete removes the first or every document matching the filter and returns how many it removed
+func (c *collection) delete(filter bson.M, many bool) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	var deleted int64
+	kept := c.order[:0:0]
+	for _, key := range c.order {
+		if many || deleted == 0 {
+			ok, err := matches(c.docs[key], filter)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				delete(c.docs, key)
+				deleted++
+				continue
+			}
+		}
+		kept = append(kept, key)
+	}
+	c.order = kept
+	return deleted, nil
+}
+
+// checkUnique rejects a document that would break a unique index, ignoring the document it replaces
+func (c *collection) checkUnique(key string, doc bson.Raw) error {
+	for _, index := range c.indexes {
+		indexKey, ok, err := index.key(doc)
+		if err != nil || !ok {
+			return err
+		}
+		for otherKey, other := range c.docs {
+			if otherKey == key {
+				continue
+			}
+			otherIndexKey, ok, err := index.key(other)
+			if err != nil {
+				return err
+			}
+			if ok && otherIndexKey == indexKey {
+				return repositories.ErrDuplicateKey
+			}
+		}
+	}
+	return nil
+}
+
+// key returns the index entry of the document, false if the index does not cover it.
+// Missing fields are indexed as null.
+func (index uniqueIndex) key(doc bson.Raw) (string, bool, error) {
+	if index.partial != nil {
+		ok, err := matches(doc, index.partial)
+		if err != nil || !ok {
+			return "", false, err
+		}
+	}
+	key := ""
+	for _, field := range index.fields {
+		value := first(doc, field)
+		key += string(rune(value.Type)) + string(value.Value) + "\x00"
+	}
+	return key, true, nil
+}
+
+// changeDocument runs change on a copy of the document and returns the new document, or nil if it did not change
+func changeDocument(current bson.Raw, change func(doc bson.M) error) (bson.Raw, error) {
+	var doc bson.M
+	if err := bson.Unmarshal(current, &doc); err != nil {
+		return nil, err
+	}
+	if err := change(doc); err != nil {
+		return nil, err
+	}
+	changed, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare decoded, the order of the fields is not kept
+	var before, after bson.M
+	if err := bson.Unmarshal(current, &before); err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(changed, &after); err != nil {
+		return nil, err
+	}
+	if equalValues(before, after) {
+		return nil, nil
+	}
+	return changed, nil
+}
+
+// withID returns the document with the given _id first
+func withID(raw bson.Raw, id interface{}) (bson.Raw, error) {
+	document := bson.D{{Key: "_id", Value: id}}
+	elements, err := raw.Elements()
+	if err != nil {
+		return nil, err
+	}
+	for _, element := range elements {
+		if element.Key() != "_id" {
+			document = append(document, bson.E{Key: element.Key(), Value: element.Value()})
+		}
+	}
+	return bson.Marshal(document)
+}
+
+// idKey tells documents apart by their _id, its type included
+func idKey(doc bson.Raw) string {
+	id := doc.Lookup("_id")
+	return string(rune(id.Type)) + string(id.Value)
+}
+
+// decodeAll decodes every document. The result is empty rather than nil when there are none, as
+// with cursor.All.
+func decodeAll[T any](docs []bson.Raw) ([]T, error) {
+	results := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		var result T
+		if err := bson.Unmarshal(doc, &result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// decodeEach decodes every document like a loop over a cursor does, leaving the result nil when there are none
+func decodeEach[T any](docs []bson.Raw) ([]T, error) {
+	var results []T
+	for _, doc := range docs {
+		var result T
+		if err := bson.Unmarshal(doc, &result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+var errUnsupported = errors.New("memory: operator not supported")
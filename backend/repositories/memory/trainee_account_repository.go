@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type TraineeAccountRepository struct {
+	collection *collection
+}
+
+// Constructor for TraineeAccountRepository
+func NewTraineeAccountRepository(store *Store) *TraineeAccountRepository {
+	return &TraineeAccountRepository{
+		collection: store.collection("trainee_accounts", uniqueIndex{
+			fields:  []string{"invite_code"},
+			partial: bson.M{"invite_code": bson.M{"$gt": ""}},
+		}),
+	}
+}
+
+func (r *TraineeAccountRepository) UpsertAccount(account models.TraineeAccount) error {
+	return r.collection.replaceOne(bson.M{"_id": account.ID}, account, true)
+}
+
+func (r *TraineeAccountRepository) GetAccount(traineeID string) (models.TraineeAccount, error) {
+	var account models.TraineeAccount
+	err := r.collection.findOne(bson.M{"_id": traineeID}, &account)
+	return account, err
+}
+
+func (r *TraineeAccountRepository) GetAccountByInviteCode(code string) (models.TraineeAccount, error) {
+	var account models.TraineeAccount
+	err := r.collection.findOne(bson.M{"invite_code": code}, &account)
+	return account, err
+}
+
+func (r *TraineeAccountRepository) RecordFailedAttempt(traineeID string, maxAttempts int) error {
+	var account models.TraineeAccount
+	err := r.collection.findOneAndUpdate(
+		bson.M{"_id": traineeID},
+		bson.M{"$inc": bson.M{"failed_attempts": 1}, "$set": bson.M{"updated_at": time.Now()}},
+		true,
+		&account,
+	)
+	if err != nil {
+		return err
+	}
+
+	if account.FailedAttempts >= maxAttempts && account.DisabledAt == nil {
+		_, _, err = r.collection.update(bson.M{"_id": traineeID}, bson.M{"$set": bson.M{"disabled_at": time.Now()}}, false)
+	}
+	return err
+}
+
+func (r *TraineeAccountRepository) RecordLogin(traineeID string) error {
+	now := time.Now()
+	_, _, err := r.collection.update(
+		bson.M{"_id": traineeID},
+		bson.M{"$set": bson.M{"failed_attempts": 0, "last_login_at": now, "updated_at": now}},
+		false,
+	)
+	return err
+}
+
+func (r *TraineeAccountRepository) DisableAccount(traineeID string) error {
+	now := time.Now()
+	matched, _, err := r.collection.update(
+		bson.M{"_id": traineeID},
+		bson.M{"$set": bson.M{"disabled_at": now, "updated_at": now}},
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *TraineeAccountRepository) UpdateTrainer(traineeID string, trainerID string) error {
+	_, _, err := r.collection.update(
+		bson.M{"_id": traineeID},
+		bson.M{"$set": bson.M{"trainer_id": trainerID, "updated_at": time.Now()}},
+		false,
+	)
+	return err
+}
+
+func (r *TraineeAccountRepository) DeleteAccountsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"_id": bson.M{"$in": traineeIDs}}, true)
+}
@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TraineeRepository keeps health data in plaintext, there is nothing at rest to protect
+type TraineeRepository struct {
+	collection *collection
+}
+
+// Constructor for TraineeRepository
+func NewTraineeRepository(store *Store) *TraineeRepository {
+	return &TraineeRepository{
+		collection: store.collection("trainees"),
+	}
+}
+
+// Trainees are searched by name, goals and notes
+var traineeSearchWeights = bson.D{{Key: "name", Value: 10}, {Key: "goals", Value: 4}, {Key: "notes", Value: 2}}
+
+func (r *TraineeRepository) CreateTrainee(trainee models.Trainee) error {
+	return r.collection.insert(trainee)
+}
+
+func (r *TraineeRepository) GetTraineesByTrainer(trainerID string, organizationIDs []string, status string, list models.ListOptions) ([]models.Trainee, string, error) {
+	query := dateRangeFilter(notDeleted(ownerOrOrganizationFilter(trainerID, organizationIDs)), "start_date", list)
+	if status != "" {
+		activeStatus, err := strconv.ParseBool(status)
+		if err != nil {
+			return nil, "", err
+		}
+		query["active_status"] = activeStatus
+	}
+	return findPage[models.Trainee](r.collection, query, list)
+}
+
+func (r *TraineeRepository) SearchTrainees(trainerID string, organizationIDs []string, query string, limit int64) ([]models.Trainee, []float64, error) {
+	return searchText[models.Trainee](r.collection, notDeleted(ownerOrOrganizationFilter(trainerID, organizationIDs)), query, limit, traineeSearchWeights)
+}
+
+func (r *TraineeRepository) GetTraineesWithMedicalHistory(trainerID string, organizationIDs []string) ([]models.Trainee, error) {
+	filter := notDeleted(ownerOrOrganizationFilter(trainerID, organizationIDs))
+	filter["medical_history"] = bson.M{"$exists": true}
+	found, err := r.collection.find(filter, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll[models.Trainee](found)
+}
+
+func (r *TraineeRepository) GetTraineeByID(id string) (models.Trainee, error) {
+	var trainee models.Trainee
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return trainee, repositories.ErrInvalidID
+	}
+	err = r.collection.findOne(notDeleted(bson.M{"_id": objectID}), &trainee)
+	return trainee, err
+}
+
+func (r *TraineeRepository) UpdateTrainee(id string, version int64, update map[string]interface{}) error {
+	return updateVersioned(r.collection, id, version, bson.M{"$set": update})
+}
+
+func (r *TraineeRepository) DeleteTrainees(ctx context.Context, ids []string) (int64, error) {
+	objectIDs, err := toObjectIDs(ids)
+	if err != nil {
+		return 0, repositories.ErrInvalidID
+	}
+	if len(objectIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"_id": bson.M{"$in": objectIDs}}, true)
+}
+
+func (r *TraineeRepository) TransferTrainee(id string, trainerID string, organizationID string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repositories.ErrInvalidID
+	}
+
+	set := bson.M{"trainer_id": trainerID, "updated_at": time.Now()}
+	update, done := bumpVersion(r.collection.store, bson.M{"$set": set})
+	defer done()
+	if organizationID != "" {
+		set["organization_id"] = organizationID
+	} else {
+		update["$unset"] = bson.M{"organization_id": ""}
+	}
+
+	matched, _, err := r.collection.update(notDeleted(bson.M{"_id": objectID}), update, false)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *TraineeRepository) SoftDeleteTrainee(id string, version int64) error {
+	return softDelete(r.collection, id, version)
+}
+
+func (r *TraineeRepository) RestoreTrainee(id string) error {
+	return restore(r.collection, id)
+}
+
+func (r *TraineeRepository) GetDeletedTraineeByID(id string) (models.Trainee, error) {
+	var trainee models.Trainee
+	err := findDeleted(r.collection, id, &trainee)
+	return trainee, err
+}
+
+func (r *TraineeRepository) GetDeletedTrainees(trainerID string, organizationIDs []string) ([]models.Trainee, error) {
+	return findAllDeleted[models.Trainee](r.collection, ownerOrOrganizationFilter(trainerID, organizationIDs))
+}
+
+func (r *TraineeRepository) GetTraineeIDs(trainerID string, organizationIDs []string) ([]string, error) {
+	return r.findIDs(notDeleted(ownerOrOrganizationFilter(trainerID, organizationIDs)))
+}
+
+func (r *TraineeRepository) GetAllTraineeIDs(trainerID string, organizationIDs []string) ([]string, error) {
+	return r.findIDs(ownerOrOrganizationFilter(trainerID, organizationIDs))
+}
+
+func (r *TraineeRepository) GetTraineeIDsDeletedBefore(cutoff time.Time) ([]string, error) {
+	return r.findIDs(bson.M{"deleted_at": bson.M{"$lt": cutoff}})
+}
+
+func (r *TraineeRepository) GetTraineeIDsByTrainer(ctx context.Context, trainerID string) ([]string, error) {
+	return r.findIDs(personalFilter(trainerID))
+}
+
+// ResealTrainees has nothing to reseal, health data is not encrypted in memory
+func (r *TraineeRepository) ResealTrainees() (int64, error) {
+	return 0, nil
+}
+
+func (r *TraineeRepository) findIDs(filter bson.M) ([]string, error) {
+	found, err := r.collection.find(filter, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var traineeIDs []string
+	for _, doc := range found {
+		traineeIDs = append(traineeIDs, idHex(doc))
+	}
+	return traineeIDs, nil
+}
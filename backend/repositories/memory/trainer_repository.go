@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type TrainerRepository struct {
+	collection *collection
+}
+
+// Constructor for TrainerRepository
+func NewTrainerRepository(store *Store) *TrainerRepository {
+	return &TrainerRepository{
+		collection: store.collection("trainers", uniqueIndex{fields: []string{"email"}}),
+	}
+}
+
+func (r *TrainerRepository) CreateTrainer(trainer models.Trainer) error {
+	return r.collection.insert(trainer)
+}
+
+func (r *TrainerRepository) FindByEmail(email string) (models.Trainer, error) {
+	var trainer models.Trainer
+	err := r.collection.findOne(bson.M{"email": email}, &trainer)
+	return trainer, err
+}
+
+func (r *TrainerRepository) GetTrainers(list models.ListOptions) ([]models.Trainer, string, error) {
+	return findPage[models.Trainer](r.collection, bson.M{}, list)
+}
+
+func (r *TrainerRepository) GetTrainerByID(id string) (models.Trainer, error) {
+	return r.FindByEmail(id)
+}
+
+func (r *TrainerRepository) DeleteTrainer(ctx context.Context, email string) (int64, error) {
+	return r.collection.delete(bson.M{"email": email}, false)
+}
+
+func (r *TrainerRepository) UpdatePassword(email string, hashedPassword string) error {
+	return r.set(email, bson.M{"password": hashedPassword})
+}
+
+func (r *TrainerRepository) SetTimeZone(email string, timeZone string) error {
+	return r.set(email, bson.M{"time_zone": timeZone})
+}
+
+func (r *TrainerRepository) SetEmailVerified(email string) error {
+	return r.set(email, bson.M{"email_verified": true})
+}
+
+func (r *TrainerRepository) set(email string, set bson.M) error {
+	set["updated_at"] = time.Now()
+	matched, _, err := r.collection.update(bson.M{"email": email}, bson.M{"$set": set}, false)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
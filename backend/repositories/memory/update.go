@@ -0,0 +1,277 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// applyUpdate applies the update operators $set, $unset, $inc, $max, $push and $pull to the decoded
+// document. The filter the document was matched with resolves positional $ paths.
+func applyUpdate(doc bson.M, update bson.M, filter bson.M) error {
+	for operator, operand := range update {
+		fields, err := toFilter(operand)
+		if err != nil {
+			return err
+		}
+		for path, value := range fields {
+			if path, err = resolvePositional(doc, path, filter); err != nil {
+				return err
+			}
+			if err := applyOperator(doc, operator, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func applyOperator(doc bson.M, operator string, path string, value interface{}) error {
+	parts := strings.Split(path, ".")
+	switch operator {
+	case "$set":
+		return setPath(doc, parts, value)
+	case "$unset":
+		unsetPath(doc, parts)
+		return nil
+	case "$inc":
+		current, _ := getPath(doc, parts)
+		sum, err := add(current, value)
+		if err != nil {
+			return err
+		}
+		return setPath(doc, parts, sum)
+	case "$max":
+		current, ok := getPath(doc, parts)
+		if ok {
+			x, err := rawValue(current)
+			if err != nil {
+				return err
+			}
+			y, err := rawValue(value)
+			if err != nil {
+				return err
+			}
+			if compareValues(y, x) <= 0 {
+				return nil
+			}
+		}
+		return setPath(doc, parts, value)
+	case "$push":
+		current, _ := getPath(doc, parts)
+		list, ok := current.(bson.A)
+		if current != nil && !ok {
+			return fmt.Errorf("memory: $push to %s, which is not an array", path)
+		}
+		return setPath(doc, parts, append(list, value))
+	case "$pull":
+		current, _ := getPath(doc, parts)
+		list, ok := current.(bson.A)
+		if !ok {
+			return nil
+		}
+		kept := bson.A{}
+		for _, element := range list {
+			pull, err := matchesElement(element, value)
+			if err != nil {
+				return err
+			}
+			if !pull {
+				kept = append(kept, element)
+			}
+		}
+		return setPath(doc, parts, kept)
+	}
+	return fmt.Errorf("%w: %s", errUnsupported, operator)
+}
+
+// matchesElement matches an array element with a $pull condition, a filter for documents or a value
+func matchesElement(element interface{}, condition interface{}) (bool, error) {
+	wrapped, err := bson.Marshal(bson.M{"v": element})
+	if err != nil {
+		return false, err
+	}
+	if filter, err := toFilter(condition); err == nil {
+		if _, ok := operatorDocument(filter); !ok {
+			if document, ok := element.(bson.M); ok {
+				raw, err := bson.Marshal(document)
+				if err != nil {
+					return false, err
+				}
+				return matches(raw, filter)
+			}
+			return false, nil
+		}
+	}
+	return matchField(wrapped, "v", condition)
+}
+
+// resolvePositional replaces a $ in the path by the index of the first array element that matched the filter
+func resolvePositional(doc bson.M, path string, filter bson.M) (string, error) {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		if part != "$" {
+			continue
+		}
+		prefix := strings.Join(parts[:i], ".")
+		list, _ := getPath(doc, parts[:i])
+		elements, ok := list.(bson.A)
+		if !ok {
+			return "", fmt.Errorf("memory: positional update of %s, which is not an array", prefix)
+		}
+
+		index := -1
+		for j, element := range elements {
+			ok, err := positionMatches(element, prefix, filter)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			return "", fmt.Errorf("memory: the filter matches no element of %s", prefix)
+		}
+		parts[i] = strconv.Itoa(index)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// positionMatches checks an array element against the conditions of the filter on that array
+func positionMatches(element interface{}, prefix string, filter bson.M) (bool, error) {
+	raw, err := bson.Marshal(bson.M{"v": element})
+	if err != nil {
+		return false, err
+	}
+	conditions := 0
+	for key, condition := range filter {
+		var ok bool
+		switch {
+		case key == prefix:
+			ok, err = matchField(raw, "v", condition)
+		case strings.HasPrefix(key, prefix+"."):
+			ok, err = matchField(raw, "v."+strings.TrimPrefix(key, prefix+"."), condition)
+		default:
+			continue
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+		conditions++
+	}
+	return conditions > 0, nil
+}
+
+func getPath(node interface{}, parts []string) (interface{}, bool) {
+	for _, part := range parts {
+		switch current := node.(type) {
+		case bson.M:
+			child, ok := current[part]
+			if !ok {
+				return nil, false
+			}
+			node = child
+		case bson.A:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			node = current[index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// setPath sets the value at the path, creating the documents on the way and padding arrays with null
+func setPath(doc bson.M, parts []string, value interface{}) error {
+	parent, ok := getPath(doc, parts[:len(parts)-1])
+	if !ok || parent == nil {
+		if len(parts) == 1 {
+			parent = doc
+		} else {
+			parent = bson.M{}
+			if err := setPath(doc, parts[:len(parts)-1], parent); err != nil {
+				return err
+			}
+		}
+	}
+
+	last := parts[len(parts)-1]
+	switch container := parent.(type) {
+	case bson.M:
+		container[last] = value
+		return nil
+	case bson.A:
+		index, err := strconv.Atoi(last)
+		if err != nil || index < 0 {
+			return fmt.Errorf("memory: cannot set field %s of an array", last)
+		}
+		for len(container) <= index {
+			container = append(container, nil)
+		}
+		container[index] = value
+		if len(parts) == 1 {
+			return nil
+		}
+		return setPath(doc, parts[:len(parts)-1], container)
+	}
+	return fmt.Errorf("memory: cannot set %s inside a %T", strings.Join(parts, "."), parent)
+}
+
+func unsetPath(doc bson.M, parts []string) {
+	parent, ok := getPath(doc, parts[:len(parts)-1])
+	if !ok {
+		return
+	}
+	last := parts[len(parts)-1]
+	switch container := parent.(type) {
+	case bson.M:
+		delete(container, last)
+	case bson.A:
+		if index, err := strconv.Atoi(last); err == nil && index >= 0 && index < len(container) {
+			container[index] = nil
+		}
+	}
+}
+
+// add sums two numbers for $inc, keeping integers integral
+func add(current interface{}, increment interface{}) (interface{}, error) {
+	if current == nil {
+		return increment, nil
+	}
+	x, y := reflect.ValueOf(current), reflect.ValueOf(increment)
+	if isInt(x) && isInt(y) {
+		return x.Int() + y.Int(), nil
+	}
+	a, okA := toFloat(x)
+	b, okB := toFloat(y)
+	if !okA || !okB {
+		return nil, fmt.Errorf("memory: cannot $inc a %T by a %T", current, increment)
+	}
+	return a + b, nil
+}
+
+func isInt(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func toFloat(value reflect.Value) (float64, bool) {
+	if isInt(value) {
+		return float64(value.Int()), true
+	}
+	if value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64 {
+		return value.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ImageRepository keeps uploaded images in memory under made up URLs
+type ImageRepository struct {
+	mu     sync.Mutex
+	images map[string][]byte
+}
+
+// Constructor for ImageRepository
+func NewImageRepository() *ImageRepository {
+	return &ImageRepository{images: map[string][]byte{}}
+}
+
+func (r *ImageRepository) UploadToCloud(file io.Reader) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	url := fmt.Sprintf("https://images.invalid/%d", len(r.images)+1)
+	r.images[url] = data
+	return url, nil
+}
+
+// Image returns an uploaded image by its URL
+func (r *ImageRepository) Image(url string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, ok := r.images[url]
+	return data, ok
+}
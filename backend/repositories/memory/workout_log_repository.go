@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WorkoutLogRepository struct {
+	collection *collection
+}
+
+// Constructor for WorkoutLogRepository
+func NewWorkoutLogRepository(store *Store) *WorkoutLogRepository {
+	return &WorkoutLogRepository{
+		collection: store.collection("workout_logs", clientIDIndex),
+	}
+}
+
+// Client IDs are unique in every collection offline clients sync
+var clientIDIndex = uniqueIndex{fields: []string{"client_id"}, partial: bson.M{"client_id": bson.M{"$type": "string"}}}
+
+// Workout logs are searched by their notes and the notes of their exercises
+var workoutLogSearchWeights = bson.D{{Key: "notes", Value: 2}, {Key: "workouts.notes", Value: 1}}
+
+func (r *WorkoutLogRepository) CreateWorkoutLog(log models.WorkoutLog) (string, error) {
+	var done func()
+	log.SyncSeq, done = r.collection.store.takeSyncSeq()
+	defer done()
+	doc, err := r.collection.insertOne(log)
+	if err != nil {
+		return "", err
+	}
+	return idHex(doc), nil
+}
+
+func (r *WorkoutLogRepository) GetWorkoutLogsByTrainee(traineeID string, dates models.ListOptions) ([]models.WorkoutLog, error) {
+	query := dateRangeFilter(notDeleted(bson.M{"trainee_id": traineeID}), "date", dates)
+	found, err := r.collection.find(query, bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEach[models.WorkoutLog](found)
+}
+
+func (r *WorkoutLogRepository) GetWorkoutLogsByExercises(traineeID string, exerciseIDs []primitive.ObjectID, names []string) ([]models.WorkoutLog, error) {
+	logs, err := r.GetWorkoutLogsByTrainee(traineeID, models.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var found []models.WorkoutLog
+	for _, log := range logs {
+		if didAnyExercise(log, exerciseIDs, names) {
+			found = append(found, log)
+		}
+	}
+	return found, nil
+}
+
+// didAnyExercise reports whether the log has an entry of one of the exercises, by ID or by name
+// whatever its case and surrounding spaces
+func didAnyExercise(log models.WorkoutLog, exerciseIDs []primitive.ObjectID, names []string) bool {
+	for _, workout := range log.Workouts {
+		for _, id := range exerciseIDs {
+			if workout.ExerciseID == id {
+				return true
+			}
+		}
+		for _, name := range names {
+			if strings.EqualFold(strings.TrimSpace(workout.Exercise), strings.TrimSpace(name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *WorkoutLogRepository) GetWorkoutLogPage(traineeID string, list models.ListOptions) ([]models.WorkoutLog, string, error) {
+	return findPage[models.WorkoutLog](r.collection, dateRangeFilter(notDeleted(bson.M{"trainee_id": traineeID}), "date", list), list)
+}
+
+func (r *WorkoutLogRepository) SearchWorkoutLogs(traineeIDs []string, query string, limit int64) ([]models.WorkoutLog, []float64, error) {
+	return searchText[models.WorkoutLog](r.collection, notDeleted(bson.M{"trainee_id": bson.M{"$in": traineeIDs}}), query, limit, workoutLogSearchWeights)
+}
+
+func (r *WorkoutLogRepository) UpdateWorkoutLog(id string, version int64, update map[string]interface{}) error {
+	return updateVersioned(r.collection, id, version, bson.M{"$set": update})
+}
+
+func (r *WorkoutLogRepository) ApplyWorkoutLogChange(id string, version int64, set map[string]interface{}) error {
+	return updateVersioned(r.collection, id, version, bson.M{"$set": set})
+}
+
+func (r *WorkoutLogRepository) GetWorkoutLogByClientID(clientID string) (models.WorkoutLog, error) {
+	var log models.WorkoutLog
+	err := findByClientID(r.collection, clientID, &log)
+	return log, err
+}
+
+func (r *WorkoutLogRepository) GetWorkoutLogChanges(traineeIDs []string, after repositories.SyncPosition, until int64, limit int64) ([]models.WorkoutLog, error) {
+	if len(traineeIDs) == 0 {
+		return []models.WorkoutLog{}, nil
+	}
+	return findChanges[models.WorkoutLog](r.collection, traineeIDs, after, until, limit)
+}
+
+func (r *WorkoutLogRepository) SoftDeleteWorkoutLog(id string, version int64) error {
+	return softDelete(r.collection, id, version)
+}
+
+func (r *WorkoutLogRepository) RestoreWorkoutLog(id string) error {
+	return restore(r.collection, id)
+}
+
+func (r *WorkoutLogRepository) GetDeletedWorkoutLogByID(id string) (models.WorkoutLog, error) {
+	var log models.WorkoutLog
+	err := findDeleted(r.collection, id, &log)
+	return log, err
+}
+
+func (r *WorkoutLogRepository) GetDeletedWorkoutLogs(traineeIDs []string) ([]models.WorkoutLog, error) {
+	if len(traineeIDs) == 0 {
+		return nil, nil
+	}
+	return findAllDeleted[models.WorkoutLog](r.collection, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
+}
+
+func (r *WorkoutLogRepository) PurgeWorkoutLog(id string) (int64, error) {
+	return purgeDeleted(r.collection, id)
+}
+
+func (r *WorkoutLogRepository) PurgeWorkoutLogsDeletedBefore(cutoff time.Time) (int64, error) {
+	return purgeDeletedBefore(r.collection, cutoff)
+}
+
+func (r *WorkoutLogRepository) DeleteWorkoutLogsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+	return r.collection.delete(bson.M{"trainee_id": bson.M{"$in": traineeIDs}}, true)
+}
+
+func (r *WorkoutLogRepository) CountExerciseUsage(ctx context.Context, exerciseIDs []primitive.ObjectID) (logs int64, sets int64, err error) {
+	if len(exerciseIDs) == 0 {
+		return 0, 0, nil
+	}
+
+	found, err := r.collection.find(bson.M{"workouts.exercise_id": bson.M{"$in": exerciseIDs}}, nil, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	used := map[primitive.ObjectID]bool{}
+	for _, id := range exerciseIDs {
+		used[id] = true
+	}
+	for _, doc := range found {
+		var log models.WorkoutLog
+		if err := bson.Unmarshal(doc, &log); err != nil {
+			return 0, 0, err
+		}
+		logs++
+		for _, workout := range log.Workouts {
+			if used[workout.ExerciseID] {
+				sets += int64(len(workout.Sets))
+			}
+		}
+	}
+	return logs, sets, nil
+}
+
+func (r *WorkoutLogRepository) PullExercises(ctx context.Context, exerciseIDs []primitive.ObjectID) (int64, error) {
+	if len(exerciseIDs) == 0 {
+		return 0, nil
+	}
+
+	update, done := bumpVersion(r.collection.store, bson.M{
+		"$pull": bson.M{"workouts": bson.M{"exercise_id": bson.M{"$in": exerciseIDs}}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	defer done()
+	_, modified, err := r.collection.update(bson.M{"workouts.exercise_id": bson.M{"$in": exerciseIDs}}, update, true)
+	return modified, err
+}
+
+func (r *WorkoutLogRepository) GetWorkoutLogByID(id string) (models.WorkoutLog, error) {
+	var log models.WorkoutLog
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return log, repositories.ErrInvalidID
+	}
+	err = r.collection.findOne(notDeleted(bson.M{"_id": objectID}), &log)
+	return log, err
+}
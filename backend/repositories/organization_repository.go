@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoOrganizationRepository struct {
+	collection *mongo.Collection
+}
+
+// Constructor for MongoOrganizationRepository
+func NewMongoOrganizationRepository(db *mongo.Database) *MongoOrganizationRepository {
+	return &MongoOrganizationRepository{
+		collection: db.Collection("organizations"),
+	}
+}
+
+// Organizations are looked up by the email of a member
+var organizationIndexes = []mongo.IndexModel{
+	ascending("member_email", "members.email"),
+}
+
+// Create a new organization
+func (r *MongoOrganizationRepository) CreateOrganization(organization models.Organization) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, organization)
+	return err
+}
+
+// Get an organization by ID
+func (r *MongoOrganizationRepository) GetOrganizationByID(id string) (models.Organization, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var organization models.Organization
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return organization, ErrInvalidID
+	}
+
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&organization)
+	return organization, found(err)
+}
+
+// Get all organizations a trainer is a member of
+func (r *MongoOrganizationRepository) GetOrganizationsByMember(email string) ([]models.Organization, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{"members.email": email})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var organizations []models.Organization
+	for cursor.Next(ctx) {
+		var organization models.Organization
+		if err := cursor.Decode(&organization); err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, organization)
+	}
+	return organizations, nil
+}
+
+// GetOrganizationIDsByMember returns the IDs of the organizations a trainer belongs to,
+// optionally only those where the trainer holds one of the given roles
+func (r *MongoOrganizationRepository) GetOrganizationIDsByMember(email string, roles ...string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	member := bson.M{"email": email}
+	if len(roles) > 0 {
+		member["role"] = bson.M{"$in": roles}
+	}
+
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"members": bson.M{"$elemMatch": member}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []string
+	for cursor.Next(ctx) {
+		var result struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		ids = append(ids, result.ID.Hex())
+	}
+	return ids, nil
+}
+
+// AddMember adds a member to an organization and reports false if the trainer already is one
+func (r *MongoOrganizationRepository) AddMember(id string, member models.OrganizationMember) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, ErrInvalidID
+	}
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": objectID, "members.email": bson.M{"$ne": member.Email}},
+		bson.M{
+			"$push": bson.M{"members": member},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// Change the role of a member
+func (r *MongoOrganizationRepository) UpdateMemberRole(id string, email string, role string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": objectID, "members.email": email},
+		bson.M{"$set": bson.M{"members.$.role": role, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Remove a member from an organization
+func (r *MongoOrganizationRepository) RemoveMember(id string, email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": objectID, "members.email": email},
+		bson.M{
+			"$pull": bson.M{"members": bson.M{"email": email}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Remove a trainer from every organization (for cascading deletes)
+func (r *MongoOrganizationRepository) RemoveMemberFromAll(ctx context.Context, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"members.email": email},
+		bson.M{
+			"$pull": bson.M{"members": bson.M{"email": email}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+var (
+	ErrInvalidCursor  = apperrors.Validation("invalid_cursor", "invalid cursor")
+	ErrUnpageableSort = apperrors.Validation("invalid_sort", "the sort field holds lists, which cannot be paged by")
+)
+
+// sortBrackets are the groups of BSON types in the order MongoDB sorts them, null (and missing) first.
+// Comparison operators only match values of their own group.
+var sortBrackets = [][]bsontype.Type{
+	{bson.TypeNull, bson.TypeUndefined},
+	{bson.TypeDouble, bson.TypeInt32, bson.TypeInt64, bson.TypeDecimal128},
+	{bson.TypeString, bson.TypeSymbol},
+	{bson.TypeEmbeddedDocument},
+	{bson.TypeArray},
+	{bson.TypeBinary},
+	{bson.TypeObjectID},
+	{bson.TypeBoolean},
+	{bson.TypeDateTime},
+	{bson.TypeTimestamp},
+	{bson.TypeRegex},
+}
+
+// pageCursor points just past the last item of a page. It repeats the sort it was made for, so it
+// cannot be used with another one.
+type pageCursor struct {
+	Sort  string             `bson:"s"`
+	Desc  bool               `bson:"d"`
+	Value bson.RawValue      `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+// findPage decodes one page of the documents matching the filter into results, a pointer to a slice,
+// ordered by the sort field and then by _id so equal values page predictably (keyset pagination).
+// It returns the cursor of the next page, or "" on the last one.
+func findPage(collection *mongo.Collection, filter bson.M, list models.ListOptions, results interface{}) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	limit := list.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	direction := 1
+	if list.Desc {
+		direction = -1
+	}
+
+	if list.Cursor != "" {
+		cursor, err := decodePageCursor(list.Cursor)
+		if err != nil || cursor.Sort != list.Sort || cursor.Desc != list.Desc {
+			return "", ErrInvalidCursor
+		}
+		filter = bson.M{"$and": []bson.M{filter, afterCursor(cursor)}}
+	}
+
+	// One more than asked for tells whether there is a next page
+	cursor, err := collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: list.Sort, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(limit+1))
+	if err != nil {
+		return "", err
+	}
+	defer cursor.Close(ctx)
+
+	items := reflect.ValueOf(results).Elem()
+	items.Set(reflect.MakeSlice(items.Type(), 0, int(limit)))
+	var last bson.Raw
+	for cursor.Next(ctx) {
+		if int64(items.Len()) == limit {
+			return encodePageCursor(list, last)
+		}
+		item := reflect.New(items.Type().Elem())
+		if err := cursor.Decode(item.Interface()); err != nil {
+			return "", err
+		}
+		items.Set(reflect.Append(items, item.Elem()))
+		last = append(last[:0], cursor.Current...)
+	}
+	return "", cursor.Err()
+}
+
+// afterCursor matches the documents that sort after the cursor. Values of the cursor's type bracket are
+// compared to it. The brackets that sort after it, such as the dates after a page of documents with a
+// null date, are matched by type, as comparisons would skip them.
+func afterCursor(cursor pageCursor) bson.M {
+	after := "$gt"
+	if cursor.Desc {
+		after = "$lt"
+	}
+
+	bracket := 0
+	for i, types := range sortBrackets {
+		for _, t := range types {
+			if t == cursor.Value.Type {
+				bracket = i
+			}
+		}
+	}
+	var later bson.A
+	for i, types := range sortBrackets {
+		if i > bracket && !cursor.Desc || i < bracket && cursor.Desc {
+			for _, t := range types {
+				later = append(later, int32(t))
+			}
+		}
+	}
+
+	// {field: null} also matches documents without the field, which sort with null
+	or := []bson.M{{cursor.Sort: cursor.Value, "_id": bson.M{after: cursor.ID}}}
+	if cursor.Value.Type != bson.TypeNull {
+		or = append(or, bson.M{cursor.Sort: bson.M{after: cursor.Value}})
+	}
+	if len(later) > 0 {
+		or = append(or, bson.M{cursor.Sort: bson.M{"$type": later}})
+	}
+	if cursor.Desc && bracket > 0 {
+		or = append(or, bson.M{cursor.Sort: nil})
+	}
+	return bson.M{"$or": or}
+}
+
+// dateRangeFilter limits a date field to the inclusive range of the list options
+func dateRangeFilter(filter bson.M, field string, list models.ListOptions) bson.M {
+	dates := bson.M{}
+	if !list.From.IsZero() {
+		dates["$gte"] = list.From
+	}
+	if !list.To.IsZero() {
+		dates["$lt"] = list.To.AddDays(1)
+	}
+	if len(dates) > 0 {
+		filter[field] = dates
+	}
+	return filter
+}
+
+func encodePageCursor(list models.ListOptions, last bson.Raw) (string, error) {
+	id, ok := last.Lookup("_id").ObjectIDOK()
+	if !ok {
+		return "", errors.New("page items need an ObjectID _id")
+	}
+	value, err := last.LookupErr(list.Sort)
+	if err != nil {
+		// A missing field sorts like null
+		value = bson.RawValue{Type: bson.TypeNull}
+	}
+	if value.Type == bson.TypeArray {
+		// Lists sort by their smallest or largest element, which no filter can resume from
+		return "", ErrUnpageableSort
+	}
+
+	raw, err := bson.Marshal(pageCursor{Sort: list.Sort, Desc: list.Desc, Value: value, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodePageCursor(encoded string) (pageCursor, error) {
+	var cursor pageCursor
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, err
+	}
+	err = bson.Unmarshal(raw, &cursor)
+	return cursor, err
+}
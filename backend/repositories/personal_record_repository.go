@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoPersonalRecordRepository struct {
+	collection *mongo.Collection
+}
+
+// Constructor for MongoPersonalRecordRepository
+func NewMongoPersonalRecordRepository(db *mongo.Database) *MongoPersonalRecordRepository {
+	return &MongoPersonalRecordRepository{
+		collection: db.Collection("personal_records"),
+	}
+}
+
+// Records are listed per trainee by date, saved by key, looked up by log to flag the logs that hold
+// one and deleted by exercise when the exercise is
+var personalRecordIndexes = []mongo.IndexModel{
+	ascending("trainee_date", "trainee_id", "date"),
+	partial(unique(ascending("trainee_key", "trainee_id", "key")), bson.M{"key": bson.M{"$type": "string"}}),
+	ascending("log", "log_id"),
+	ascending("exercise", "exercise_id"),
+}
+
+// Save records of a trainee. Each is matched by its key: one already stored is updated and keeps its
+// ID and the time it was first seen, so records saved twice, even at once, are stored once.
+func (r *MongoPersonalRecordRepository) SavePersonalRecords(ctx context.Context, records []models.PersonalRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	writes := make([]mongo.WriteModel, len(records))
+	for i, record := range records {
+		fields, err := recordFields(record)
+		if err != nil {
+			return err
+		}
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"trainee_id": record.TraineeID, "key": record.Key}).
+			SetUpdate(bson.M{"$set": fields, "$setOnInsert": bson.M{"_id": record.ID, "created_at": record.CreatedAt}}).
+			SetUpsert(true)
+	}
+
+	// Two upserts of a new key at once may both insert, the index turns one away and it updates
+	// the other's record when tried again
+	_, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if mongo.IsDuplicateKeyError(err) {
+		_, err = r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	}
+	return err
+}
+
+// recordFields are the fields of a record that a save overwrites
+func recordFields(record models.PersonalRecord) (bson.M, error) {
+	data, err := bson.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "_id")
+	delete(fields, "created_at")
+	return fields, nil
+}
+
+// Get the records of a trainee, newest first, or only those not beaten since if current is set
+func (r *MongoPersonalRecordRepository) GetPersonalRecords(traineeID string, current bool) ([]models.PersonalRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"trainee_id": traineeID}
+	if current {
+		filter["current"] = true
+	}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "exercise", Value: 1}, {Key: "kind", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records := []models.PersonalRecord{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Get the IDs of the logs among the given ones that hold a record
+func (r *MongoPersonalRecordRepository) GetLogIDsWithRecords(logIDs []string) ([]string, error) {
+	if len(logIDs) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	values, err := r.collection.Distinct(ctx, "log_id", bson.M{"log_id": bson.M{"$in": logIDs}})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Delete records by ID, those a trainee no longer holds
+func (r *MongoPersonalRecordRepository) DeletePersonalRecords(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return r.deleteRecords(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+// Delete the records of trainees (for cascading deletes)
+func (r *MongoPersonalRecordRepository) DeletePersonalRecordsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+	return r.deleteRecords(ctx, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
+}
+
+// Delete the records of exercises removed from the workout logs (for cascading deletes)
+func (r *MongoPersonalRecordRepository) DeletePersonalRecordsByExercises(ctx context.Context, exerciseIDs []primitive.ObjectID) (int64, error) {
+	if len(exerciseIDs) == 0 {
+		return 0, nil
+	}
+	return r.deleteRecords(ctx, bson.M{"exercise_id": bson.M{"$in": exerciseIDs}})
+}
+
+func (r *MongoPersonalRecordRepository) deleteRecords(ctx context.Context, filter bson.M) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoProgramRepository struct {
+	collection  *mongo.Collection
+	assignments *mongo.Collection
+}
+
+// Constructor for MongoProgramRepository
+func NewMongoProgramRepository(db *mongo.Database) *MongoProgramRepository {
+	return &MongoProgramRepository{
+		collection:  db.Collection("programs"),
+		assignments: db.Collection("program_assignments"),
+	}
+}
+
+// Programs are listed per trainer
+var programIndexes = []mongo.IndexModel{
+	ascending("trainer_id", "trainer_id"),
+}
+
+// Assignments are keyed by trainee and looked up by program before a program is deleted
+var programAssignmentIndexes = []mongo.IndexModel{
+	ascending("program_id", "program_id"),
+}
+
+// Create a new program
+func (r *MongoProgramRepository) CreateProgram(program models.Program) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, program)
+	return err
+}
+
+// Get a program by ID
+func (r *MongoProgramRepository) GetProgramByID(id string) (models.Program, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var program models.Program
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return program, ErrInvalidID
+	}
+
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&program)
+	return program, found(err)
+}
+
+// Get the programs of a trainer, by name
+func (r *MongoProgramRepository) GetProgramsByTrainer(trainerID string) ([]models.Program, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{"trainer_id": trainerID}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	programs := []models.Program{}
+	if err := cursor.All(ctx, &programs); err != nil {
+		return nil, err
+	}
+	return programs, nil
+}
+
+// Replace the plan of a program: its name, description, length and workouts
+func (r *MongoProgramRepository) UpdateProgram(program models.Program) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": program.ID}, bson.M{"$set": bson.M{
+		"name":        program.Name,
+		"description": program.Description,
+		"weeks":       program.Weeks,
+		"workouts":    program.Workouts,
+		"updated_at":  program.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete programs by ID
+func (r *MongoProgramRepository) DeletePrograms(ctx context.Context, ids []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	objectIDs, err := toObjectIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(objectIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": objectIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// Get the IDs of the programs a trainer wrote (for cascading deletes)
+func (r *MongoProgramRepository) GetProgramIDsByTrainer(ctx context.Context, trainerID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{"trainer_id": trainerID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var programIDs []string
+	for cursor.Next(ctx) {
+		var result struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		programIDs = append(programIDs, result.ID.Hex())
+	}
+	return programIDs, nil
+}
+
+// Assign a program to a trainee, replacing the one they followed
+func (r *MongoProgramRepository) UpsertAssignment(assignment models.ProgramAssignment) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.assignments.ReplaceOne(ctx, bson.M{"_id": assignment.TraineeID}, assignment, options.Replace().SetUpsert(true))
+	return err
+}
+
+// Get the program assignment of a trainee
+func (r *MongoProgramRepository) GetAssignment(traineeID string) (models.ProgramAssignment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var assignment models.ProgramAssignment
+	err := r.assignments.FindOne(ctx, bson.M{"_id": traineeID}).Decode(&assignment)
+	return assignment, found(err)
+}
+
+// Count the trainees following a program
+func (r *MongoProgramRepository) CountAssignments(programID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.assignments.CountDocuments(ctx, bson.M{"program_id": programID})
+}
+
+// Stop a trainee following their program
+func (r *MongoProgramRepository) DeleteAssignment(traineeID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.assignments.DeleteOne(ctx, bson.M{"_id": traineeID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete the assignments of trainees (for cascading deletes)
+func (r *MongoProgramRepository) DeleteAssignmentsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	return r.deleteAssignments(ctx, bson.M{"_id": bson.M{"$in": traineeIDs}}, len(traineeIDs))
+}
+
+// Delete the assignments of programs (for cascading deletes)
+func (r *MongoProgramRepository) DeleteAssignmentsByPrograms(ctx context.Context, programIDs []string) (int64, error) {
+	return r.deleteAssignments(ctx, bson.M{"program_id": bson.M{"$in": programIDs}}, len(programIDs))
+}
+
+func (r *MongoProgramRepository) deleteAssignments(ctx context.Context, filter bson.M, ids int) (int64, error) {
+	if ids == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := r.assignments.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -2,31 +2,40 @@ package repositories
 
 import (
 	"context"
-	"ironcoach/database"
 	"ironcoach/models"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type ProgressRepository struct {
+type MongoProgressRepository struct {
 	collection *mongo.Collection
 }
 
-func NewProgressRepository() *ProgressRepository {
-	db := database.DB.Database("ironcoach")
-	return &ProgressRepository{
+// Constructor for MongoProgressRepository
+func NewMongoProgressRepository(db *mongo.Database) *MongoProgressRepository {
+	return &MongoProgressRepository{
 		collection: db.Collection("progress"),
 	}
 }
 
-func (r *ProgressRepository) GetWeightBMIProgress(traineeID string) ([]models.WeightBMIProgress, error) {
+// Client IDs are unique so a change synced twice at once cannot create the record twice
+var progressIndexes = []mongo.IndexModel{
+	ascending("trainee_date", "trainee_id", "date", "_id"),
+	ascending("trainee_sync", "trainee_id", "sync_seq", "_id"),
+	partial(unique(ascending("client_id", "client_id")), bson.M{"client_id": bson.M{"$type": "string"}}),
+	partial(ascending("deleted", "deleted_at"), bson.M{"deleted_at": bson.M{"$exists": true}}),
+}
+
+// Get the weight entries of a trainee, oldest first, optionally within the date range of the list options
+func (r *MongoProgressRepository) GetWeightBMIProgress(traineeID string, dates models.ListOptions) ([]models.WeightBMIProgress, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{"trainee_id": traineeID}
-	cursor, err := r.collection.Find(ctx, filter)
+	filter := dateRangeFilter(notDeleted(bson.M{"trainee_id": traineeID}), "date", dates)
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}))
 	if err != nil {
 		return nil, err
 	}
@@ -44,23 +53,59 @@ func (r *ProgressRepository) GetWeightBMIProgress(traineeID string) ([]models.We
 	return progress, nil
 }
 
-func (r *ProgressRepository) AddWeightProgress(progress models.WeightBMIProgress) error {
+func (r *MongoProgressRepository) AddWeightProgress(progress models.WeightBMIProgress) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := r.collection.InsertOne(ctx, progress)
+	var done func()
+	var err error
+	if progress.SyncSeq, done, err = takeSyncSeq(ctx, r.collection.Database()); err != nil {
+		return err
+	}
+	defer done()
+	_, err = r.collection.InsertOne(ctx, progress)
 	return err
 }
 
-// Delete all progress entries by trainee IDs
-func (r *ProgressRepository) DeleteProgressByTrainees(traineeIDs []string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Apply a change an offline client made to a weight entry that is still at the expected version
+func (r *MongoProgressRepository) ApplyWeightProgressChange(id string, version int64, set map[string]interface{}) error {
+	return updateVersioned(r.collection, id, version, bson.M{"$set": set})
+}
+
+// Get the weight entry an offline client created under the given ID, removed or not
+func (r *MongoProgressRepository) GetWeightProgressByClientID(clientID string) (models.WeightBMIProgress, error) {
+	var progress models.WeightBMIProgress
+	err := findByClientID(r.collection, clientID, &progress)
+	return progress, err
+}
+
+// Get the weight entries of the trainees written after the position, removed ones included, oldest change first
+func (r *MongoProgressRepository) GetWeightProgressChanges(traineeIDs []string, after SyncPosition, until int64, limit int64) ([]models.WeightBMIProgress, error) {
+	progress := []models.WeightBMIProgress{}
+	if len(traineeIDs) == 0 {
+		return progress, nil
+	}
+	err := findChanges(r.collection, traineeIDs, after, until, limit, &progress)
+	return progress, err
+}
+
+// Permanently delete weight entries that have been removed since before the cutoff
+func (r *MongoProgressRepository) PurgeWeightProgressDeletedBefore(cutoff time.Time) (int64, error) {
+	return purgeDeletedBefore(r.collection, cutoff)
+}
+
+// Delete all progress entries by trainee IDs (for cascading deletes)
+func (r *MongoProgressRepository) DeleteProgressByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	if len(traineeIDs) == 0 {
-		return nil // No trainees to delete progress for
+		return 0, nil // No trainees to delete progress for
 	}
 
-	_, err := r.collection.DeleteMany(ctx, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
-	return err
+	result, err := r.collection.DeleteMany(ctx, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
 }
@@ -0,0 +1,295 @@
+package repositories
+
+import (
+	"context"
+	"io"
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Services depend on the interfaces below rather than on MongoDB. The Mongo implementations in this
+// package are what the server runs on, the memory package implements the same interfaces for tests.
+// Repositories report a missing document with ErrNotFound and a stale version with ErrVersionConflict
+// whatever they store in.
+
+var (
+	ErrNotFound  = apperrors.NotFound("not_found", "resource not found")   // The document does not exist, or is in the trash
+	ErrInvalidID = apperrors.Validation("invalid_id", "invalid ID format") // The ID is not an ObjectID
+)
+
+// found reports a document the driver did not find as ErrNotFound
+func found(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Repositories holds one of each repository, the storage the services run on
+type Repositories struct {
+	Trainers        TrainerRepository
+	Trainees        TraineeRepository
+	TraineeAccounts TraineeAccountRepository
+	Organizations   OrganizationRepository
+	Sessions        SessionRepository
+	AccountTokens   AccountTokenRepository
+	Categories      CategoryRepository
+	Exercises       ExerciseRepository
+	WorkoutLogs     WorkoutLogRepository
+	DietEntries     DietEntryRepository
+	PersonalRecords PersonalRecordRepository
+	Programs        ProgramRepository
+	Progress        ProgressRepository
+	Audit           AuditRepository
+	Idempotency     IdempotencyRepository
+	SyncFeed        SyncFeedRepository
+	Images          ImageRepository
+	Transactions    Transactor
+}
+
+// NewMongoRepositories returns the repositories of the database, with images stored on Cloudinary
+func NewMongoRepositories(db *mongo.Database) Repositories {
+	return Repositories{
+		Trainers:        NewMongoTrainerRepository(db),
+		Trainees:        NewMongoTraineeRepository(db),
+		TraineeAccounts: NewMongoTraineeAccountRepository(db),
+		Organizations:   NewMongoOrganizationRepository(db),
+		Sessions:        NewMongoSessionRepository(db),
+		AccountTokens:   NewMongoAccountTokenRepository(db),
+		Categories:      NewMongoCategoryRepository(db),
+		Exercises:       NewMongoExerciseRepository(db),
+		WorkoutLogs:     NewMongoWorkoutLogRepository(db),
+		DietEntries:     NewMongoDietEntryRepository(db),
+		PersonalRecords: NewMongoPersonalRecordRepository(db),
+		Programs:        NewMongoProgramRepository(db),
+		Progress:        NewMongoProgressRepository(db),
+		Audit:           NewMongoAuditRepository(db),
+		Idempotency:     NewMongoIdempotencyRepository(db),
+		SyncFeed:        NewMongoSyncFeedRepository(db),
+		Images:          NewCloudinaryImageRepository(),
+		Transactions:    NewMongoTransactor(db.Client()),
+	}
+}
+
+// Transactor runs fn so either every write in it is applied or none is. Repository calls made
+// with the context fn gets join the transaction. fn may be run more than once.
+type Transactor interface {
+	WithTransaction(fn func(ctx context.Context) error) error
+}
+
+// TrainerRepository stores trainer accounts, keyed by email
+type TrainerRepository interface {
+	CreateTrainer(trainer models.Trainer) error
+	FindByEmail(email string) (models.Trainer, error)
+	GetTrainers(list models.ListOptions) ([]models.Trainer, string, error)
+	GetTrainerByID(id string) (models.Trainer, error)
+	DeleteTrainer(ctx context.Context, email string) (int64, error)
+	UpdatePassword(email string, hashedPassword string) error
+	SetTimeZone(email string, timeZone string) error
+	SetEmailVerified(email string) error
+}
+
+// TraineeRepository stores trainees, their health data encrypted at rest
+type TraineeRepository interface {
+	CreateTrainee(trainee models.Trainee) error
+	GetTraineesByTrainer(trainerID string, organizationIDs []string, status string, list models.ListOptions) ([]models.Trainee, string, error)
+	SearchTrainees(trainerID string, organizationIDs []string, query string, limit int64) ([]models.Trainee, []float64, error)
+	GetTraineesWithMedicalHistory(trainerID string, organizationIDs []string) ([]models.Trainee, error)
+	GetTraineeByID(id string) (models.Trainee, error)
+	UpdateTrainee(id string, version int64, update map[string]interface{}) error
+	DeleteTrainees(ctx context.Context, ids []string) (int64, error)
+	TransferTrainee(id string, trainerID string, organizationID string) error
+	SoftDeleteTrainee(id string, version int64) error
+	RestoreTrainee(id string) error
+	GetDeletedTraineeByID(id string) (models.Trainee, error)
+	GetDeletedTrainees(trainerID string, organizationIDs []string) ([]models.Trainee, error)
+	GetTraineeIDs(trainerID string, organizationIDs []string) ([]string, error)
+	GetAllTraineeIDs(trainerID string, organizationIDs []string) ([]string, error)
+	GetTraineeIDsDeletedBefore(cutoff time.Time) ([]string, error)
+	GetTraineeIDsByTrainer(ctx context.Context, trainerID string) ([]string, error)
+	ResealTrainees() (int64, error)
+}
+
+// TraineeAccountRepository stores the invite code logins of trainees
+type TraineeAccountRepository interface {
+	UpsertAccount(account models.TraineeAccount) error
+	GetAccount(traineeID string) (models.TraineeAccount, error)
+	GetAccountByInviteCode(code string) (models.TraineeAccount, error)
+	RecordFailedAttempt(traineeID string, maxAttempts int) error
+	RecordLogin(traineeID string) error
+	DisableAccount(traineeID string) error
+	UpdateTrainer(traineeID string, trainerID string) error
+	DeleteAccountsByTrainees(ctx context.Context, traineeIDs []string) (int64, error)
+}
+
+// OrganizationRepository stores organizations and their members
+type OrganizationRepository interface {
+	CreateOrganization(organization models.Organization) error
+	GetOrganizationByID(id string) (models.Organization, error)
+	GetOrganizationsByMember(email string) ([]models.Organization, error)
+	GetOrganizationIDsByMember(email string, roles ...string) ([]string, error)
+	AddMember(id string, member models.OrganizationMember) (bool, error)
+	UpdateMemberRole(id string, email string, role string) error
+	RemoveMember(id string, email string) error
+	RemoveMemberFromAll(ctx context.Context, email string) error
+}
+
+// SessionRepository stores login sessions
+type SessionRepository interface {
+	CreateSession(session models.Session) error
+	GetSessionByID(id string) (models.Session, error)
+	IsSessionActive(id string) (bool, error)
+	RotateRefreshToken(id string, currentHash string, newHash string, expiresAt time.Time) (bool, error)
+	RevokeSession(id string) error
+	RevokeSessionsByEmail(email string) error
+	RevokeSessionsByTrainee(traineeID string) error
+	DeleteSessionsByEmail(ctx context.Context, email string) (int64, error)
+	DeleteSessionsByTrainees(ctx context.Context, traineeIDs []string) (int64, error)
+}
+
+// AccountTokenRepository stores single use tokens for email verification and password resets
+type AccountTokenRepository interface {
+	CreateToken(token models.AccountToken) error
+	ConsumeToken(id string, purpose string) (models.AccountToken, error)
+	InvalidateTokens(email string, purpose string) error
+	DeleteTokensByEmail(ctx context.Context, email string) (int64, error)
+}
+
+// CategoryRepository stores the exercise categories of trainer and organization libraries
+type CategoryRepository interface {
+	AddCategory(category models.Category) error
+	GetCategories(trainerID string, organizationIDs []string) ([]models.Category, error)
+	UpdateCategory(id string, updatedName string) error
+	CascadeUpdateCategoryInExercises(categoryID string, updatedCategoryName string) error
+	DeleteCategories(ctx context.Context, ids []string) (int64, error)
+	IsCategoryExists(name string, trainerID string, organizationID string) (bool, error)
+	GetCategoryByID(id string) (models.Category, error)
+	GetCategoryIDsByTrainer(ctx context.Context, trainerID string) ([]string, error)
+}
+
+// ExerciseRepository stores the exercises of the categories
+type ExerciseRepository interface {
+	AddExercise(exercise models.Exercise) error
+	GetExercisesByCategoryID(categoryID string) ([]models.Exercise, error)
+	UpdateExercise(id string, updatedName string) error
+	CascadeUpdateExerciseInWorkoutLogs(exerciseID string, updatedName string) error
+	DeleteExercise(ctx context.Context, id string) error
+	ArchiveExercise(id string) error
+	GetExerciseIDsByCategories(ctx context.Context, categoryIDs []string) ([]primitive.ObjectID, error)
+	SearchExercises(categoryIDs []primitive.ObjectID, query string, limit int64) ([]models.Exercise, []float64, error)
+	DeleteExercisesByCategories(ctx context.Context, categoryIDs []string) (int64, error)
+	IsExerciseExists(name string, categoryID string) (bool, error)
+	GetExerciseByID(id string) (models.Exercise, error)
+	GetExercisesByIDs(ids []primitive.ObjectID) ([]models.Exercise, error) // Archived ones included
+	IsExerciseExistsInCategory(name string, categoryID string, excludeID string) (bool, error)
+}
+
+// WorkoutLogRepository stores workout logs
+type WorkoutLogRepository interface {
+	CreateWorkoutLog(log models.WorkoutLog) (string, error) // Returns the ID of the new log
+	GetWorkoutLogsByTrainee(traineeID string, dates models.ListOptions) ([]models.WorkoutLog, error)
+	GetWorkoutLogsByExercises(traineeID string, exerciseIDs []primitive.ObjectID, names []string) ([]models.WorkoutLog, error) // Names match entries whatever their case
+	GetWorkoutLogPage(traineeID string, list models.ListOptions) ([]models.WorkoutLog, string, error)
+	SearchWorkoutLogs(traineeIDs []string, query string, limit int64) ([]models.WorkoutLog, []float64, error)
+	UpdateWorkoutLog(id string, version int64, update map[string]interface{}) error
+	ApplyWorkoutLogChange(id string, version int64, set map[string]interface{}) error
+	GetWorkoutLogByClientID(clientID string) (models.WorkoutLog, error)
+	GetWorkoutLogChanges(traineeIDs []string, after SyncPosition, until int64, limit int64) ([]models.WorkoutLog, error)
+	SoftDeleteWorkoutLog(id string, version int64) error
+	RestoreWorkoutLog(id string) error
+	GetDeletedWorkoutLogByID(id string) (models.WorkoutLog, error)
+	GetDeletedWorkoutLogs(traineeIDs []string) ([]models.WorkoutLog, error)
+	PurgeWorkoutLog(id string) (int64, error)
+	PurgeWorkoutLogsDeletedBefore(cutoff time.Time) (int64, error)
+	DeleteWorkoutLogsByTrainees(ctx context.Context, traineeIDs []string) (int64, error)
+	CountExerciseUsage(ctx context.Context, exerciseIDs []primitive.ObjectID) (logs int64, sets int64, err error)
+	PullExercises(ctx context.Context, exerciseIDs []primitive.ObjectID) (int64, error)
+	GetWorkoutLogByID(id string) (models.WorkoutLog, error)
+}
+
+// DietEntryRepository stores diet entries
+type DietEntryRepository interface {
+	CreateDietEntry(entry models.DietEntry) error
+	GetDietEntriesByTrainee(traineeID string, list models.ListOptions) ([]models.DietEntry, string, error)
+	SearchDietEntries(traineeIDs []string, query string, limit int64) ([]models.DietEntry, []float64, error)
+	GetDietEntryByID(entryID string) (models.DietEntry, error)
+	UpdateDietEntry(entryID string, version int64, updateData models.DietEntry) error
+	ApplyDietEntryChange(entryID string, version int64, set map[string]interface{}) error
+	GetDietEntryByClientID(clientID string) (models.DietEntry, error)
+	GetDietEntryChanges(traineeIDs []string, after SyncPosition, until int64, limit int64) ([]models.DietEntry, error)
+	SoftDeleteDietEntry(entryID string, version int64) error
+	RestoreDietEntry(entryID string) error
+	GetDeletedDietEntryByID(entryID string) (models.DietEntry, error)
+	GetDeletedDietEntries(traineeIDs []string) ([]models.DietEntry, error)
+	PurgeDietEntry(entryID string) (int64, error)
+	PurgeDietEntriesDeletedBefore(cutoff time.Time) (int64, error)
+	DeleteDietEntriesByTrainees(ctx context.Context, traineeIDs []string) (int64, error)
+}
+
+// ProgramRepository stores training programs and the program each trainee follows
+type ProgramRepository interface {
+	CreateProgram(program models.Program) error
+	GetProgramByID(id string) (models.Program, error)
+	GetProgramsByTrainer(trainerID string) ([]models.Program, error)
+	UpdateProgram(program models.Program) error
+	DeletePrograms(ctx context.Context, ids []string) (int64, error)
+	GetProgramIDsByTrainer(ctx context.Context, trainerID string) ([]string, error)
+	UpsertAssignment(assignment models.ProgramAssignment) error
+	GetAssignment(traineeID string) (models.ProgramAssignment, error)
+	CountAssignments(programID string) (int64, error)
+	DeleteAssignment(traineeID string) error
+	DeleteAssignmentsByTrainees(ctx context.Context, traineeIDs []string) (int64, error)
+	DeleteAssignmentsByPrograms(ctx context.Context, programIDs []string) (int64, error)
+}
+
+// PersonalRecordRepository stores the personal records of trainees
+type PersonalRecordRepository interface {
+	SavePersonalRecords(ctx context.Context, records []models.PersonalRecord) error     // Inserts each or updates the one stored under its key
+	GetPersonalRecords(traineeID string, current bool) ([]models.PersonalRecord, error) // Newest first
+	GetLogIDsWithRecords(logIDs []string) ([]string, error)                             // Those of the logs that hold a record
+	DeletePersonalRecords(ctx context.Context, ids []primitive.ObjectID) (int64, error)
+	DeletePersonalRecordsByTrainees(ctx context.Context, traineeIDs []string) (int64, error)
+	DeletePersonalRecordsByExercises(ctx context.Context, exerciseIDs []primitive.ObjectID) (int64, error)
+}
+
+// ProgressRepository stores weight and BMI entries
+type ProgressRepository interface {
+	GetWeightBMIProgress(traineeID string, dates models.ListOptions) ([]models.WeightBMIProgress, error)
+	AddWeightProgress(progress models.WeightBMIProgress) error
+	ApplyWeightProgressChange(id string, version int64, set map[string]interface{}) error
+	GetWeightProgressByClientID(clientID string) (models.WeightBMIProgress, error)
+	GetWeightProgressChanges(traineeIDs []string, after SyncPosition, until int64, limit int64) ([]models.WeightBMIProgress, error)
+	PurgeWeightProgressDeletedBefore(cutoff time.Time) (int64, error)
+	DeleteProgressByTrainees(ctx context.Context, traineeIDs []string) (int64, error)
+}
+
+// SyncFeedRepository tells how far the change feed offline clients pull can be read
+type SyncFeedRepository interface {
+	ReadableSeq() (int64, error) // Every write stamped with this sequence number or a lower one is over
+}
+
+// AuditRepository appends to and reads the audit log
+type AuditRepository interface {
+	InsertEntry(entry models.AuditEntry) error
+	FindEntries(filter bson.M, limit int64) ([]models.AuditEntry, error)
+	GetDocument(collection string, filter bson.M) (bson.M, error)
+}
+
+// IdempotencyRepository stores the responses replayed for retried create requests
+type IdempotencyRepository interface {
+	ClaimRecord(record models.IdempotencyRecord) (bool, error)
+	GetRecord(id string) (models.IdempotencyRecord, error)
+	CompleteRecord(id string, status int, contentType string, body []byte) error
+	ReleaseRecord(id string) error
+	DeleteExpiredRecords() (int64, error)
+}
+
+// ImageRepository stores uploaded images
+type ImageRepository interface {
+	UploadToCloud(file io.Reader) (string, error)
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	searchIndexName  = "search"
+	searchScoreField = "search_score"
+)
+
+// textIndex declares the text index search runs on, over the fields with their weights. A collection
+// can only have one text index.
+func textIndex(fields bson.D) mongo.IndexModel {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field.Key, Value: "text"})
+	}
+	return mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName(searchIndexName).SetWeights(fields).SetDefaultLanguage("english"),
+	}
+}
+
+// searchText decodes the documents matching both the filter and the text query into results, a pointer
+// to a slice, best match first. It returns the text score of each result in the same order.
+func searchText(collection *mongo.Collection, filter bson.M, query string, limit int64, results interface{}) ([]float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	filter["$text"] = bson.M{"$search": query}
+	score := bson.M{"$meta": "textScore"}
+	cursor, err := collection.Find(ctx, filter, options.Find().
+		SetProjection(bson.M{searchScoreField: score}).
+		SetSort(bson.D{{Key: searchScoreField, Value: score}}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := reflect.ValueOf(results).Elem()
+	items.Set(reflect.MakeSlice(items.Type(), 0, int(limit)))
+	var scores []float64
+	for cursor.Next(ctx) {
+		item := reflect.New(items.Type().Elem())
+		if err := cursor.Decode(item.Interface()); err != nil {
+			return nil, err
+		}
+		items.Set(reflect.Append(items, item.Elem()))
+		value, _ := cursor.Current.Lookup(searchScoreField).DoubleOK()
+		scores = append(scores, value)
+	}
+	return scores, cursor.Err()
+}
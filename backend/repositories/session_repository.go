@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoSessionRepository struct {
+	collection *mongo.Collection
+}
+
+// Constructor for MongoSessionRepository
+func NewMongoSessionRepository(db *mongo.Database) *MongoSessionRepository {
+	return &MongoSessionRepository{
+		collection: db.Collection("sessions"),
+	}
+}
+
+// Sessions are revoked and deleted by trainer email and by trainee
+var sessionIndexes = []mongo.IndexModel{
+	ascending("email", "email"),
+	ascending("trainer", "trainer_id"),
+	ascending("trainee", "trainee_id"),
+}
+
+// Create a new session
+func (r *MongoSessionRepository) CreateSession(session models.Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+// Get a session by ID
+func (r *MongoSessionRepository) GetSessionByID(id string) (models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var session models.Session
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&session)
+	return session, found(err)
+}
+
+// IsSessionActive reports whether the session exists, is not revoked and has not expired
+func (r *MongoSessionRepository) IsSessionActive(id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"_id":        id,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	return count > 0, err
+}
+
+// RotateRefreshToken swaps the refresh token hash only if the presented one is still current.
+// It returns false when another request rotated or revoked the session first.
+func (r *MongoSessionRepository) RotateRefreshToken(id string, currentHash string, newHash string, expiresAt time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{
+			"_id":                id,
+			"refresh_token_hash": currentHash,
+			"revoked_at":         bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{
+			"refresh_token_hash": newHash,
+			"expires_at":         expiresAt,
+			"updated_at":         time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Revoke a single session
+func (r *MongoSessionRepository) RevokeSession(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}},
+	)
+	return err
+}
+
+// Revoke every session of a trainer
+func (r *MongoSessionRepository) RevokeSessionsByEmail(email string) error {
+	return r.revokeMany(bson.M{"email": email, "role": bson.M{"$ne": "trainee"}})
+}
+
+// Revoke every session of a trainee
+func (r *MongoSessionRepository) RevokeSessionsByTrainee(traineeID string) error {
+	return r.revokeMany(bson.M{"trainee_id": traineeID})
+}
+
+func (r *MongoSessionRepository) revokeMany(filter bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter["revoked_at"] = bson.M{"$exists": false}
+	_, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}})
+	return err
+}
+
+// Delete all sessions of a trainer, including those of the trainer's trainees (for cascading deletes)
+func (r *MongoSessionRepository) DeleteSessionsByEmail(ctx context.Context, email string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"$or": []bson.M{
+		{"email": email},
+		{"trainer_id": email},
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// Delete all sessions of the given trainees (for cascading deletes)
+func (r *MongoSessionRepository) DeleteSessionsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Helpers shared by the collections that support the trash (trainees, workout logs, diet entries)
+
+// softDelete moves a document at the expected version to the trash, returning ErrNotFound
+// if it is missing or already there and ErrVersionConflict if it has changed
+func softDelete(collection *mongo.Collection, id string, version int64) error {
+	return updateVersioned(collection, id, version, bson.M{"$set": bson.M{"deleted_at": time.Now()}})
+}
+
+// restore takes a document out of the trash, returning ErrNotFound if it is not in the trash
+func restore(collection *mongo.Collection, id string) error {
+	update, done, err := bumpVersion(context.Background(), collection.Database(), bson.M{"$unset": bson.M{"deleted_at": ""}})
+	if err != nil {
+		return err
+	}
+	defer done()
+	return setDeletedAt(collection, id, onlyDeleted(bson.M{}), update)
+}
+
+func setDeletedAt(collection *mongo.Collection, id string, filter bson.M, update bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	filter["_id"] = objectID
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// findDeleted decodes a document that is in the trash
+func findDeleted(collection *mongo.Collection, id string, result interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	return found(collection.FindOne(ctx, onlyDeleted(bson.M{"_id": objectID})).Decode(result))
+}
+
+// findAllDeleted decodes every trashed document matching the filter, most recently deleted first
+func findAllDeleted(collection *mongo.Collection, filter bson.M, results interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, onlyDeleted(filter), options.Find().SetSort(bson.M{"deleted_at": -1}))
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+// purgeDeletedBefore hard-deletes documents that have been in the trash since before the cutoff
+func purgeDeletedBefore(collection *mongo.Collection, cutoff time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// purgeDeleted hard-deletes a single document that is in the trash
+func purgeDeleted(collection *mongo.Collection, id string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, ErrNotFound
+	}
+
+	result, err := collection.DeleteOne(ctx, onlyDeleted(bson.M{"_id": objectID}))
+	if err != nil {
+		return 0, err
+	}
+	if result.DeletedCount == 0 {
+		return 0, ErrNotFound
+	}
+	return result.DeletedCount, nil
+}
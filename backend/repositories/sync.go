@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Helpers shared by the collections offline clients sync (workout logs, diet entries, weight progress).
+// Every write to them is stamped with the next number of a global sequence, so a client can pull
+// everything that changed since the last sequence number it has seen.
+//
+// A write takes its number before it commits, so writes can become visible out of order: while the
+// write stamped N is still running, the one stamped N+1 may commit and be pulled, and a client whose
+// cursor moved past N+1 would never see N. Numbers are therefore kept in flight, in the counter
+// document and by the same update that takes them, until their write is done, and pulls read no
+// further than the number before the oldest one still in flight.
+
+// syncLease is how long a number is held in flight at most. A writer that dies without releasing its
+// number holds the feed back no longer than this, well past the time a write or transaction may take.
+const syncLease = 2 * time.Minute
+
+// SyncPosition is where a client stopped reading the change feed. Writes that touch several documents
+// at once stamp them with the same sequence number, the ID orders them within it.
+type SyncPosition struct {
+	Seq int64
+	ID  string
+}
+
+// syncCounter is the counter document of the change sequence
+type syncCounter struct {
+	Seq      int64 `bson:"seq"`
+	InFlight []struct {
+		Seq     int64     `bson:"seq"`
+		TakenAt time.Time `bson:"taken_at"`
+	} `bson:"in_flight"`
+}
+
+// takeSyncSeq takes the next number of the change sequence and holds it in flight. done must be
+// called once the write stamped with it is over, whether it succeeded or not. In a transaction the
+// number is held until the transaction ends, and done does nothing.
+func takeSyncSeq(ctx context.Context, db *mongo.Database) (seq int64, done func(), err error) {
+	// The counter is not part of the transaction, concurrent writers would conflict on it
+	counterCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Numbers whose lease ran out are dropped on the way
+	leaseStart := bson.M{"$subtract": bson.A{"$$NOW", syncLease.Milliseconds()}}
+	var counter syncCounter
+	err = db.Collection("counters").FindOneAndUpdate(counterCtx,
+		bson.M{"_id": "sync"},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"seq": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$seq", 0}}, 1}}}}},
+			{{Key: "$set", Value: bson.M{"in_flight": bson.M{"$concatArrays": bson.A{
+				bson.M{"$filter": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$in_flight", bson.A{}}},
+					"cond":  bson.M{"$gt": bson.A{"$$this.taken_at", leaseStart}},
+				}},
+				bson.A{bson.M{"seq": "$seq", "taken_at": "$$NOW"}},
+			}}}}},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	release := func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// A number that fails to be released holds the feed back until its lease runs out
+		_, err := db.Collection("counters").UpdateOne(releaseCtx, bson.M{"_id": "sync"}, bson.M{"$pull": bson.M{"in_flight": bson.M{"seq": counter.Seq}}})
+		if err != nil {
+			log.Printf("Failed to release sync sequence number %d: %v", counter.Seq, err)
+		}
+	}
+	if afterTransaction(ctx, release) {
+		return counter.Seq, func() {}, nil
+	}
+	return counter.Seq, release, nil
+}
+
+type MongoSyncFeedRepository struct {
+	counters *mongo.Collection
+}
+
+// Constructor for MongoSyncFeedRepository
+func NewMongoSyncFeedRepository(db *mongo.Database) *MongoSyncFeedRepository {
+	return &MongoSyncFeedRepository{
+		counters: db.Collection("counters"),
+	}
+}
+
+// Get the highest sequence number up to which every write is over
+func (r *MongoSyncFeedRepository) ReadableSeq() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var counter syncCounter
+	err := r.counters.FindOne(ctx, bson.M{"_id": "sync"}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	readable := counter.Seq
+	for _, inFlight := range counter.InFlight {
+		if time.Since(inFlight.TakenAt) < syncLease && inFlight.Seq <= readable {
+			readable = inFlight.Seq - 1
+		}
+	}
+	return readable, nil
+}
+
+// findByClientID decodes the document an offline client created under the given ID, trashed or not.
+// Documents created on the server are known to clients by their own ID.
+func findByClientID(collection *mongo.Collection, clientID string, result interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"client_id": clientID}
+	if objectID, err := primitive.ObjectIDFromHex(clientID); err == nil {
+		filter = bson.M{"$or": []bson.M{filter, {"_id": objectID}}}
+	}
+	return found(collection.FindOne(ctx, filter).Decode(result))
+}
+
+// findChanges decodes up to limit documents of the trainees written after the position and up to the
+// until sequence number, trashed ones included, in the order they were written
+func findChanges(collection *mongo.Collection, traineeIDs []string, after SyncPosition, until int64, limit int64, results interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// Documents written before sync was added have no sequence number and count as 0
+	sameSeq := bson.M{"sync_seq": after.Seq}
+	if after.Seq == 0 {
+		sameSeq["sync_seq"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	if afterID, err := primitive.ObjectIDFromHex(after.ID); err == nil {
+		sameSeq["_id"] = bson.M{"$gt": afterID}
+	}
+	filter := bson.M{
+		"trainee_id": bson.M{"$in": traineeIDs},
+		"$or":        []bson.M{{"sync_seq": bson.M{"$gt": after.Seq, "$lte": until}}, sameSeq},
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "sync_seq", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit))
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
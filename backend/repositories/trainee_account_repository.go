@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoTraineeAccountRepository struct {
+	collection *mongo.Collection
+}
+
+// Constructor for MongoTraineeAccountRepository
+func NewMongoTraineeAccountRepository(db *mongo.Database) *MongoTraineeAccountRepository {
+	return &MongoTraineeAccountRepository{
+		collection: db.Collection("trainee_accounts"),
+	}
+}
+
+// Invite codes are how trainees log in, each must lead to a single account
+var traineeAccountIndexes = []mongo.IndexModel{
+	partial(unique(ascending("invite_code", "invite_code")), bson.M{"invite_code": bson.M{"$gt": ""}}),
+}
+
+// Create or replace the account of a trainee
+func (r *MongoTraineeAccountRepository) UpsertAccount(account models.TraineeAccount) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": account.ID}, account, options.Replace().SetUpsert(true))
+	return err
+}
+
+// Get the account of a trainee
+func (r *MongoTraineeAccountRepository) GetAccount(traineeID string) (models.TraineeAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var account models.TraineeAccount
+	err := r.collection.FindOne(ctx, bson.M{"_id": traineeID}).Decode(&account)
+	return account, found(err)
+}
+
+// Find an account by its invite code
+func (r *MongoTraineeAccountRepository) GetAccountByInviteCode(code string) (models.TraineeAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var account models.TraineeAccount
+	err := r.collection.FindOne(ctx, bson.M{"invite_code": code}).Decode(&account)
+	return account, found(err)
+}
+
+// RecordFailedAttempt counts a wrong PIN and disables the account once maxAttempts is reached
+func (r *MongoTraineeAccountRepository) RecordFailedAttempt(traineeID string, maxAttempts int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var account models.TraineeAccount
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": traineeID},
+		bson.M{"$inc": bson.M{"failed_attempts": 1}, "$set": bson.M{"updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&account)
+	if err != nil {
+		return found(err)
+	}
+
+	if account.FailedAttempts >= maxAttempts && account.DisabledAt == nil {
+		_, err = r.collection.UpdateOne(ctx,
+			bson.M{"_id": traineeID},
+			bson.M{"$set": bson.M{"disabled_at": time.Now()}},
+		)
+	}
+	return err
+}
+
+// RecordLogin resets the failed attempt counter after a successful login
+func (r *MongoTraineeAccountRepository) RecordLogin(traineeID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": traineeID},
+		bson.M{"$set": bson.M{"failed_attempts": 0, "last_login_at": now, "updated_at": now}},
+	)
+	return err
+}
+
+// Disable the account of a trainee
+func (r *MongoTraineeAccountRepository) DisableAccount(traineeID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": traineeID},
+		bson.M{"$set": bson.M{"disabled_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Point the account at the trainee's new trainer after a transfer
+func (r *MongoTraineeAccountRepository) UpdateTrainer(traineeID string, trainerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": traineeID},
+		bson.M{"$set": bson.M{"trainer_id": trainerID, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// Delete the accounts of the given trainees (for cascading deletes)
+func (r *MongoTraineeAccountRepository) DeleteAccountsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if len(traineeIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": traineeIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -2,8 +2,6 @@ package repositories
 
 import (
 	"context"
-	"errors"
-	"ironcoach/database"
 	"ironcoach/models"
 	"strconv"
 	"time"
@@ -14,20 +12,33 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type TraineeRepository struct {
+// Health data is encrypted at rest, the repository seals it on every write and opens it on every read
+var encryptedTraineeFields = []string{"medical_history", "lab_tests", "health_questionnaire", "emergency_contact"}
+
+type MongoTraineeRepository struct {
 	collection *mongo.Collection
+	cipher     *fieldCipher
 }
 
-// Constructor for TraineeRepository
-func NewTraineeRepository() *TraineeRepository {
-	db := database.DB.Database("ironcoach")
-	return &TraineeRepository{
-		collection: db.Collection("trainees"),
+// Constructor for MongoTraineeRepository
+func NewMongoTraineeRepository(db *mongo.Database) *MongoTraineeRepository {
+	cipher := newFieldCipher("trainees", encryptedTraineeFields...)
+	return &MongoTraineeRepository{
+		collection: db.Collection("trainees", options.Collection().SetRegistry(cipher.registry(models.Trainee{}))),
+		cipher:     cipher,
 	}
 }
 
+// Trainees are listed by trainer or organization, trashed ones are purged by deletion time
+var traineeIndexes = []mongo.IndexModel{
+	ascending("trainer_name", "trainer_id", "name", "_id"),
+	partial(ascending("organization_name", "organization_id", "name", "_id"), bson.M{"organization_id": bson.M{"$exists": true}}),
+	partial(ascending("deleted", "deleted_at"), bson.M{"deleted_at": bson.M{"$exists": true}}),
+	textIndex(bson.D{{Key: "name", Value: 10}, {Key: "goals", Value: 4}, {Key: "notes", Value: 2}}),
+}
+
 // Add a new trainee
-func (r *TraineeRepository) CreateTrainee(trainee models.Trainee) error {
+func (r *MongoTraineeRepository) CreateTrainee(trainee models.Trainee) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -35,45 +46,55 @@ func (r *TraineeRepository) CreateTrainee(trainee models.Trainee) error {
 	return err
 }
 
-func (r *TraineeRepository) GetTraineesByTrainer(trainerID string, status string) ([]models.Trainee, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-
-	var trainees []models.Trainee
-
+// Get a page of the trainees of a trainer together with those shared through the given organizations,
+// optionally limited to those whose start date is within the date range of the list options
+func (r *MongoTraineeRepository) GetTraineesByTrainer(trainerID string, organizationIDs []string, status string, list models.ListOptions) ([]models.Trainee, string, error) {
 	// Build the query based on the inputs
-	query := bson.M{"trainer_id": trainerID}
+	query := dateRangeFilter(notDeleted(ownerOrOrganizationFilter(trainerID, organizationIDs)), "start_date", list)
 
 	// Convert `status` string to boolean if it's not empty
 	if status != "" {
 		activeStatus, err := strconv.ParseBool(status)
 		if err != nil {
-			return nil, err // Handle invalid status values gracefully
+			return nil, "", err // Handle invalid status values gracefully
 		}
 		query["active_status"] = activeStatus
 	}
 
-	// Perform the query
-	cursor, err := r.collection.Find(ctx, query)
+	var trainees []models.Trainee
+	next, err := findPage(r.collection, query, list, &trainees)
+	return trainees, next, err
+}
+
+// Find the trainees of a trainer and of the given organizations whose name, goals or notes match the text query, best match first
+func (r *MongoTraineeRepository) SearchTrainees(trainerID string, organizationIDs []string, query string, limit int64) ([]models.Trainee, []float64, error) {
+	var trainees []models.Trainee
+	scores, err := searchText(r.collection, notDeleted(ownerOrOrganizationFilter(trainerID, organizationIDs)), query, limit, &trainees)
+	return trainees, scores, err
+}
+
+// Get the trainees of a trainer and of the given organizations that have a medical history on file, decrypted
+func (r *MongoTraineeRepository) GetTraineesWithMedicalHistory(trainerID string, organizationIDs []string) ([]models.Trainee, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	filter := notDeleted(ownerOrOrganizationFilter(trainerID, organizationIDs))
+	filter["medical_history"] = bson.M{"$exists": true}
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	// Decode the results
-	for cursor.Next(ctx) {
-		var trainee models.Trainee
-		if err := cursor.Decode(&trainee); err != nil {
-			return nil, err
-		}
-		trainees = append(trainees, trainee)
+	var trainees []models.Trainee
+	if err := cursor.All(ctx, &trainees); err != nil {
+		return nil, err
 	}
-
 	return trainees, nil
 }
 
 // Get trainee by ID
-func (r *TraineeRepository) GetTraineeByID(id string) (models.Trainee, error) {
+func (r *MongoTraineeRepository) GetTraineeByID(id string) (models.Trainee, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
@@ -81,72 +102,164 @@ func (r *TraineeRepository) GetTraineeByID(id string) (models.Trainee, error) {
 	// Convert the id to MongoDB's ObjectID type
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return trainee, errors.New("invalid trainee ID format")
+		return trainee, ErrInvalidID
 	}
 
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&trainee)
-	return trainee, err
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&trainee)
+	return trainee, found(err)
+}
+
+// Update a trainee that is still at the expected version
+func (r *MongoTraineeRepository) UpdateTrainee(id string, version int64, update map[string]interface{}) error {
+	if err := r.cipher.sealUpdate(id, update); err != nil {
+		return err
+	}
+	return updateVersioned(r.collection, id, version, bson.M{"$set": update})
+}
+
+// Delete trainees by ID (for cascading deletes)
+func (r *MongoTraineeRepository) DeleteTrainees(ctx context.Context, ids []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	objectIDs, err := toObjectIDs(ids)
+	if err != nil {
+		return 0, ErrInvalidID
+	}
+	if len(objectIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": objectIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
 }
 
-// Update a trainee
-func (r *TraineeRepository) UpdateTrainee(id string, update map[string]interface{}) error {
+// Transfer a trainee to another trainer, moving it into the organization or making it personal when organizationID is empty
+func (r *MongoTraineeRepository) TransferTrainee(id string, trainerID string, organizationID string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Convert the id to MongoDB's ObjectID type
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("invalid trainee ID format")
+		return ErrInvalidID
 	}
 
-	// Apply the update
-	result, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": objectID}, // Match by ID
-		bson.M{"$set": update},  // Apply updates
-	)
+	set := bson.M{"trainer_id": trainerID, "updated_at": time.Now()}
+	update, done, err := bumpVersion(ctx, r.collection.Database(), bson.M{"$set": set})
 	if err != nil {
 		return err
 	}
-
-	// Check if the document was actually updated
-	if result.ModifiedCount == 0 {
-		return errors.New("no document updated, trainee not found or no changes applied")
+	defer done()
+	if organizationID != "" {
+		set["organization_id"] = organizationID
+	} else {
+		update["$unset"] = bson.M{"organization_id": ""}
 	}
 
+	result, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
 
-// Delete a trainee
-func (r *TraineeRepository) DeleteTrainee(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Move a trainee at the expected version to the trash, their logs and entries stay hidden with them until restored or purged
+func (r *MongoTraineeRepository) SoftDeleteTrainee(id string, version int64) error {
+	return softDelete(r.collection, id, version)
+}
 
-	// Convert the id to MongoDB's ObjectID type
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return errors.New("invalid trainee ID format")
-	}
+// Take a trainee out of the trash
+func (r *MongoTraineeRepository) RestoreTrainee(id string) error {
+	return restore(r.collection, id)
+}
 
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
-	return err
+// Get a trainee that is in the trash
+func (r *MongoTraineeRepository) GetDeletedTraineeByID(id string) (models.Trainee, error) {
+	var trainee models.Trainee
+	err := findDeleted(r.collection, id, &trainee)
+	return trainee, err
+}
+
+// Get the trashed trainees of a trainer and of the given organizations
+func (r *MongoTraineeRepository) GetDeletedTrainees(trainerID string, organizationIDs []string) ([]models.Trainee, error) {
+	var trainees []models.Trainee
+	err := findAllDeleted(r.collection, ownerOrOrganizationFilter(trainerID, organizationIDs), &trainees)
+	return trainees, err
+}
+
+// Get the IDs of the trainees of a trainer and of the given organizations, excluding trashed ones
+func (r *MongoTraineeRepository) GetTraineeIDs(trainerID string, organizationIDs []string) ([]string, error) {
+	return r.findIDs(context.Background(), notDeleted(ownerOrOrganizationFilter(trainerID, organizationIDs)))
+}
+
+// Get the IDs of the trainees of a trainer and of the given organizations, including trashed ones
+func (r *MongoTraineeRepository) GetAllTraineeIDs(trainerID string, organizationIDs []string) ([]string, error) {
+	return r.findIDs(context.Background(), ownerOrOrganizationFilter(trainerID, organizationIDs))
+}
+
+// Get the IDs of trainees that have been in the trash since before the cutoff (for purging)
+func (r *MongoTraineeRepository) GetTraineeIDsDeletedBefore(cutoff time.Time) ([]string, error) {
+	return r.findIDs(context.Background(), bson.M{"deleted_at": bson.M{"$lt": cutoff}})
+}
+
+// Get all personal trainee IDs by trainer ID (for cascading deletes).
+// Organization trainees stay with the organization.
+func (r *MongoTraineeRepository) GetTraineeIDsByTrainer(ctx context.Context, trainerID string) ([]string, error) {
+	return r.findIDs(ctx, personalFilter(trainerID))
 }
 
-// Delete all trainees by trainer ID
-func (r *TraineeRepository) DeleteTraineesByTrainer(trainerID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// ResealTrainees encrypts health data still stored in plaintext, seals again values not yet bound to
+// their trainee, and rewraps data keys sealed with a retired key, including trashed trainees.
+// It returns the number of trainees updated.
+func (r *MongoTraineeRepository) ResealTrainees() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
-	_, err := r.collection.DeleteMany(ctx, bson.M{"trainer_id": trainerID})
-	return err
+	projection := bson.M{}
+	for _, field := range encryptedTraineeFields {
+		projection[field] = 1
+	}
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var updated int64
+	for cursor.Next(ctx) {
+		resealed, err := r.cipher.resealDocument(cursor.Current)
+		if err != nil {
+			return updated, err
+		}
+		if len(resealed) == 0 {
+			continue
+		}
+
+		// Only replace values nobody has written since they were read
+		filter := bson.M{"_id": cursor.Current.Lookup("_id")}
+		for field := range resealed {
+			filter[field] = cursor.Current.Lookup(field)
+		}
+		result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": resealed})
+		if err != nil {
+			return updated, err
+		}
+		updated += result.ModifiedCount
+	}
+	return updated, cursor.Err()
 }
 
-// Get all trainee IDs by trainer ID (for cascading deletes)
-func (r *TraineeRepository) GetTraineeIDsByTrainer(trainerID string) ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *MongoTraineeRepository) findIDs(ctx context.Context, filter bson.M) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{"trainer_id": trainerID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
 	if err != nil {
 		return nil, err
 	}
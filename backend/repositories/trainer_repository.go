@@ -2,7 +2,6 @@ package repositories
 
 import (
 	"context"
-	"ironcoach/database"
 	"ironcoach/models"
 	"time"
 
@@ -10,72 +9,119 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-type TrainerRepository struct {
+type MongoTrainerRepository struct {
 	collection *mongo.Collection
 }
 
-// constructor for TrainerRepository
-func NewTrainerRepository() *TrainerRepository {
-	db := database.DB.Database("ironcoach")
-	return &TrainerRepository{
+// Constructor for MongoTrainerRepository
+func NewMongoTrainerRepository(db *mongo.Database) *MongoTrainerRepository {
+	return &MongoTrainerRepository{
 		collection: db.Collection("trainers"),
 	}
 }
 
-// Insert a new Trainer
-func (r *TrainerRepository) CreateTrainer(trainer models.Trainer) error {
+// Emails identify trainers, the unique index keeps concurrent registrations from sharing one
+var trainerIndexes = []mongo.IndexModel{
+	unique(ascending("email", "email")),
+}
+
+// Insert a new Trainer. It returns ErrDuplicateKey if the email is taken.
+func (r *MongoTrainerRepository) CreateTrainer(trainer models.Trainer) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	_, err := r.collection.InsertOne(ctx, trainer)
-	return err
+	return duplicateKey(err)
 }
 
 // Find trainer by email
-func (r *TrainerRepository) FindByEmail(email string) (models.Trainer, error) {
+func (r *MongoTrainerRepository) FindByEmail(email string) (models.Trainer, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var trainer models.Trainer
 	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&trainer)
-	return trainer, err
+	return trainer, found(err)
+}
+
+func (r *MongoTrainerRepository) GetTrainers(list models.ListOptions) ([]models.Trainer, string, error) {
+	var trainers []models.Trainer
+	next, err := findPage(r.collection, bson.M{}, list, &trainers)
+	return trainers, next, err
 }
 
-func (r *TrainerRepository) GetTrainers() ([]models.Trainer, error) {
+// In `trainer_repository.go`
+func (r *MongoTrainerRepository) GetTrainerByID(id string) (models.Trainer, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	cursor, err := r.collection.Find(ctx, bson.M{})
+
+	var trainer models.Trainer
+	err := r.collection.FindOne(ctx, bson.M{"email": id}).Decode(&trainer)
+	return trainer, found(err)
+}
+
+// Delete trainer by email (for cascading deletes)
+func (r *MongoTrainerRepository) DeleteTrainer(ctx context.Context, email string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"email": email})
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer cursor.Close(ctx)
+	return result.DeletedCount, nil
+}
 
-	var trainers []models.Trainer
-	for cursor.Next(ctx) {
-		var trainer models.Trainer
-		if err := cursor.Decode(&trainer); err != nil {
-			return nil, err
-		}
-		trainers = append(trainers, trainer)
+// Update the password hash of a trainer
+func (r *MongoTrainerRepository) UpdatePassword(email string, hashedPassword string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"email": email},
+		bson.M{"$set": bson.M{"password": hashedPassword, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
 	}
-	return trainers, nil
+	return nil
 }
 
-// In `trainer_repository.go`
-func (r *TrainerRepository) GetTrainerByID(id string) (models.Trainer, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Set the time zone of a trainer
+func (r *MongoTrainerRepository) SetTimeZone(email string, timeZone string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	var trainer models.Trainer
-	err := r.collection.FindOne(ctx, bson.M{"email": id}).Decode(&trainer)
-	return trainer, err
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"email": email},
+		bson.M{"$set": bson.M{"time_zone": timeZone, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-// Delete trainer by email
-func (r *TrainerRepository) DeleteTrainer(email string) error {
+// Mark the email address of a trainer as verified
+func (r *MongoTrainerRepository) SetEmailVerified(email string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := r.collection.DeleteOne(ctx, bson.M{"email": email})
-	return err
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"email": email},
+		bson.M{"$set": bson.M{"email_verified": true, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoTransactor runs transactions on a MongoDB deployment
+type MongoTransactor struct {
+	client *mongo.Client
+}
+
+// Constructor for MongoTransactor
+func NewMongoTransactor(client *mongo.Client) *MongoTransactor {
+	return &MongoTransactor{client: client}
+}
+
+// WithTransaction runs fn inside a multi-document transaction, so either every write in it
+// is applied or none is. Repository calls made with the context fn gets join the transaction.
+// Transactions need a replica set or sharded cluster (Atlas clusters always are).
+func (t *MongoTransactor) WithTransaction(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	hooks := &transactionHooks{}
+	defer hooks.run()
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(context.WithValue(sessionCtx, transactionHooksKey{}, hooks))
+	})
+	return err
+}
+
+type transactionHooksKey struct{}
+
+// transactionHooks are run once a transaction is over, committed or not
+type transactionHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+func (h *transactionHooks) run() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, hook := range h.hooks {
+		hook()
+	}
+	h.hooks = nil
+}
+
+// afterTransaction runs fn once the transaction of the context is over, committed or not. It reports
+// false, and does nothing, if the context is not in a transaction.
+func afterTransaction(ctx context.Context, fn func()) bool {
+	hooks, ok := ctx.Value(transactionHooksKey{}).(*transactionHooks)
+	if !ok {
+		return false
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.hooks = append(hooks.hooks, fn)
+	return true
+}
@@ -11,12 +11,12 @@ import (
 	"github.com/cloudinary/cloudinary-go/api/uploader"
 )
 
-type ImageRepository struct {
+type CloudinaryImageRepository struct {
     cloudinary *cloudinary.Cloudinary
 }
 
-// Constructor for ImageRepository
-func NewImageRepository() *ImageRepository {
+// Constructor for CloudinaryImageRepository
+func NewCloudinaryImageRepository() *CloudinaryImageRepository {
 	cloudName := os.Getenv("CLOUDINARY_CLOUD_NAME")
 	apiKey := os.Getenv("CLOUDINARY_API_KEY")
 	apiSecret := os.Getenv("CLOUDINARY_API_SECRET")
@@ -26,13 +26,13 @@ func NewImageRepository() *ImageRepository {
         log.Fatalf("Failed to initialize Cloudinary: %v", err)
     }
 
-    return &ImageRepository{
+    return &CloudinaryImageRepository{
         cloudinary: cld,
     }
 }
 
 // UploadToCloud handles the interaction with Cloudinary
-func (r *ImageRepository) UploadToCloud(file io.Reader) (string, error) {
+func (r *CloudinaryImageRepository) UploadToCloud(file io.Reader) (string, error) {
     // fmt.Println("bfr file here is : ", file)
     resp, err := r.cloudinary.Upload.Upload(context.Background(), file, uploader.UploadParams{})
     // fmt.Println("respnse is : ", resp)
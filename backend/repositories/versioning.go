@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"ironcoach/apperrors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Trainees, workout logs and diet entries carry a version that every write increments, so a
+// client can only change a document it has seen in its current state (optimistic concurrency).
+
+var ErrVersionConflict = apperrors.PreconditionFailed("version_conflict", "document has been changed since it was read")
+
+// versionFilter matches a document at the given version. Documents written before versioning count as version 0.
+func versionFilter(filter bson.M, version int64) bson.M {
+	if version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["version"] = version
+	}
+	return filter
+}
+
+// bumpVersion adds the version increment and the sync sequence number every write to a versioned document
+// must carry. done must be called once the write is over, see takeSyncSeq.
+func bumpVersion(ctx context.Context, db *mongo.Database, update bson.M) (bson.M, func(), error) {
+	seq, done, err := takeSyncSeq(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	update["$inc"] = bson.M{"version": 1}
+	update["$max"] = bson.M{"sync_seq": seq}
+	return update, done, nil
+}
+
+// updateVersioned applies the update to a document outside the trash if it is still at the expected version.
+// It returns ErrNotFound if the document is gone and ErrVersionConflict if it has changed.
+func updateVersioned(collection *mongo.Collection, id string, version int64, update bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	update, done, err := bumpVersion(ctx, collection.Database(), update)
+	if err != nil {
+		return err
+	}
+	defer done()
+	result, err := collection.UpdateOne(ctx, versionFilter(notDeleted(bson.M{"_id": objectID}), version), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	// Tell a stale version apart from a missing document
+	count, err := collection.CountDocuments(ctx, notDeleted(bson.M{"_id": objectID}))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrVersionConflict
+	}
+	return ErrNotFound
+}
@@ -2,9 +2,10 @@ package repositories
 
 import (
 	"context"
-	"errors"
-	"ironcoach/database"
+	"fmt"
 	"ironcoach/models"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,40 +13,60 @@ import (
 
 	// "go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type WorkoutLogRepository struct {
+type MongoWorkoutLogRepository struct {
 	collection *mongo.Collection
 }
 
-// Constructor for WorkoutLogRepository
-func NewWorkoutLogRepository() *WorkoutLogRepository {
-	db := database.DB.Database("ironcoach")
-	return &WorkoutLogRepository{
+// Constructor for MongoWorkoutLogRepository
+func NewMongoWorkoutLogRepository(db *mongo.Database) *MongoWorkoutLogRepository {
+	return &MongoWorkoutLogRepository{
 		collection: db.Collection("workout_logs"),
 	}
 }
 
+// Client IDs are unique so a change synced twice at once cannot create the log twice
+var workoutLogIndexes = []mongo.IndexModel{
+	ascending("trainee_date", "trainee_id", "date", "_id"),
+	ascending("trainee_sync", "trainee_id", "sync_seq", "_id"),
+	ascending("exercise", "workouts.exercise_id"),
+	partial(unique(ascending("client_id", "client_id")), bson.M{"client_id": bson.M{"$type": "string"}}),
+	partial(ascending("deleted", "deleted_at"), bson.M{"deleted_at": bson.M{"$exists": true}}),
+	textIndex(bson.D{{Key: "notes", Value: 2}, {Key: "workouts.notes", Value: 1}}),
+}
+
 // Add a new workout log
-func (r *WorkoutLogRepository) CreateWorkoutLog(log models.WorkoutLog) error {
+func (r *MongoWorkoutLogRepository) CreateWorkoutLog(log models.WorkoutLog) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := r.collection.InsertOne(ctx, log)
-	return err
+	var done func()
+	var err error
+	if log.SyncSeq, done, err = takeSyncSeq(ctx, r.collection.Database()); err != nil {
+		return "", err
+	}
+	defer done()
+	result, err := r.collection.InsertOne(ctx, log)
+	if err != nil {
+		return "", err
+	}
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return "", fmt.Errorf("workout log was stored under a %T _id rather than an ObjectID", result.InsertedID)
+	}
+	return id.Hex(), nil
 }
 
-// Get all workout logs for a trainee
-func (r *WorkoutLogRepository) GetWorkoutLogsByTrainee(traineeID string, date string) ([]models.WorkoutLog, error) {
+// Get all workout logs for a trainee, oldest first, optionally within the date range of the list options
+func (r *MongoWorkoutLogRepository) GetWorkoutLogsByTrainee(traineeID string, dates models.ListOptions) ([]models.WorkoutLog, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var logs []models.WorkoutLog
-	query := bson.M{"trainee_id": traineeID}
-	if date != "" {
-		query["date"] = date // Filter by date if provided
-	}
-	cursor, err := r.collection.Find(ctx, query)
+	query := dateRangeFilter(notDeleted(bson.M{"trainee_id": traineeID}), "date", dates)
+	cursor, err := r.collection.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}))
 	if err != nil {
 		return nil, err
 	}
@@ -62,65 +83,214 @@ func (r *WorkoutLogRepository) GetWorkoutLogsByTrainee(traineeID string, date st
 	return logs, nil
 }
 
-func (r *WorkoutLogRepository) UpdateWorkoutLog(id string, update map[string]interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Second)
+// Get the workout logs of a trainee that did any of the exercises, sorted by date. Entries not linked
+// to an exercise are matched by name, whatever its case and surrounding spaces.
+func (r *MongoWorkoutLogRepository) GetWorkoutLogsByExercises(traineeID string, exerciseIDs []primitive.ObjectID, names []string) ([]models.WorkoutLog, error) {
+	exercises := bson.A{}
+	if len(exerciseIDs) > 0 {
+		exercises = append(exercises, bson.M{"workouts.exercise_id": bson.M{"$in": exerciseIDs}})
+	}
+	if len(names) > 0 {
+		patterns := make(bson.A, len(names))
+		for i, name := range names {
+			patterns[i] = primitive.Regex{Pattern: `^\s*` + regexp.QuoteMeta(strings.TrimSpace(name)) + `\s*$`, Options: "i"}
+		}
+		exercises = append(exercises, bson.M{"workouts.exercise": bson.M{"$in": patterns}})
+	}
+	if len(exercises) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	objectID, err := primitive.ObjectIDFromHex(id)
+	query := notDeleted(bson.M{"trainee_id": traineeID, "$or": exercises})
+	cursor, err := r.collection.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}))
 	if err != nil {
-		return errors.New("invalid trainee ID format")
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	// fmt.Println("log id: ", id)
-	// Update the workout log
-	result, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": objectID}, // Match by log ID
-		bson.M{"$set": update},  // Apply the update
-	)
-	if err != nil {
-		return err
+	var logs []models.WorkoutLog
+	if err := cursor.All(ctx, &logs); err != nil {
+		return nil, err
 	}
+	return logs, nil
+}
+
+// Get a page of the workout logs of a trainee, optionally within a date range
+func (r *MongoWorkoutLogRepository) GetWorkoutLogPage(traineeID string, list models.ListOptions) ([]models.WorkoutLog, string, error) {
+	var logs []models.WorkoutLog
+	next, err := findPage(r.collection, dateRangeFilter(notDeleted(bson.M{"trainee_id": traineeID}), "date", list), list, &logs)
+	return logs, next, err
+}
+
+// Find the workout logs of the trainees whose notes or exercise notes match the text query, best match first
+func (r *MongoWorkoutLogRepository) SearchWorkoutLogs(traineeIDs []string, query string, limit int64) ([]models.WorkoutLog, []float64, error) {
+	var logs []models.WorkoutLog
+	scores, err := searchText(r.collection, notDeleted(bson.M{"trainee_id": bson.M{"$in": traineeIDs}}), query, limit, &logs)
+	return logs, scores, err
+}
+
+// Update a workout log that is still at the expected version
+func (r *MongoWorkoutLogRepository) UpdateWorkoutLog(id string, version int64, update map[string]interface{}) error {
+	return updateVersioned(r.collection, id, version, bson.M{"$set": update})
+}
 
-	if result.ModifiedCount == 0 {
-		return errors.New("no log updated, log not found or no changes applied")
+// Apply a change an offline client made to a workout log that is still at the expected version
+func (r *MongoWorkoutLogRepository) ApplyWorkoutLogChange(id string, version int64, set map[string]interface{}) error {
+	return updateVersioned(r.collection, id, version, bson.M{"$set": set})
+}
+
+// Get the workout log an offline client created under the given ID, trashed or not
+func (r *MongoWorkoutLogRepository) GetWorkoutLogByClientID(clientID string) (models.WorkoutLog, error) {
+	var log models.WorkoutLog
+	err := findByClientID(r.collection, clientID, &log)
+	return log, err
+}
+
+// Get the workout logs of the trainees written after the position, trashed ones included, oldest change first
+func (r *MongoWorkoutLogRepository) GetWorkoutLogChanges(traineeIDs []string, after SyncPosition, until int64, limit int64) ([]models.WorkoutLog, error) {
+	logs := []models.WorkoutLog{}
+	if len(traineeIDs) == 0 {
+		return logs, nil
 	}
+	err := findChanges(r.collection, traineeIDs, after, until, limit, &logs)
+	return logs, err
+}
 
-	return nil
+// Move a workout log at the expected version to the trash
+func (r *MongoWorkoutLogRepository) SoftDeleteWorkoutLog(id string, version int64) error {
+	return softDelete(r.collection, id, version)
 }
 
-func (r *WorkoutLogRepository) DeleteWorkoutLog(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// Take a workout log out of the trash
+func (r *MongoWorkoutLogRepository) RestoreWorkoutLog(id string) error {
+	return restore(r.collection, id)
+}
+
+// Get a workout log that is in the trash
+func (r *MongoWorkoutLogRepository) GetDeletedWorkoutLogByID(id string) (models.WorkoutLog, error) {
+	var log models.WorkoutLog
+	err := findDeleted(r.collection, id, &log)
+	return log, err
+}
+
+// Get the trashed workout logs of the given trainees
+func (r *MongoWorkoutLogRepository) GetDeletedWorkoutLogs(traineeIDs []string) ([]models.WorkoutLog, error) {
+	var logs []models.WorkoutLog
+	if len(traineeIDs) == 0 {
+		return logs, nil
+	}
+	err := findAllDeleted(r.collection, bson.M{"trainee_id": bson.M{"$in": traineeIDs}}, &logs)
+	return logs, err
+}
+
+// Permanently delete a workout log that is in the trash
+func (r *MongoWorkoutLogRepository) PurgeWorkoutLog(id string) (int64, error) {
+	return purgeDeleted(r.collection, id)
+}
+
+// Permanently delete workout logs that have been in the trash since before the cutoff
+func (r *MongoWorkoutLogRepository) PurgeWorkoutLogsDeletedBefore(cutoff time.Time) (int64, error) {
+	return purgeDeletedBefore(r.collection, cutoff)
+}
+
+// Delete all workout logs by trainee IDs (for cascading deletes)
+func (r *MongoWorkoutLogRepository) DeleteWorkoutLogsByTrainees(ctx context.Context, traineeIDs []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	objectID, err := primitive.ObjectIDFromHex(id)
+	if len(traineeIDs) == 0 {
+		return 0, nil // No trainees to delete logs for
+	}
+
+	// TraineeID is stored as string in workout logs, so use string array directly
+	result, err := r.collection.DeleteMany(ctx, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
 	if err != nil {
-		return errors.New("invalid trainee ID format")
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// CountExerciseUsage counts the workout logs, trashed ones included, that contain one of the
+// exercises and the sets recorded for those exercises
+func (r *MongoWorkoutLogRepository) CountExerciseUsage(ctx context.Context, exerciseIDs []primitive.ObjectID) (logs int64, sets int64, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	if len(exerciseIDs) == 0 {
+		return 0, 0, nil
 	}
 
-	// Delete the workout log
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	matchExercises := bson.M{"workouts.exercise_id": bson.M{"$in": exerciseIDs}}
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: matchExercises}},
+		{{Key: "$unwind", Value: "$workouts"}},
+		{{Key: "$match", Value: matchExercises}},
+		{{Key: "$group", Value: bson.M{
+			"_id":  "$_id",
+			"sets": bson.M{"$sum": bson.M{"$size": bson.M{"$ifNull": bson.A{"$workouts.sets", bson.A{}}}}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":  nil,
+			"logs": bson.M{"$sum": 1},
+			"sets": bson.M{"$sum": "$sets"},
+		}}},
+	})
 	if err != nil {
-		return err
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Logs int64 `bson:"logs"`
+		Sets int64 `bson:"sets"`
 	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return 0, 0, err
+		}
+	}
+	return result.Logs, result.Sets, cursor.Err()
+}
+
+// PullExercises removes the entries for the exercises from every workout log, trashed ones
+// included, and leaves the rest of each log alone. It returns the number of logs changed.
+func (r *MongoWorkoutLogRepository) PullExercises(ctx context.Context, exerciseIDs []primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
 
-	if result.DeletedCount == 0 {
-		return errors.New("no log found with the provided ID")
+	if len(exerciseIDs) == 0 {
+		return 0, nil
 	}
 
-	return nil
+	update, done, err := bumpVersion(ctx, r.collection.Database(), bson.M{
+		"$pull": bson.M{"workouts": bson.M{"exercise_id": bson.M{"$in": exerciseIDs}}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	result, err := r.collection.UpdateMany(ctx, bson.M{"workouts.exercise_id": bson.M{"$in": exerciseIDs}}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
 }
 
-// Delete all workout logs by trainee IDs
-func (r *WorkoutLogRepository) DeleteWorkoutLogsByTrainees(traineeIDs []string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Get a workout log by ID
+func (r *MongoWorkoutLogRepository) GetWorkoutLogByID(id string) (models.WorkoutLog, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if len(traineeIDs) == 0 {
-		return nil // No trainees to delete logs for
+	var log models.WorkoutLog
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return log, ErrInvalidID
 	}
 
-	// TraineeID is stored as string in workout logs, so use string array directly
-	_, err := r.collection.DeleteMany(ctx, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
-	return err
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&log)
+	return log, found(err)
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"ironcoach/controllers"
+	"ironcoach/middlewares"
+	"ironcoach/services"
+	"ironcoach/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAuditRoutes(router *gin.Engine, app *services.Services) {
+	auditController := controllers.NewAuditController(app.Audit)
+	protected := router.Group("/audit").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.RequireRole(utils.RoleTrainer))
+
+	protected.GET("", auditController.GetAuditLog)
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"ironcoach/controllers"
+	"ironcoach/middlewares"
+	"ironcoach/services"
+	"ironcoach/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAuthRoutes(router *gin.Engine, app *services.Services) {
+	authController := controllers.NewAuthController(app.Sessions, app.Accounts, app.TraineeAccounts)
+
+	// TRAINEE LOGIN - PINs are short, keep brute force protection as strict as trainer login
+	traineeLoginGroup := router.Group("/auth")
+	traineeLoginGroup.Use(middlewares.RateLimitMiddleware(10, 15*time.Minute, 3))
+	traineeLoginGroup.POST("/trainee/login", authController.TraineeLogin)
+
+	// REFRESH - Runs every few minutes per device, but a refresh token is still a credential
+	refreshGroup := router.Group("/auth")
+	refreshGroup.Use(middlewares.RateLimitMiddleware(30, 15*time.Minute, 5))
+	refreshGroup.POST("/refresh", authController.Refresh)
+
+	// RECOVERY - Sends email, so keep it as strict as registration
+	recoveryGroup := router.Group("/auth")
+	recoveryGroup.Use(middlewares.RateLimitMiddleware(5, time.Hour, 2))
+	recoveryGroup.POST("/forgot-password", authController.ForgotPassword)
+
+	// TOKEN REDEMPTION - Tokens are single-use, allow genuine retries
+	tokenGroup := router.Group("/auth")
+	tokenGroup.Use(middlewares.RateLimitMiddleware(10, 15*time.Minute, 3))
+	tokenGroup.POST("/reset-password", authController.ResetPassword)
+	tokenGroup.POST("/verify-email", authController.VerifyEmail)
+
+	protected := router.Group("/auth").Use(middlewares.AuthMiddleware(app.Sessions))
+	protected.POST("/logout", authController.Logout)
+	protected.POST("/logout-all", authController.LogoutAll)
+	protected.POST("/resend-verification", middlewares.RequireRole(utils.RoleTrainer), authController.ResendVerification)
+}
@@ -3,17 +3,22 @@ package routes
 import (
 	"ironcoach/controllers"
 	"ironcoach/middlewares"
+	"ironcoach/services"
+	"ironcoach/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterCategoryRoutes(router *gin.Engine) {
-	categoryController := controllers.NewCategoryController()
-    protected := router.Group("/categories").Use(middlewares.AuthMiddleware())
+func RegisterCategoryRoutes(router *gin.Engine, app *services.Services) {
+	categoryController := controllers.NewCategoryController(app.Categories)
+	guard := middlewares.NewOwnershipGuard(app.Authorization)
+    protected := router.Group("/categories").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.NewAuditor(app.Audit).Resource("categories"))
+    trainerOnly := middlewares.RequireRole(utils.RoleTrainer)
+    idempotent := middlewares.Idempotent(app.Idempotency)
 
-    protected.POST("",categoryController.AddCategory)
+    protected.POST("", trainerOnly, idempotent, categoryController.AddCategory)
     protected.GET("", categoryController.GetCategories)
-    protected.PUT("/:id", categoryController.UpdateCategory)
-    protected.DELETE("/:id", categoryController.DeleteCategory)
+    protected.PUT("/:id", trainerOnly, guard.Category("id"), categoryController.UpdateCategory)
+    protected.DELETE("/:id", trainerOnly, guard.Category("id"), categoryController.DeleteCategory)
 }
 
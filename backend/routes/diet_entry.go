@@ -4,16 +4,20 @@ import (
     "github.com/gin-gonic/gin"
     "ironcoach/controllers"
     "ironcoach/middlewares"
+    "ironcoach/services"
 )
 
-func RegisterDietEntryRoutes(router *gin.Engine) {
-    dietEntryController := controllers.NewDietEntryController()
+func RegisterDietEntryRoutes(router *gin.Engine, app *services.Services) {
+    dietEntryController := controllers.NewDietEntryController(app.DietEntries)
+    guard := middlewares.NewOwnershipGuard(app.Authorization)
+    ifMatch := middlewares.RequireIfMatch()
+    idempotent := middlewares.Idempotent(app.Idempotency)
 
-    protected := router.Group("/diet_entries").Use(middlewares.AuthMiddleware())
+    protected := router.Group("/diet_entries").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.NewAuditor(app.Audit).Resource("diet_entries"))
 
-    protected.POST("", dietEntryController.AddDietEntry)
-    protected.GET("/:trainee_id", dietEntryController.GetDietEntries)
-    protected.GET("/entry/:entry_id", dietEntryController.GetDietEntryByID)
-    protected.PUT("/:entry_id", dietEntryController.UpdateDietEntry)
-    protected.DELETE("/:entry_id", dietEntryController.DeleteDietEntry)
+    protected.POST("", guard.TraineeInBody(), idempotent, dietEntryController.AddDietEntry)
+    protected.GET("/:trainee_id", guard.Trainee("trainee_id"), dietEntryController.GetDietEntries)
+    protected.GET("/entry/:entry_id", guard.DietEntry("entry_id"), dietEntryController.GetDietEntryByID)
+    protected.PUT("/:entry_id", guard.DietEntry("entry_id"), ifMatch, dietEntryController.UpdateDietEntry)
+    protected.DELETE("/:entry_id", guard.DietEntry("entry_id"), ifMatch, dietEntryController.DeleteDietEntry)
 }
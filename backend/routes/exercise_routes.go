@@ -3,17 +3,22 @@ package routes
 import (
 	"ironcoach/controllers"
 	"ironcoach/middlewares"
+	"ironcoach/services"
+	"ironcoach/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterExerciseRoutes(router *gin.Engine) {
-	exerciseController := controllers.NewExerciseController()
-	protected := router.Group("/exercises").Use(middlewares.AuthMiddleware())
+func RegisterExerciseRoutes(router *gin.Engine, app *services.Services) {
+	exerciseController := controllers.NewExerciseController(app.Exercises)
+	guard := middlewares.NewOwnershipGuard(app.Authorization)
+	protected := router.Group("/exercises").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.NewAuditor(app.Audit).Resource("exercises"))
+	trainerOnly := middlewares.RequireRole(utils.RoleTrainer)
+	idempotent := middlewares.Idempotent(app.Idempotency)
 
-	protected.POST("", exerciseController.AddExercise)
+	protected.POST("", trainerOnly, guard.CategoryInBody(), idempotent, exerciseController.AddExercise)
 	protected.GET("/:category", exerciseController.GetExercisesByCategory)
-	protected.PUT("/:id", exerciseController.UpdateExercise)
-	protected.DELETE("/:id", exerciseController.DeleteExercise)
+	protected.PUT("/:id", trainerOnly, guard.Exercise("id"), exerciseController.UpdateExercise)
+	protected.DELETE("/:id", trainerOnly, guard.Exercise("id"), exerciseController.DeleteExercise)
 
 }
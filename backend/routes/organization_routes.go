@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"ironcoach/controllers"
+	"ironcoach/middlewares"
+	"ironcoach/services"
+	"ironcoach/models"
+	"ironcoach/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterOrganizationRoutes(router *gin.Engine, app *services.Services) {
+	organizationController := controllers.NewOrganizationController(app.Organizations)
+	guard := middlewares.NewOwnershipGuard(app.Authorization)
+	protected := router.Group("/organizations").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.RequireRole(utils.RoleTrainer), middlewares.NewAuditor(app.Audit).Resource("organizations"))
+	ownerOnly := guard.Organization("org_id", models.OrgRoleOwner)
+	idempotent := middlewares.Idempotent(app.Idempotency)
+
+	protected.POST("", idempotent, organizationController.CreateOrganization)
+	protected.GET("", organizationController.GetOrganizations)
+	protected.GET("/:org_id", guard.Organization("org_id"), organizationController.GetOrganizationByID)
+	protected.POST("/:org_id/members", ownerOnly, organizationController.AddMember)
+	protected.PUT("/:org_id/members/:email", ownerOnly, organizationController.UpdateMemberRole)
+	protected.DELETE("/:org_id/members/:email", ownerOnly, organizationController.RemoveMember)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"ironcoach/controllers"
+	"ironcoach/middlewares"
+	"ironcoach/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterPersonalRecordRoutes(router *gin.Engine, app *services.Services) {
+	personalRecordController := controllers.NewPersonalRecordController(app.PersonalRecords)
+	guard := middlewares.NewOwnershipGuard(app.Authorization)
+
+	// Records are worked out from the workout logs, they are only ever read
+	records := router.Group("/trainees").Use(middlewares.AuthMiddleware(app.Sessions))
+	records.GET("/:trainee_id/prs", guard.Trainee("trainee_id"), personalRecordController.GetPersonalRecords)
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"ironcoach/controllers"
+	"ironcoach/middlewares"
+	"ironcoach/services"
+	"ironcoach/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterProgramRoutes(router *gin.Engine, app *services.Services) {
+	programController := controllers.NewProgramController(app.Programs)
+	guard := middlewares.NewOwnershipGuard(app.Authorization)
+	idempotent := middlewares.Idempotent(app.Idempotency)
+	trainerOnly := middlewares.RequireRole(utils.RoleTrainer)
+
+	programs := router.Group("/programs").Use(middlewares.AuthMiddleware(app.Sessions), trainerOnly, middlewares.NewAuditor(app.Audit).Resource("programs"))
+	programs.POST("", idempotent, programController.CreateProgram)
+	programs.GET("", programController.GetPrograms)
+	programs.GET("/:program_id", guard.Program("program_id"), programController.GetProgramByID)
+	programs.PUT("/:program_id", guard.Program("program_id"), programController.UpdateProgram)
+	programs.DELETE("/:program_id", guard.Program("program_id"), programController.DeleteProgram)
+
+	// Trainers assign programs, trainees read theirs and start the planned workouts
+	assignments := router.Group("/trainees").Use(middlewares.AuthMiddleware(app.Sessions))
+	assignments.POST("/:id/program", trainerOnly, guard.ManageTrainee("id"), programController.AssignProgram)
+	assignments.GET("/:trainee_id/program", guard.Trainee("trainee_id"), programController.GetAssignment)
+	assignments.DELETE("/:id/program", trainerOnly, guard.ManageTrainee("id"), programController.UnassignProgram)
+	assignments.GET("/:trainee_id/program/today", guard.Trainee("trainee_id"), programController.GetPlannedWorkout)
+	assignments.POST("/:id/program/log", guard.Trainee("id"), idempotent, programController.LogPlannedWorkout)
+}
@@ -4,13 +4,16 @@ import (
 	"github.com/gin-gonic/gin"
 	"ironcoach/controllers"
 	"ironcoach/middlewares"
+	"ironcoach/services"
 )
 
-func RegisterProgressRoutes(router *gin.Engine) {
-	progressController := controllers.NewProgressController()
+func RegisterProgressRoutes(router *gin.Engine, app *services.Services) {
+	progressController := controllers.NewProgressController(app.Progress)
+	guard := middlewares.NewOwnershipGuard(app.Authorization)
+	idempotent := middlewares.Idempotent(app.Idempotency)
 
-	protected := router.Group("/progress").Use(middlewares.AuthMiddleware())
+	protected := router.Group("/progress").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.NewAuditor(app.Audit).Resource("progress"))
 
-	protected.GET("/:trainee_id", progressController.GetProgress)
-	protected.POST("", progressController.AddWeightProgress)
+	protected.GET("/:trainee_id", guard.Trainee("trainee_id"), progressController.GetProgress)
+	protected.POST("", guard.TraineeInBody(), idempotent, progressController.AddWeightProgress)
 }
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"ironcoach/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the routes of every resource, served by the given services
+func RegisterRoutes(router *gin.Engine, app *services.Services) {
+	RegisterTrainerRoutes(router, app)
+	RegisterAuthRoutes(router, app)
+	RegisterOrganizationRoutes(router, app)
+	RegisterTraineeRoutes(router, app)
+	RegisterWorkoutLogRoutes(router, app)
+	RegisterProgramRoutes(router, app)
+	RegisterPersonalRecordRoutes(router, app)
+	RegisterCategoryRoutes(router, app)
+	RegisterExerciseRoutes(router, app)
+	RegisterDietEntryRoutes(router, app)
+	RegisterProgressRoutes(router, app)
+	RegisterImageRoutes(router, app)
+	RegisterTrashRoutes(router, app)
+	RegisterAuditRoutes(router, app)
+	RegisterSyncRoutes(router, app)
+	RegisterSearchRoutes(router, app)
+}
@@ -0,0 +1,259 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ironcoach/mailer"
+	"ironcoach/middlewares"
+	"ironcoach/repositories/memory"
+	"ironcoach/routes"
+	"ironcoach/services"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter wires every route onto services backed by the in-memory repositories
+func newRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	app := services.New(memory.NewRepositories(), mailer.NewLogMailer(log.New(io.Discard, "", 0), "noreply@ironcoach.test"))
+	router := gin.New()
+	router.Use(middlewares.Problems())
+	routes.RegisterRoutes(router, app)
+	return router
+}
+
+func newRequest(method, path, body, token string, headers ...string) *http.Request {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return req
+}
+
+func send(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func request(router *gin.Engine, method, path, body, token string, headers ...string) *httptest.ResponseRecorder {
+	return send(router, newRequest(method, path, body, token, headers...))
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, into interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), into); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+}
+
+// clients hands out a client address per trainer, registration is rate limited per client
+var clients atomic.Int32
+
+// signIn registers a trainer and returns their access token
+func signIn(t *testing.T, router *gin.Engine, email string) string {
+	t.Helper()
+	register := newRequest(http.MethodPost, "/registerTrainer", `{"name":"Coach","email":"`+email+`","password":"secret123"}`, "")
+	register.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", clients.Add(1))
+	if w := send(router, register); w.Code != http.StatusOK {
+		t.Fatalf("register %s: %d %s", email, w.Code, w.Body.String())
+	}
+
+	login := newRequest(http.MethodPost, "/login", `{"email":"`+email+`","password":"secret123"}`, "")
+	login.RemoteAddr = register.RemoteAddr
+	w := send(router, login)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login %s: %d %s", email, w.Code, w.Body.String())
+	}
+	var session struct {
+		Token string `json:"token"`
+	}
+	decode(t, w, &session)
+	return session.Token
+}
+
+// addTrainee creates a trainee for the signed in trainer and returns its ID
+func addTrainee(t *testing.T, router *gin.Engine, token string) string {
+	t.Helper()
+	w := request(router, http.MethodPost, "/trainees", `{"name":"Alex","phone_number":"555","dob":"1990-04-01","gender":"m","height":180}`, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("add trainee: %d %s", w.Code, w.Body.String())
+	}
+
+	w = request(router, http.MethodGet, "/trainees", "", token)
+	var page struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	decode(t, w, &page)
+	if len(page.Items) != 1 {
+		t.Fatalf("listed %d trainees, want 1", len(page.Items))
+	}
+	return page.Items[0].ID
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	router := newRouter(t)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/login"},
+		{http.MethodPost, "/trainees"},
+		{http.MethodGet, "/trainees/:trainee_id"},
+		{http.MethodPatch, "/trainees/:id"},
+		{http.MethodPost, "/trainees/:id/transfer"},
+		{http.MethodPost, "/trainees/:id/account"},
+		{http.MethodPost, "/sync/push"},
+	} {
+		found := false
+		for _, registered := range router.Routes() {
+			if registered.Method == route.method && registered.Path == route.path {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%s %s is not registered", route.method, route.path)
+		}
+	}
+}
+
+func TestAddTraineeTakesTrainerFromToken(t *testing.T) {
+	router := newRouter(t)
+	token := signIn(t, router, "coach@ironcoach.test")
+
+	id := addTrainee(t, router, token)
+	w := request(router, http.MethodGet, "/trainees/"+id, "", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get trainee: %d %s", w.Code, w.Body.String())
+	}
+	var trainee struct {
+		TrainerID string `json:"trainer_id"`
+	}
+	decode(t, w, &trainee)
+	if trainee.TrainerID != "coach@ironcoach.test" {
+		t.Errorf("trainer_id = %q, want the caller", trainee.TrainerID)
+	}
+}
+
+func TestTraineeOwnership(t *testing.T) {
+	router := newRouter(t)
+	owner := signIn(t, router, "owner@ironcoach.test")
+	other := signIn(t, router, "other@ironcoach.test")
+	id := addTrainee(t, router, owner)
+
+	for _, tc := range []struct {
+		name, method, path, body string
+		want                     int
+	}{
+		{"read", http.MethodGet, "/trainees/" + id, "", http.StatusForbidden},
+		{"update", http.MethodPatch, "/trainees/" + id, `{"name":"Taken"}`, http.StatusForbidden},
+		{"delete", http.MethodDelete, "/trainees/" + id, "", http.StatusForbidden},
+		{"transfer", http.MethodPost, "/trainees/" + id + "/transfer", `{"trainer_email":"other@ironcoach.test"}`, http.StatusForbidden},
+		{"log workout", http.MethodPost, "/workout_logs", `{"trainee_id":"` + id + `","date":"2024-01-01","workouts":[]}`, http.StatusForbidden},
+		{"unknown trainee", http.MethodGet, "/trainees/000000000000000000000000", "", http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := request(router, tc.method, tc.path, tc.body, other, "If-Match", `"1"`)
+			if w.Code != tc.want {
+				t.Errorf("%s %s = %d, want %d: %s", tc.method, tc.path, w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+
+	if w := request(router, http.MethodGet, "/trainees/"+id, "", owner); w.Code != http.StatusOK {
+		t.Errorf("owner read = %d, want 200", w.Code)
+	}
+}
+
+func TestUpdateTraineeIfMatch(t *testing.T) {
+	router := newRouter(t)
+	token := signIn(t, router, "coach@ironcoach.test")
+	id := addTrainee(t, router, token)
+
+	etag := request(router, http.MethodGet, "/trainees/"+id, "", token).Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET did not return an ETag")
+	}
+
+	if w := request(router, http.MethodPatch, "/trainees/"+id, `{"name":"Sam"}`, token); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("without If-Match = %d, want 428", w.Code)
+	}
+
+	w := request(router, http.MethodPatch, "/trainees/"+id, `{"name":"Sam"}`, token, "If-Match", etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("with current ETag = %d: %s", w.Code, w.Body.String())
+	}
+	next := w.Header().Get("ETag")
+	if next == "" || next == etag {
+		t.Errorf("ETag after update = %q, was %q", next, etag)
+	}
+
+	w = request(router, http.MethodPatch, "/trainees/"+id, `{"name":"Kim"}`, token, "If-Match", etag)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("with stale ETag = %d, want 412: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != next {
+		t.Errorf("conflict ETag = %q, want %q", w.Header().Get("ETag"), next)
+	}
+
+	var trainee struct {
+		Name string `json:"name"`
+	}
+	decode(t, request(router, http.MethodGet, "/trainees/"+id, "", token), &trainee)
+	if trainee.Name != "Sam" {
+		t.Errorf("name = %q, the stale update must not apply", trainee.Name)
+	}
+
+	if w := request(router, http.MethodDelete, "/trainees/"+id, "", token, "If-Match", etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("delete with stale ETag = %d, want 412", w.Code)
+	}
+	if w := request(router, http.MethodDelete, "/trainees/"+id, "", token, "If-Match", next); w.Code != http.StatusOK {
+		t.Errorf("delete with current ETag = %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAddWorkoutLogIgnoresServerFields(t *testing.T) {
+	router := newRouter(t)
+	token := signIn(t, router, "coach@ironcoach.test")
+	traineeID := addTrainee(t, router, token)
+
+	body := `{"id":"x","trainee_id":"` + traineeID + `","date":"2024-01-01","client_id":"c1","program_id":"p1","template_id":"t1",` +
+		`"deleted_at":"2024-01-02T00:00:00Z","workouts":[{"exercise":"Squat","sets":[{"reps":5,"weight":100}]}]}`
+	w := request(router, http.MethodPost, "/workout_logs", body, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("add workout log: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	decode(t, w, &created)
+	if created.ID == "x" || len(created.ID) != 24 {
+		t.Fatalf("id = %q, want a new ObjectID", created.ID)
+	}
+
+	w = request(router, http.MethodGet, "/workout_logs/log/"+created.ID, "", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get workout log: %d %s", w.Code, w.Body.String())
+	}
+	var stored map[string]interface{}
+	decode(t, w, &stored)
+	for _, field := range []string{"client_id", "program_id", "template_id", "deleted_at"} {
+		if value, ok := stored[field]; ok {
+			t.Errorf("%s = %v, the server owns it", field, value)
+		}
+	}
+}
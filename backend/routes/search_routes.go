@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"ironcoach/controllers"
+	"ironcoach/middlewares"
+	"ironcoach/services"
+	"ironcoach/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterSearchRoutes(router *gin.Engine, app *services.Services) {
+	searchController := controllers.NewSearchController(app.Search)
+	protected := router.Group("/search").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.RequireRole(utils.RoleTrainer))
+
+	protected.GET("", searchController.Search)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"ironcoach/controllers"
+	"ironcoach/middlewares"
+	"ironcoach/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Changes pushed through sync are audited by the service, one entry per record
+func RegisterSyncRoutes(router *gin.Engine, app *services.Services) {
+	syncController := controllers.NewSyncController(app.Sync)
+	protected := router.Group("/sync").Use(middlewares.AuthMiddleware(app.Sessions))
+
+	protected.POST("/push", syncController.Push)
+	protected.GET("/pull", syncController.Pull)
+}
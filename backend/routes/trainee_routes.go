@@ -4,17 +4,26 @@ import (
     "github.com/gin-gonic/gin"
     "ironcoach/controllers"
     "ironcoach/middlewares"
+    "ironcoach/services"
+    "ironcoach/utils"
 )
 
-func RegisterTraineeRoutes(router *gin.Engine) {
-    traineeController := controllers.NewTraineeController()
-    protected := router.Group("/trainees").Use(middlewares.AuthMiddleware())
+func RegisterTraineeRoutes(router *gin.Engine, app *services.Services) {
+    traineeController := controllers.NewTraineeController(app.Trainees, app.TraineeAccounts)
+    guard := middlewares.NewOwnershipGuard(app.Authorization)
+    ifMatch := middlewares.RequireIfMatch()
+    idempotent := middlewares.Idempotent(app.Idempotency)
+    protected := router.Group("/trainees").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.RequireRole(utils.RoleTrainer), middlewares.NewAuditor(app.Audit).Resource("trainees"))
 
-    protected.POST("", traineeController.AddTrainee)
-    protected.POST("/", traineeController.AddTrainee)
+    protected.POST("", idempotent, traineeController.AddTrainee)
+    protected.POST("/", idempotent, traineeController.AddTrainee)
     protected.GET("", traineeController.GetTrainees)
     protected.GET("/", traineeController.GetTrainees)
-    protected.GET("/:trainee_id", traineeController.GetTraineeByID)
-    protected.PUT("/:id", traineeController.UpdateTrainee)
-    protected.DELETE("/:id", traineeController.DeleteTrainee)
+    protected.GET("/:trainee_id", guard.Trainee("trainee_id"), traineeController.GetTraineeByID)
+    protected.PUT("/:id", guard.ManageTrainee("id"), ifMatch, traineeController.UpdateTrainee)
+    protected.PATCH("/:id", guard.ManageTrainee("id"), ifMatch, traineeController.UpdateTrainee)
+    protected.DELETE("/:id", guard.ManageTrainee("id"), ifMatch, traineeController.DeleteTrainee)
+    protected.POST("/:id/transfer", guard.ManageTrainee("id"), traineeController.TransferTrainee)
+    protected.POST("/:id/account", guard.ManageTrainee("id"), traineeController.IssueTraineeAccess)
+    protected.DELETE("/:id/account", guard.ManageTrainee("id"), traineeController.RevokeTraineeAccess)
 }
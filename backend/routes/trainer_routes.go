@@ -3,13 +3,15 @@ package routes
 import (
 	"ironcoach/controllers"
 	"ironcoach/middlewares"
+	"ironcoach/services"
+	"ironcoach/utils"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterTrainerRoutes(router *gin.Engine) {
-	trainerController := controllers.NewTrainerController()
+func RegisterTrainerRoutes(router *gin.Engine, app *services.Services) {
+	trainerController := controllers.NewTrainerController(app.Trainers)
 
 	// REGISTRATION - Strict anti-spam protection
 	registerGroup := router.Group("/")
@@ -27,7 +29,7 @@ func RegisterTrainerRoutes(router *gin.Engine) {
 	publicGroup.GET("/getTrainers", trainerController.GetTrainers)
 
 	// PROTECTED - Higher limits for authenticated users
-	protected := router.Group("/").Use(middlewares.AuthMiddleware())
+	protected := router.Group("/").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.RequireRole(utils.RoleTrainer), middlewares.NewAuditor(app.Audit).Resource("trainers"))
 	protected.Use(middlewares.RateLimitMiddleware(200, time.Minute, 30))
 	
 	protected.GET("/profile", func(c *gin.Context) {
@@ -36,5 +38,6 @@ func RegisterTrainerRoutes(router *gin.Engine) {
 	})
 
 	protected.GET("/getTrainerDetails", trainerController.GetTrainerDetails)
+	protected.PUT("/trainers/:email/time_zone", trainerController.SetTimeZone)
 	protected.DELETE("/deleteTrainer/:email", trainerController.DeleteTrainer)
 }
\ No newline at end of file
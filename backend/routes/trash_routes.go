@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ironcoach/controllers"
+	"ironcoach/middlewares"
+	"ironcoach/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trash kinds are trainees, workout_logs and diet_entries
+func RegisterTrashRoutes(router *gin.Engine, app *services.Services) {
+	trashController := controllers.NewTrashController(app.Trash)
+	protected := router.Group("/trash").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.NewAuditor(app.Audit).Trash())
+
+	protected.GET("", trashController.GetTrash)
+	protected.POST("/:kind/:id/restore", trashController.Restore)
+	protected.DELETE("/:kind/:id", trashController.Purge)
+}
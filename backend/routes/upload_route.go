@@ -3,10 +3,11 @@ package routes
 import (
     "github.com/gin-gonic/gin"
     "ironcoach/controllers"
+    "ironcoach/services"
 )
 
-func RegisterImageRoutes(router *gin.Engine) {
-    imageController := controllers.NewImageController()
+func RegisterImageRoutes(router *gin.Engine, app *services.Services) {
+    imageController := controllers.NewImageController(app.Images)
 
     protected := router.Group("/images") // Add authentication middleware if needed
     protected.POST("/upload", imageController.UploadImage)
@@ -4,17 +4,23 @@ import (
     "github.com/gin-gonic/gin"
     "ironcoach/controllers"
     "ironcoach/middlewares"
+    "ironcoach/services"
 )
 
-func RegisterWorkoutLogRoutes(router *gin.Engine) {
-    workoutLogController := controllers.NewWorkoutLogController()
+func RegisterWorkoutLogRoutes(router *gin.Engine, app *services.Services) {
+    workoutLogController := controllers.NewWorkoutLogController(app.WorkoutLogs)
+    guard := middlewares.NewOwnershipGuard(app.Authorization)
+    ifMatch := middlewares.RequireIfMatch()
+    idempotent := middlewares.Idempotent(app.Idempotency)
 
-    protected := router.Group("/workout_logs").Use(middlewares.AuthMiddleware())
+    protected := router.Group("/workout_logs").Use(middlewares.AuthMiddleware(app.Sessions), middlewares.NewAuditor(app.Audit).Resource("workout_logs"))
 
-    protected.POST("", workoutLogController.AddWorkoutLog)
-    protected.GET("/:trainee_id", workoutLogController.GetWorkoutLogs)
-	protected.PUT("/:log_id", workoutLogController.UpdateWorkoutLog)
-	protected.DELETE("/:log_id", workoutLogController.DeleteWorkoutLog)
-	protected.GET("/:trainee_id/progress", workoutLogController.GetTraineeProgress)
+    protected.POST("", guard.TraineeInBody(), idempotent, workoutLogController.AddWorkoutLog)
+    protected.GET("/:trainee_id", guard.Trainee("trainee_id"), workoutLogController.GetWorkoutLogs)
+	protected.GET("/log/:log_id", guard.WorkoutLog("log_id"), workoutLogController.GetWorkoutLogByID)
+	protected.PUT("/:log_id", guard.WorkoutLog("log_id"), ifMatch, workoutLogController.UpdateWorkoutLog)
+	protected.PATCH("/:log_id", guard.WorkoutLog("log_id"), ifMatch, workoutLogController.UpdateWorkoutLog)
+	protected.DELETE("/:log_id", guard.WorkoutLog("log_id"), ifMatch, workoutLogController.DeleteWorkoutLog)
+	protected.GET("/:trainee_id/progress", guard.Trainee("trainee_id"), workoutLogController.GetTraineeProgress)
 
 }
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"fmt"
+	"ironcoach/apperrors"
+	"ironcoach/mailer"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/utils"
+	"log"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+var (
+	ErrInvalidAccountToken = apperrors.Validation("invalid_account_token", "invalid or expired token")
+	ErrEmailNotVerified    = apperrors.Forbidden("email_not_verified", "email address has not been verified")
+)
+
+// AccountService handles password resets and email verification for trainers
+type AccountService struct {
+	trainerRepo repositories.TrainerRepository
+	tokenRepo   repositories.AccountTokenRepository
+	sessionRepo repositories.SessionRepository
+	mailer      mailer.Mailer
+	appBaseURL  string
+}
+
+// Constructor for AccountService
+func NewAccountService(repos repositories.Repositories, mail mailer.Mailer) *AccountService {
+	return &AccountService{
+		trainerRepo: repos.Trainers,
+		tokenRepo:   repos.AccountTokens,
+		sessionRepo: repos.Sessions,
+		mailer:      mail,
+		appBaseURL:  appBaseURL(),
+	}
+}
+
+// appBaseURL is where links sent to users point to
+func appBaseURL() string {
+	if baseURL := os.Getenv("APP_BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+	return "https://ironcoach.expo.app"
+}
+
+// RequireEmailVerification reports whether unverified trainers are refused at login
+func RequireEmailVerification() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+// SendVerificationEmail issues a new verification token, invalidating earlier ones
+func (s *AccountService) SendVerificationEmail(email string) error {
+	trainer, err := s.trainerRepo.FindByEmail(email)
+	if err != nil {
+		return err
+	}
+	if trainer.EmailVerified {
+		return nil
+	}
+
+	link, err := s.issueToken(email, models.TokenPurposeEmailVerification, emailVerificationTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Verify your IronCoach email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\n"+
+			"The link expires in 48 hours.\n", trainer.Name, link),
+	})
+}
+
+// VerifyEmail consumes a verification token and marks the trainer as verified
+func (s *AccountService) VerifyEmail(token string) error {
+	accountToken, err := s.consumeToken(token, models.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	return s.trainerRepo.SetEmailVerified(accountToken.Email)
+}
+
+// ForgotPassword emails a reset link if the trainer exists.
+// Unknown emails succeed silently so the endpoint cannot be used to discover accounts.
+func (s *AccountService) ForgotPassword(email string) error {
+	trainer, err := s.trainerRepo.FindByEmail(email)
+	if err != nil {
+		if err == ErrResourceNotFound {
+			return nil
+		}
+		return err
+	}
+
+	link, err := s.issueToken(trainer.Email, models.TokenPurposePasswordReset, passwordResetTTL, "/reset-password")
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      trainer.Email,
+		Subject: "Reset your IronCoach password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your IronCoach account. "+
+			"If it was you, open the link below to choose a new password:\n\n%s\n\n"+
+			"The link expires in one hour. If you did not ask for this, you can ignore this email.\n", trainer.Name, link),
+	})
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs out every session
+func (s *AccountService) ResetPassword(token string, newPassword string) error {
+	accountToken, err := s.consumeToken(token, models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.trainerRepo.UpdatePassword(accountToken.Email, hashedPassword); err != nil {
+		return err
+	}
+
+	// Receiving the reset email proves ownership of the address
+	if err := s.trainerRepo.SetEmailVerified(accountToken.Email); err != nil {
+		return err
+	}
+
+	return s.sessionRepo.RevokeSessionsByEmail(accountToken.Email)
+}
+
+// issueToken stores a new single-use token and returns the app link that carries it
+func (s *AccountService) issueToken(email string, purpose string, ttl time.Duration, path string) (string, error) {
+	if err := s.tokenRepo.InvalidateTokens(email, purpose); err != nil {
+		return "", err
+	}
+
+	tokenID, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := s.tokenRepo.CreateToken(models.AccountToken{
+		ID:        tokenID,
+		Email:     email,
+		Purpose:   purpose,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+
+	signed, err := utils.GenerateActionToken(email, purpose, tokenID, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	return s.appBaseURL + path + "?token=" + url.QueryEscape(signed), nil
+}
+
+// consumeToken verifies the signature and then marks the stored token as used
+func (s *AccountService) consumeToken(token string, purpose string) (models.AccountToken, error) {
+	claims, err := utils.VerifyActionToken(token, purpose)
+	if err != nil {
+		return models.AccountToken{}, ErrInvalidAccountToken
+	}
+
+	accountToken, err := s.tokenRepo.ConsumeToken(claims.ID, purpose)
+	if err != nil {
+		if err == ErrResourceNotFound {
+			return models.AccountToken{}, ErrInvalidAccountToken
+		}
+		return models.AccountToken{}, err
+	}
+	if accountToken.Email != claims.Email {
+		return models.AccountToken{}, ErrInvalidAccountToken
+	}
+
+	return accountToken, nil
+}
+
+// sendVerificationEmailAsync is used after registration, where a mail failure must not fail the request
+func (s *AccountService) sendVerificationEmailAsync(email string) {
+	go func() {
+		if err := s.SendVerificationEmail(email); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", email, err)
+		}
+	}()
+}
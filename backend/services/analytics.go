@@ -0,0 +1,308 @@
+package services
+
+import (
+	"ironcoach/models"
+	"math"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Days of training the acute and chronic loads of the workload ratio cover
+const (
+	acuteDays   = 7
+	chronicDays = 28
+)
+
+// Muscle group of the exercises logged without a link to the exercise library
+const uncategorized = "Uncategorized"
+
+// estimateOneRepMax estimates the one-rep max of a set. A single is its own max. Brzycki is undefined
+// from 37 reps, those sets fall back to Epley.
+func estimateOneRepMax(weight float64, reps int, formula string) float64 {
+	if reps <= 0 || weight <= 0 {
+		return 0
+	}
+	if reps == 1 {
+		return weight
+	}
+	if formula == models.FormulaBrzycki && reps < 37 {
+		return weight * 36 / float64(37-reps)
+	}
+	return weight * (1 + float64(reps)/30)
+}
+
+// workSets are the sets of a workout that count towards analytics and records, all but the warm-ups
+func workSets(workout models.Workout) []models.Set {
+	sets := make([]models.Set, 0, len(workout.Sets))
+	for _, set := range workout.Sets {
+		if set.Type != models.SetWarmUp {
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+// exerciseKey identifies an exercise across logs: by its ID, or by its name for those logged
+// without one
+func exerciseKey(workout models.Workout) string {
+	if !workout.ExerciseID.IsZero() {
+		return workout.ExerciseID.Hex()
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(workout.Exercise))
+}
+
+// bucketStart returns the first day of the bucket the date falls in
+func bucketStart(date models.Date, bucket string) models.Date {
+	switch bucket {
+	case models.BucketDay:
+		return date
+	case models.BucketMonth:
+		return models.NewDate(date.Year(), date.Month(), 1)
+	default:
+		return date.AddDays(-((int(date.Weekday()) + 6) % 7))
+	}
+}
+
+// nextBucket returns the first day of the bucket after the one starting on the date
+func nextBucket(start models.Date, bucket string) models.Date {
+	switch bucket {
+	case models.BucketDay:
+		return start.AddDays(1)
+	case models.BucketMonth:
+		return models.Date{Time: start.AddDate(0, 1, 0)}
+	default:
+		return start.AddDays(7)
+	}
+}
+
+// exerciseTotals accumulates the sets of one exercise
+type exerciseTotals struct {
+	progress models.ExerciseProgress
+	points   map[models.Date]*models.ExercisePoint
+}
+
+// groupTotals accumulates the sets of one muscle group
+type groupTotals struct {
+	volume models.MuscleGroupVolume
+	points map[models.Date]*models.VolumePoint
+}
+
+// analyzeWorkoutLogs works out the analytics of the work sets of workout logs sorted by date: per
+// exercise, per session, per bucket and per muscle group for the logs from the from date to the to date, and the workload
+// ratio at the end of each bucket, for which the logs of the 27 days before from count too. An open
+// from starts at the first log, an open to ends at the last. groups gives the muscle group of the
+// exercises by ID. The options must be valid.
+func analyzeWorkoutLogs(logs []models.WorkoutLog, groups map[primitive.ObjectID]string, from models.Date, to models.Date, options models.AnalyticsOptions) models.Progress {
+	if options.Bucket == "" {
+		options.Bucket = models.BucketWeek
+	}
+	if options.Formula == "" {
+		options.Formula = models.FormulaEpley
+	}
+	progress := models.Progress{
+		Exercises:    []models.ExerciseProgress{},
+		Sessions:     []models.SessionVolume{},
+		Volume:       []models.VolumePoint{},
+		MuscleGroups: []models.MuscleGroupVolume{},
+		Workload:     []models.WorkloadPoint{},
+		Bucket:       options.Bucket,
+		Formula:      options.Formula,
+	}
+	if len(logs) == 0 {
+		return progress
+	}
+	if from.IsZero() {
+		from = logs[0].Date
+	}
+	if to.IsZero() {
+		to = logs[len(logs)-1].Date
+	}
+
+	daily := map[models.Date]float64{}
+	volume := map[models.Date]*models.VolumePoint{}
+	exercises := map[string]*exerciseTotals{}
+	var exerciseOrder []string
+	muscleGroups := map[string]*groupTotals{}
+	for _, log := range logs {
+		if log.Date.IsZero() || log.Date.After(to.Time) {
+			continue
+		}
+		inRange := !log.Date.Before(from.Time)
+		bucket := bucketStart(log.Date, options.Bucket)
+		session := models.SessionVolume{LogID: log.ID, Date: log.Date}
+		trained := map[*groupTotals]bool{}
+
+		for _, workout := range log.Workouts {
+			counted := workSets(workout)
+			sets := len(counted)
+			var tonnage float64
+			for _, set := range counted {
+				tonnage += set.Weight * float64(set.Reps)
+			}
+			daily[log.Date] += tonnage
+			if !inRange || sets == 0 {
+				continue
+			}
+
+			key := exerciseKey(workout)
+			totals, ok := exercises[key]
+			if !ok {
+				group := uncategorized
+				if name, found := groups[workout.ExerciseID]; found && name != "" {
+					group = name
+				}
+				totals = &exerciseTotals{
+					progress: models.ExerciseProgress{MuscleGroup: group, Records: []models.ExerciseRecord{}},
+					points:   map[models.Date]*models.ExercisePoint{},
+				}
+				if !workout.ExerciseID.IsZero() {
+					totals.progress.ExerciseID = workout.ExerciseID.Hex()
+				}
+				exercises[key] = totals
+				exerciseOrder = append(exerciseOrder, key)
+			}
+			// Exercises renamed since are shown under their latest name
+			totals.progress.Exercise = workout.Exercise
+
+			point, ok := totals.points[bucket]
+			if !ok {
+				point = &models.ExercisePoint{Start: bucket}
+				totals.points[bucket] = point
+			}
+			var workoutReps int
+			for _, set := range counted {
+				weight, reps := set.Weight, set.Reps
+				workoutReps += reps
+				oneRepMax := estimateOneRepMax(weight, reps, options.Formula)
+				totals.progress.Records = append(totals.progress.Records, models.ExerciseRecord{
+					Date:         log.Date,
+					Weight:       weight,
+					Reps:         reps,
+					Estimated1RM: oneRepMax,
+				})
+				totals.progress.MaxWeight = math.Max(totals.progress.MaxWeight, weight)
+				totals.progress.Estimated1RM = math.Max(totals.progress.Estimated1RM, oneRepMax)
+				point.MaxWeight = math.Max(point.MaxWeight, weight)
+				point.Estimated1RM = math.Max(point.Estimated1RM, oneRepMax)
+			}
+			totals.progress.Tonnage += tonnage
+			totals.progress.Sets += sets
+			totals.progress.Reps += workoutReps
+			point.Tonnage += tonnage
+			point.Sets += sets
+			point.Reps += workoutReps
+
+			group, ok := muscleGroups[totals.progress.MuscleGroup]
+			if !ok {
+				group = &groupTotals{
+					volume: models.MuscleGroupVolume{MuscleGroup: totals.progress.MuscleGroup},
+					points: map[models.Date]*models.VolumePoint{},
+				}
+				muscleGroups[totals.progress.MuscleGroup] = group
+			}
+			group.volume.Tonnage += tonnage
+			group.volume.Sets += sets
+			sessions := 0
+			if !trained[group] {
+				trained[group] = true
+				sessions = 1
+			}
+			addVolume(group.points, bucket, tonnage, sets, workoutReps, sessions)
+
+			session.Tonnage += tonnage
+			session.Sets += sets
+			session.Reps += workoutReps
+		}
+
+		if inRange {
+			progress.Sessions = append(progress.Sessions, session)
+			addVolume(volume, bucket, session.Tonnage, session.Sets, session.Reps, 1)
+		}
+	}
+
+	for _, key := range exerciseOrder {
+		totals := exercises[key]
+		exercise := totals.progress
+		if exercise.Reps > 0 {
+			exercise.AvgWeight = exercise.Tonnage / float64(exercise.Reps)
+		}
+		exercise.AvgReps = float64(exercise.Reps) / float64(exercise.Sets)
+		exercise.Series = make([]models.ExercisePoint, 0, len(totals.points))
+		for _, point := range totals.points {
+			exercise.Series = append(exercise.Series, *point)
+		}
+		sort.Slice(exercise.Series, func(i, j int) bool { return exercise.Series[i].Start.Before(exercise.Series[j].Start.Time) })
+		progress.Exercises = append(progress.Exercises, exercise)
+	}
+	sort.SliceStable(progress.Exercises, func(i, j int) bool {
+		return strings.ToLower(progress.Exercises[i].Exercise) < strings.ToLower(progress.Exercises[j].Exercise)
+	})
+
+	for _, group := range muscleGroups {
+		group.volume.Series = volumeSeries(group.points, from, to, options.Bucket)
+		progress.MuscleGroups = append(progress.MuscleGroups, group.volume)
+	}
+	sort.Slice(progress.MuscleGroups, func(i, j int) bool {
+		if progress.MuscleGroups[i].Tonnage != progress.MuscleGroups[j].Tonnage {
+			return progress.MuscleGroups[i].Tonnage > progress.MuscleGroups[j].Tonnage
+		}
+		return progress.MuscleGroups[i].MuscleGroup < progress.MuscleGroups[j].MuscleGroup
+	})
+
+	progress.Volume = volumeSeries(volume, from, to, options.Bucket)
+	for start := bucketStart(from, options.Bucket); !start.After(to.Time); start = nextBucket(start, options.Bucket) {
+		end := nextBucket(start, options.Bucket).AddDays(-1)
+		if end.After(to.Time) {
+			end = to
+		}
+		progress.Workload = append(progress.Workload, workloadOn(daily, end))
+	}
+	return progress
+}
+
+// addVolume adds sets to the point of a bucket, creating it on the first sets
+func addVolume(points map[models.Date]*models.VolumePoint, bucket models.Date, tonnage float64, sets int, reps int, sessions int) {
+	point, ok := points[bucket]
+	if !ok {
+		point = &models.VolumePoint{Start: bucket}
+		points[bucket] = point
+	}
+	point.Tonnage += tonnage
+	point.Sets += sets
+	point.Reps += reps
+	point.Sessions += sessions
+}
+
+// volumeSeries lists the points of every bucket from the from date to the to date, those without
+// sets at zero so charts keep the gaps
+func volumeSeries(points map[models.Date]*models.VolumePoint, from models.Date, to models.Date, bucket string) []models.VolumePoint {
+	series := []models.VolumePoint{}
+	for start := bucketStart(from, bucket); !start.After(to.Time); start = nextBucket(start, bucket) {
+		if point, ok := points[start]; ok {
+			series = append(series, *point)
+		} else {
+			series = append(series, models.VolumePoint{Start: start})
+		}
+	}
+	return series
+}
+
+// workloadOn works out the acute and chronic loads of the days up to the date from the daily tonnage
+func workloadOn(daily map[models.Date]float64, date models.Date) models.WorkloadPoint {
+	point := models.WorkloadPoint{Date: date}
+	var chronic float64
+	for day := 0; day < chronicDays; day++ {
+		tonnage := daily[date.AddDays(-day)]
+		if day < acuteDays {
+			point.Acute += tonnage
+		}
+		chronic += tonnage
+	}
+	point.Chronic = chronic / (chronicDays / acuteDays)
+	if point.Chronic > 0 {
+		point.Ratio = math.Round(point.Acute/point.Chronic*100) / 100
+	}
+	return point
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/utils"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 500
+	redactedValue     = "[redacted]"
+)
+
+// auditedResources maps each audited collection to the field its route parameter matches
+var auditedResources = map[string]string{
+	"trainees":      "_id",
+	"workout_logs":  "_id",
+	"diet_entries":  "_id",
+	"progress":      "_id",
+	"categories":    "_id",
+	"exercises":     "_id",
+	"organizations": "_id",
+	"programs":      "_id",
+	"trainers":      "email",
+}
+
+// Fields every write touches, they would only clutter the changes
+var bookkeepingFields = map[string]bool{
+	"_id":            true,
+	"updated_at":     true,
+	"version":        true,
+	"sync_seq":       true,
+	"last_change_id": true,
+}
+
+// Secrets never make it into the audit log, only the fact that they changed
+var redactedFields = map[string]bool{
+	"password":      true,
+	"pin":           true,
+	"pin_hash":      true,
+	"refresh_token": true,
+}
+
+// AuditFilter narrows down the audit log. Zero values match everything.
+type AuditFilter struct {
+	TraineeID    string
+	ResourceType string
+	ResourceID   string
+	Actor        string
+	From         *time.Time
+	To           *time.Time
+	Limit        int64
+}
+
+// AuditService records who changed what and lets trainers look back through it
+type AuditService struct {
+	repository       repositories.AuditRepository
+	traineeRepo      repositories.TraineeRepository
+	organizationRepo repositories.OrganizationRepository
+	authorization    *AuthorizationService
+}
+
+// Constructor for AuditService
+func NewAuditService(repos repositories.Repositories, authorization *AuthorizationService) *AuditService {
+	return &AuditService{
+		repository:       repos.Audit,
+		traineeRepo:      repos.Trainees,
+		organizationRepo: repos.Organizations,
+		authorization:    authorization,
+	}
+}
+
+// Snapshot loads the stored state of a resource so it can be compared after the request.
+// It returns nil for resources that are not audited or do not exist (any more).
+func (s *AuditService) Snapshot(resourceType string, id string) (bson.M, error) {
+	key, ok := auditedResources[resourceType]
+	if !ok || id == "" {
+		return nil, nil
+	}
+
+	var value interface{} = id
+	if key == "_id" {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, nil
+		}
+		value = objectID
+	}
+
+	document, err := s.repository.GetDocument(resourceType, bson.M{key: value})
+	if err == ErrResourceNotFound {
+		return nil, nil
+	}
+	return document, err
+}
+
+// Record appends an entry with the fields that differ between before and after.
+// The trainee and organization the change belongs to are taken from the documents when not already set.
+func (s *AuditService) Record(entry models.AuditEntry, before bson.M, after bson.M) error {
+	if _, ok := auditedResources[entry.ResourceType]; !ok {
+		return nil
+	}
+
+	entry.Changes = diffDocuments(before, after)
+	entry.CreatedAt = time.Now()
+
+	switch entry.ResourceType {
+	case "trainees":
+		entry.TraineeID = entry.ResourceID
+	case "organizations":
+		entry.OrganizationID = entry.ResourceID
+	}
+	if entry.TraineeID == "" {
+		entry.TraineeID = documentField(before, after, "trainee_id")
+	}
+	if entry.OrganizationID == "" {
+		entry.OrganizationID = documentField(before, after, "organization_id")
+	}
+	if entry.OrganizationID == "" && entry.ResourceType == "exercises" {
+		// Exercises are shared through the library their category belongs to
+		if categoryID, ok := firstValue(before, after, "category_id").(primitive.ObjectID); ok {
+			if category, err := s.repository.GetDocument("categories", bson.M{"_id": categoryID}); err == nil {
+				entry.OrganizationID, _ = category["organization_id"].(string)
+			}
+		}
+	}
+
+	return s.repository.InsertEntry(entry)
+}
+
+// GetAuditLog lists the newest entries the trainer may see: changes they made themselves, changes to
+// their trainees and those shared with them, and, for owners and coaches, everything in their organizations.
+func (s *AuditService) GetAuditLog(identity utils.Identity, filter AuditFilter) ([]models.AuditEntry, error) {
+	query := bson.M{}
+	if filter.TraineeID != "" {
+		// Trashed trainees still have a history worth looking at
+		_, err := s.authorization.AuthorizeTrainee(identity, filter.TraineeID, AccessRead)
+		if err == ErrResourceNotFound {
+			_, err = s.authorization.AuthorizeDeletedTrainee(identity, filter.TraineeID, AccessRead)
+		}
+		if err != nil {
+			return nil, err
+		}
+		query["trainee_id"] = filter.TraineeID
+	} else {
+		scope, err := s.scope(identity.Email)
+		if err != nil {
+			return nil, err
+		}
+		query["$or"] = scope
+	}
+
+	if filter.ResourceType != "" {
+		query["resource_type"] = filter.ResourceType
+	}
+	if filter.ResourceID != "" {
+		query["resource_id"] = filter.ResourceID
+	}
+	if filter.Actor != "" {
+		query["actor_email"] = filter.Actor
+	}
+	if filter.From != nil || filter.To != nil {
+		createdAt := bson.M{}
+		if filter.From != nil {
+			createdAt["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			createdAt["$lte"] = *filter.To
+		}
+		query["created_at"] = createdAt
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	return s.repository.FindEntries(query, limit)
+}
+
+func (s *AuditService) scope(email string) ([]bson.M, error) {
+	scope := []bson.M{{"actor_email": email}}
+
+	organizationIDs, err := s.organizationRepo.GetOrganizationIDsByMember(email)
+	if err != nil {
+		return nil, err
+	}
+	traineeIDs, err := s.traineeRepo.GetAllTraineeIDs(email, organizationIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(traineeIDs) > 0 {
+		scope = append(scope, bson.M{"trainee_id": bson.M{"$in": traineeIDs}})
+	}
+
+	headOrganizationIDs, err := s.organizationRepo.GetOrganizationIDsByMember(email, models.OrgRoleOwner, models.OrgRoleCoach)
+	if err != nil {
+		return nil, err
+	}
+	if len(headOrganizationIDs) > 0 {
+		scope = append(scope, bson.M{"organization_id": bson.M{"$in": headOrganizationIDs}})
+	}
+	return scope, nil
+}
+
+// diffDocuments compares two documents field by field. A nil document stands for one that does not exist.
+func diffDocuments(before bson.M, after bson.M) map[string]models.AuditChange {
+	changes := map[string]models.AuditChange{}
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	for field := range fields {
+		// Bookkeeping fields change on every write, operator-like keys cannot be stored
+		if bookkeepingFields[field] || strings.HasPrefix(field, "$") || strings.Contains(field, ".") {
+			continue
+		}
+
+		oldValue, hadOld := before[field]
+		newValue, hasNew := after[field]
+		if hadOld == hasNew && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		if redactedFields[field] {
+			if hadOld {
+				oldValue = redactedValue
+			}
+			if hasNew {
+				newValue = redactedValue
+			}
+		}
+		changes[field] = models.AuditChange{Before: oldValue, After: newValue}
+	}
+	return changes
+}
+
+func documentField(before bson.M, after bson.M, field string) string {
+	value, _ := firstValue(before, after, field).(string)
+	return value
+}
+
+func firstValue(before bson.M, after bson.M, field string) interface{} {
+	if value, ok := after[field]; ok {
+		return value
+	}
+	return before[field]
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrResourceNotFound = repositories.ErrNotFound // The same error repositories report, services pass it on as it is
+	ErrAccessDenied     = apperrors.Forbidden("access_denied", "access denied")
+)
+
+// Access is the level of access a request needs on a resource
+type Access int
+
+const (
+	AccessRead   Access = iota // View the trainee, their logs, diet and progress
+	AccessWrite                // Record and edit logs, diet entries and progress
+	AccessManage               // Edit or delete the trainee itself, issue logins and transfer
+)
+
+// AuthorizationService resolves trainee-scoped resources back to the trainer that owns them.
+// Trainers may access their own trainees and those shared through their organizations,
+// trainees may only access themselves.
+type AuthorizationService struct {
+	traineeRepo      repositories.TraineeRepository
+	workoutLogRepo   repositories.WorkoutLogRepository
+	dietEntryRepo    repositories.DietEntryRepository
+	categoryRepo     repositories.CategoryRepository
+	exerciseRepo     repositories.ExerciseRepository
+	organizationRepo repositories.OrganizationRepository
+	programRepo      repositories.ProgramRepository
+}
+
+// Constructor for AuthorizationService
+func NewAuthorizationService(repos repositories.Repositories) *AuthorizationService {
+	return &AuthorizationService{
+		traineeRepo:      repos.Trainees,
+		workoutLogRepo:   repos.WorkoutLogs,
+		dietEntryRepo:    repos.DietEntries,
+		categoryRepo:     repos.Categories,
+		exerciseRepo:     repos.Exercises,
+		organizationRepo: repos.Organizations,
+		programRepo:      repos.Programs,
+	}
+}
+
+// AuthorizeTrainee checks that the trainee exists and is accessible to the caller
+func (s *AuthorizationService) AuthorizeTrainee(identity utils.Identity, traineeID string, access Access) (models.Trainee, error) {
+	var trainee models.Trainee
+	if !primitive.IsValidObjectID(traineeID) {
+		return trainee, ErrResourceNotFound
+	}
+
+	trainee, err := s.traineeRepo.GetTraineeByID(traineeID)
+	if err != nil {
+		return trainee, err
+	}
+	return trainee, s.checkTraineeAccess(identity, trainee, access)
+}
+
+// AuthorizeDeletedTrainee is AuthorizeTrainee for a trainee that is in the trash
+func (s *AuthorizationService) AuthorizeDeletedTrainee(identity utils.Identity, traineeID string, access Access) (models.Trainee, error) {
+	trainee, err := s.traineeRepo.GetDeletedTraineeByID(traineeID)
+	if err != nil {
+		return trainee, err
+	}
+	return trainee, s.checkTraineeAccess(identity, trainee, access)
+}
+
+// AuthorizeWorkoutLog checks that the log exists and its trainee is accessible to the caller.
+// It returns the ID of the trainee the log was recorded for.
+func (s *AuthorizationService) AuthorizeWorkoutLog(identity utils.Identity, logID string, access Access) (string, error) {
+	if !primitive.IsValidObjectID(logID) {
+		return "", ErrResourceNotFound
+	}
+
+	log, err := s.workoutLogRepo.GetWorkoutLogByID(logID)
+	if err != nil {
+		return "", err
+	}
+	return s.authorizeOwningTrainee(identity, log.TraineeID, access)
+}
+
+// AuthorizeDietEntry checks that the entry exists and its trainee is accessible to the caller.
+// It returns the ID of the trainee the entry was recorded for.
+func (s *AuthorizationService) AuthorizeDietEntry(identity utils.Identity, entryID string, access Access) (string, error) {
+	if !primitive.IsValidObjectID(entryID) {
+		return "", ErrResourceNotFound
+	}
+
+	entry, err := s.dietEntryRepo.GetDietEntryByID(entryID)
+	if err != nil {
+		return "", err
+	}
+	return s.authorizeOwningTrainee(identity, entry.TraineeID, access)
+}
+
+// AuthorizeCategory checks that the category exists and the trainer may use it.
+// Organization libraries are readable by every member and editable by owners and coaches.
+func (s *AuthorizationService) AuthorizeCategory(identity utils.Identity, categoryID string, access Access) (models.Category, error) {
+	var category models.Category
+	if !primitive.IsValidObjectID(categoryID) {
+		return category, ErrResourceNotFound
+	}
+
+	category, err := s.categoryRepo.GetCategoryByID(categoryID)
+	if err != nil {
+		return category, err
+	}
+
+	if identity.Role != utils.RoleTrainer {
+		return category, ErrAccessDenied
+	}
+	if category.OrganizationID == "" {
+		if category.TrainerID != identity.Email {
+			return category, ErrAccessDenied
+		}
+		return category, nil
+	}
+
+	granted, err := s.organizationAccess(identity.Email, category.OrganizationID)
+	if err != nil {
+		return category, err
+	}
+	if access > AccessRead && granted < AccessManage {
+		return category, ErrAccessDenied
+	}
+	return category, nil
+}
+
+// AuthorizeExercise checks that the exercise exists and the trainer may use its category
+func (s *AuthorizationService) AuthorizeExercise(identity utils.Identity, exerciseID string, access Access) (models.Exercise, error) {
+	var exercise models.Exercise
+	if !primitive.IsValidObjectID(exerciseID) {
+		return exercise, ErrResourceNotFound
+	}
+
+	exercise, err := s.exerciseRepo.GetExerciseByID(exerciseID)
+	if err != nil {
+		return exercise, err
+	}
+
+	if _, err := s.AuthorizeCategory(identity, exercise.CategoryID.Hex(), access); err != nil {
+		return exercise, err
+	}
+	return exercise, nil
+}
+
+// AuthorizeProgram checks that the program exists and the trainer wrote it
+func (s *AuthorizationService) AuthorizeProgram(identity utils.Identity, programID string) (models.Program, error) {
+	var program models.Program
+	if !primitive.IsValidObjectID(programID) {
+		return program, ErrResourceNotFound
+	}
+
+	program, err := s.programRepo.GetProgramByID(programID)
+	if err != nil {
+		return program, err
+	}
+	if identity.Role != utils.RoleTrainer || program.TrainerID != identity.Email {
+		return program, ErrAccessDenied
+	}
+	return program, nil
+}
+
+// AuthorizeOrganization checks that the trainer is a member of the organization with one of the given roles
+func (s *AuthorizationService) AuthorizeOrganization(email string, organizationID string, roles ...string) (models.Organization, error) {
+	var organization models.Organization
+	if !primitive.IsValidObjectID(organizationID) {
+		return organization, ErrResourceNotFound
+	}
+
+	organization, err := s.organizationRepo.GetOrganizationByID(organizationID)
+	if err != nil {
+		return organization, err
+	}
+
+	role := organization.MemberRole(email)
+	if role == "" {
+		// Non-members cannot tell an organization exists
+		return organization, ErrResourceNotFound
+	}
+	for _, allowed := range roles {
+		if role == allowed {
+			return organization, nil
+		}
+	}
+	if len(roles) == 0 {
+		return organization, nil
+	}
+	return organization, ErrAccessDenied
+}
+
+func (s *AuthorizationService) checkTraineeAccess(identity utils.Identity, trainee models.Trainee, access Access) error {
+	switch identity.Role {
+	case utils.RoleTrainer:
+		if trainee.TrainerID == identity.Email {
+			return nil
+		}
+		granted, err := s.organizationAccess(identity.Email, trainee.OrganizationID)
+		if err != nil {
+			return err
+		}
+		if access > granted {
+			return ErrAccessDenied
+		}
+	case utils.RoleTrainee:
+		if trainee.ID != identity.TraineeID || access > AccessWrite {
+			return ErrAccessDenied
+		}
+	default:
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// A log or entry whose trainee no longer exists is treated as not found
+func (s *AuthorizationService) authorizeOwningTrainee(identity utils.Identity, traineeID string, access Access) (string, error) {
+	if _, err := s.AuthorizeTrainee(identity, traineeID, access); err != nil {
+		return "", err
+	}
+	return traineeID, nil
+}
+
+// organizationAccess returns the access a trainer's membership grants, or ErrAccessDenied for non-members
+func (s *AuthorizationService) organizationAccess(email string, organizationID string) (Access, error) {
+	if organizationID == "" {
+		return AccessRead, ErrAccessDenied
+	}
+
+	organization, err := s.organizationRepo.GetOrganizationByID(organizationID)
+	if err != nil {
+		if err == ErrResourceNotFound {
+			return AccessRead, ErrAccessDenied
+		}
+		return AccessRead, err
+	}
+
+	switch organization.MemberRole(email) {
+	case models.OrgRoleOwner, models.OrgRoleCoach:
+		return AccessManage, nil
+	case models.OrgRoleAssistant:
+		return AccessWrite, nil
+	default:
+		return AccessRead, ErrAccessDenied
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"time"
+)
+
+var ErrInvalidTimeZone = apperrors.Validation("invalid_time_zone", "invalid time zone, expected an IANA name such as Europe/Berlin")
+
+// LoadTimeZone resolves an IANA time zone name. The empty name is UTC.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	// "Local" would be wherever the server happens to run
+	if name == "Local" {
+		return nil, ErrInvalidTimeZone
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+	return location, nil
+}
+
+// calendar works out what day it is for a trainee. Trainees live by the time zone of their trainer,
+// so a trainer and their trainees agree on what today is wherever the server runs.
+type calendar struct {
+	traineeRepo repositories.TraineeRepository
+	trainerRepo repositories.TrainerRepository
+}
+
+func newCalendar(repos repositories.Repositories) *calendar {
+	return &calendar{
+		traineeRepo: repos.Trainees,
+		trainerRepo: repos.Trainers,
+	}
+}
+
+// today returns the current date in the time zone of the trainee's trainer
+func (c *calendar) today(traineeID string) (models.Date, error) {
+	trainee, err := c.traineeRepo.GetTraineeByID(traineeID)
+	if err != nil {
+		return models.Date{}, err
+	}
+	return c.trainerToday(trainee.TrainerID)
+}
+
+// trainerToday returns the current date in the time zone of the trainer. Trainers that cannot be
+// found or whose time zone no longer loads are on UTC.
+func (c *calendar) trainerToday(trainerID string) (models.Date, error) {
+	trainer, err := c.trainerRepo.FindByEmail(trainerID)
+	if err != nil && err != ErrResourceNotFound {
+		return models.Date{}, err
+	}
+	location, err := LoadTimeZone(trainer.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	return models.Today(location), nil
+}
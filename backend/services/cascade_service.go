@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"ironcoach/models"
+	"ironcoach/repositories"
+)
+
+// CascadeService deletes trainers, trainees and categories together with everything that
+// hangs off them. Each cascade runs in a single transaction, so a failure part way
+// leaves the data untouched instead of orphaned.
+type CascadeService struct {
+	trainerRepo      repositories.TrainerRepository
+	traineeRepo      repositories.TraineeRepository
+	categoryRepo     repositories.CategoryRepository
+	exerciseRepo     repositories.ExerciseRepository
+	workoutLogRepo   repositories.WorkoutLogRepository
+	dietEntryRepo    repositories.DietEntryRepository
+	progressRepo     repositories.ProgressRepository
+	accountRepo      repositories.TraineeAccountRepository
+	sessionRepo      repositories.SessionRepository
+	tokenRepo        repositories.AccountTokenRepository
+	organizationRepo repositories.OrganizationRepository
+	programRepo      repositories.ProgramRepository
+	recordRepo       repositories.PersonalRecordRepository
+	transactions     repositories.Transactor
+}
+
+// Constructor for CascadeService
+func NewCascadeService(repos repositories.Repositories) *CascadeService {
+	return &CascadeService{
+		trainerRepo:      repos.Trainers,
+		traineeRepo:      repos.Trainees,
+		categoryRepo:     repos.Categories,
+		exerciseRepo:     repos.Exercises,
+		workoutLogRepo:   repos.WorkoutLogs,
+		dietEntryRepo:    repos.DietEntries,
+		progressRepo:     repos.Progress,
+		accountRepo:      repos.TraineeAccounts,
+		sessionRepo:      repos.Sessions,
+		tokenRepo:        repos.AccountTokens,
+		organizationRepo: repos.Organizations,
+		programRepo:      repos.Programs,
+		recordRepo:       repos.PersonalRecords,
+		transactions:     repos.Transactions,
+	}
+}
+
+// DeleteTrainer removes a trainer with their personal trainees, exercise libraries, programs,
+// sessions and account tokens. Trainees and libraries shared through an organization
+// stay with the organization, trainees following one of the programs stop following it.
+func (s *CascadeService) DeleteTrainer(email string) (models.DeletionSummary, error) {
+	var summary models.DeletionSummary
+	err := s.transactions.WithTransaction(func(ctx context.Context) error {
+		summary = models.DeletionSummary{} // The transaction may be retried
+		traineeIDs, err := s.traineeRepo.GetTraineeIDsByTrainer(ctx, email)
+		if err != nil {
+			return err
+		}
+		if err := s.deleteTrainees(ctx, traineeIDs, summary); err != nil {
+			return err
+		}
+
+		categoryIDs, err := s.categoryRepo.GetCategoryIDsByTrainer(ctx, email)
+		if err != nil {
+			return err
+		}
+		if err := s.deleteCategories(ctx, categoryIDs, summary); err != nil {
+			return err
+		}
+
+		programIDs, err := s.programRepo.GetProgramIDsByTrainer(ctx, email)
+		if err != nil {
+			return err
+		}
+		deleted, err := s.programRepo.DeleteAssignmentsByPrograms(ctx, programIDs)
+		if err != nil {
+			return err
+		}
+		summary.Add("program_assignments", deleted)
+		if deleted, err = s.programRepo.DeletePrograms(ctx, programIDs); err != nil {
+			return err
+		}
+		summary.Add("programs", deleted)
+
+		if err := s.organizationRepo.RemoveMemberFromAll(ctx, email); err != nil {
+			return err
+		}
+
+		deleted, err = s.trainerRepo.DeleteTrainer(ctx, email)
+		if err != nil {
+			return err
+		}
+		summary.Add("trainers", deleted)
+
+		// Drop the trainer's sessions so outstanding tokens stop working immediately
+		if deleted, err = s.sessionRepo.DeleteSessionsByEmail(ctx, email); err != nil {
+			return err
+		}
+		summary.Add("sessions", deleted)
+
+		if deleted, err = s.tokenRepo.DeleteTokensByEmail(ctx, email); err != nil {
+			return err
+		}
+		summary.Add("account_tokens", deleted)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// DeleteTrainees removes trainees with their workout logs, diet entries, weight records, program and logins
+func (s *CascadeService) DeleteTrainees(ids []string) (models.DeletionSummary, error) {
+	var summary models.DeletionSummary
+	err := s.transactions.WithTransaction(func(ctx context.Context) error {
+		summary = models.DeletionSummary{} // The transaction may be retried
+		return s.deleteTrainees(ctx, ids, summary)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// DeleteCategory removes a category with its exercises and their entries in workout logs
+func (s *CascadeService) DeleteCategory(id string) (models.DeletionSummary, error) {
+	var summary models.DeletionSummary
+	err := s.transactions.WithTransaction(func(ctx context.Context) error {
+		summary = models.DeletionSummary{} // The transaction may be retried
+		return s.deleteCategories(ctx, []string{id}, summary)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (s *CascadeService) deleteTrainees(ctx context.Context, traineeIDs []string, summary models.DeletionSummary) error {
+	steps := []struct {
+		collection string
+		delete     func(context.Context, []string) (int64, error)
+	}{
+		{"workout_logs", s.workoutLogRepo.DeleteWorkoutLogsByTrainees},
+		{"personal_records", s.recordRepo.DeletePersonalRecordsByTrainees},
+		{"diet_entries", s.dietEntryRepo.DeleteDietEntriesByTrainees},
+		{"progress", s.progressRepo.DeleteProgressByTrainees},
+		{"program_assignments", s.programRepo.DeleteAssignmentsByTrainees},
+		{"trainee_accounts", s.accountRepo.DeleteAccountsByTrainees},
+		{"sessions", s.sessionRepo.DeleteSessionsByTrainees},
+		{"trainees", s.traineeRepo.DeleteTrainees},
+	}
+
+	for _, step := range steps {
+		deleted, err := step.delete(ctx, traineeIDs)
+		if err != nil {
+			return err
+		}
+		summary.Add(step.collection, deleted)
+	}
+	return nil
+}
+
+func (s *CascadeService) deleteCategories(ctx context.Context, categoryIDs []string, summary models.DeletionSummary) error {
+	exerciseIDs, err := s.exerciseRepo.GetExerciseIDsByCategories(ctx, categoryIDs)
+	if err != nil {
+		return err
+	}
+
+	// Only the entries for these exercises go, the rest of each workout log is kept
+	if _, err := s.workoutLogRepo.PullExercises(ctx, exerciseIDs); err != nil {
+		return err
+	}
+	deleted, err := s.recordRepo.DeletePersonalRecordsByExercises(ctx, exerciseIDs)
+	if err != nil {
+		return err
+	}
+	summary.Add("personal_records", deleted)
+
+	if deleted, err = s.exerciseRepo.DeleteExercisesByCategories(ctx, categoryIDs); err != nil {
+		return err
+	}
+	summary.Add("exercises", deleted)
+
+	if deleted, err = s.categoryRepo.DeleteCategories(ctx, categoryIDs); err != nil {
+		return err
+	}
+	summary.Add("categories", deleted)
+	return nil
+}
@@ -1,53 +1,90 @@
 package services
 
 import (
-	"errors"
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/repositories"
+	"ironcoach/utils"
 	"time"
 )
 
+var ErrCategoryExists = apperrors.Conflict("category_exists", "category already exists for this trainer")
+
 type CategoryService struct {
-	repository   *repositories.CategoryRepository
-	exerciseRepo *repositories.ExerciseRepository
+	repository       repositories.CategoryRepository
+	traineeRepo      repositories.TraineeRepository
+	organizationRepo repositories.OrganizationRepository
+	authorization    *AuthorizationService
+	cascade          *CascadeService
 }
 
 // Constructor for CategoryService
-func NewCategoryService() *CategoryService {
+func NewCategoryService(repos repositories.Repositories, authorization *AuthorizationService, cascade *CascadeService) *CategoryService {
 	return &CategoryService{
-		repository:   repositories.NewCategoryRepository(),
-		exerciseRepo: repositories.NewExerciseRepository(),
+		repository:       repos.Categories,
+		traineeRepo:      repos.Trainees,
+		organizationRepo: repos.Organizations,
+		authorization:    authorization,
+		cascade:          cascade,
 	}
 }
 
+// AddCategory adds a category to the trainer's library, or to the organization's
+// library when OrganizationID is set (owners and coaches only)
 func (s *CategoryService) AddCategory(category models.Category) error {
-	exists, err := s.repository.IsCategoryExists(category.Name, category.TrainerID)
+	if category.OrganizationID != "" {
+		if _, err := s.authorization.AuthorizeOrganization(category.TrainerID, category.OrganizationID, models.OrgRoleOwner, models.OrgRoleCoach); err != nil {
+			return err
+		}
+	}
+
+	exists, err := s.repository.IsCategoryExists(category.Name, category.TrainerID, category.OrganizationID)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return errors.New("category already exists for this trainer")
+		return ErrCategoryExists
 	}
 	category.CreatedAt = time.Now()
-	return s.repository.AddCategory(category)
+	// The check above misses a category added concurrently, the unique index catches it
+	if err := s.repository.AddCategory(category); err != nil {
+		if err == repositories.ErrDuplicateKey {
+			return ErrCategoryExists
+		}
+		return err
+	}
+	return nil
 }
 
-func (s *CategoryService) GetCategories(trainerID string) ([]models.Category, error) {
-	return s.repository.GetCategories(trainerID)
+// GetCategories returns every category visible to the caller
+func (s *CategoryService) GetCategories(identity utils.Identity) ([]models.Category, error) {
+	trainerID, organizationIDs, err := s.libraryScope(identity)
+	if err != nil {
+		return nil, err
+	}
+	return s.repository.GetCategories(trainerID, organizationIDs)
 }
 
-func (s *CategoryService) UpdateCategory(id string, updatedName string, trainerID string) error {
-	// Check if category name already exists for this trainer
-	exists, err := s.repository.IsCategoryExists(updatedName, trainerID)
+func (s *CategoryService) UpdateCategory(id string, updatedName string) error {
+	category, err := s.repository.GetCategoryByID(id)
+	if err != nil {
+		return err
+	}
+
+	// Check if category name already exists in the same library
+	exists, err := s.repository.IsCategoryExists(updatedName, category.TrainerID, category.OrganizationID)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return errors.New("category already exists for this trainer")
+		return ErrCategoryExists
 	}
 
 	// Cascade update in exercises
 	if err := s.repository.UpdateCategory(id, updatedName); err != nil {
+		if err == repositories.ErrDuplicateKey {
+			return ErrCategoryExists
+		}
 		return err
 	}
 
@@ -59,16 +96,28 @@ func (s *CategoryService) UpdateCategory(id string, updatedName string, trainerI
 	return nil
 }
 
-func (s *CategoryService) DeleteCategory(id string) error {
-	// Retrieve category name before deletion
-	category, err := s.repository.GetCategoryByID(id)
-	if err != nil {
-		return err
-	}
-	if err := s.repository.DeleteCategory(id); err != nil {
-		return err
+// DeleteCategory deletes a category with its exercises and their entries in workout logs in one transaction
+func (s *CategoryService) DeleteCategory(id string) (models.DeletionSummary, error) {
+	return s.cascade.DeleteCategory(id)
+}
+
+// libraryScope resolves whose libraries the caller sees. Trainers see their own library and
+// those of their organizations, trainees see their trainer's and their organization's.
+func (s *CategoryService) libraryScope(identity utils.Identity) (string, []string, error) {
+	if identity.Role == utils.RoleTrainee {
+		trainee, err := s.traineeRepo.GetTraineeByID(identity.TraineeID)
+		if err != nil {
+			return "", nil, err
+		}
+		if trainee.OrganizationID == "" {
+			return trainee.TrainerID, nil, nil
+		}
+		return trainee.TrainerID, []string{trainee.OrganizationID}, nil
 	}
 
-	// Cascade delete exercises
-	return s.exerciseRepo.DeleteExercisesByCategoryID(category.ID.Hex())
+	organizationIDs, err := s.organizationRepo.GetOrganizationIDsByMember(identity.Email)
+	if err != nil {
+		return "", nil, err
+	}
+	return identity.Email, organizationIDs, nil
 }
@@ -7,20 +7,30 @@ import (
 )
 
 type DietEntryService struct {
-	repository *repositories.DietEntryRepository
+	repository repositories.DietEntryRepository
+	calendar   *calendar
 }
 
 // Constructor for DietEntryService
-func NewDietEntryService() *DietEntryService {
+func NewDietEntryService(repos repositories.Repositories) *DietEntryService {
 	return &DietEntryService{
-		repository: repositories.NewDietEntryRepository(),
+		repository: repos.DietEntries,
+		calendar:   newCalendar(repos),
 	}
 }
 
-// Add a new diet entry
+// Add a new diet entry, dated today where the trainer is unless it has a date
 func (s *DietEntryService) AddDietEntry(entry models.DietEntry) error {
+	if entry.Date.IsZero() {
+		var err error
+		if entry.Date, err = s.calendar.today(entry.TraineeID); err != nil {
+			return err
+		}
+	}
+
 	entry.CreatedAt = time.Now()
 	entry.UpdatedAt = time.Now()
+	entry.Version = 1
 
 	// Calculate calories and proteins for each meal
     calculateMealStats(&entry)
@@ -28,9 +38,10 @@ func (s *DietEntryService) AddDietEntry(entry models.DietEntry) error {
 	return s.repository.CreateDietEntry(entry)
 }
 
-// Get all diet entries for a trainee
-func (s *DietEntryService) GetDietEntriesByTrainee(traineeID string, date string) ([]models.DietEntry, error) {
-	return s.repository.GetDietEntriesByTrainee(traineeID, date)
+// Get a page of the diet entries of a trainee
+func (s *DietEntryService) GetDietEntriesByTrainee(traineeID string, list models.ListOptions) (models.Page, error) {
+	entries, next, err := s.repository.GetDietEntriesByTrainee(traineeID, list)
+	return models.Page{Items: entries, NextCursor: next}, err
 }
 
 // Get diet entry by ID
@@ -38,8 +49,8 @@ func (s *DietEntryService) GetDietEntryByID(entryID string) (models.DietEntry, e
 	return s.repository.GetDietEntryByID(entryID)
 }
 
-// Update a diet entry
-func (s *DietEntryService) UpdateDietEntry(entryID string, updateData models.DietEntry) error {
+// Update a diet entry that is still at the given version
+func (s *DietEntryService) UpdateDietEntry(entryID string, version int64, updateData models.DietEntry) error {
 
 	// Calculate calories and proteins for each meal
     calculateMealStats(&updateData)
@@ -47,12 +58,12 @@ func (s *DietEntryService) UpdateDietEntry(entryID string, updateData models.Die
 	updateData.UpdatedAt = time.Now()
 
     // Call the repository to update the entry
-    return s.repository.UpdateDietEntry(entryID, updateData)
+    return s.repository.UpdateDietEntry(entryID, version, updateData)
 }
 
-// Delete a diet entry
-func (s *DietEntryService) DeleteDietEntry(entryID string) error {
-	return s.repository.DeleteDietEntry(entryID)
+// Move a diet entry that is still at the given version to the trash
+func (s *DietEntryService) DeleteDietEntry(entryID string, version int64) error {
+	return s.repository.SoftDeleteDietEntry(entryID, version)
 }
 
 
@@ -1,22 +1,53 @@
 package services
 
 import (
-	"errors"
+	"context"
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/repositories"
+	"ironcoach/utils"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ways of deleting an exercise that is already used in workout logs
+const (
+	ExerciseDeleteArchive = "archive" // Hide the exercise from the library, logged history stays as it is
+	ExerciseDeletePull    = "pull"    // Delete the exercise and remove only its entries from workout logs
+)
+
+var (
+	ErrInvalidExerciseDeleteMode = apperrors.Validation("invalid_delete_mode", "mode must be archive or pull")
+	ErrCategoryNotFound          = apperrors.NotFound("category_not_found", "category does not exist")
+	ErrExerciseExists            = apperrors.Conflict("exercise_exists", "exercise already exists in this category")
 )
 
+// ExerciseDeletion reports what deleting an exercise touched
+type ExerciseDeletion struct {
+	Mode        string `json:"mode"`
+	WorkoutLogs int64  `json:"workout_logs"` // Logs containing the exercise (kept when archiving, changed when pulling)
+	Sets        int64  `json:"sets"`         // Sets recorded for the exercise in those logs
+}
+
 type ExerciseService struct {
-	repository   *repositories.ExerciseRepository
-	categoryRepo *repositories.CategoryRepository
+	repository     repositories.ExerciseRepository
+	categoryRepo   repositories.CategoryRepository
+	workoutLogRepo repositories.WorkoutLogRepository
+	recordRepo     repositories.PersonalRecordRepository
+	transactions   repositories.Transactor
+	categories     *CategoryService
 }
 
 // Constructor for ExerciseService
-func NewExerciseService() *ExerciseService {
+func NewExerciseService(repos repositories.Repositories, categories *CategoryService) *ExerciseService {
 	return &ExerciseService{
-		repository:   repositories.NewExerciseRepository(),
-		categoryRepo: repositories.NewCategoryRepository(),
+		repository:     repos.Exercises,
+		categoryRepo:   repos.Categories,
+		workoutLogRepo: repos.WorkoutLogs,
+		recordRepo:     repos.PersonalRecords,
+		transactions:   repos.Transactions,
+		categories:     categories,
 	}
 }
 
@@ -24,15 +55,15 @@ func (s *ExerciseService) AddExercise(exercise models.Exercise) error {
 	// Get category to find trainer ID
 	category, err := s.categoryRepo.GetCategoryByID(exercise.CategoryID.Hex())
 	if err != nil {
-		return errors.New("category does not exist")
+		return ErrCategoryNotFound
 	}
 
-	exists, err := s.categoryRepo.IsCategoryExists(exercise.Category, category.TrainerID)
+	exists, err := s.categoryRepo.IsCategoryExists(exercise.Category, category.TrainerID, category.OrganizationID)
 	if err != nil {
 		return err
 	}
 	if !exists {
-		return errors.New("category does not exist")
+		return ErrCategoryNotFound
 	}
 
 	// Check if the exercise already exists
@@ -41,12 +72,18 @@ func (s *ExerciseService) AddExercise(exercise models.Exercise) error {
 		return err
 	}
 	if duplicate {
-		return errors.New("exercise already exists in this category")
+		return ErrExerciseExists
 	}
 
 	exercise.CreatedAt = time.Now()
-	// Add exercise to the database
-	return s.repository.AddExercise(exercise)
+	// Add exercise to the database, the unique index catches one added since the check above
+	if err := s.repository.AddExercise(exercise); err != nil {
+		if err == repositories.ErrDuplicateKey {
+			return ErrExerciseExists
+		}
+		return err
+	}
+	return nil
 
 }
 
@@ -54,8 +91,8 @@ func (s *ExerciseService) GetExercisesByCategoryID(categoryID string) ([]models.
 	return s.repository.GetExercisesByCategoryID(categoryID)
 }
 
-func (s *ExerciseService) GetCategoryIDByName(categoryName string, trainerEmail string) (string, error) {
-	categories, err := s.categoryRepo.GetCategories(trainerEmail)
+func (s *ExerciseService) GetCategoryIDByName(categoryName string, identity utils.Identity) (string, error) {
+	categories, err := s.categories.GetCategories(identity)
 	if err != nil {
 		return "", err
 	}
@@ -66,7 +103,7 @@ func (s *ExerciseService) GetCategoryIDByName(categoryName string, trainerEmail
 		}
 	}
 
-	return "", errors.New("category not found")
+	return "", ErrCategoryNotFound
 }
 
 func (s *ExerciseService) UpdateExercise(id string, updatedName string) error {
@@ -82,24 +119,55 @@ func (s *ExerciseService) UpdateExercise(id string, updatedName string) error {
 		return err
 	}
 	if exists {
-		return errors.New("exercise already exists in this category")
+		return ErrExerciseExists
 	}
 
 	if err := s.repository.UpdateExercise(id, updatedName); err != nil {
+		if err == repositories.ErrDuplicateKey {
+			return ErrExerciseExists
+		}
 		return err
 	}
 
 	// Cascade update in workout logs
-	// s.repository.MigrateWorkoutLogs()
 	return s.repository.CascadeUpdateExerciseInWorkoutLogs(id, updatedName)
 }
 
-func (s *ExerciseService) DeleteExercise(id string) error {
-	// Delete the exercise from the exercises collection
-	if err := s.repository.DeleteExercise(id); err != nil {
-		return err
+// DeleteExercise archives the exercise or removes it together with its entries in workout logs.
+// Other exercises logged on the same days are never touched.
+func (s *ExerciseService) DeleteExercise(id string, mode string) (ExerciseDeletion, error) {
+	deletion := ExerciseDeletion{Mode: mode}
+	exerciseID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return deletion, err
 	}
+	exerciseIDs := []primitive.ObjectID{exerciseID}
 
-	// Cascade delete all logs containing this exercise
-	return s.repository.CascadeDeleteExerciseFromWorkoutLogs(id)
+	switch mode {
+	case ExerciseDeleteArchive:
+		deletion.WorkoutLogs, deletion.Sets, err = s.workoutLogRepo.CountExerciseUsage(context.Background(), exerciseIDs)
+		if err != nil {
+			return deletion, err
+		}
+		return deletion, s.repository.ArchiveExercise(id)
+
+	case ExerciseDeletePull:
+		err = s.transactions.WithTransaction(func(ctx context.Context) error {
+			if _, deletion.Sets, err = s.workoutLogRepo.CountExerciseUsage(ctx, exerciseIDs); err != nil {
+				return err
+			}
+			if deletion.WorkoutLogs, err = s.workoutLogRepo.PullExercises(ctx, exerciseIDs); err != nil {
+				return err
+			}
+			// The records of the exercise were made with the sets just removed
+			if _, err = s.recordRepo.DeletePersonalRecordsByExercises(ctx, exerciseIDs); err != nil {
+				return err
+			}
+			return s.repository.DeleteExercise(ctx, id)
+		})
+		return deletion, err
+
+	default:
+		return deletion, ErrInvalidExerciseDeleteMode
+	}
 }
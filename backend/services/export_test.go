@@ -0,0 +1,4 @@
+package services
+
+// Refresh lets tests run what a write to a workout log runs
+var Refresh = (*PersonalRecordService).refresh
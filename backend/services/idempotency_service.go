@@ -0,0 +1,109 @@
+package services
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/utils"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultIdempotencyTTL      = 24 * time.Hour
+	idempotencyCleanupInterval = time.Hour
+)
+
+var (
+	ErrIdempotencyKeyReused     = apperrors.Validation("idempotency_key_reused", "Idempotency-Key has already been used with a different request")
+	ErrIdempotencyKeyInProgress = apperrors.Conflict("idempotency_key_in_progress", "a request with this Idempotency-Key is still being processed")
+)
+
+// IdempotencyTTL is how long a key and its response are kept, set in hours with IDEMPOTENCY_KEY_TTL_HOURS
+func IdempotencyTTL() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"))
+	if err != nil || hours <= 0 {
+		return defaultIdempotencyTTL
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// IdempotencyService remembers the responses to requests sent with an Idempotency-Key, so a client
+// retrying after a dropped connection gets the original response instead of a duplicate
+type IdempotencyService struct {
+	repository repositories.IdempotencyRepository
+}
+
+// Constructor for IdempotencyService
+func NewIdempotencyService(repos repositories.Repositories) *IdempotencyService {
+	return &IdempotencyService{
+		repository: repos.Idempotency,
+	}
+}
+
+// Begin claims the key for a request. Keys are scoped to the caller and the route. It returns replay=true
+// with the stored record when the same request already completed, and ErrIdempotencyKeyReused when the
+// key was used with a different body.
+func (s *IdempotencyService) Begin(actor string, route string, key string, body []byte) (record models.IdempotencyRecord, replay bool, err error) {
+	now := time.Now()
+	record = models.IdempotencyRecord{
+		ID:          utils.HashToken(actor + "\n" + route + "\n" + key),
+		Key:         key,
+		Actor:       actor,
+		Route:       route,
+		RequestHash: utils.HashToken(string(body)),
+		State:       models.IdempotencyPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(IdempotencyTTL()),
+	}
+
+	claimed, err := s.repository.ClaimRecord(record)
+	if err != nil || claimed {
+		return record, false, err
+	}
+
+	stored, err := s.repository.GetRecord(record.ID)
+	if err == ErrResourceNotFound {
+		// Expired or released between the claim and now, the client can simply retry
+		return record, false, ErrIdempotencyKeyInProgress
+	}
+	if err != nil {
+		return record, false, err
+	}
+	switch {
+	case stored.RequestHash != record.RequestHash:
+		return stored, false, ErrIdempotencyKeyReused
+	case stored.State != models.IdempotencyCompleted:
+		return stored, false, ErrIdempotencyKeyInProgress
+	}
+	return stored, true, nil
+}
+
+// Finish stores the response of a claimed request. Server errors are not stored, the key is freed
+// instead so the client can retry.
+func (s *IdempotencyService) Finish(id string, status int, contentType string, body []byte) error {
+	if status >= http.StatusInternalServerError {
+		return s.repository.ReleaseRecord(id)
+	}
+	return s.repository.CompleteRecord(id, status, contentType, body)
+}
+
+// StartCleanup deletes expired keys in the background every hour
+func (s *IdempotencyService) StartCleanup() {
+	go func() {
+		ticker := time.NewTicker(idempotencyCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			if deleted, err := s.repository.DeleteExpiredRecords(); err != nil {
+				log.Printf("Failed to delete expired idempotency keys: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Deleted %d expired idempotency keys", deleted)
+			}
+			<-ticker.C
+		}
+	}()
+}
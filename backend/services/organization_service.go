@@ -0,0 +1,142 @@
+package services
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidOrganizationRole = apperrors.Validation("invalid_role", "role must be owner, coach or assistant")
+	ErrTrainerNotFound         = apperrors.NotFound("trainer_not_found", "trainer not found")
+	ErrAlreadyMember           = apperrors.Conflict("already_member", "trainer is already a member")
+	ErrLastOwner               = apperrors.Conflict("last_owner", "an organization must keep at least one owner")
+)
+
+// OrganizationService manages organizations and their members
+type OrganizationService struct {
+	repository  repositories.OrganizationRepository
+	trainerRepo repositories.TrainerRepository
+}
+
+// Constructor for OrganizationService
+func NewOrganizationService(repos repositories.Repositories) *OrganizationService {
+	return &OrganizationService{
+		repository:  repos.Organizations,
+		trainerRepo: repos.Trainers,
+	}
+}
+
+// CreateOrganization creates an organization with the trainer as its first owner
+func (s *OrganizationService) CreateOrganization(name string, ownerEmail string) (models.Organization, error) {
+	now := time.Now()
+	organization := models.Organization{
+		ID:   primitive.NewObjectID(),
+		Name: name,
+		Members: []models.OrganizationMember{
+			{Email: ownerEmail, Role: models.OrgRoleOwner, JoinedAt: now},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.repository.CreateOrganization(organization); err != nil {
+		return models.Organization{}, err
+	}
+	return organization, nil
+}
+
+// Get the organizations a trainer is a member of
+func (s *OrganizationService) GetOrganizations(email string) ([]models.Organization, error) {
+	return s.repository.GetOrganizationsByMember(email)
+}
+
+// Get an organization by ID
+func (s *OrganizationService) GetOrganizationByID(id string) (models.Organization, error) {
+	return s.repository.GetOrganizationByID(id)
+}
+
+// AddMember adds an existing trainer to the organization
+func (s *OrganizationService) AddMember(id string, email string, role string) error {
+	if !validOrganizationRole(role) {
+		return ErrInvalidOrganizationRole
+	}
+
+	if _, err := s.trainerRepo.FindByEmail(email); err != nil {
+		if err == ErrResourceNotFound {
+			return ErrTrainerNotFound
+		}
+		return err
+	}
+
+	added, err := s.repository.AddMember(id, models.OrganizationMember{
+		Email:    email,
+		Role:     role,
+		JoinedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrAlreadyMember
+	}
+	return nil
+}
+
+// UpdateMemberRole changes the role of a member, keeping at least one owner
+func (s *OrganizationService) UpdateMemberRole(id string, email string, role string) error {
+	if !validOrganizationRole(role) {
+		return ErrInvalidOrganizationRole
+	}
+
+	if role != models.OrgRoleOwner {
+		if err := s.ensureAnotherOwner(id, email); err != nil {
+			return err
+		}
+	}
+
+	return s.repository.UpdateMemberRole(id, email, role)
+}
+
+// RemoveMember removes a trainer from the organization, keeping at least one owner.
+// Trainees and libraries the trainer added stay with the organization.
+func (s *OrganizationService) RemoveMember(id string, email string) error {
+	if err := s.ensureAnotherOwner(id, email); err != nil {
+		return err
+	}
+
+	return s.repository.RemoveMember(id, email)
+}
+
+// ensureAnotherOwner fails if the trainer is the only owner left
+func (s *OrganizationService) ensureAnotherOwner(id string, email string) error {
+	organization, err := s.repository.GetOrganizationByID(id)
+	if err != nil {
+		return err
+	}
+
+	if organization.MemberRole(email) == "" {
+		return ErrResourceNotFound
+	}
+	if organization.MemberRole(email) != models.OrgRoleOwner {
+		return nil
+	}
+
+	for _, member := range organization.Members {
+		if member.Role == models.OrgRoleOwner && member.Email != email {
+			return nil
+		}
+	}
+	return ErrLastOwner
+}
+
+func validOrganizationRole(role string) bool {
+	switch role {
+	case models.OrgRoleOwner, models.OrgRoleCoach, models.OrgRoleAssistant:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PersonalRecordService struct {
+	repository     repositories.PersonalRecordRepository
+	workoutLogRepo repositories.WorkoutLogRepository
+}
+
+// Constructor for PersonalRecordService
+func NewPersonalRecordService(repos repositories.Repositories) *PersonalRecordService {
+	return &PersonalRecordService{
+		repository:     repos.PersonalRecords,
+		workoutLogRepo: repos.WorkoutLogs,
+	}
+}
+
+// GetPersonalRecords returns the records of a trainee newest first, or only those not beaten since if
+// current is set
+func (s *PersonalRecordService) GetPersonalRecords(traineeID string, current bool) ([]models.PersonalRecord, error) {
+	return s.repository.GetPersonalRecords(traineeID, current)
+}
+
+// MarkRecords sets PRsAchieved on the logs that hold a record
+func (s *PersonalRecordService) MarkRecords(logs []models.WorkoutLog) error {
+	ids := make([]string, 0, len(logs))
+	for _, workoutLog := range logs {
+		ids = append(ids, workoutLog.ID)
+	}
+	withRecords, err := s.repository.GetLogIDsWithRecords(ids)
+	if err != nil {
+		return err
+	}
+
+	marked := map[string]bool{}
+	for _, id := range withRecords {
+		marked[id] = true
+	}
+	for i := range logs {
+		logs[i].PRsAchieved = marked[logs[i].ID]
+	}
+	return nil
+}
+
+// Recompute works out every record of a trainee again from the logs that are not in the trash. It
+// backfills the records of logs written before records were kept, see migrations, writes keep them
+// up to date after that.
+func (s *PersonalRecordService) Recompute(traineeID string) error {
+	logs, err := s.workoutLogRepo.GetWorkoutLogsByTrainee(traineeID, models.ListOptions{})
+	if err != nil {
+		return err
+	}
+	return s.replaceRecords(traineeID, logs, nil)
+}
+
+// refresh works out the records of the exercises a log did again after it was written, given the log
+// as it was before and after the write. The records of other exercises cannot have changed. The
+// write stands if this fails, the records catch up with the next write to the exercises.
+func (s *PersonalRecordService) refresh(traineeID string, versions ...models.WorkoutLog) {
+	exercises := map[string]bool{}
+	var exerciseIDs []primitive.ObjectID
+	var names []string
+	for _, version := range versions {
+		for _, workout := range version.Workouts {
+			key := exerciseKey(workout)
+			if exercises[key] {
+				continue
+			}
+			exercises[key] = true
+			if workout.ExerciseID.IsZero() {
+				names = append(names, workout.Exercise)
+			} else {
+				exerciseIDs = append(exerciseIDs, workout.ExerciseID)
+			}
+		}
+	}
+	if len(exercises) == 0 {
+		return
+	}
+
+	logs, err := s.workoutLogRepo.GetWorkoutLogsByExercises(traineeID, exerciseIDs, names)
+	if err == nil {
+		err = s.replaceRecords(traineeID, logs, exercises)
+	}
+	if err != nil {
+		log.Printf("Failed to recompute personal records of trainee %s: %v", traineeID, err)
+	}
+}
+
+// replaceRecords stores the records detected in the logs in place of those the trainee holds, only
+// for the exercises given by exerciseKey unless exercises is nil.
+// It runs without a transaction. Records are saved by their key, unique per trainee, so refreshes
+// running at once store each record once, and one already stored keeps its ID and the time it was
+// first seen. Records no longer held are deleted after the others are saved, so a reader may briefly
+// see one that is gone but never misses one that stands.
+func (s *PersonalRecordService) replaceRecords(traineeID string, logs []models.WorkoutLog, exercises map[string]bool) error {
+	existing, err := s.repository.GetPersonalRecords(traineeID, false)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	records := []models.PersonalRecord{}
+	held := map[string]bool{}
+	for _, record := range detectPersonalRecords(traineeID, logs) {
+		if exercises != nil && !exercises[recordExercise(record)] {
+			continue
+		}
+		record.ID, record.CreatedAt, record.Key = primitive.NewObjectID(), now, recordKey(record)
+		held[record.Key] = true
+		records = append(records, record)
+	}
+
+	ctx := context.Background()
+	if err := s.repository.SavePersonalRecords(ctx, records); err != nil {
+		return err
+	}
+	// Records saved before they had a key are replaced too
+	var stale []primitive.ObjectID
+	for _, record := range existing {
+		if (exercises == nil || exercises[recordExercise(record)]) && !held[record.Key] {
+			stale = append(stale, record.ID)
+		}
+	}
+	_, err = s.repository.DeletePersonalRecords(ctx, stale)
+	return err
+}
+
+// recordKey tells a record apart from the others of the trainee across recomputes, it is stored as
+// its Key
+func recordKey(record models.PersonalRecord) string {
+	return record.LogID + "|" + recordKind(record)
+}
+
+// recordKind is the exercise and kind of a record, and the weight for reps. A later record of the
+// same kind beats it.
+func recordKind(record models.PersonalRecord) string {
+	kind := recordExercise(record) + "|" + record.Kind
+	if record.Kind == models.RecordRepsAtWeight {
+		kind += fmt.Sprintf("|%g", record.Weight)
+	}
+	return kind
+}
+
+// recordExercise is the exerciseKey of the exercise a record was made at
+func recordExercise(record models.PersonalRecord) string {
+	return exerciseKey(models.Workout{Exercise: record.Exercise, ExerciseID: record.ExerciseID})
+}
+
+// exerciseSession is what one workout log did at an exercise, its sets merged if it was logged twice
+type exerciseSession struct {
+	key        string
+	exercise   string
+	exerciseID primitive.ObjectID
+	weights    []float64
+	reps       []int
+}
+
+// exerciseBests is the best a trainee had done at an exercise before a log
+type exerciseBests struct {
+	heaviest  float64
+	oneRepMax float64
+	volume    float64
+	repsAt    map[float64]int // Most reps done at each weight
+}
+
+// detectPersonalRecords goes through the logs of a trainee sorted by date and returns the records they
+// hold, oldest first. Warm-ups and sets without reps are left out.
+func detectPersonalRecords(traineeID string, logs []models.WorkoutLog) []models.PersonalRecord {
+	records := []models.PersonalRecord{}
+	bests := map[string]*exerciseBests{}
+	for _, workoutLog := range logs {
+		for _, session := range exerciseSessions(workoutLog) {
+			record := func(kind string, value float64, previous float64, weight float64, reps int) {
+				records = append(records, models.PersonalRecord{
+					TraineeID:  traineeID,
+					Exercise:   session.exercise,
+					ExerciseID: session.exerciseID,
+					Kind:       kind,
+					Value:      value,
+					Previous:   previous,
+					Weight:     weight,
+					Reps:       reps,
+					LogID:      workoutLog.ID,
+					Date:       workoutLog.Date,
+				})
+			}
+
+			// The heaviest set, the set with the best estimate and the most reps at each weight
+			var heaviest, best int
+			var oneRepMax, volume float64
+			repsAt := map[float64]int{}
+			for i, weight := range session.weights {
+				reps := session.reps[i]
+				if weight > session.weights[heaviest] || weight == session.weights[heaviest] && reps > session.reps[heaviest] {
+					heaviest = i
+				}
+				if estimate := estimateOneRepMax(weight, reps, models.FormulaEpley); estimate > oneRepMax {
+					best, oneRepMax = i, estimate
+				}
+				volume += weight * float64(reps)
+				repsAt[weight] = max(repsAt[weight], reps)
+			}
+
+			previous, seen := bests[session.key]
+			if !seen {
+				// The first session sets the marks to beat
+				previous = &exerciseBests{repsAt: map[float64]int{}}
+				bests[session.key] = previous
+			} else {
+				if weight := session.weights[heaviest]; weight > previous.heaviest {
+					record(models.RecordHeaviestWeight, weight, previous.heaviest, weight, session.reps[heaviest])
+				}
+				if oneRepMax > previous.oneRepMax {
+					record(models.RecordEstimated1RM, oneRepMax, previous.oneRepMax, session.weights[best], session.reps[best])
+				}
+				if volume > previous.volume {
+					record(models.RecordSessionVolume, volume, previous.volume, 0, 0)
+				}
+
+				// More reps than ever at a weight or a heavier one, unless a heavier set of this log
+				// did as many
+				weights := make([]float64, 0, len(repsAt))
+				for weight := range repsAt {
+					weights = append(weights, weight)
+				}
+				sort.Sort(sort.Reverse(sort.Float64Slice(weights)))
+				heavierReps := 0
+				for _, weight := range weights {
+					reps := repsAt[weight]
+					if reps > heavierReps {
+						if before := previous.repsAtLeast(weight); before > 0 && reps > before {
+							record(models.RecordRepsAtWeight, float64(reps), float64(before), weight, reps)
+						}
+					}
+					heavierReps = max(heavierReps, reps)
+				}
+			}
+
+			previous.heaviest = max(previous.heaviest, session.weights[heaviest])
+			previous.oneRepMax = max(previous.oneRepMax, oneRepMax)
+			previous.volume = max(previous.volume, volume)
+			for weight, reps := range repsAt {
+				previous.repsAt[weight] = max(previous.repsAt[weight], reps)
+			}
+		}
+	}
+
+	// The latest record of each kind of an exercise, and of each weight for reps, still stands
+	standing := map[string]bool{}
+	for i := len(records) - 1; i >= 0; i-- {
+		kind := recordKind(records[i])
+		if !standing[kind] {
+			standing[kind] = true
+			records[i].Current = true
+		}
+	}
+	return records
+}
+
+// repsAtLeast is the most reps done at the weight or a heavier one
+func (b *exerciseBests) repsAtLeast(weight float64) int {
+	most := 0
+	for w, reps := range b.repsAt {
+		if w >= weight {
+			most = max(most, reps)
+		}
+	}
+	return most
+}
+
+// exerciseSessions groups the sets of a log by exercise, in the order the exercises were logged
+func exerciseSessions(workoutLog models.WorkoutLog) []*exerciseSession {
+	var sessions []*exerciseSession
+	byKey := map[string]*exerciseSession{}
+	for _, workout := range workoutLog.Workouts {
+		key := exerciseKey(workout)
+		session, ok := byKey[key]
+		for _, set := range workSets(workout) {
+			if set.Reps <= 0 || set.Weight < 0 {
+				continue
+			}
+			if !ok {
+				session = &exerciseSession{key: key, exercise: workout.Exercise, exerciseID: workout.ExerciseID}
+				byKey[key] = session
+				sessions = append(sessions, session)
+				ok = true
+			}
+			session.weights = append(session.weights, set.Weight)
+			session.reps = append(session.reps, set.Reps)
+		}
+	}
+	return sessions
+}
@@ -0,0 +1,191 @@
+package services_test
+
+import (
+	"io"
+	"ironcoach/mailer"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/repositories/memory"
+	"ironcoach/services"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func workoutLog(t *testing.T, traineeID, date string, workouts ...models.Workout) models.WorkoutLog {
+	t.Helper()
+	parsed, err := models.ParseDate(date)
+	if err != nil {
+		t.Fatalf("ParseDate(%q): %v", date, err)
+	}
+	return models.WorkoutLog{TraineeID: traineeID, Date: parsed, Workouts: workouts}
+}
+
+func workout(exercise string, weight float64, reps int) models.Workout {
+	return models.Workout{Exercise: exercise, Sets: []models.Set{{Type: models.SetWorking, Reps: reps, Weight: weight}}}
+}
+
+// recordsOf returns the records of the trainee for an exercise by kind
+func recordsOf(t *testing.T, app *services.Services, traineeID, exercise string) map[string]models.PersonalRecord {
+	t.Helper()
+	records, err := app.PersonalRecords.GetPersonalRecords(traineeID, false)
+	if err != nil {
+		t.Fatalf("GetPersonalRecords: %v", err)
+	}
+	byKind := map[string]models.PersonalRecord{}
+	for _, record := range records {
+		if record.Exercise == exercise {
+			byKind[record.Kind] = record
+		}
+	}
+	return byKind
+}
+
+// summary lists the records without their IDs and timestamps, to compare two ways of working them out
+func summary(t *testing.T, app *services.Services, traineeID string) []string {
+	t.Helper()
+	records, err := app.PersonalRecords.GetPersonalRecords(traineeID, false)
+	if err != nil {
+		t.Fatalf("GetPersonalRecords: %v", err)
+	}
+	lines := make([]string, len(records))
+	for i, record := range records {
+		lines[i] = record.LogID + " " + record.Exercise + " " + record.Kind
+		if record.Current {
+			lines[i] += " current"
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestPersonalRecordsFollowWrites(t *testing.T) {
+	app := newServices()
+	const traineeID = "trainee"
+
+	if _, err := app.WorkoutLogs.AddWorkoutLog(workoutLog(t, traineeID, "2024-01-01", workout("Squat", 100, 5), workout("Bench", 60, 5))); err != nil {
+		t.Fatalf("AddWorkoutLog: %v", err)
+	}
+	id, err := app.WorkoutLogs.AddWorkoutLog(workoutLog(t, traineeID, "2024-01-08", workout("Squat", 110, 5), workout("Bench", 65, 5)))
+	if err != nil {
+		t.Fatalf("AddWorkoutLog: %v", err)
+	}
+
+	squat := recordsOf(t, app, traineeID, "Squat")
+	if record, ok := squat[models.RecordHeaviestWeight]; !ok || record.Value != 110 || record.LogID != id {
+		t.Fatalf("heaviest squat = %+v, want 110 in the second log", record)
+	}
+	bench := recordsOf(t, app, traineeID, "Bench")
+	if len(bench) == 0 {
+		t.Fatal("no bench records")
+	}
+
+	// Lighter squats no longer beat the first log, the bench records are not touched
+	lighter := []models.Workout{workout("Squat", 90, 5), workout("Bench", 65, 5)}
+	if err := app.WorkoutLogs.UpdateWorkoutLog(id, 1, models.WorkoutLogPatch{Workouts: &lighter}); err != nil {
+		t.Fatalf("UpdateWorkoutLog: %v", err)
+	}
+	if squat := recordsOf(t, app, traineeID, "Squat"); len(squat) != 0 {
+		t.Errorf("squat records after the update = %+v, want none", squat)
+	}
+	for kind, record := range recordsOf(t, app, traineeID, "Bench") {
+		if before := bench[kind]; record.ID != before.ID || !record.CreatedAt.Equal(before.CreatedAt) {
+			t.Errorf("bench %s record was rewritten: %+v, was %+v", kind, record, before)
+		}
+	}
+
+	// What the writes left is what working everything out again gives
+	incremental := summary(t, app, traineeID)
+	if err := app.PersonalRecords.Recompute(traineeID); err != nil {
+		t.Fatalf("Recompute: %v", err)
+	}
+	if full := summary(t, app, traineeID); !reflect.DeepEqual(full, incremental) {
+		t.Errorf("records after writes = %v, after Recompute = %v", incremental, full)
+	}
+
+	// An exercise that is only renamed away loses its records
+	renamed := []models.Workout{workout("Squat", 90, 5), workout("Incline bench", 65, 5)}
+	if err := app.WorkoutLogs.UpdateWorkoutLog(id, 2, models.WorkoutLogPatch{Workouts: &renamed}); err != nil {
+		t.Fatalf("UpdateWorkoutLog: %v", err)
+	}
+	if bench := recordsOf(t, app, traineeID, "Bench"); len(bench) != 0 {
+		t.Errorf("bench records after the rename = %+v, want none", bench)
+	}
+
+	if err := app.WorkoutLogs.DeleteWorkoutLog(id, 3); err != nil {
+		t.Fatalf("DeleteWorkoutLog: %v", err)
+	}
+	if records, _ := app.PersonalRecords.GetPersonalRecords(traineeID, false); len(records) != 0 {
+		t.Errorf("records after deleting the log = %+v, want none", records)
+	}
+}
+
+// readBarrier holds the first reads of personal records until all of them are made, so refreshes
+// running at once all see the records as they were before any of them saved
+type readBarrier struct {
+	repositories.PersonalRecordRepository
+	readers int32
+	reads   atomic.Int32
+	open    chan struct{}
+}
+
+func (b *readBarrier) GetPersonalRecords(traineeID string, current bool) ([]models.PersonalRecord, error) {
+	records, err := b.PersonalRecordRepository.GetPersonalRecords(traineeID, current)
+	if n := b.reads.Add(1); n <= b.readers {
+		if n == b.readers {
+			close(b.open)
+		}
+		<-b.open
+	}
+	return records, err
+}
+
+func TestConcurrentRefreshesStoreRecordsOnce(t *testing.T) {
+	const refreshes = 16
+	repos := memory.NewRepositories()
+	repos.PersonalRecords = &readBarrier{PersonalRecordRepository: repos.PersonalRecords, readers: refreshes, open: make(chan struct{})}
+	app := services.New(repos, mailer.NewLogMailer(log.New(io.Discard, "", 0), "noreply@ironcoach.test"))
+	const traineeID = "trainee"
+
+	// Stored behind the service's back, so no records exist until the refreshes below
+	var logs []models.WorkoutLog
+	for i, date := range []string{"2024-01-01", "2024-01-08", "2024-01-15"} {
+		workoutLog := workoutLog(t, traineeID, date, workout("Squat", 100+float64(i)*10, 5+i), workout("Bench", 60+float64(i)*5, 5))
+		if _, err := repos.WorkoutLogs.CreateWorkoutLog(workoutLog); err != nil {
+			t.Fatalf("CreateWorkoutLog: %v", err)
+		}
+		logs = append(logs, workoutLog)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < refreshes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			services.Refresh(app.PersonalRecords, traineeID, logs...)
+		}()
+	}
+	wg.Wait()
+
+	concurrent := summary(t, app, traineeID)
+	if len(concurrent) == 0 {
+		t.Fatal("no records after the refreshes")
+	}
+	seen := map[string]bool{}
+	for _, line := range concurrent {
+		if seen[line] {
+			t.Errorf("record %s is stored more than once", line)
+		}
+		seen[line] = true
+	}
+
+	if err := app.PersonalRecords.Recompute(traineeID); err != nil {
+		t.Fatalf("Recompute: %v", err)
+	}
+	if full := summary(t, app, traineeID); !reflect.DeepEqual(full, concurrent) {
+		t.Errorf("records after concurrent refreshes = %v, after Recompute = %v", concurrent, full)
+	}
+}
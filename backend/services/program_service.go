@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrProgramInUse     = apperrors.Conflict("program_in_use", "program is assigned to trainees, unassign it first")
+	ErrTemplateNotFound = apperrors.NotFound("template_not_found", "workout template not found in the program")
+	ErrNoWorkoutPlanned = apperrors.NotFound("no_workout_planned", "no workout is planned for this date")
+)
+
+// Loads worked out from a percentage of the 1RM are rounded to what can be loaded on a bar
+const plateIncrement = 2.5
+
+type ProgramService struct {
+	repository     repositories.ProgramRepository
+	workoutLogRepo repositories.WorkoutLogRepository
+	workoutLogs    *WorkoutLogService
+	calendar       *calendar
+}
+
+// Constructor for ProgramService
+func NewProgramService(repos repositories.Repositories, workoutLogs *WorkoutLogService) *ProgramService {
+	return &ProgramService{
+		repository:     repos.Programs,
+		workoutLogRepo: repos.WorkoutLogs,
+		workoutLogs:    workoutLogs,
+		calendar:       newCalendar(repos),
+	}
+}
+
+// CreateProgram saves a new program written by the trainer
+func (s *ProgramService) CreateProgram(trainerID string, program models.Program) (models.Program, error) {
+	if err := prepareProgram(&program); err != nil {
+		return models.Program{}, err
+	}
+
+	program.ID = primitive.NewObjectID()
+	program.TrainerID = trainerID
+	program.CreatedAt = time.Now()
+	program.UpdatedAt = program.CreatedAt
+	if err := s.repository.CreateProgram(program); err != nil {
+		return models.Program{}, err
+	}
+	return program, nil
+}
+
+// Get the programs a trainer wrote
+func (s *ProgramService) GetPrograms(trainerID string) ([]models.Program, error) {
+	return s.repository.GetProgramsByTrainer(trainerID)
+}
+
+// Get a program by ID
+func (s *ProgramService) GetProgramByID(id string) (models.Program, error) {
+	return s.repository.GetProgramByID(id)
+}
+
+// UpdateProgram replaces the plan of a program. Trainees following it see the new plan from then on,
+// workouts they already logged keep what was planned at the time.
+func (s *ProgramService) UpdateProgram(id string, update models.Program) (models.Program, error) {
+	program, err := s.repository.GetProgramByID(id)
+	if err != nil {
+		return models.Program{}, err
+	}
+	if err := prepareProgram(&update); err != nil {
+		return models.Program{}, err
+	}
+
+	program.Name = update.Name
+	program.Description = update.Description
+	program.Weeks = update.Weeks
+	program.Workouts = update.Workouts
+	program.UpdatedAt = time.Now()
+	if err := s.repository.UpdateProgram(program); err != nil {
+		return models.Program{}, err
+	}
+	return program, nil
+}
+
+// DeleteProgram deletes a program no trainee follows anymore
+func (s *ProgramService) DeleteProgram(id string) error {
+	assigned, err := s.repository.CountAssignments(id)
+	if err != nil {
+		return err
+	}
+	if assigned > 0 {
+		return ErrProgramInUse
+	}
+
+	deleted, err := s.repository.DeletePrograms(context.Background(), []string{id})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrResourceNotFound
+	}
+	return nil
+}
+
+// AssignProgram makes the trainee follow one of the trainer's programs from the start date, today
+// where the trainer is if it has none. It replaces the program the trainee followed before.
+func (s *ProgramService) AssignProgram(traineeID string, trainerID string, programID string, startDate models.Date) (models.ProgramAssignment, error) {
+	program, err := s.repository.GetProgramByID(programID)
+	if err != nil {
+		return models.ProgramAssignment{}, err
+	}
+	if program.TrainerID != trainerID {
+		return models.ProgramAssignment{}, ErrAccessDenied
+	}
+
+	if startDate.IsZero() {
+		if startDate, err = s.calendar.today(traineeID); err != nil {
+			return models.ProgramAssignment{}, err
+		}
+	}
+
+	assignment := models.ProgramAssignment{
+		TraineeID:  traineeID,
+		ProgramID:  programID,
+		AssignedBy: trainerID,
+		StartDate:  startDate,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.repository.UpsertAssignment(assignment); err != nil {
+		return models.ProgramAssignment{}, err
+	}
+	assignment.Program = &program
+	return assignment, nil
+}
+
+// GetAssignment returns the program a trainee follows, with the program itself
+func (s *ProgramService) GetAssignment(traineeID string) (models.ProgramAssignment, error) {
+	assignment, err := s.repository.GetAssignment(traineeID)
+	if err != nil {
+		return assignment, err
+	}
+
+	program, err := s.repository.GetProgramByID(assignment.ProgramID)
+	if err != nil {
+		return assignment, err
+	}
+	assignment.Program = &program
+	return assignment, nil
+}
+
+// UnassignProgram stops the trainee following their program. Their logs are kept.
+func (s *ProgramService) UnassignProgram(traineeID string) error {
+	return s.repository.DeleteAssignment(traineeID)
+}
+
+// GetPlannedWorkout returns what the trainee's program plans for the date, today where the trainer
+// is if it is zero
+func (s *ProgramService) GetPlannedWorkout(traineeID string, date models.Date) (models.PlannedWorkout, error) {
+	_, planned, err := s.plan(traineeID, date)
+	return planned, err
+}
+
+// plan returns the program the trainee follows and what it plans for the date
+func (s *ProgramService) plan(traineeID string, date models.Date) (models.Program, models.PlannedWorkout, error) {
+	assignment, err := s.GetAssignment(traineeID)
+	if err != nil {
+		return models.Program{}, models.PlannedWorkout{}, err
+	}
+	if date.IsZero() {
+		if date, err = s.calendar.today(traineeID); err != nil {
+			return models.Program{}, models.PlannedWorkout{}, err
+		}
+	}
+	return *assignment.Program, planFor(*assignment.Program, assignment.StartDate, date), nil
+}
+
+// LogPlannedWorkout starts a workout log from a template of the trainee's program: the one with the
+// given ID, or the one planned for the date if templateID is empty. The log is filled in with the
+// planned sets, reps and loads, which the trainee then edits into what they did. The plan is kept
+// on each exercise of the log.
+func (s *ProgramService) LogPlannedWorkout(traineeID string, templateID string, date models.Date) (models.WorkoutLog, error) {
+	program, planned, err := s.plan(traineeID, date)
+	if err != nil {
+		return models.WorkoutLog{}, err
+	}
+
+	var template models.WorkoutTemplate
+	if templateID == "" {
+		if planned.Workout == nil {
+			return models.WorkoutLog{}, ErrNoWorkoutPlanned
+		}
+		template = *planned.Workout
+	} else {
+		var ok bool
+		if template, ok = program.Template(templateID); !ok {
+			return models.WorkoutLog{}, ErrTemplateNotFound
+		}
+	}
+
+	workouts, err := s.plannedWorkouts(traineeID, template)
+	if err != nil {
+		return models.WorkoutLog{}, err
+	}
+	id, err := s.workoutLogs.addWorkoutLog(models.WorkoutLog{
+		TraineeID:  traineeID,
+		Date:       planned.Date,
+		Workouts:   workouts,
+		Notes:      template.Notes,
+		ProgramID:  planned.ProgramID,
+		TemplateID: template.ID,
+	})
+	if err != nil {
+		return models.WorkoutLog{}, err
+	}
+	return s.workoutLogs.GetWorkoutLogByID(id)
+}
+
+// plannedWorkouts fills in the exercises of a template, with loads given as a percentage of the 1RM
+// worked out from the best set the trainee has logged. Without one the load is left for the trainee.
+func (s *ProgramService) plannedWorkouts(traineeID string, template models.WorkoutTemplate) ([]models.Workout, error) {
+	var logs []models.WorkoutLog
+	loaded := false
+	workouts := make([]models.Workout, 0, len(template.Exercises))
+	for _, exercise := range template.Exercises {
+		planned := exercise.SetTarget
+		if planned.Weight == 0 && planned.Percent1RM > 0 {
+			if !loaded {
+				var err error
+				if logs, err = s.workoutLogRepo.GetWorkoutLogsByTrainee(traineeID, models.ListOptions{}); err != nil {
+					return nil, err
+				}
+				loaded = true
+			}
+			oneRepMax := bestOneRepMax(logs, exercise)
+			planned.Weight = math.Round(oneRepMax*planned.Percent1RM/100/plateIncrement) * plateIncrement
+		}
+
+		workout := models.Workout{
+			Exercise:   exercise.Exercise,
+			ExerciseID: exercise.ExerciseID,
+			Sets:       make([]models.Set, planned.Sets),
+			Notes:      exercise.Notes,
+			Planned:    &planned,
+		}
+		for i := range workout.Sets {
+			workout.Sets[i] = models.Set{
+				Type:        models.SetWorking,
+				Reps:        planned.RepsMin,
+				Weight:      planned.Weight,
+				RestSeconds: planned.RestSeconds,
+			}
+		}
+		workouts = append(workouts, workout)
+	}
+	return workouts, nil
+}
+
+// bestOneRepMax is the highest one-rep max estimated from the sets logged for the exercise
+func bestOneRepMax(logs []models.WorkoutLog, exercise models.TemplateExercise) float64 {
+	var best float64
+	for _, log := range logs {
+		for _, workout := range log.Workouts {
+			if !sameExercise(workout, exercise) {
+				continue
+			}
+			for _, set := range workSets(workout) {
+				best = math.Max(best, estimateOneRepMax(set.Weight, set.Reps, models.FormulaEpley))
+			}
+		}
+	}
+	return best
+}
+
+// Logged exercises are matched by ID, or by name for those logged without one
+func sameExercise(workout models.Workout, exercise models.TemplateExercise) bool {
+	if !exercise.ExerciseID.IsZero() && !workout.ExerciseID.IsZero() {
+		return workout.ExerciseID == exercise.ExerciseID
+	}
+	return strings.EqualFold(workout.Exercise, exercise.Exercise)
+}
+
+// planFor works out the week and day of the program a date falls on and the workout planned for it
+func planFor(program models.Program, start models.Date, date models.Date) models.PlannedWorkout {
+	planned := models.PlannedWorkout{
+		ProgramID:   program.ID.Hex(),
+		ProgramName: program.Name,
+		Date:        date,
+	}
+
+	days := int(date.Sub(start.Time).Hours() / 24)
+	if days < 0 {
+		return planned
+	}
+	planned.Week, planned.Day = days/7+1, days%7+1
+	if planned.Week > program.Weeks {
+		planned.Finished = true
+		return planned
+	}
+	if template, ok := program.TemplateOn(planned.Week, planned.Day); ok {
+		planned.Workout = &template
+	}
+	return planned
+}
+
+// prepareProgram checks that the workouts fit in the program, one per day, and gives new
+// templates an ID
+func prepareProgram(program *models.Program) error {
+	var fieldErrs []apperrors.FieldError
+	days := map[[2]int]bool{}
+	ids := map[string]bool{}
+	for i := range program.Workouts {
+		template := &program.Workouts[i]
+		field := fmt.Sprintf("workouts[%d]", i)
+		if template.Week > program.Weeks {
+			fieldErrs = append(fieldErrs, apperrors.FieldError{Field: field + ".week", Message: fmt.Sprintf("must be at most %d", program.Weeks)})
+		}
+		day := [2]int{template.Week, template.Day}
+		if days[day] {
+			fieldErrs = append(fieldErrs, apperrors.FieldError{Field: field + ".day", Message: "already has a workout"})
+		}
+		days[day] = true
+
+		if template.ID == "" || ids[template.ID] {
+			template.ID = primitive.NewObjectID().Hex()
+		}
+		ids[template.ID] = true
+		for j := range template.Exercises {
+			if template.Exercises[j].RepsMax == 0 {
+				template.Exercises[j].RepsMax = template.Exercises[j].RepsMin
+			}
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		return apperrors.InvalidFields(fieldErrs...)
+	}
+	return nil
+}
@@ -4,99 +4,113 @@ import (
 	"ironcoach/models"
 	"ironcoach/repositories"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ProgressService struct {
-	repository        *repositories.ProgressRepository
-	workoutLogRepo    *repositories.WorkoutLogRepository
-	traineeRepo       *repositories.TraineeRepository
+	repository        repositories.ProgressRepository
+	workoutLogRepo    repositories.WorkoutLogRepository
+	traineeRepo       repositories.TraineeRepository
+	exerciseRepo      repositories.ExerciseRepository
+	calendar          *calendar
 }
 
-func NewProgressService() *ProgressService {
+func NewProgressService(repos repositories.Repositories) *ProgressService {
 	return &ProgressService{
-		repository:        repositories.NewProgressRepository(),
-		workoutLogRepo:    repositories.NewWorkoutLogRepository(),
-		traineeRepo:       repositories.NewTraineeRepository(),
+		repository:        repos.Progress,
+		workoutLogRepo:    repos.WorkoutLogs,
+		traineeRepo:       repos.Trainees,
+		exerciseRepo:      repos.Exercises,
+		calendar:          newCalendar(repos),
 	}
 }
 
-// GetProgress fetches the progress data for a trainee
-func (s *ProgressService) GetProgress(traineeID string) (*models.Progress, error) {
-	workoutLogs, err := s.workoutLogRepo.GetWorkoutLogsByTrainee(traineeID,"")
+// GetProgress fetches the progress data for a trainee, optionally within the date range of the list
+// options. The workout analytics run up to today where the trainer is unless the range has an end.
+func (s *ProgressService) GetProgress(traineeID string, dates models.ListOptions, options models.AnalyticsOptions) (*models.Progress, error) {
+	to := dates.To
+	if to.IsZero() {
+		var err error
+		if to, err = s.calendar.today(traineeID); err != nil {
+			return nil, err
+		}
+	}
+
+	// The workload ratio at the start of the range looks back over the chronic window
+	logDates := models.ListOptions{To: to}
+	if !dates.From.IsZero() {
+		logDates.From = dates.From.AddDays(1 - chronicDays)
+	}
+	workoutLogs, err := s.workoutLogRepo.GetWorkoutLogsByTrainee(traineeID, logDates)
+	if err != nil {
+		return nil, err
+	}
+
+	weightBMI, err := s.repository.GetWeightBMIProgress(traineeID, dates)
 	if err != nil {
 		return nil, err
 	}
 
-	weightBMI, err := s.repository.GetWeightBMIProgress(traineeID)
+	groups, err := s.muscleGroups(workoutLogs)
 	if err != nil {
 		return nil, err
 	}
 
-	exerciseProgress := calculateExerciseProgress(workoutLogs)
+	progress := analyzeWorkoutLogs(workoutLogs, groups, dates.From, to, options)
+	progress.TraineeID = traineeID
+	progress.WeightBMI = weightBMI
+	return &progress, nil
+}
+
+// muscleGroups returns the category of each exercise of the logs that links to the exercise library
+func (s *ProgressService) muscleGroups(logs []models.WorkoutLog) (map[primitive.ObjectID]string, error) {
+	seen := map[primitive.ObjectID]bool{}
+	var ids []primitive.ObjectID
+	for _, log := range logs {
+		for _, workout := range log.Workouts {
+			if !workout.ExerciseID.IsZero() && !seen[workout.ExerciseID] {
+				seen[workout.ExerciseID] = true
+				ids = append(ids, workout.ExerciseID)
+			}
+		}
+	}
 
-	return &models.Progress{
-		TraineeID:  traineeID,
-		WeightBMI:  weightBMI,
-		Exercises:  exerciseProgress,
-	}, nil
+	exercises, err := s.exerciseRepo.GetExercisesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[primitive.ObjectID]string, len(exercises))
+	for _, exercise := range exercises {
+		groups[exercise.ID] = exercise.Category
+	}
+	return groups, nil
 }
 
-// AddWeightProgress adds a new weight progress entry
+// AddWeightProgress adds a new weight progress entry, dated today where the trainer is unless it has a date
 func (s *ProgressService) AddWeightProgress(weightBMI models.WeightBMIProgress) error {
 	trainee, err := s.traineeRepo.GetTraineeByID(weightBMI.TraineeID)
 	if err != nil {
 		return err
 	}
-
-	// Calculate BMI if height is available
-	if trainee.Height > 0 {
-		weightBMI.BMI = weightBMI.Weight / ((trainee.Height / 100) * (trainee.Height / 100))
+	if weightBMI.Date.IsZero() {
+		if weightBMI.Date, err = s.calendar.trainerToday(trainee.TrainerID); err != nil {
+			return err
+		}
 	}
 
+	weightBMI.BMI = calculateBMI(weightBMI.Weight, trainee.Height)
 	weightBMI.CreatedAt = time.Now()
 	weightBMI.UpdatedAt = time.Now()
+	weightBMI.Version = 1
 
 	return s.repository.AddWeightProgress(weightBMI)
 }
 
-func calculateExerciseProgress(workoutLogs []models.WorkoutLog) []models.ExerciseProgress {
-	exerciseMap := map[string][]models.ExerciseRecord{}
-	for _, log := range workoutLogs {
-		for _, workout := range log.Workouts {
-			for i, weight := range workout.Weight {
-				record := models.ExerciseRecord{
-					Date:   log.Date,
-					Weight: weight,
-					Reps:   workout.Reps[i],
-				}
-				exerciseMap[workout.Exercise] = append(exerciseMap[workout.Exercise], record)
-			}
-		}
-	}
-
-	var exercises []models.ExerciseProgress
-	for name, records := range exerciseMap {
-		var totalWeight float64
-		for _, r := range records {
-			totalWeight += r.Weight
-		}
-
-		exercises = append(exercises, models.ExerciseProgress{
-			Exercise:  name,
-			MaxWeight: maxWeight(records),
-			AvgWeight: totalWeight / float64(len(records)),
-			Records:   records,
-		})
-	}
-	return exercises
-}
-
-func maxWeight(records []models.ExerciseRecord) float64 {
-	max := 0.0
-	for _, r := range records {
-		if r.Weight > max {
-			max = r.Weight
-		}
+// calculateBMI works out the BMI from a weight in kg and a height in cm, or returns 0 if the height is unknown
+func calculateBMI(weight float64, height float64) float64 {
+	if height <= 0 {
+		return 0
 	}
-	return max
+	return weight / ((height / 100) * (height / 100))
 }
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/utils"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+
+	// Weight of a term found in a medical history, on the scale of the weights of the trainee text index
+	medicalHistoryWeight = 4
+)
+
+// SearchTypes are the kinds of records search covers, in the order they are searched
+var SearchTypes = []string{models.SearchTrainees, models.SearchExercises, models.SearchWorkoutLogs, models.SearchDietEntries}
+
+// SearchService finds trainees, exercises, workout logs and diet entries by the words in their names
+// and notes, within what the calling trainer can see. Everything but medical histories runs on the
+// text indexes, medical histories are encrypted and matched after decrypting them.
+type SearchService struct {
+	traineeRepo      repositories.TraineeRepository
+	exerciseRepo     repositories.ExerciseRepository
+	categoryRepo     repositories.CategoryRepository
+	workoutLogRepo   repositories.WorkoutLogRepository
+	dietEntryRepo    repositories.DietEntryRepository
+	organizationRepo repositories.OrganizationRepository
+}
+
+// Constructor for SearchService
+func NewSearchService(repos repositories.Repositories) *SearchService {
+	return &SearchService{
+		traineeRepo:      repos.Trainees,
+		exerciseRepo:     repos.Exercises,
+		categoryRepo:     repos.Categories,
+		workoutLogRepo:   repos.WorkoutLogs,
+		dietEntryRepo:    repos.DietEntries,
+		organizationRepo: repos.Organizations,
+	}
+}
+
+// Search looks for the query in the records of the given types, at most limit hits per type.
+// The query uses MongoDB text search syntax: words match any of their forms, "quoted phrases"
+// must all appear and -words exclude records.
+func (s *SearchService) Search(identity utils.Identity, query string, types []string, limit int64) (models.SearchResults, error) {
+	results := models.SearchResults{
+		Query:       query,
+		Trainees:    []models.SearchHit{},
+		Exercises:   []models.SearchHit{},
+		WorkoutLogs: []models.SearchHit{},
+		DietEntries: []models.SearchHit{},
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	organizationIDs, err := s.organizationRepo.GetOrganizationIDsByMember(identity.Email)
+	if err != nil {
+		return results, err
+	}
+
+	for _, kind := range types {
+		switch kind {
+		case models.SearchTrainees:
+			results.Trainees, err = s.searchTrainees(identity.Email, organizationIDs, query, limit)
+		case models.SearchExercises:
+			results.Exercises, err = s.searchExercises(identity.Email, organizationIDs, query, limit)
+		case models.SearchWorkoutLogs:
+			results.WorkoutLogs, err = s.searchWorkoutLogs(identity.Email, organizationIDs, query, limit)
+		case models.SearchDietEntries:
+			results.DietEntries, err = s.searchDietEntries(identity.Email, organizationIDs, query, limit)
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func (s *SearchService) searchTrainees(trainerID string, organizationIDs []string, query string, limit int64) ([]models.SearchHit, error) {
+	trainees, scores, err := s.traineeRepo.SearchTrainees(trainerID, organizationIDs, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]models.SearchHit, 0, len(trainees))
+	found := map[string]int{}
+	for i, trainee := range trainees {
+		found[trainee.ID] = i
+		hits = append(hits, models.SearchHit{ID: trainee.ID, TraineeID: trainee.ID, Score: scores[i], Data: trainee})
+	}
+
+	// Medical histories cannot be indexed, scan the decrypted ones of the trainer's trainees
+	terms, excluded := searchTerms(query)
+	trainees, err = s.traineeRepo.GetTraineesWithMedicalHistory(trainerID, organizationIDs)
+	if err != nil {
+		return nil, err
+	}
+	dropped := map[string]bool{}
+	for _, trainee := range trainees {
+		if countTerms(trainee.MedicalHistory, excluded) > 0 || countTerms(strings.Join([]string{trainee.Name, trainee.Goals, trainee.Notes}, " "), excluded) > 0 {
+			dropped[trainee.ID] = true
+			continue
+		}
+		score := float64(countTerms(trainee.MedicalHistory, terms)) * medicalHistoryWeight
+		if score == 0 {
+			continue
+		}
+		if i, ok := found[trainee.ID]; ok {
+			hits[i].Score += score
+			continue
+		}
+		hits = append(hits, models.SearchHit{ID: trainee.ID, TraineeID: trainee.ID, Score: score, Data: trainee})
+	}
+
+	kept := hits[:0]
+	for _, hit := range hits {
+		if !dropped[hit.ID] {
+			kept = append(kept, hit)
+		}
+	}
+	hits = kept
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if int64(len(hits)) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func (s *SearchService) searchExercises(trainerID string, organizationIDs []string, query string, limit int64) ([]models.SearchHit, error) {
+	categories, err := s.categoryRepo.GetCategories(trainerID, organizationIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) == 0 {
+		return []models.SearchHit{}, nil
+	}
+	categoryIDs := make([]primitive.ObjectID, 0, len(categories))
+	for _, category := range categories {
+		categoryIDs = append(categoryIDs, category.ID)
+	}
+
+	exercises, scores, err := s.exerciseRepo.SearchExercises(categoryIDs, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]models.SearchHit, 0, len(exercises))
+	for i, exercise := range exercises {
+		hits = append(hits, models.SearchHit{ID: exercise.ID.Hex(), Score: scores[i], Data: exercise})
+	}
+	return hits, nil
+}
+
+func (s *SearchService) searchWorkoutLogs(trainerID string, organizationIDs []string, query string, limit int64) ([]models.SearchHit, error) {
+	traineeIDs, err := s.traineeRepo.GetTraineeIDs(trainerID, organizationIDs)
+	if err != nil || len(traineeIDs) == 0 {
+		return []models.SearchHit{}, err
+	}
+
+	logs, scores, err := s.workoutLogRepo.SearchWorkoutLogs(traineeIDs, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]models.SearchHit, 0, len(logs))
+	for i, log := range logs {
+		hits = append(hits, models.SearchHit{ID: log.ID, TraineeID: log.TraineeID, Score: scores[i], Data: log})
+	}
+	return hits, nil
+}
+
+func (s *SearchService) searchDietEntries(trainerID string, organizationIDs []string, query string, limit int64) ([]models.SearchHit, error) {
+	traineeIDs, err := s.traineeRepo.GetTraineeIDs(trainerID, organizationIDs)
+	if err != nil || len(traineeIDs) == 0 {
+		return []models.SearchHit{}, err
+	}
+
+	entries, scores, err := s.dietEntryRepo.SearchDietEntries(traineeIDs, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]models.SearchHit, 0, len(entries))
+	for i, entry := range entries {
+		hits = append(hits, models.SearchHit{ID: entry.ID, TraineeID: entry.TraineeID, Score: scores[i], Data: entry})
+	}
+	return hits, nil
+}
+
+// searchTerms splits a query into the lowercased words to look for and those excluded with a leading -,
+// for matching outside the text index. Phrases are matched word by word.
+func searchTerms(query string) (terms []string, excluded []string) {
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		exclude := strings.HasPrefix(word, "-")
+		if word = strings.Trim(word, `-"'.,;:!?()`); word == "" {
+			continue
+		}
+		if exclude {
+			excluded = append(excluded, word)
+		} else {
+			terms = append(terms, word)
+		}
+	}
+	return terms, excluded
+}
+
+// countTerms counts the terms found in a text. A term also finds the words it is the start of ("knee"
+// finds "knees"), a rough stand-in for the stemming of the text index.
+func countTerms(text string, terms []string) int {
+	if text == "" || len(terms) == 0 {
+		return 0
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	count := 0
+	for _, term := range terms {
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
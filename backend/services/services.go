@@ -0,0 +1,60 @@
+package services
+
+import (
+	"ironcoach/mailer"
+	"ironcoach/repositories"
+)
+
+// Services holds one instance of every service, wired to the same repositories. Services that
+// depend on each other share these instances rather than building their own.
+type Services struct {
+	Accounts        *AccountService
+	Audit           *AuditService
+	Authorization   *AuthorizationService
+	Cascade         *CascadeService
+	Categories      *CategoryService
+	DietEntries     *DietEntryService
+	Exercises       *ExerciseService
+	Idempotency     *IdempotencyService
+	Images          *ImageService
+	Organizations   *OrganizationService
+	PersonalRecords *PersonalRecordService
+	Programs        *ProgramService
+	Progress        *ProgressService
+	Search          *SearchService
+	Sessions        *SessionService
+	Sync            *SyncService
+	TraineeAccounts *TraineeAccountService
+	Trainees        *TraineeService
+	Trainers        *TrainerService
+	Trash           *TrashService
+	WorkoutLogs     *WorkoutLogService
+}
+
+// New builds every service over the given repositories. Pass the repositories of the memory
+// package and a mailer of your own to run services without MongoDB or SMTP.
+func New(repos repositories.Repositories, mail mailer.Mailer) *Services {
+	s := &Services{}
+	s.Authorization = NewAuthorizationService(repos)
+	s.Cascade = NewCascadeService(repos)
+	s.Sessions = NewSessionService(repos)
+	s.Accounts = NewAccountService(repos, mail)
+	s.Audit = NewAuditService(repos, s.Authorization)
+	s.Categories = NewCategoryService(repos, s.Authorization, s.Cascade)
+	s.Exercises = NewExerciseService(repos, s.Categories)
+	s.DietEntries = NewDietEntryService(repos)
+	s.Idempotency = NewIdempotencyService(repos)
+	s.Images = NewImageService(repos)
+	s.Organizations = NewOrganizationService(repos)
+	s.PersonalRecords = NewPersonalRecordService(repos)
+	s.Progress = NewProgressService(repos)
+	s.Search = NewSearchService(repos)
+	s.Sync = NewSyncService(repos, s.Authorization, s.Audit, s.PersonalRecords)
+	s.TraineeAccounts = NewTraineeAccountService(repos, s.Sessions)
+	s.Trainees = NewTraineeService(repos, s.Authorization)
+	s.Trainers = NewTrainerService(repos, s.Sessions, s.Accounts, s.Cascade)
+	s.Trash = NewTrashService(repos, s.Authorization, s.Cascade, s.PersonalRecords)
+	s.WorkoutLogs = NewWorkoutLogService(repos, s.PersonalRecords)
+	s.Programs = NewProgramService(repos, s.WorkoutLogs)
+	return s
+}
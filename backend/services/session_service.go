@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/utils"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRefreshToken = apperrors.Unauthorized("invalid_refresh_token", "invalid or expired refresh token")
+
+// AuthTokens is the token pair handed out on login and refresh
+type AuthTokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+	Role         string `json:"role"`
+	TraineeID    string `json:"trainee_id,omitempty"` // Set for trainee sessions
+}
+
+type SessionService struct {
+	repository repositories.SessionRepository
+}
+
+// Constructor for SessionService
+func NewSessionService(repos repositories.Repositories) *SessionService {
+	return &SessionService{
+		repository: repos.Sessions,
+	}
+}
+
+// CreateSession starts a new session for the identity and issues its first token pair
+func (s *SessionService) CreateSession(identity utils.Identity) (AuthTokens, error) {
+	sessionID, err := utils.RandomToken(16)
+	if err != nil {
+		return AuthTokens{}, err
+	}
+
+	refreshToken, refreshHash, err := newRefreshToken(sessionID)
+	if err != nil {
+		return AuthTokens{}, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:               sessionID,
+		Role:             identity.Role,
+		Email:            identity.Email,
+		TraineeID:        identity.TraineeID,
+		TrainerID:        identity.TrainerID,
+		RefreshTokenHash: refreshHash,
+		ExpiresAt:        now.Add(utils.RefreshTokenTTL),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.repository.CreateSession(session); err != nil {
+		return AuthTokens{}, err
+	}
+
+	return issueTokens(session, refreshToken)
+}
+
+// Refresh rotates the refresh token and issues a new access token.
+// Presenting a refresh token that was already rotated revokes the whole session,
+// since it means the token was copied.
+func (s *SessionService) Refresh(refreshToken string) (AuthTokens, error) {
+	sessionID, _, ok := strings.Cut(refreshToken, ".")
+	if !ok || sessionID == "" {
+		return AuthTokens{}, ErrInvalidRefreshToken
+	}
+
+	session, err := s.repository.GetSessionByID(sessionID)
+	if err != nil {
+		if err == ErrResourceNotFound {
+			return AuthTokens{}, ErrInvalidRefreshToken
+		}
+		return AuthTokens{}, err
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return AuthTokens{}, ErrInvalidRefreshToken
+	}
+
+	presentedHash := utils.HashToken(refreshToken)
+	if presentedHash != session.RefreshTokenHash {
+		if err := s.repository.RevokeSession(session.ID); err != nil {
+			return AuthTokens{}, err
+		}
+		return AuthTokens{}, ErrInvalidRefreshToken
+	}
+
+	newToken, newHash, err := newRefreshToken(session.ID)
+	if err != nil {
+		return AuthTokens{}, err
+	}
+
+	expiresAt := time.Now().Add(utils.RefreshTokenTTL)
+	rotated, err := s.repository.RotateRefreshToken(session.ID, presentedHash, newHash, expiresAt)
+	if err != nil {
+		return AuthTokens{}, err
+	}
+	if !rotated {
+		return AuthTokens{}, ErrInvalidRefreshToken
+	}
+
+	session.ExpiresAt = expiresAt
+	return issueTokens(session, newToken)
+}
+
+// IsSessionActive reports whether access tokens for the session are still accepted
+func (s *SessionService) IsSessionActive(sessionID string) (bool, error) {
+	return s.repository.IsSessionActive(sessionID)
+}
+
+// Logout revokes a single session
+func (s *SessionService) Logout(sessionID string) error {
+	return s.repository.RevokeSession(sessionID)
+}
+
+// LogoutAll revokes every session of the trainer or trainee
+func (s *SessionService) LogoutAll(identity utils.Identity) error {
+	if identity.Role == utils.RoleTrainee {
+		return s.repository.RevokeSessionsByTrainee(identity.TraineeID)
+	}
+	return s.repository.RevokeSessionsByEmail(identity.Email)
+}
+
+// Refresh tokens are "<session id>.<secret>" so the session can be found without a hash lookup
+func newRefreshToken(sessionID string) (token string, hash string, err error) {
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	token = sessionID + "." + secret
+	return token, utils.HashToken(token), nil
+}
+
+func issueTokens(session models.Session, refreshToken string) (AuthTokens, error) {
+	identity := utils.Identity{
+		Role:      session.Role,
+		Email:     session.Email,
+		TraineeID: session.TraineeID,
+		TrainerID: session.TrainerID,
+	}
+	if identity.Role == "" {
+		identity.Role = utils.RoleTrainer // Sessions created before roles existed
+	}
+
+	accessToken, err := utils.GenerateJWT(identity, session.ID)
+	if err != nil {
+		return AuthTokens{}, errors.New("failed to generate token")
+	}
+
+	return AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL.Seconds()),
+		Role:         identity.Role,
+		TraineeID:    identity.TraineeID,
+	}, nil
+}
@@ -0,0 +1,604 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/utils"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	defaultSyncPullLimit = 200
+	maxSyncPullLimit     = 1000
+	syncPushRoute        = "POST /sync/push"
+)
+
+var (
+	ErrInvalidSyncCursor  = apperrors.Validation("invalid_sync_cursor", "invalid sync cursor")
+	ErrInvalidSyncData    = apperrors.Validation("invalid_sync_data", "invalid data")
+	ErrUnknownSyncType    = apperrors.Validation("unknown_sync_type", "unknown record type")
+	ErrSyncTraineeChanged = apperrors.Validation("sync_trainee_changed", "records cannot be moved to another trainee")
+)
+
+// syncValidator checks pushed records against the binding rules of their models, as requests to the
+// REST API are checked
+var syncValidator = func() *validator.Validate {
+	validate := validator.New()
+	validate.SetTagName("binding")
+	return validate
+}()
+
+// syncKind stores one type of record for the SyncService
+type syncKind struct {
+	// load returns the record an offline client created under the given ID, trashed or not
+	load func(clientID string) (models.SyncRecord, error)
+	// create stores a new record for the trainee from the data of the change
+	create func(trainee models.Trainee, change models.SyncChange) error
+	// fields decodes the data of an edit into the fields it changes
+	fields func(trainee models.Trainee, data json.RawMessage) (map[string]interface{}, error)
+	// apply writes the fields to a record that is still at the version
+	apply func(id string, version int64, set map[string]interface{}) error
+	// changes lists the records of the trainees written after the position and up to the until sequence
+	// number, oldest change first
+	changes func(traineeIDs []string, after repositories.SyncPosition, until int64, limit int64) ([]models.SyncRecord, error)
+	// written, if set, is told the trainee whose record a change created, edited or deleted, with the
+	// record as it was before the change, if it existed, and after
+	written func(traineeID string, versions ...models.SyncRecord)
+}
+
+// syncCursor is what a pull cursor stands for: the position in the change feed and when it was handed out
+type syncCursor struct {
+	repositories.SyncPosition
+	IssuedAt time.Time
+}
+
+// SyncService lets offline clients push the workout logs, diet entries and weight entries they changed
+// and pull everything that changed on the server since their cursor.
+// Conflicts are settled by version: an edit or delete made on anything but the current version loses
+// against the server copy, which is sent back so the client can redo the edit on top of it.
+type SyncService struct {
+	workoutLogRepo   repositories.WorkoutLogRepository
+	dietEntryRepo    repositories.DietEntryRepository
+	progressRepo     repositories.ProgressRepository
+	traineeRepo      repositories.TraineeRepository
+	organizationRepo repositories.OrganizationRepository
+	syncFeedRepo     repositories.SyncFeedRepository
+	authorization    *AuthorizationService
+	audit            *AuditService
+	records          *PersonalRecordService
+	kinds            map[string]syncKind
+}
+
+// Constructor for SyncService
+func NewSyncService(repos repositories.Repositories, authorization *AuthorizationService, audit *AuditService, records *PersonalRecordService) *SyncService {
+	s := &SyncService{
+		workoutLogRepo:   repos.WorkoutLogs,
+		dietEntryRepo:    repos.DietEntries,
+		progressRepo:     repos.Progress,
+		traineeRepo:      repos.Trainees,
+		organizationRepo: repos.Organizations,
+		syncFeedRepo:     repos.SyncFeed,
+		authorization:    authorization,
+		audit:            audit,
+		records:          records,
+	}
+	s.kinds = map[string]syncKind{
+		models.SyncWorkoutLogs: s.workoutLogKind(),
+		models.SyncDietEntries: s.dietEntryKind(),
+		models.SyncProgress:    s.progressKind(),
+	}
+	return s
+}
+
+// Push applies the changes in order and reports what became of each. A change that cannot be
+// applied does not stop the ones after it.
+func (s *SyncService) Push(identity utils.Identity, changes []models.SyncChange) []models.SyncResult {
+	results := make([]models.SyncResult, 0, len(changes))
+	for _, change := range changes {
+		results = append(results, s.push(identity, change))
+	}
+	return results
+}
+
+func (s *SyncService) push(identity utils.Identity, change models.SyncChange) models.SyncResult {
+	result := models.SyncResult{ClientID: change.ClientID, ChangeID: change.ChangeID}
+	kind, ok := s.kinds[change.Type]
+	if !ok {
+		return rejectedChange(result, ErrUnknownSyncType)
+	}
+
+	current, err := kind.load(change.ClientID)
+	exists := err == nil
+	if err != nil && err != ErrResourceNotFound {
+		return failedChange(result, err)
+	}
+	if !exists && change.Deleted {
+		// Created and deleted before it ever reached the server
+		result.Status = models.SyncApplied
+		return result
+	}
+
+	var data struct {
+		TraineeID string `json:"trainee_id"`
+	}
+	if !change.Deleted {
+		if err := json.Unmarshal(change.Data, &data); err != nil {
+			return rejectedChange(result, fmt.Errorf("%w: data must be a JSON object", ErrInvalidSyncData))
+		}
+	}
+	traineeID := data.TraineeID
+	if exists {
+		if traineeID != "" && traineeID != current.TraineeID {
+			return rejectedChange(result, ErrSyncTraineeChanged)
+		}
+		traineeID = current.TraineeID
+	}
+
+	trainee, err := s.authorization.AuthorizeTrainee(identity, traineeID, AccessWrite)
+	if err != nil {
+		if err == ErrResourceNotFound || err == ErrAccessDenied {
+			return rejectedChange(result, err)
+		}
+		return failedChange(result, err)
+	}
+
+	if !exists {
+		if err := kind.create(trainee, change); err != nil {
+			return writeFailed(result, err)
+		}
+		if current, err = kind.load(change.ClientID); err != nil {
+			return failedChange(result, err)
+		}
+		s.record(identity, models.AuditCreate, current, nil)
+		if kind.written != nil {
+			kind.written(trainee.ID, current)
+		}
+		return appliedChange(result, current)
+	}
+
+	switch {
+	case current.LastChangeID == change.ChangeID, current.Deleted && change.Deleted:
+		// Applied by an earlier push whose response never reached the client
+		return appliedChange(result, current)
+	case current.Deleted, current.Version != change.BaseVersion:
+		return conflictingChange(result, current)
+	}
+
+	action := models.AuditDelete
+	set := map[string]interface{}{"deleted_at": time.Now()}
+	if !change.Deleted {
+		action = models.AuditUpdate
+		if set, err = kind.fields(trainee, change.Data); err != nil {
+			return writeFailed(result, err)
+		}
+		set["updated_at"] = time.Now()
+	}
+	set["last_change_id"] = change.ChangeID
+
+	before, err := s.audit.Snapshot(change.Type, current.ID)
+	if err != nil {
+		log.Printf("sync: failed to load %s %s: %v", change.Type, current.ID, err)
+	}
+	if err := kind.apply(current.ID, current.Version, set); err != nil {
+		if err != repositories.ErrVersionConflict && err != ErrResourceNotFound {
+			return failedChange(result, err)
+		}
+		// Changed or trashed on the server since it was loaded above
+		if current, err = kind.load(change.ClientID); err != nil {
+			return failedChange(result, err)
+		}
+		return conflictingChange(result, current)
+	}
+
+	previous := current
+	current, err = kind.load(change.ClientID)
+	if kind.written != nil {
+		kind.written(trainee.ID, previous, current)
+	}
+	if err != nil {
+		return failedChange(result, err)
+	}
+	s.record(identity, action, current, before)
+	return appliedChange(result, current)
+}
+
+// Pull returns the next page of changes after the cursor to the records of every trainee the caller can see.
+// Without a cursor it starts from the beginning and leaves out tombstones, as there is nothing to delete yet.
+func (s *SyncService) Pull(identity utils.Identity, cursor string, limit int64) (models.SyncPull, error) {
+	pull := models.SyncPull{Changes: []models.SyncRecord{}}
+
+	after, err := decodeSyncCursor(cursor)
+	if err != nil {
+		return pull, err
+	}
+	if cursor != "" && time.Since(after.IssuedAt) > TrashRetention() {
+		// Tombstones are purged with the trash, a client this far behind could miss deletes
+		after, pull.Reset = syncCursor{}, true
+	}
+
+	if pull.Trainees, err = s.scope(identity); err != nil {
+		return pull, err
+	}
+
+	if limit <= 0 {
+		limit = defaultSyncPullLimit
+	}
+	if limit > maxSyncPullLimit {
+		limit = maxSyncPullLimit
+	}
+
+	// Writes that are not over yet may commit after later ones. The feed is read up to where every write
+	// is over, the same point for every type, so the cursor never moves past a change still to come.
+	until, err := s.syncFeedRepo.ReadableSeq()
+	if err != nil {
+		return pull, err
+	}
+
+	// Each type is read up to one past the limit, so the merged page knows whether more are waiting
+	var records []models.SyncRecord
+	for _, kind := range s.kinds {
+		changes, err := kind.changes(pull.Trainees, after.SyncPosition, until, limit+1)
+		if err != nil {
+			return pull, err
+		}
+		records = append(records, changes...)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Seq != records[j].Seq {
+			return records[i].Seq < records[j].Seq
+		}
+		return records[i].ID < records[j].ID
+	})
+	if int64(len(records)) > limit {
+		records, pull.HasMore = records[:limit], true
+	}
+
+	next := after
+	for _, record := range records {
+		next.SyncPosition = repositories.SyncPosition{Seq: record.Seq, ID: record.ID}
+		if record.Deleted && after.Seq == 0 {
+			continue
+		}
+		pull.Changes = append(pull.Changes, record)
+	}
+	next.IssuedAt = time.Now()
+	pull.Cursor = next.encode()
+	return pull, nil
+}
+
+// scope lists the trainees whose records the caller syncs: a trainee only themselves, a trainer their
+// own trainees and those shared through their organizations
+func (s *SyncService) scope(identity utils.Identity) ([]string, error) {
+	if identity.Role != utils.RoleTrainer {
+		if _, err := s.authorization.AuthorizeTrainee(identity, identity.TraineeID, AccessRead); err != nil {
+			return nil, err
+		}
+		return []string{identity.TraineeID}, nil
+	}
+
+	organizationIDs, err := s.organizationRepo.GetOrganizationIDsByMember(identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	traineeIDs, err := s.traineeRepo.GetTraineeIDs(identity.Email, organizationIDs)
+	if err != nil {
+		return nil, err
+	}
+	if traineeIDs == nil {
+		traineeIDs = []string{}
+	}
+	return traineeIDs, nil
+}
+
+// record adds an applied change to the audit log, as the Auditor does for the REST routes
+func (s *SyncService) record(identity utils.Identity, action string, record models.SyncRecord, before bson.M) {
+	after, err := s.audit.Snapshot(record.Type, record.ID)
+	if err != nil {
+		log.Printf("sync: failed to load %s %s: %v", record.Type, record.ID, err)
+	}
+
+	entry := models.AuditEntry{
+		ActorEmail:     identity.Email,
+		ActorTraineeID: identity.TraineeID,
+		ActorRole:      identity.Role,
+		Action:         action,
+		Route:          syncPushRoute,
+		ResourceType:   record.Type,
+		ResourceID:     record.ID,
+		TraineeID:      record.TraineeID,
+		Status:         http.StatusOK,
+	}
+	if err := s.audit.Record(entry, before, after); err != nil {
+		log.Printf("sync: failed to record %s %s: %v", record.Type, record.ID, err)
+	}
+}
+
+func (s *SyncService) workoutLogKind() syncKind {
+	decode := func(data json.RawMessage) (models.WorkoutLog, error) {
+		var log models.WorkoutLog
+		if err := json.Unmarshal(data, &log); err != nil {
+			return log, fmt.Errorf("%w: %v", ErrInvalidSyncData, err)
+		}
+		if log.Date.IsZero() {
+			return log, fmt.Errorf("%w: date is required", ErrInvalidSyncData)
+		}
+		if err := syncValidator.Struct(log); err != nil {
+			return log, fmt.Errorf("%w: %v", ErrInvalidSyncData, err)
+		}
+		return log, nil
+	}
+
+	return syncKind{
+		load: func(clientID string) (models.SyncRecord, error) {
+			log, err := s.workoutLogRepo.GetWorkoutLogByClientID(clientID)
+			return workoutLogRecord(log), err
+		},
+		create: func(trainee models.Trainee, change models.SyncChange) error {
+			log, err := decode(change.Data)
+			if err != nil {
+				return err
+			}
+			log.ID, log.TraineeID, log.DeletedAt = "", trainee.ID, nil
+			log.ClientID, log.LastChangeID = change.ClientID, change.ChangeID
+			log.CreatedAt = time.Now()
+			log.UpdatedAt = log.CreatedAt
+			log.Version = 1
+			_, err = s.workoutLogRepo.CreateWorkoutLog(log)
+			return err
+		},
+		fields: func(trainee models.Trainee, data json.RawMessage) (map[string]interface{}, error) {
+			log, err := decode(data)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"date": log.Date, "workouts": log.Workouts, "notes": log.Notes}, nil
+		},
+		apply: s.workoutLogRepo.ApplyWorkoutLogChange,
+		written: func(traineeID string, versions ...models.SyncRecord) {
+			logs := make([]models.WorkoutLog, 0, len(versions))
+			for _, version := range versions {
+				if log, ok := version.Data.(models.WorkoutLog); ok {
+					logs = append(logs, log)
+				}
+			}
+			s.records.refresh(traineeID, logs...)
+		},
+		changes: func(traineeIDs []string, after repositories.SyncPosition, until int64, limit int64) ([]models.SyncRecord, error) {
+			logs, err := s.workoutLogRepo.GetWorkoutLogChanges(traineeIDs, after, until, limit)
+			records := make([]models.SyncRecord, 0, len(logs))
+			for _, log := range logs {
+				records = append(records, workoutLogRecord(log))
+			}
+			return records, err
+		},
+	}
+}
+
+func (s *SyncService) dietEntryKind() syncKind {
+	decode := func(data json.RawMessage) (models.DietEntry, error) {
+		var entry models.DietEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return entry, fmt.Errorf("%w: %v", ErrInvalidSyncData, err)
+		}
+		if entry.Date.IsZero() {
+			return entry, fmt.Errorf("%w: date is required", ErrInvalidSyncData)
+		}
+		calculateMealStats(&entry)
+		return entry, nil
+	}
+
+	return syncKind{
+		load: func(clientID string) (models.SyncRecord, error) {
+			entry, err := s.dietEntryRepo.GetDietEntryByClientID(clientID)
+			return dietEntryRecord(entry), err
+		},
+		create: func(trainee models.Trainee, change models.SyncChange) error {
+			entry, err := decode(change.Data)
+			if err != nil {
+				return err
+			}
+			entry.ID, entry.TraineeID, entry.DeletedAt = "", trainee.ID, nil
+			entry.ClientID, entry.LastChangeID = change.ClientID, change.ChangeID
+			entry.CreatedAt = time.Now()
+			entry.UpdatedAt = entry.CreatedAt
+			entry.Version = 1
+			return s.dietEntryRepo.CreateDietEntry(entry)
+		},
+		fields: func(trainee models.Trainee, data json.RawMessage) (map[string]interface{}, error) {
+			entry, err := decode(data)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"date":           entry.Date,
+				"meals":          entry.Meals,
+				"total_calories": entry.TotalCalories,
+				"total_proteins": entry.TotalProteins,
+				"notes":          entry.Notes,
+			}, nil
+		},
+		apply: s.dietEntryRepo.ApplyDietEntryChange,
+		changes: func(traineeIDs []string, after repositories.SyncPosition, until int64, limit int64) ([]models.SyncRecord, error) {
+			entries, err := s.dietEntryRepo.GetDietEntryChanges(traineeIDs, after, until, limit)
+			records := make([]models.SyncRecord, 0, len(entries))
+			for _, entry := range entries {
+				records = append(records, dietEntryRecord(entry))
+			}
+			return records, err
+		},
+	}
+}
+
+func (s *SyncService) progressKind() syncKind {
+	decode := func(trainee models.Trainee, data json.RawMessage) (models.WeightBMIProgress, error) {
+		var progress models.WeightBMIProgress
+		if err := json.Unmarshal(data, &progress); err != nil {
+			return progress, fmt.Errorf("%w: %v", ErrInvalidSyncData, err)
+		}
+		if progress.Date.IsZero() || progress.Weight <= 0 {
+			return progress, fmt.Errorf("%w: date and weight are required", ErrInvalidSyncData)
+		}
+		progress.BMI = calculateBMI(progress.Weight, trainee.Height)
+		return progress, nil
+	}
+
+	return syncKind{
+		load: func(clientID string) (models.SyncRecord, error) {
+			progress, err := s.progressRepo.GetWeightProgressByClientID(clientID)
+			return progressRecord(progress), err
+		},
+		create: func(trainee models.Trainee, change models.SyncChange) error {
+			progress, err := decode(trainee, change.Data)
+			if err != nil {
+				return err
+			}
+			progress.ID, progress.TraineeID, progress.DeletedAt = "", trainee.ID, nil
+			progress.ClientID, progress.LastChangeID = change.ClientID, change.ChangeID
+			progress.CreatedAt = time.Now()
+			progress.UpdatedAt = progress.CreatedAt
+			progress.Version = 1
+			return s.progressRepo.AddWeightProgress(progress)
+		},
+		fields: func(trainee models.Trainee, data json.RawMessage) (map[string]interface{}, error) {
+			progress, err := decode(trainee, data)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"date":     progress.Date,
+				"weight":   progress.Weight,
+				"bmi":      progress.BMI,
+				"body_fat": progress.BodyFat,
+			}, nil
+		},
+		apply: s.progressRepo.ApplyWeightProgressChange,
+		changes: func(traineeIDs []string, after repositories.SyncPosition, until int64, limit int64) ([]models.SyncRecord, error) {
+			entries, err := s.progressRepo.GetWeightProgressChanges(traineeIDs, after, until, limit)
+			records := make([]models.SyncRecord, 0, len(entries))
+			for _, progress := range entries {
+				records = append(records, progressRecord(progress))
+			}
+			return records, err
+		},
+	}
+}
+
+func workoutLogRecord(log models.WorkoutLog) models.SyncRecord {
+	return syncRecord(models.SyncWorkoutLogs, log.ID, log.ClientID, log.TraineeID, log.Version, log.LastChangeID, log.SyncSeq, log.DeletedAt, log)
+}
+
+func dietEntryRecord(entry models.DietEntry) models.SyncRecord {
+	return syncRecord(models.SyncDietEntries, entry.ID, entry.ClientID, entry.TraineeID, entry.Version, entry.LastChangeID, entry.SyncSeq, entry.DeletedAt, entry)
+}
+
+func progressRecord(progress models.WeightBMIProgress) models.SyncRecord {
+	return syncRecord(models.SyncProgress, progress.ID, progress.ClientID, progress.TraineeID, progress.Version, progress.LastChangeID, progress.SyncSeq, progress.DeletedAt, progress)
+}
+
+// syncRecord wraps a stored record for the client. Deleted records become tombstones.
+func syncRecord(kind, id, clientID, traineeID string, version int64, lastChangeID string, seq int64, deletedAt *time.Time, data interface{}) models.SyncRecord {
+	// Records created on the server are known to clients by their server ID
+	if clientID == "" {
+		clientID = id
+	}
+	record := models.SyncRecord{
+		Type:         kind,
+		ID:           id,
+		ClientID:     clientID,
+		TraineeID:    traineeID,
+		Version:      version,
+		Deleted:      deletedAt != nil,
+		LastChangeID: lastChangeID,
+		Seq:          seq,
+	}
+	if !record.Deleted {
+		record.Data = data
+	}
+	return record
+}
+
+func appliedChange(result models.SyncResult, current models.SyncRecord) models.SyncResult {
+	result.Status = models.SyncApplied
+	result.ID, result.Version = current.ID, current.Version
+	return result
+}
+
+func conflictingChange(result models.SyncResult, current models.SyncRecord) models.SyncResult {
+	result.Status = models.SyncConflict
+	result.ID, result.Version = current.ID, current.Version
+	result.Current = &current
+	return result
+}
+
+func rejectedChange(result models.SyncResult, err error) models.SyncResult {
+	result.Status = models.SyncRejected
+	result.Error = err.Error()
+	if appErr, ok := apperrors.As(err); ok {
+		result.Code = appErr.Code
+	}
+	return result
+}
+
+// failedChange reports an error on the server side, the details stay in the log
+func failedChange(result models.SyncResult, err error) models.SyncResult {
+	log.Printf("sync: failed to apply change %s to %s: %v", result.ChangeID, result.ClientID, err)
+	result.Status = models.SyncFailed
+	result.Error = "Failed to apply change"
+	result.Code = "internal_error"
+	return result
+}
+
+// writeFailed tells invalid data, which the client has to fix, apart from server errors
+func writeFailed(result models.SyncResult, err error) models.SyncResult {
+	if errors.Is(err, ErrInvalidSyncData) {
+		return rejectedChange(result, err)
+	}
+	return failedChange(result, err)
+}
+
+// encode turns the cursor into the opaque string clients pass back
+func (c syncCursor) encode() string {
+	raw := fmt.Sprintf("%d:%s:%d", c.Seq, c.ID, c.IssuedAt.Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSyncCursor(cursor string) (syncCursor, error) {
+	if cursor == "" {
+		return syncCursor{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return syncCursor{}, ErrInvalidSyncCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return syncCursor{}, ErrInvalidSyncCursor
+	}
+	seq, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || seq < 0 {
+		return syncCursor{}, ErrInvalidSyncCursor
+	}
+	issuedAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return syncCursor{}, ErrInvalidSyncCursor
+	}
+
+	return syncCursor{
+		SyncPosition: repositories.SyncPosition{Seq: seq, ID: parts[1]},
+		IssuedAt:     time.Unix(issuedAt, 0),
+	}, nil
+}
@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"ironcoach/models"
+	"testing"
+)
+
+func TestPushValidatesWorkoutLogs(t *testing.T) {
+	app := newServices()
+	trainee := addTrainee(t, app, "coach@ironcoach.test")
+	coach := trainer("coach@ironcoach.test")
+
+	push := func(clientID string, baseVersion int64, workouts string) models.SyncResult {
+		t.Helper()
+		data := `{"trainee_id":"` + trainee.ID + `","date":"2024-01-01","workouts":` + workouts + `}`
+		results := app.Sync.Push(coach, []models.SyncChange{{
+			Type:        models.SyncWorkoutLogs,
+			ClientID:    clientID,
+			ChangeID:    fmt.Sprintf("%s-%d", clientID, baseVersion),
+			BaseVersion: baseVersion,
+			Data:        json.RawMessage(data),
+		}})
+		return results[0]
+	}
+
+	for _, tc := range []struct {
+		name, workouts, want string
+	}{
+		{"structured", `[{"exercise":"Squat","sets":[{"type":"working","reps":5,"weight":100}]}]`, models.SyncApplied},
+		{"legacy", `[{"exercise":"Squat","sets":2,"reps":[5,5],"weight":[100,100]}]`, models.SyncApplied},
+		{"unknown set type", `[{"exercise":"Squat","sets":[{"type":"heavy","reps":5,"weight":100}]}]`, models.SyncRejected},
+		{"negative reps", `[{"exercise":"Squat","sets":[{"reps":-5,"weight":100}]}]`, models.SyncRejected},
+		{"rpe out of range", `[{"exercise":"Squat","sets":[{"reps":5,"weight":100,"rpe":11}]}]`, models.SyncRejected},
+		{"legacy sets unlike the reps", `[{"exercise":"Squat","sets":3,"reps":[5,5],"weight":[100,100]}]`, models.SyncRejected},
+		{"legacy reps unlike the weights", `[{"exercise":"Squat","sets":2,"reps":[5,5],"weight":[100]}]`, models.SyncRejected},
+		{"reps without the number of sets", `[{"exercise":"Squat","reps":[5,5],"weight":[100,100]}]`, models.SyncRejected},
+		{"reps next to structured sets", `[{"exercise":"Squat","sets":[{"reps":5,"weight":100}],"reps":[5]}]`, models.SyncRejected},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := push(tc.name, 0, tc.workouts)
+			if result.Status != tc.want {
+				t.Fatalf("status = %s (%s), want %s", result.Status, result.Error, tc.want)
+			}
+			if tc.want == models.SyncRejected && result.Code != "invalid_sync_data" {
+				t.Errorf("code = %q, want invalid_sync_data", result.Code)
+			}
+		})
+	}
+
+	// Edits are held to the same rules as new logs
+	result := push("structured", 1, `[{"exercise":"Squat","sets":[{"type":"heavy","reps":5,"weight":100}]}]`)
+	if result.Status != models.SyncRejected {
+		t.Errorf("edit with an unknown set type = %s, want rejected", result.Status)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/utils"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	inviteCodeAlphabet    = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // No 0/O or 1/I, codes are typed by hand
+	inviteCodeLength      = 8
+	traineePinLength      = 6
+	maxTraineePinAttempts = 5
+)
+
+var ErrInvalidTraineeLogin = apperrors.Unauthorized("invalid_trainee_login", "invalid invite code or PIN")
+
+// TraineeAccess is handed to the trainer once, so they can pass it on to the trainee
+type TraineeAccess struct {
+	InviteCode string `json:"invite_code"`
+	PIN        string `json:"pin"`
+	InviteLink string `json:"invite_link"`
+}
+
+// TraineeAccountService manages self-service logins for trainees
+type TraineeAccountService struct {
+	repository  repositories.TraineeAccountRepository
+	traineeRepo repositories.TraineeRepository
+	sessionRepo repositories.SessionRepository
+	sessions    *SessionService
+}
+
+// Constructor for TraineeAccountService
+func NewTraineeAccountService(repos repositories.Repositories, sessions *SessionService) *TraineeAccountService {
+	return &TraineeAccountService{
+		repository:  repos.TraineeAccounts,
+		traineeRepo: repos.Trainees,
+		sessionRepo: repos.Sessions,
+		sessions:    sessions,
+	}
+}
+
+// IssueAccess creates a new invite code and PIN for the trainee.
+// Issuing again replaces the old credentials and signs the trainee out everywhere.
+func (s *TraineeAccountService) IssueAccess(traineeID string, trainerID string) (TraineeAccess, error) {
+	inviteCode, err := utils.RandomCode(inviteCodeLength, inviteCodeAlphabet)
+	if err != nil {
+		return TraineeAccess{}, err
+	}
+	pin, err := utils.RandomCode(traineePinLength, "0123456789")
+	if err != nil {
+		return TraineeAccess{}, err
+	}
+	pinHash, err := utils.HashPassword(pin)
+	if err != nil {
+		return TraineeAccess{}, err
+	}
+
+	now := time.Now()
+	account := models.TraineeAccount{
+		ID:         traineeID,
+		TrainerID:  trainerID,
+		InviteCode: inviteCode,
+		PinHash:    pinHash,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if existing, err := s.repository.GetAccount(traineeID); err == nil {
+		account.CreatedAt = existing.CreatedAt
+	}
+
+	if err := s.repository.UpsertAccount(account); err != nil {
+		return TraineeAccess{}, err
+	}
+	if err := s.sessionRepo.RevokeSessionsByTrainee(traineeID); err != nil {
+		return TraineeAccess{}, err
+	}
+
+	return TraineeAccess{
+		InviteCode: inviteCode,
+		PIN:        pin,
+		InviteLink: appBaseURL() + "/trainee-login?code=" + url.QueryEscape(inviteCode),
+	}, nil
+}
+
+// RevokeAccess disables the trainee's login and ends their sessions
+func (s *TraineeAccountService) RevokeAccess(traineeID string) error {
+	if err := s.repository.DisableAccount(traineeID); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeSessionsByTrainee(traineeID)
+}
+
+// Login authenticates a trainee with an invite code and PIN and starts a trainee session
+func (s *TraineeAccountService) Login(inviteCode string, pin string) (AuthTokens, error) {
+	account, err := s.repository.GetAccountByInviteCode(strings.ToUpper(strings.TrimSpace(inviteCode)))
+	if err != nil {
+		if err == ErrResourceNotFound {
+			return AuthTokens{}, ErrInvalidTraineeLogin
+		}
+		return AuthTokens{}, err
+	}
+	if account.DisabledAt != nil {
+		return AuthTokens{}, ErrInvalidTraineeLogin
+	}
+
+	if !utils.CheckPassword(account.PinHash, pin) {
+		if err := s.repository.RecordFailedAttempt(account.ID, maxTraineePinAttempts); err != nil {
+			return AuthTokens{}, err
+		}
+		return AuthTokens{}, ErrInvalidTraineeLogin
+	}
+
+	// The trainee may have been removed since the access was issued
+	trainee, err := s.traineeRepo.GetTraineeByID(account.ID)
+	if err != nil {
+		if err == ErrResourceNotFound {
+			return AuthTokens{}, ErrInvalidTraineeLogin
+		}
+		return AuthTokens{}, err
+	}
+
+	if err := s.repository.RecordLogin(account.ID); err != nil {
+		return AuthTokens{}, err
+	}
+
+	return s.sessions.CreateSession(utils.Identity{
+		Role:      utils.RoleTrainee,
+		TraineeID: account.ID,
+		TrainerID: trainee.TrainerID,
+	})
+}
@@ -1,32 +1,61 @@
 package services
 
 import (
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/repositories"
+	"log"
 	"time"
 )
 
+var (
+	ErrInvalidTransferTarget = apperrors.Validation("invalid_transfer_target", "trainee can only be transferred to an owner or coach of the organization")
+	ErrEncryptedFieldPath    = repositories.ErrSealedFieldPath // Updates must replace medical and health fields as a whole
+)
+
 type TraineeService struct {
-    repository *repositories.TraineeRepository
+    repository       repositories.TraineeRepository
+    organizationRepo repositories.OrganizationRepository
+    trainerRepo      repositories.TrainerRepository
+    accountRepo      repositories.TraineeAccountRepository
+    sessionRepo      repositories.SessionRepository
+    authorization    *AuthorizationService
 }
 
 // Constructor for TraineeService
-func NewTraineeService() *TraineeService {
+func NewTraineeService(repos repositories.Repositories, authorization *AuthorizationService) *TraineeService {
     return &TraineeService{
-        repository: repositories.NewTraineeRepository(),
+        repository:       repos.Trainees,
+        organizationRepo: repos.Organizations,
+        trainerRepo:      repos.Trainers,
+        accountRepo:      repos.TraineeAccounts,
+        sessionRepo:      repos.Sessions,
+        authorization:    authorization,
     }
 }
 
-// Add a new trainee
+// Add a new trainee, only owners and coaches may add trainees to an organization
 func (s *TraineeService) AddTrainee(trainee models.Trainee) error {
+    if trainee.OrganizationID != "" {
+        if _, err := s.authorization.AuthorizeOrganization(trainee.TrainerID, trainee.OrganizationID, models.OrgRoleOwner, models.OrgRoleCoach); err != nil {
+            return err
+        }
+    }
+
     trainee.CreatedAt=time.Now()
     trainee.UpdatedAt=time.Now()
+    trainee.Version = 1
     return s.repository.CreateTrainee(trainee)
 }
 
-// Get all trainees for a trainer
-func (s *TraineeService) GetTraineesByTrainer(trainerID string, status string) ([]models.Trainee, error) {
-    return s.repository.GetTraineesByTrainer(trainerID, status)
+// Get a page of the trainees of a trainer, including those shared through their organizations
+func (s *TraineeService) GetTraineesByTrainer(trainerID string, status string, list models.ListOptions) (models.Page, error) {
+    organizationIDs, err := s.organizationRepo.GetOrganizationIDsByMember(trainerID)
+    if err != nil {
+        return models.Page{}, err
+    }
+    trainees, next, err := s.repository.GetTraineesByTrainer(trainerID, organizationIDs, status, list)
+    return models.Page{Items: trainees, NextCursor: next}, err
 }
 
 // Get trainee of a trainer by ID
@@ -36,12 +65,66 @@ func (s *TraineeService) GetTraineeByID(traineeID string) (models.Trainee, error
 
 
 // Update a trainee
-func (s *TraineeService) UpdateTrainee(id string, update map[string]interface{}) error {
+func (s *TraineeService) UpdateTrainee(id string, version int64, patch models.TraineePatch) error {
+    update := models.PatchFields(patch)
+    if len(update) == 0 {
+        return ErrEmptyUpdate
+    }
+
     update["updated_at"] = time.Now()
-    return s.repository.UpdateTrainee(id, update)
+    return s.repository.UpdateTrainee(id, version, update)
+}
+
+// Move a trainee to the trash and sign them out, everything they recorded stays until restored or purged
+func (s *TraineeService) DeleteTrainee(id string, version int64) error {
+    if err := s.repository.SoftDeleteTrainee(id, version); err != nil {
+        return err
+    }
+    return s.sessionRepo.RevokeSessionsByTrainee(id)
+}
+
+// TransferTrainee hands a trainee over to another trainer.
+// Moving into an organization needs the caller and the new trainer to be owners or coaches of it,
+// an empty organizationID makes the trainee a personal trainee of the new trainer.
+// The trainee is signed out so their next login picks up the new trainer.
+func (s *TraineeService) TransferTrainee(id string, callerEmail string, trainerEmail string, organizationID string) error {
+    if _, err := s.trainerRepo.FindByEmail(trainerEmail); err != nil {
+        if err == ErrResourceNotFound {
+            return ErrTrainerNotFound
+        }
+        return err
+    }
+
+    if organizationID != "" {
+        if _, err := s.authorization.AuthorizeOrganization(callerEmail, organizationID, models.OrgRoleOwner, models.OrgRoleCoach); err != nil {
+            return err
+        }
+        if _, err := s.authorization.AuthorizeOrganization(trainerEmail, organizationID, models.OrgRoleOwner, models.OrgRoleCoach); err != nil {
+            if err == ErrResourceNotFound || err == ErrAccessDenied {
+                return ErrInvalidTransferTarget
+            }
+            return err
+        }
+    }
+
+    if err := s.repository.TransferTrainee(id, trainerEmail, organizationID); err != nil {
+        return err
+    }
+    if err := s.accountRepo.UpdateTrainer(id, trainerEmail); err != nil {
+        return err
+    }
+    return s.sessionRepo.RevokeSessionsByTrainee(id)
 }
 
-// Delete a trainee
-func (s *TraineeService) DeleteTrainee(id string) error {
-    return s.repository.DeleteTrainee(id)
+// StartResealing encrypts health data left in plaintext and moves data keys onto the active
+// encryption key in the background, so a key rotation completes without downtime
+func (s *TraineeService) StartResealing() {
+    go func() {
+        updated, err := s.repository.ResealTrainees()
+        if err != nil {
+            log.Printf("Failed to reseal trainee health data: %v", err)
+        } else if updated > 0 {
+            log.Printf("Resealed health data of %d trainees", updated)
+        }
+    }()
 }
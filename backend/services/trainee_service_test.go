@@ -0,0 +1,123 @@
+package services_test
+
+import (
+	"io"
+	"ironcoach/mailer"
+	"ironcoach/models"
+	"ironcoach/repositories/memory"
+	"ironcoach/services"
+	"ironcoach/utils"
+	"log"
+	"testing"
+)
+
+func newServices() *services.Services {
+	return services.New(memory.NewRepositories(), mailer.NewLogMailer(log.New(io.Discard, "", 0), "noreply@ironcoach.test"))
+}
+
+func trainer(email string) utils.Identity {
+	return utils.Identity{Role: utils.RoleTrainer, Email: email}
+}
+
+// addTrainee adds a trainee for the trainer and returns it as stored
+func addTrainee(t *testing.T, app *services.Services, trainerID string) models.Trainee {
+	t.Helper()
+	if err := app.Trainees.AddTrainee(models.Trainee{Name: "Alex", PhoneNumber: "555", Gender: "m", TrainerID: trainerID}); err != nil {
+		t.Fatalf("AddTrainee: %v", err)
+	}
+
+	page, err := app.Trainees.GetTraineesByTrainer(trainerID, "", models.ListOptions{})
+	if err != nil {
+		t.Fatalf("GetTraineesByTrainer: %v", err)
+	}
+	trainees := page.Items.([]models.Trainee)
+	if len(trainees) != 1 {
+		t.Fatalf("listed %d trainees, want 1", len(trainees))
+	}
+	return trainees[0]
+}
+
+func TestAddTrainee(t *testing.T) {
+	app := newServices()
+	trainee := addTrainee(t, app, "coach@ironcoach.test")
+
+	if trainee.ID == "" {
+		t.Error("trainee was stored without an ID")
+	}
+	if trainee.Version != 1 {
+		t.Errorf("version = %d, want 1", trainee.Version)
+	}
+	if trainee.CreatedAt.IsZero() || trainee.UpdatedAt.IsZero() {
+		t.Error("timestamps were not set")
+	}
+
+	page, err := app.Trainees.GetTraineesByTrainer("other@ironcoach.test", "", models.ListOptions{})
+	if err != nil {
+		t.Fatalf("GetTraineesByTrainer: %v", err)
+	}
+	if others := page.Items.([]models.Trainee); len(others) != 0 {
+		t.Errorf("another trainer lists %d trainees, want 0", len(others))
+	}
+}
+
+func TestAuthorizeTrainee(t *testing.T) {
+	app := newServices()
+	trainee := addTrainee(t, app, "owner@ironcoach.test")
+	self := utils.Identity{Role: utils.RoleTrainee, TraineeID: trainee.ID, TrainerID: "owner@ironcoach.test"}
+
+	for _, tc := range []struct {
+		name      string
+		identity  utils.Identity
+		traineeID string
+		access    services.Access
+		want      error
+	}{
+		{"owner manages", trainer("owner@ironcoach.test"), trainee.ID, services.AccessManage, nil},
+		{"other trainer reads", trainer("other@ironcoach.test"), trainee.ID, services.AccessRead, services.ErrAccessDenied},
+		{"trainee writes own logs", self, trainee.ID, services.AccessWrite, nil},
+		{"trainee manages self", self, trainee.ID, services.AccessManage, services.ErrAccessDenied},
+		{"unknown trainee", trainer("owner@ironcoach.test"), "000000000000000000000000", services.AccessRead, services.ErrResourceNotFound},
+		{"malformed ID", trainer("owner@ironcoach.test"), "nope", services.AccessRead, services.ErrResourceNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := app.Authorization.AuthorizeTrainee(tc.identity, tc.traineeID, tc.access); err != tc.want {
+				t.Errorf("AuthorizeTrainee = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpdateTraineeVersion(t *testing.T) {
+	app := newServices()
+	trainee := addTrainee(t, app, "coach@ironcoach.test")
+	name := "Sam"
+
+	if err := app.Trainees.UpdateTrainee(trainee.ID, trainee.Version, models.TraineePatch{Name: &name}); err != nil {
+		t.Fatalf("UpdateTrainee with the current version: %v", err)
+	}
+	updated, err := app.Trainees.GetTraineeByID(trainee.ID)
+	if err != nil {
+		t.Fatalf("GetTraineeByID: %v", err)
+	}
+	if updated.Name != name || updated.Version != trainee.Version+1 {
+		t.Errorf("got name %q version %d, want %q version %d", updated.Name, updated.Version, name, trainee.Version+1)
+	}
+
+	stale := "Kim"
+	if err := app.Trainees.UpdateTrainee(trainee.ID, trainee.Version, models.TraineePatch{Name: &stale}); err != services.ErrVersionConflict {
+		t.Errorf("UpdateTrainee with a stale version = %v, want ErrVersionConflict", err)
+	}
+	if err := app.Trainees.UpdateTrainee(trainee.ID, updated.Version, models.TraineePatch{}); err != services.ErrEmptyUpdate {
+		t.Errorf("UpdateTrainee without fields = %v, want ErrEmptyUpdate", err)
+	}
+
+	if err := app.Trainees.DeleteTrainee(trainee.ID, trainee.Version); err != services.ErrVersionConflict {
+		t.Errorf("DeleteTrainee with a stale version = %v, want ErrVersionConflict", err)
+	}
+	if err := app.Trainees.DeleteTrainee(trainee.ID, updated.Version); err != nil {
+		t.Errorf("DeleteTrainee with the current version: %v", err)
+	}
+	if _, err := app.Trainees.GetTraineeByID(trainee.ID); err != services.ErrResourceNotFound {
+		t.Errorf("GetTraineeByID after delete = %v, want ErrResourceNotFound", err)
+	}
+}
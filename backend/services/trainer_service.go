@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/repositories"
 	"ironcoach/utils"
@@ -10,26 +11,29 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var (
+	ErrTrainerExists      = apperrors.Conflict("trainer_exists", "trainer with this email already exists")
+	ErrInvalidCredentials = apperrors.Unauthorized("invalid_credentials", "invalid email or password")
+)
+
 type TrainerService struct {
-	repository     *repositories.TrainerRepository
-	categoryRepo   *repositories.CategoryRepository
-	exerciseRepo   *repositories.ExerciseRepository
-	traineeRepo    *repositories.TraineeRepository
-	workoutLogRepo *repositories.WorkoutLogRepository
-	dietEntryRepo  *repositories.DietEntryRepository
-	progressRepo   *repositories.ProgressRepository
+	repository   repositories.TrainerRepository
+	categoryRepo repositories.CategoryRepository
+	exerciseRepo repositories.ExerciseRepository
+	sessions     *SessionService
+	accounts     *AccountService
+	cascade      *CascadeService
 }
 
 // constructor for TrainerService
-func NewTrainerService() *TrainerService {
+func NewTrainerService(repos repositories.Repositories, sessions *SessionService, accounts *AccountService, cascade *CascadeService) *TrainerService {
 	return &TrainerService{
-		repository:     repositories.NewTrainerRepository(),
-		categoryRepo:   repositories.NewCategoryRepository(),
-		exerciseRepo:   repositories.NewExerciseRepository(),
-		traineeRepo:    repositories.NewTraineeRepository(),
-		workoutLogRepo: repositories.NewWorkoutLogRepository(),
-		dietEntryRepo:  repositories.NewDietEntryRepository(),
-		progressRepo:   repositories.NewProgressRepository(),
+		repository:   repos.Trainers,
+		categoryRepo: repos.Categories,
+		exerciseRepo: repos.Exercises,
+		sessions:     sessions,
+		accounts:     accounts,
+		cascade:      cascade,
 	}
 }
 
@@ -38,7 +42,11 @@ func (s *TrainerService) RegisterTrainer(trainer models.Trainer) error {
 
 	existingTrainer, _ := s.repository.FindByEmail(trainer.Email)
 	if existingTrainer.Email != "" {
-		return errors.New("trainer with this email already exists")
+		return ErrTrainerExists
+	}
+
+	if _, err := LoadTimeZone(trainer.TimeZone); err != nil {
+		return err
 	}
 
 	//Hash the password
@@ -48,10 +56,14 @@ func (s *TrainerService) RegisterTrainer(trainer models.Trainer) error {
 	}
 
 	trainer.Password = hashedPassword
+	trainer.EmailVerified = false
 	trainer.CreatedAt = time.Now()
 
 	//save trainer to database
 	if err := s.repository.CreateTrainer(trainer); err != nil {
+		if err == repositories.ErrDuplicateKey {
+			return ErrTrainerExists
+		}
 		return err
 	}
 
@@ -99,26 +111,34 @@ func (s *TrainerService) RegisterTrainer(trainer models.Trainer) error {
 		}
 	}
 
+	s.accounts.sendVerificationEmailAsync(trainer.Email)
+
 	return nil
 }
 
-func (s *TrainerService) LoginTrainer(email, password string) (token string, err error) {
+func (s *TrainerService) LoginTrainer(email, password string) (tokens AuthTokens, err error) {
 
 	//Find Trainer by email
 	trainer, err := s.repository.FindByEmail(email)
 	if err != nil || trainer.Email == "" {
-		err = errors.New("invalid email or password")
+		err = ErrInvalidCredentials
 		return
 	}
 
 	//verify password
 	if !utils.CheckPassword(trainer.Password, password) {
-		err = errors.New("invalid email or password")
+		err = ErrInvalidCredentials
 		return
 	}
 
-	//Generate JWT token
-	token, err = utils.GenerateJWT(trainer.Email)
+	//Optionally refuse accounts that never confirmed their email
+	if RequireEmailVerification() && !trainer.EmailVerified {
+		err = ErrEmailNotVerified
+		return
+	}
+
+	//Start a session and issue the access/refresh token pair
+	tokens, err = s.sessions.CreateSession(utils.Identity{Role: utils.RoleTrainer, Email: trainer.Email})
 	if err != nil {
 		err = errors.New("failed to generate token")
 		return
@@ -127,65 +147,26 @@ func (s *TrainerService) LoginTrainer(email, password string) (token string, err
 	return
 }
 
-func (s *TrainerService) GetTrainers() ([]models.Trainer, error) {
-	return s.repository.GetTrainers()
+// Get a page of all trainers
+func (s *TrainerService) GetTrainers(list models.ListOptions) (models.Page, error) {
+	trainers, next, err := s.repository.GetTrainers(list)
+	return models.Page{Items: trainers, NextCursor: next}, err
 }
 
 func (s *TrainerService) GetTrainerByID(id string) (models.Trainer, error) {
 	return s.repository.GetTrainerByID(id)
 }
 
-// DeleteTrainerCascade performs cascading delete of trainer and all associated data
-func (s *TrainerService) DeleteTrainerCascade(trainerEmail string) error {
-	// Step 1: Get all trainee IDs for this trainer
-	traineeIDs, err := s.traineeRepo.GetTraineeIDsByTrainer(trainerEmail)
-	if err != nil {
-		return err
-	}
-
-	// Step 3: Delete all trainee-related data first
-	if len(traineeIDs) > 0 {
-		// Delete workout logs
-		if err := s.workoutLogRepo.DeleteWorkoutLogsByTrainees(traineeIDs); err != nil {
-			return err
-		}
-
-		// Delete diet entries
-		if err := s.dietEntryRepo.DeleteDietEntriesByTrainees(traineeIDs); err != nil {
-			return err
-		}
-
-		// Delete progress entries
-		if err := s.progressRepo.DeleteProgressByTrainees(traineeIDs); err != nil {
-			return err
-		}
-
-		// Delete trainees
-		if err := s.traineeRepo.DeleteTraineesByTrainer(trainerEmail); err != nil {
-			return err
-		}
-	}
-
-	// Step 4: Delete exercises for each category (this will also delete related workout logs)
-	categories, err := s.categoryRepo.GetCategories(trainerEmail)
-	if err != nil {
-		return err
-	}
-	for _, category := range categories {
-		if err := s.exerciseRepo.DeleteExercisesByCategoryID(category.ID.Hex()); err != nil {
-			return err
-		}
-	}
-
-	// Step 5: Delete categories
-	if err := s.categoryRepo.DeleteCategoriesByTrainer(trainerEmail); err != nil {
-		return err
-	}
-
-	// Step 6: Finally, delete the trainer
-	if err := s.repository.DeleteTrainer(trainerEmail); err != nil {
+// SetTimeZone changes the time zone that decides what today is for the trainer and their trainees
+func (s *TrainerService) SetTimeZone(email string, timeZone string) error {
+	if _, err := LoadTimeZone(timeZone); err != nil {
 		return err
 	}
+	return s.repository.SetTimeZone(email, timeZone)
+}
 
-	return nil
+// DeleteTrainerCascade performs cascading delete of trainer and all associated data in one transaction.
+// Trainees and libraries shared through an organization stay with the organization.
+func (s *TrainerService) DeleteTrainerCascade(trainerEmail string) (models.DeletionSummary, error) {
+	return s.cascade.DeleteTrainer(trainerEmail)
 }
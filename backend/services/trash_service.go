@@ -0,0 +1,228 @@
+package services
+
+import (
+	"ironcoach/apperrors"
+	"ironcoach/models"
+	"ironcoach/repositories"
+	"ironcoach/utils"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Kinds of documents that can be in the trash, named after their collections
+const (
+	TrashTrainees    = "trainees"
+	TrashWorkoutLogs = "workout_logs"
+	TrashDietEntries = "diet_entries"
+)
+
+const (
+	defaultTrashRetention = 30 * 24 * time.Hour
+	trashPurgeInterval    = time.Hour
+)
+
+var ErrUnknownTrashKind = apperrors.NotFound("unknown_trash_kind", "unknown trash kind")
+
+// Trash lists what the caller can restore
+type Trash struct {
+	Trainees    []models.Trainee    `json:"trainees"`
+	WorkoutLogs []models.WorkoutLog `json:"workout_logs"`
+	DietEntries []models.DietEntry  `json:"diet_entries"`
+}
+
+// TrashService lists, restores and purges soft deleted trainees, workout logs and diet entries
+type TrashService struct {
+	traineeRepo      repositories.TraineeRepository
+	workoutLogRepo   repositories.WorkoutLogRepository
+	dietEntryRepo    repositories.DietEntryRepository
+	progressRepo     repositories.ProgressRepository
+	organizationRepo repositories.OrganizationRepository
+	authorization    *AuthorizationService
+	cascade          *CascadeService
+	records          *PersonalRecordService
+}
+
+// Constructor for TrashService
+func NewTrashService(repos repositories.Repositories, authorization *AuthorizationService, cascade *CascadeService, records *PersonalRecordService) *TrashService {
+	return &TrashService{
+		traineeRepo:      repos.Trainees,
+		workoutLogRepo:   repos.WorkoutLogs,
+		dietEntryRepo:    repos.DietEntries,
+		progressRepo:     repos.Progress,
+		organizationRepo: repos.Organizations,
+		authorization:    authorization,
+		cascade:          cascade,
+		records:          records,
+	}
+}
+
+// TrashRetention is how long deleted documents stay restorable, set in days with TRASH_RETENTION_DAYS
+func TrashRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		return defaultTrashRetention
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// GetTrash lists the trashed trainees, workout logs and diet entries the caller can see.
+// Trainees only see their own logs and entries.
+func (s *TrashService) GetTrash(identity utils.Identity) (Trash, error) {
+	trash := Trash{
+		Trainees:    []models.Trainee{},
+		WorkoutLogs: []models.WorkoutLog{},
+		DietEntries: []models.DietEntry{},
+	}
+
+	traineeIDs := []string{identity.TraineeID}
+	if identity.Role == utils.RoleTrainer {
+		organizationIDs, err := s.organizationRepo.GetOrganizationIDsByMember(identity.Email)
+		if err != nil {
+			return trash, err
+		}
+		if trash.Trainees, err = s.traineeRepo.GetDeletedTrainees(identity.Email, organizationIDs); err != nil {
+			return trash, err
+		}
+		if traineeIDs, err = s.traineeRepo.GetTraineeIDs(identity.Email, organizationIDs); err != nil {
+			return trash, err
+		}
+	}
+
+	var err error
+	if trash.WorkoutLogs, err = s.workoutLogRepo.GetDeletedWorkoutLogs(traineeIDs); err != nil {
+		return trash, err
+	}
+	if trash.DietEntries, err = s.dietEntryRepo.GetDeletedDietEntries(traineeIDs); err != nil {
+		return trash, err
+	}
+	return trash, nil
+}
+
+// Restore takes a document out of the trash
+func (s *TrashService) Restore(identity utils.Identity, kind string, id string) error {
+	if err := s.authorize(identity, kind, id); err != nil {
+		return err
+	}
+
+	var err error
+	switch kind {
+	case TrashTrainees:
+		err = s.traineeRepo.RestoreTrainee(id)
+	case TrashWorkoutLogs:
+		if err = s.workoutLogRepo.RestoreWorkoutLog(id); err == nil {
+			// The log counts for the personal records again
+			if workoutLog, err := s.workoutLogRepo.GetWorkoutLogByID(id); err == nil {
+				s.records.refresh(workoutLog.TraineeID, workoutLog)
+			}
+		}
+	case TrashDietEntries:
+		err = s.dietEntryRepo.RestoreDietEntry(id)
+	}
+	return err
+}
+
+// Purge permanently deletes a document that is in the trash.
+// Purging a trainee also removes everything recorded for them.
+func (s *TrashService) Purge(identity utils.Identity, kind string, id string) (models.DeletionSummary, error) {
+	if err := s.authorize(identity, kind, id); err != nil {
+		return nil, err
+	}
+
+	summary := models.DeletionSummary{}
+	var deleted int64
+	var err error
+	switch kind {
+	case TrashTrainees:
+		return s.cascade.DeleteTrainees([]string{id})
+	case TrashWorkoutLogs:
+		deleted, err = s.workoutLogRepo.PurgeWorkoutLog(id)
+	case TrashDietEntries:
+		deleted, err = s.dietEntryRepo.PurgeDietEntry(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	summary.Add(kind, deleted)
+	return summary, nil
+}
+
+// PurgeExpired permanently deletes everything that has been in the trash for longer than the retention
+func (s *TrashService) PurgeExpired(retention time.Duration) (models.DeletionSummary, error) {
+	cutoff := time.Now().Add(-retention)
+
+	traineeIDs, err := s.traineeRepo.GetTraineeIDsDeletedBefore(cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := models.DeletionSummary{}
+	if len(traineeIDs) > 0 {
+		if summary, err = s.cascade.DeleteTrainees(traineeIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	deleted, err := s.workoutLogRepo.PurgeWorkoutLogsDeletedBefore(cutoff)
+	if err != nil {
+		return summary, err
+	}
+	summary.Add(TrashWorkoutLogs, deleted)
+
+	if deleted, err = s.dietEntryRepo.PurgeDietEntriesDeletedBefore(cutoff); err != nil {
+		return summary, err
+	}
+	summary.Add(TrashDietEntries, deleted)
+
+	// Weight entries deleted by offline clients are kept as tombstones for as long as the rest of the trash
+	if deleted, err = s.progressRepo.PurgeWeightProgressDeletedBefore(cutoff); err != nil {
+		return summary, err
+	}
+	summary.Add(models.SyncProgress, deleted)
+	return summary, nil
+}
+
+// StartPurger runs PurgeExpired in the background every hour
+func (s *TrashService) StartPurger(retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			summary, err := s.PurgeExpired(retention)
+			if err != nil {
+				log.Printf("Failed to purge trash: %v", err)
+			} else if summary.Total() > 0 {
+				log.Printf("Purged trash older than %s: %v", retention, summary)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// authorize checks the caller may restore or purge the document.
+// Trainees need manage access, logs and entries need write access to a trainee that is not in the trash.
+func (s *TrashService) authorize(identity utils.Identity, kind string, id string) error {
+	switch kind {
+	case TrashTrainees:
+		_, err := s.authorization.AuthorizeDeletedTrainee(identity, id, AccessManage)
+		return err
+	case TrashWorkoutLogs:
+		workoutLog, err := s.workoutLogRepo.GetDeletedWorkoutLogByID(id)
+		if err != nil {
+			return err
+		}
+		_, err = s.authorization.AuthorizeTrainee(identity, workoutLog.TraineeID, AccessWrite)
+		return err
+	case TrashDietEntries:
+		entry, err := s.dietEntryRepo.GetDeletedDietEntryByID(id)
+		if err != nil {
+			return err
+		}
+		_, err = s.authorization.AuthorizeTrainee(identity, entry.TraineeID, AccessWrite)
+		return err
+	default:
+		return ErrUnknownTrashKind
+	}
+}
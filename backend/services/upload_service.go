@@ -7,13 +7,13 @@ import (
 )
 
 type ImageService struct {
-    repository *repositories.ImageRepository
+    repository repositories.ImageRepository
 }
 
 // Constructor for ImageService
-func NewImageService() *ImageService {
+func NewImageService(repos repositories.Repositories) *ImageService {
     return &ImageService{
-        repository: repositories.NewImageRepository(),
+        repository: repos.Images,
     }
 }
 
@@ -1,90 +1,144 @@
 package services
 
 import (
-	"errors"
+	"ironcoach/apperrors"
 	"ironcoach/models"
 	"ironcoach/repositories"
 	"time"
 )
 
+var (
+	ErrEmptyUpdate     = apperrors.Validation("empty_update", "update data cannot be empty")
+	ErrVersionConflict = repositories.ErrVersionConflict // The document was changed since the client read it
+	ErrInvalidCursor   = repositories.ErrInvalidCursor   // A list cursor that is malformed or was made for another sort
+)
+
 type WorkoutLogService struct {
-	repository *repositories.WorkoutLogRepository
+	repository repositories.WorkoutLogRepository
+	records    *PersonalRecordService
+	calendar   *calendar
 }
 
 // Constructor for WorkoutLogService
-func NewWorkoutLogService() *WorkoutLogService {
+func NewWorkoutLogService(repos repositories.Repositories, records *PersonalRecordService) *WorkoutLogService {
 	return &WorkoutLogService{
-		repository: repositories.NewWorkoutLogRepository(),
+		repository: repos.WorkoutLogs,
+		records:    records,
+		calendar:   newCalendar(repos),
 	}
 }
 
-// Add a new workout log
-func (s *WorkoutLogService) AddWorkoutLog(log models.WorkoutLog) error {
+// Add a new workout log, dated today where the trainer is unless it has a date. It returns the ID of the log.
+// Logs are only tied to a program by logging its planned workout, see ProgramService.
+func (s *WorkoutLogService) AddWorkoutLog(log models.WorkoutLog) (string, error) {
+	log.ProgramID, log.TemplateID = "", ""
+	return s.addWorkoutLog(log)
+}
+
+// addWorkoutLog stores a new log, clearing the fields the server owns. The program and template it
+// was planned in are kept.
+func (s *WorkoutLogService) addWorkoutLog(log models.WorkoutLog) (string, error) {
+	log.ID, log.DeletedAt = "", nil
+	log.ClientID, log.LastChangeID, log.SyncSeq = "", "", 0
+	if log.Date.IsZero() {
+		var err error
+		if log.Date, err = s.calendar.today(log.TraineeID); err != nil {
+			return "", err
+		}
+	}
+
 	// Set timestamps
 	log.CreatedAt = time.Now()
 	log.UpdatedAt = time.Now()
-	return s.repository.CreateWorkoutLog(log)
+	log.Version = 1
+	id, err := s.repository.CreateWorkoutLog(log)
+	if err != nil {
+		return "", err
+	}
+	s.records.refresh(log.TraineeID, log)
+	return id, nil
+}
+
+// Get a page of the workout logs of a trainee, flagged if they hold a personal record
+func (s *WorkoutLogService) GetWorkoutLogsByTrainee(traineeID string, list models.ListOptions) (models.Page, error) {
+	logs, next, err := s.repository.GetWorkoutLogPage(traineeID, list)
+	if err != nil {
+		return models.Page{}, err
+	}
+	if err := s.records.MarkRecords(logs); err != nil {
+		return models.Page{}, err
+	}
+	return models.Page{Items: logs, NextCursor: next}, nil
 }
 
-// Get all workout logs for a trainee
-func (s *WorkoutLogService) GetWorkoutLogsByTrainee(traineeID string, date string) ([]models.WorkoutLog, error) {
-	return s.repository.GetWorkoutLogsByTrainee(traineeID, date)
+// Get a workout log by ID, flagged if it holds a personal record
+func (s *WorkoutLogService) GetWorkoutLogByID(logID string) (models.WorkoutLog, error) {
+	log, err := s.repository.GetWorkoutLogByID(logID)
+	if err != nil {
+		return log, err
+	}
+	logs := []models.WorkoutLog{log}
+	if err := s.records.MarkRecords(logs); err != nil {
+		return log, err
+	}
+	return logs[0], nil
 }
 
-// Update an existing workout log
-func (s *WorkoutLogService) UpdateWorkoutLog(logID string, update map[string]interface{}) error {
+// Update an existing workout log that is still at the given version
+func (s *WorkoutLogService) UpdateWorkoutLog(logID string, version int64, patch models.WorkoutLogPatch) error {
+	update := models.PatchFields(patch)
 	if len(update) == 0 {
-		return errors.New("update data cannot be empty")
+		return ErrEmptyUpdate
 	}
 
 	// Add an UpdatedAt timestamp to the update data
 	update["updated_at"] = time.Now()
 
+	// The records of the exercises it did before count as much as those it does after
+	before, err := s.repository.GetWorkoutLogByID(logID)
+	if err != nil {
+		return err
+	}
+
 	// Call the repository to apply the update
-	return s.repository.UpdateWorkoutLog(logID, update)
+	if err := s.repository.UpdateWorkoutLog(logID, version, update); err != nil {
+		return err
+	}
+	if after, err := s.repository.GetWorkoutLogByID(logID); err == nil {
+		s.records.refresh(before.TraineeID, before, after)
+	}
+	return nil
 }
 
-// Delete a workout log
-func (s *WorkoutLogService) DeleteWorkoutLog(logID string) error {
-	return s.repository.DeleteWorkoutLog(logID)
+// Move a workout log that is still at the given version to the trash
+func (s *WorkoutLogService) DeleteWorkoutLog(logID string, version int64) error {
+	log, err := s.repository.GetWorkoutLogByID(logID)
+	if err != nil {
+		return err
+	}
+	if err := s.repository.SoftDeleteWorkoutLog(logID, version); err != nil {
+		return err
+	}
+	s.records.refresh(log.TraineeID, log)
+	return nil
 }
 
 
-func (s *WorkoutLogService) GetTraineeProgress(traineeID string) (map[string]interface{}, error) {
-    logs, err := s.repository.GetWorkoutLogsByTrainee(traineeID,"")
-    if err != nil {
-        return nil, err
-    }
-
-    // Initialize stats
-    progress := make(map[string]interface{})
-    exerciseStats := make(map[string]map[string]float64)
-
-    // Calculate metrics for each exercise
-    for _, log := range logs {
-        for _, workout := range log.Workouts {
-            if _, exists := exerciseStats[workout.Exercise]; !exists {
-                exerciseStats[workout.Exercise] = map[string]float64{
-                    "total_weight": 0,
-                    "total_reps":   0,
-                    "entry_count":  0,
-                }
-            }
-            for i, reps := range workout.Reps {
-                exerciseStats[workout.Exercise]["total_weight"] += workout.Weight[i] * float64(reps)
-                exerciseStats[workout.Exercise]["total_reps"] += float64(reps)
-                exerciseStats[workout.Exercise]["entry_count"]++
-            }
-        }
-    }
-
-    // Summarize stats
-    for exercise, stats := range exerciseStats {
-        progress[exercise] = map[string]float64{
-            "average_weight": stats["total_weight"] / stats["entry_count"],
-            "average_reps":   stats["total_reps"] / stats["entry_count"],
-        }
-    }
-
-    return progress, nil
+// GetTraineeProgress sums up each exercise the trainee logged by name with its average load per rep
+// and reps per set, worked out by the same analytics as the progress of the trainee
+func (s *WorkoutLogService) GetTraineeProgress(traineeID string) (map[string]models.ExerciseAverages, error) {
+	logs, err := s.repository.GetWorkoutLogsByTrainee(traineeID, models.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	exercises := analyzeWorkoutLogs(logs, nil, models.Date{}, models.Date{}, models.AnalyticsOptions{}).Exercises
+	progress := make(map[string]models.ExerciseAverages, len(exercises))
+	for _, exercise := range exercises {
+		progress[exercise.Exercise] = models.ExerciseAverages{
+			AverageWeight: exercise.AvgWeight,
+			AverageReps:   exercise.AvgReps,
+		}
+	}
+	return progress, nil
 }
@@ -1,6 +1,11 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
 	"os"
 	"time"
 
@@ -8,6 +13,17 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	AccessTokenTTL  = 15 * time.Minute    // Lifetime of a signed access token
+	RefreshTokenTTL = 30 * 24 * time.Hour // Lifetime of a session's refresh token
+)
+
+// Roles carried in the JWT
+const (
+	RoleTrainer = "trainer"
+	RoleTrainee = "trainee"
+)
+
 // HashPassword hashes a plain-text password
 func HashPassword(password string) (string, error) {
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -20,22 +36,62 @@ func CheckPassword(hashedPassword, plainPassword string) bool {
 	return err == nil
 }
 
+// RandomToken returns a hex encoded string of n cryptographically random bytes
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// RandomCode returns a string of the given length drawn uniformly from alphabet
+func RandomCode(length int, alphabet string) (string, error) {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = alphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// HashToken returns the SHA-256 hex digest of an opaque token so it can be stored safely
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 var jwtKey = []byte(os.Getenv("JWT_SECRET"))
 
+// Identity is who a session and its tokens belong to.
+// Trainers are identified by email, trainees by their trainee ID and owning trainer.
+type Identity struct {
+	Role      string `json:"role" bson:"role"`
+	Email     string `json:"email" bson:"email"`
+	TraineeID string `json:"trainee_id,omitempty" bson:"trainee_id,omitempty"`
+	TrainerID string `json:"trainer_id,omitempty" bson:"trainer_id,omitempty"`
+}
+
 //JWT= header.payload.signature
 // Claims(Payload) structure for JWT
 type Claims struct {
-	Email string `json:"email"`
+	Identity
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT creates a signed token for a given email
-func GenerateJWT(email string) (string, error) {
-	expirationTime := time.Now().Add(7 *24 * time.Hour)
+// GenerateJWT creates a short-lived signed token for an identity, bound to a session (jti)
+func GenerateJWT(identity Identity, sessionID string) (string, error) {
+	now := time.Now()
 	claims := &Claims{
-		Email: email,
+		Identity: identity,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ID:        sessionID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
 		},
 	}
 
@@ -43,17 +99,76 @@ func GenerateJWT(email string) (string, error) {
 	return token.SignedString(jwtKey)
 }
 
-// VerifyJWT validates a token and extracts the claims
+// VerifyJWT validates a token and extracts the claims.
+// Tokens without a session ID (jti) cannot be revoked and are rejected.
 func VerifyJWT(tokenString string) (*Claims, error) {
-    claims := &Claims{}
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if claims.ID == "" {
+		return nil, errors.New("token is not bound to a session")
+	}
+	if claims.Role == "" {
+		claims.Role = RoleTrainer // Tokens issued before roles existed
+	}
+
+	return claims, nil
+}
+
+// ActionClaims is the payload of a single-use token sent by email (password reset, email verification)
+type ActionClaims struct {
+	Email   string `json:"email"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
 
-    token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-        return jwtKey, nil
-    })
+// Action tokens are signed with a key derived per purpose, so they can never pass as access tokens
+// and a verification token cannot be replayed as a reset token.
+func actionKey(purpose string) []byte {
+	sum := sha256.Sum256(append(append([]byte{}, jwtKey...), []byte(":"+purpose)...))
+	return sum[:]
+}
 
-    if err != nil || !token.Valid {
-        return nil, err
-    }
+// GenerateActionToken creates a signed token for the given purpose and token ID (jti)
+func GenerateActionToken(email string, purpose string, tokenID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &ActionClaims{
+		Email:   email,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
 
-    return claims, nil
-}
\ No newline at end of file
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(actionKey(purpose))
+}
+
+// VerifyActionToken checks the signature, expiry and purpose of an action token
+func VerifyActionToken(tokenString string, purpose string) (*ActionClaims, error) {
+	claims := &ActionClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return actionKey(purpose), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.Purpose != purpose || claims.ID == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}